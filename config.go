@@ -45,6 +45,32 @@ const (
 
 	SettingGateway        = "mender-gateway"
 	SettingGatewayDefault = "localhost:9080"
+
+	SettingReaper                = "reaper"
+	SettingReaperInterval        = SettingReaper + ".interval"
+	SettingReaperIntervalDefault = "10m"
+	SettingReaperTimeout         = SettingReaper + ".timeout"
+	SettingReaperTimeoutDefault  = "24h"
+
+	SettingExpiry                = "expiry"
+	SettingExpiryInterval        = SettingExpiry + ".interval"
+	SettingExpiryIntervalDefault = "1m"
+
+	SettingRetention                = "retention"
+	SettingRetentionDays            = SettingRetention + ".days"
+	SettingRetentionDaysDefault     = 0
+	SettingRetentionArchive         = SettingRetention + ".archive"
+	SettingRetentionArchiveDefault  = false
+	SettingRetentionInterval        = SettingRetention + ".interval"
+	SettingRetentionIntervalDefault = "1h"
+
+	SettingWebhooks                   = "webhooks"
+	SettingWebhooksInterval           = SettingWebhooks + ".interval"
+	SettingWebhooksIntervalDefault    = "10s"
+	SettingWebhooksTimeout            = SettingWebhooks + ".timeout"
+	SettingWebhooksTimeoutDefault     = "10s"
+	SettingWebhooksMaxAttempts        = SettingWebhooks + ".max_attempts"
+	SettingWebhooksMaxAttemptsDefault = 10
 )
 
 // ValidateAwsAuth validates configuration of SettingsAwsAuth section if provided.
@@ -90,6 +116,47 @@ func ValidateHttps(c config.ConfigReader) error {
 	return nil
 }
 
+// ValidateReaper validates stale device deployments reaper configuration.
+func ValidateReaper(c config.ConfigReader) error {
+
+	if c.GetDuration(SettingReaperTimeout) > 0 && c.GetDuration(SettingReaperInterval) <= 0 {
+		return fmt.Errorf("Option '%s' has to be positive", SettingReaperInterval)
+	}
+
+	return nil
+}
+
+// ValidateRetention validates finished deployments retention configuration.
+func ValidateRetention(c config.ConfigReader) error {
+
+	days := c.GetInt(SettingRetentionDays)
+	if days < 0 {
+		return fmt.Errorf("Option '%s' can not be negative", SettingRetentionDays)
+	}
+
+	if days > 0 && c.GetDuration(SettingRetentionInterval) <= 0 {
+		return fmt.Errorf("Option '%s' has to be positive", SettingRetentionInterval)
+	}
+
+	return nil
+}
+
+// ValidateWebhooks validates webhook deliveries configuration.
+func ValidateWebhooks(c config.ConfigReader) error {
+
+	for _, key := range []string{SettingWebhooksInterval, SettingWebhooksTimeout} {
+		if c.GetDuration(key) <= 0 {
+			return fmt.Errorf("Option '%s' has to be positive", key)
+		}
+	}
+
+	if c.GetInt(SettingWebhooksMaxAttempts) < 1 {
+		return fmt.Errorf("Option '%s' has to be positive", SettingWebhooksMaxAttempts)
+	}
+
+	return nil
+}
+
 // Generate error with missing reuired option message.
 func MissingOptionError(option string) error {
 	return fmt.Errorf("Required option: '%s'", option)
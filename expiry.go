@@ -0,0 +1,43 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/mendersoftware/go-lib-micro/log"
+)
+
+// DeploymentsExpirer expires pending devices of deployments past their expiry
+// time.
+type DeploymentsExpirer interface {
+	ExpireDeployments() (int, error)
+}
+
+// RunExpiry periodically expires pending devices of deployments which passed
+// their expiry time. Runs until stop is closed.
+func RunExpiry(expirer DeploymentsExpirer, interval time.Duration, stop <-chan struct{}) {
+	l := log.New(log.Ctx{"job": "expiry"})
+
+	runPeriodically(interval, stop, func() {
+		expired, err := expirer.ExpireDeployments()
+		if err != nil {
+			l.Errorf("expiring deployments: %s", err)
+		}
+		if expired != 0 {
+			l.Infof("expired %d device deployments", expired)
+		}
+	})
+}
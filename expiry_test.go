@@ -0,0 +1,34 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+type expirerFunc func() (int, error)
+
+func (f expirerFunc) ExpireDeployments() (int, error) {
+	return f()
+}
+
+func TestRunExpiry(t *testing.T) {
+
+	job := newTestJob()
+	runTestJob(t, "expiry", job, func(stop <-chan struct{}) {
+		RunExpiry(expirerFunc(job.run), time.Millisecond, stop)
+	})
+}
@@ -19,9 +19,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/mendersoftware/go-lib-micro/requestid"
 	"github.com/pkg/errors"
 )
@@ -29,6 +32,13 @@ import (
 // Routes
 const (
 	DevicesInventory string = "/api/0.1.0/devices/%s"
+	DevicesSearch    string = "/api/0.1.0/devices"
+)
+
+// Defaults
+const (
+	// Page size used while listing devices
+	DefaultDevicesPerPage = 500
 )
 
 type Attribute struct {
@@ -98,3 +108,70 @@ func (api *MenderAPI) GetDeviceInventory(ctx context.Context, id DeviceID) (*Dev
 
 	return &device, nil
 }
+
+// GetDevices returns all devices from inventory with attributes matching
+// given values. Walks through all result pages, so attributes can not be
+// named as paging parameters.
+func (api *MenderAPI) GetDevices(ctx context.Context, attributes map[string]string) ([]*Device, error) {
+	devices := []*Device{}
+
+	for page := 1; ; page++ {
+		query := url.Values{}
+		for name, value := range attributes {
+			query.Set(name, value)
+		}
+		query.Set(paging.ParamPage, strconv.Itoa(page))
+		query.Set(paging.ParamPerPage, strconv.Itoa(DefaultDevicesPerPage))
+
+		req, err := http.NewRequest(http.MethodGet, api.uri+DevicesSearch+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "preparing request for devices search")
+		}
+
+		//propagate request id
+		reqId := ctx.Value(requestid.RequestIdHeader)
+		if reqId != nil {
+			req.Header.Set(requestid.RequestIdHeader, reqId.(string))
+		}
+
+		resp, err := api.client.Do(req)
+		if err != nil {
+			return nil, errors.Wrap(err, "sending request for devices search")
+		}
+
+		result, err := api.parseDevicesResponse(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		devices = append(devices, result...)
+
+		if len(result) < DefaultDevicesPerPage {
+			return devices, nil
+		}
+	}
+}
+
+func (api *MenderAPI) parseDevicesResponse(resp *http.Response) ([]*Device, error) {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrap(api.parseErrorResponse(resp.Body), "error server response")
+	}
+
+	var devices []*Device
+	if err := json.NewDecoder(resp.Body).Decode(&devices); err != nil {
+		return nil, errors.Wrap(err, "parsig server response")
+	}
+
+	for _, device := range devices {
+		if device == nil {
+			return nil, errors.New("validating server response: empty device")
+		}
+		if err := device.Validate(); err != nil {
+			return nil, errors.Wrap(err, "validating server response")
+		}
+	}
+
+	return devices, nil
+}
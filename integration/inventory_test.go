@@ -20,6 +20,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -118,3 +119,91 @@ func TestGetDeviceInventory(t *testing.T) {
 	}
 
 }
+
+func TestGetDevices(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		// Input
+		Code  int
+		Total int
+		Body  interface{}
+
+		//Output
+		Count int
+		Err   error
+	}{
+		"internal server error with payload": {
+			Code: http.StatusInternalServerError,
+			Body: struct {
+				Error string `json:"error"`
+			}{Error: "dead db"},
+
+			Err: errors.New("error server response: dead db"),
+		},
+		"success - broken payload": {
+			Code: http.StatusOK,
+			Body: []*Device{&Device{Updated: time.Unix(10, 10)}},
+
+			Err: errors.New("validating server response: ID: non zero value required;"),
+		},
+		"success - no devices": {
+			Code: http.StatusOK,
+		},
+		"success - multiple pages": {
+			Code:  http.StatusOK,
+			Total: DefaultDevicesPerPage + 10,
+
+			Count: DefaultDevicesPerPage + 10,
+		},
+	}
+
+	for caseName, test := range testCases {
+
+		t.Logf("Case: %s\n", caseName)
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, DevicesSearch, r.URL.Path)
+			assert.Equal(t, "rpi3", r.URL.Query().Get("device_type"))
+
+			w.WriteHeader(test.Code)
+
+			body := test.Body
+			if body == nil {
+				// serve requested page of `Total` devices
+				page, err := strconv.Atoi(r.URL.Query().Get("page"))
+				assert.NoError(t, err, "invalid test")
+
+				devices := []*Device{}
+				for i := (page - 1) * DefaultDevicesPerPage; i < test.Total && i < page*DefaultDevicesPerPage; i++ {
+					devices = append(devices, &Device{
+						ID:      DeviceID(strconv.Itoa(i)),
+						Updated: time.Unix(10, 10),
+					})
+				}
+				body = devices
+			}
+
+			payload, err := json.Marshal(body)
+			assert.NoError(t, err, "invalid test")
+
+			_, err = w.Write(payload)
+			assert.NoError(t, err, "invalid test")
+		}))
+		defer ts.Close()
+
+		api, err := NewMenderAPI(ts.URL)
+		assert.NoError(t, err, "api client init")
+
+		devices, err := api.GetDevices(context.TODO(), map[string]string{"device_type": "rpi3"})
+
+		if test.Err != nil {
+			assert.EqualError(t, err, test.Err.Error())
+		} else {
+			assert.NoError(t, err)
+			assert.Len(t, devices, test.Count)
+		}
+	}
+
+}
@@ -29,8 +29,10 @@ func main() {
 
 	var configPath string
 	var printVersion bool
+	var migrate bool
 	flag.StringVar(&configPath, "config", "", "Configuration file path. Supports JSON, TOML, YAML and HCL formatted configs.")
 	flag.BoolVar(&printVersion, "version", false, "Show version")
+	flag.BoolVar(&migrate, "migrate", false, "Migrate data stored by earlier versions and exit")
 
 	flag.Parse()
 
@@ -47,6 +49,13 @@ func main() {
 		l.Fatalf("error loading configuration: %s", err)
 	}
 
+	if migrate {
+		if err := Migrate(configuration); err != nil {
+			l.Fatalf("error migrating data: %s", err)
+		}
+		os.Exit(0)
+	}
+
 	l.Fatal(RunServer(configuration))
 }
 
@@ -81,6 +90,9 @@ func HandleConfigFile(filePath string) (config.ConfigReader, error) {
 	if err := config.ValidateConfig(c,
 		ValidateAwsAuth,
 		ValidateHttps,
+		ValidateReaper,
+		ValidateRetention,
+		ValidateWebhooks,
 	); err != nil {
 		return nil, err
 	}
@@ -94,4 +106,13 @@ func SetDefaultConfigs(config *viper.Viper) {
 	config.SetDefault(SettingAweS3Bucket, SettingAwsS3BucketDefault)
 	config.SetDefault(SettingMongo, SettingMongoDefault)
 	config.SetDefault(SettingGateway, SettingGatewayDefault)
+	config.SetDefault(SettingReaperInterval, SettingReaperIntervalDefault)
+	config.SetDefault(SettingReaperTimeout, SettingReaperTimeoutDefault)
+	config.SetDefault(SettingExpiryInterval, SettingExpiryIntervalDefault)
+	config.SetDefault(SettingRetentionDays, SettingRetentionDaysDefault)
+	config.SetDefault(SettingRetentionArchive, SettingRetentionArchiveDefault)
+	config.SetDefault(SettingRetentionInterval, SettingRetentionIntervalDefault)
+	config.SetDefault(SettingWebhooksInterval, SettingWebhooksIntervalDefault)
+	config.SetDefault(SettingWebhooksTimeout, SettingWebhooksTimeoutDefault)
+	config.SetDefault(SettingWebhooksMaxAttempts, SettingWebhooksMaxAttemptsDefault)
 }
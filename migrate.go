@@ -0,0 +1,45 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"github.com/mendersoftware/go-lib-micro/log"
+	"github.com/pkg/errors"
+	"gopkg.in/mgo.v2"
+
+	"github.com/mendersoftware/deployments/config"
+	deploymentsMongo "github.com/mendersoftware/deployments/resources/deployments/mongo"
+)
+
+// Migrate updates data stored by earlier versions of the service.
+func Migrate(c config.ConfigReader) error {
+
+	l := log.New(log.Ctx{"job": "migrate"})
+
+	dbSession, err := mgo.Dial(c.GetString(SettingMongo))
+	if err != nil {
+		return err
+	}
+	defer dbSession.Close()
+	dbSession.SetSafe(&mgo.Safe{})
+
+	updated, err := deploymentsMongo.NewDeviceDeploymentsStorage(dbSession).MigrateMissingPriority()
+	if err != nil {
+		return errors.Wrap(err, "setting missing device deployment priority")
+	}
+	l.Infof("set default priority on %d device deployments", updated)
+
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testJob counts the calls of a periodic job and fails every second one;
+// errors must not stop the job.
+type testJob struct {
+	calls chan struct{}
+	n     int
+}
+
+func newTestJob() *testJob {
+	return &testJob{calls: make(chan struct{}, 1)}
+}
+
+func (j *testJob) run() (int, error) {
+	select {
+	case j.calls <- struct{}{}:
+	default:
+	}
+	j.n++
+	if j.n%2 == 0 {
+		return 0, errors.New("storage error")
+	}
+	return 1, nil
+}
+
+// runTestJob starts run, waits for 3 calls of job and stops it.
+func runTestJob(t *testing.T, name string, job *testJob, run func(stop <-chan struct{})) {
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		run(stop)
+		close(done)
+	}()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-job.calls:
+		case <-time.After(time.Second):
+			t.Fatalf("%s not running", name)
+		}
+	}
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("%s not stopped", name)
+	}
+}
+
+func TestRunPeriodically(t *testing.T) {
+
+	job := newTestJob()
+	runTestJob(t, "job", job, func(stop <-chan struct{}) {
+		runPeriodically(time.Millisecond, stop, func() { job.run() })
+	})
+}
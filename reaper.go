@@ -0,0 +1,42 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/mendersoftware/go-lib-micro/log"
+)
+
+// StaleDeploymentsFailer fails device deployments stuck in active state.
+type StaleDeploymentsFailer interface {
+	FailStaleDeviceDeployments(timeout time.Duration) (int, error)
+}
+
+// RunReaper periodically fails device deployments that did not change their
+// active status for longer than timeout. Runs until stop is closed.
+func RunReaper(failer StaleDeploymentsFailer, interval, timeout time.Duration, stop <-chan struct{}) {
+	l := log.New(log.Ctx{"job": "reaper"})
+
+	runPeriodically(interval, stop, func() {
+		failed, err := failer.FailStaleDeviceDeployments(timeout)
+		if err != nil {
+			l.Errorf("failing stale device deployments: %s", err)
+		}
+		if failed != 0 {
+			l.Infof("failed %d stale device deployments", failed)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type failerFunc func(timeout time.Duration) (int, error)
+
+func (f failerFunc) FailStaleDeviceDeployments(timeout time.Duration) (int, error) {
+	return f(timeout)
+}
+
+func TestRunReaper(t *testing.T) {
+
+	job := newTestJob()
+	failer := failerFunc(func(timeout time.Duration) (int, error) {
+		if timeout != time.Hour {
+			t.Errorf("unexpected timeout: %s", timeout)
+		}
+		return job.run()
+	})
+
+	runTestJob(t, "reaper", job, func(stop <-chan struct{}) {
+		RunReaper(failer, time.Millisecond, time.Hour, stop)
+	})
+}
+
+func TestValidateReaper(t *testing.T) {
+
+	c := viper.New()
+	SetDefaultConfigs(c)
+	if err := ValidateReaper(c); err != nil {
+		t.FailNow()
+	}
+
+	c.Set(SettingReaperInterval, "0s")
+	if err := ValidateReaper(c); err == nil {
+		t.FailNow()
+	}
+
+	// disabled reaper does not need interval
+	c.Set(SettingReaperTimeout, "0s")
+	if err := ValidateReaper(c); err != nil {
+		t.FailNow()
+	}
+}
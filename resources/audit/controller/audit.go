@@ -0,0 +1,165 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package controller
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/mendersoftware/deployments/resources/audit"
+	"github.com/mendersoftware/deployments/utils/identity"
+	"github.com/mendersoftware/deployments/utils/paging"
+	"github.com/mendersoftware/deployments/utils/params"
+	"github.com/mendersoftware/go-lib-micro/requestid"
+	"github.com/mendersoftware/go-lib-micro/requestlog"
+	"github.com/pkg/errors"
+)
+
+type AuditController struct {
+	view  RESTView
+	model AuditModel
+}
+
+func NewAuditController(model AuditModel, view RESTView) *AuditController {
+	return &AuditController{
+		model: model,
+		view:  view,
+	}
+}
+
+// Audit wraps handler of a mutating management call, so that every call is
+// recorded in audit log once it's responded to. Target is the resource
+// identified by "id" path parameter or, for creations, the resource created.
+func (c *AuditController) Audit(action string, handler rest.HandlerFunc) rest.HandlerFunc {
+	return func(w rest.ResponseWriter, r *rest.Request) {
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		handler(recorder, r)
+
+		// actor is unknown if the request carries no identity
+		var actor string
+		if idata, err := identity.ExtractIdentityFromHeaders(r.Header); err == nil {
+			actor = idata.Subject
+		}
+
+		target := r.PathParam("id")
+		if location := w.Header().Get("Location"); target == "" && location != "" {
+			target = path.Base(location)
+		}
+
+		entry := audit.NewEntry(actor, action, target, requestid.GetReqId(r), recorder.Status())
+		if err := c.model.Record(entry); err != nil {
+			// the call is already responded to, audit failure can only be logged
+			l := requestlog.GetRequestLogger(r.Env)
+			l.Errorf("recording audit entry of %s: %v", action, err)
+		}
+	}
+}
+
+// ListEntries returns audit entries matching the query, newest first.
+func (c *AuditController) ListEntries(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	query, err := ParseQuery(r.URL.Query())
+	if err != nil {
+		c.view.RenderError(w, r, err, http.StatusBadRequest, l)
+		return
+	}
+
+	list, total, err := c.model.ListEntries(query)
+	if err != nil {
+		c.view.RenderInternalError(w, r, err, l)
+		return
+	}
+
+	c.view.RenderSuccessGetPage(w, r, list, query.Paging, total)
+}
+
+// ParseQuery reads audit entries filter and paging parameters.
+func ParseQuery(vals url.Values) (audit.Query, error) {
+	query := audit.Query{
+		Actor:    vals.Get("actor"),
+		Action:   vals.Get("action"),
+		TargetID: vals.Get("target_id"),
+		Outcome:  vals.Get("outcome"),
+	}
+
+	if query.Action != "" && !isOneOf(query.Action, audit.Actions()) {
+		return query, errors.Errorf("unknown action %s, use one of: %s",
+			query.Action, strings.Join(audit.Actions(), ", "))
+	}
+
+	outcomes := []string{audit.OutcomeSuccess, audit.OutcomeFailure}
+	if query.Outcome != "" && !isOneOf(query.Outcome, outcomes) {
+		return query, errors.Errorf("unknown outcome %s, use one of: %s",
+			query.Outcome, strings.Join(outcomes, ", "))
+	}
+
+	var err error
+	if query.CreatedAfter, err = params.ParseTime(vals, "created_after"); err != nil {
+		return query, err
+	}
+	if query.CreatedBefore, err = params.ParseTime(vals, "created_before"); err != nil {
+		return query, err
+	}
+
+	page, err := paging.ParsePagedQuery(vals)
+	if err != nil {
+		return query, err
+	}
+	query.Paging = page
+
+	return query, nil
+}
+
+func isOneOf(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// statusRecorder remembers response status written by the wrapped handler.
+type statusRecorder struct {
+	rest.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) WriteJson(v interface{}) error {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.WriteJson(v)
+}
+
+// Status returns written response status, 200 if the handler wrote none.
+func (s *statusRecorder) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
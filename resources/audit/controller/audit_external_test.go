@@ -0,0 +1,275 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package controller_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/ant0ine/go-json-rest/rest/test"
+	"github.com/mendersoftware/deployments/resources/audit"
+	. "github.com/mendersoftware/deployments/resources/audit/controller"
+	"github.com/mendersoftware/deployments/resources/audit/controller/mocks"
+	"github.com/mendersoftware/deployments/resources/images/view"
+	"github.com/mendersoftware/deployments/utils/paging"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	"github.com/mendersoftware/go-lib-micro/requestid"
+	"github.com/mendersoftware/go-lib-micro/requestlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func makeAuthHeader(claim string) string {
+	return fmt.Sprintf("Bearer foo.%s.bar",
+		base64.StdEncoding.EncodeToString([]byte(claim)))
+}
+
+func makeApi(router rest.App) *rest.Api {
+	api := rest.NewApi()
+	api.Use(
+		&requestlog.RequestLogMiddleware{
+			BaseLogger: &logrus.Logger{Out: ioutil.Discard},
+		},
+		&requestid.RequestIdMiddleware{},
+	)
+	api.SetApp(router)
+	return api
+}
+
+func TestControllerAudit(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputRoute       string
+		InputURL         string
+		InputAuth        string
+		InputHandler     rest.HandlerFunc
+		InputRecordError error
+
+		OutputStatus   int
+		OutputActor    string
+		OutputTargetID string
+		OutputOutcome  string
+	}{
+		"created": {
+			InputRoute: "/r",
+			InputURL:   "http://localhost/r",
+			InputAuth:  makeAuthHeader(`{"sub": "user-1"}`),
+			InputHandler: func(w rest.ResponseWriter, r *rest.Request) {
+				w.Header().Add("Location", "./r/123")
+				w.WriteHeader(http.StatusCreated)
+			},
+			OutputStatus:   http.StatusCreated,
+			OutputActor:    "user-1",
+			OutputTargetID: "123",
+			OutputOutcome:  audit.OutcomeSuccess,
+		},
+		"target from path": {
+			InputRoute: "/r/:id",
+			InputURL:   "http://localhost/r/456",
+			InputAuth:  makeAuthHeader(`{"sub": "user-1"}`),
+			InputHandler: func(w rest.ResponseWriter, r *rest.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			OutputStatus:   http.StatusNoContent,
+			OutputActor:    "user-1",
+			OutputTargetID: "456",
+			OutputOutcome:  audit.OutcomeSuccess,
+		},
+		"failed, no identity": {
+			InputRoute: "/r/:id",
+			InputURL:   "http://localhost/r/456",
+			InputHandler: func(w rest.ResponseWriter, r *rest.Request) {
+				w.WriteHeader(http.StatusConflict)
+				w.WriteJson(map[string]string{"error": "conflict"})
+			},
+			OutputStatus:   http.StatusConflict,
+			OutputTargetID: "456",
+			OutputOutcome:  audit.OutcomeFailure,
+		},
+		"implicit status": {
+			InputRoute: "/r/:id",
+			InputURL:   "http://localhost/r/456",
+			InputHandler: func(w rest.ResponseWriter, r *rest.Request) {
+				w.WriteJson(map[string]string{})
+			},
+			OutputStatus:   http.StatusOK,
+			OutputTargetID: "456",
+			OutputOutcome:  audit.OutcomeSuccess,
+		},
+		"record error does not change response": {
+			InputRoute: "/r/:id",
+			InputURL:   "http://localhost/r/456",
+			InputHandler: func(w rest.ResponseWriter, r *rest.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			InputRecordError: errors.New("storage error"),
+			OutputStatus:     http.StatusNoContent,
+			OutputTargetID:   "456",
+			OutputOutcome:    audit.OutcomeSuccess,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		var recorded *audit.Entry
+		model := new(mocks.AuditModel)
+		model.On("Record", mock.AnythingOfType("*audit.Entry")).
+			Run(func(args mock.Arguments) {
+				recorded = args.Get(0).(*audit.Entry)
+			}).
+			Return(testCase.InputRecordError)
+
+		c := NewAuditController(model, new(view.RESTView))
+
+		router, err := rest.MakeRouter(
+			rest.Post(testCase.InputRoute, c.Audit(audit.ActionDeploymentCreate, testCase.InputHandler)))
+		assert.NoError(t, err)
+
+		req := test.MakeSimpleRequest("POST", testCase.InputURL, nil)
+		req.Header.Add(requestid.RequestIdHeader, "test")
+		if testCase.InputAuth != "" {
+			req.Header.Add("Authorization", testCase.InputAuth)
+		}
+		test.RunRequest(t, makeApi(router).MakeHandler(), req).
+			CodeIs(testCase.OutputStatus)
+
+		if assert.NotNil(t, recorded) {
+			assert.Equal(t, testCase.OutputActor, recorded.Actor)
+			assert.Equal(t, audit.ActionDeploymentCreate, recorded.Action)
+			assert.Equal(t, testCase.OutputTargetID, recorded.TargetID)
+			assert.Equal(t, "test", recorded.RequestID)
+			assert.Equal(t, testCase.OutputStatus, recorded.Status)
+			assert.Equal(t, testCase.OutputOutcome, recorded.Outcome)
+		}
+	}
+}
+
+func TestControllerListEntries(t *testing.T) {
+
+	t.Parallel()
+
+	entry := audit.NewEntry("user-1", audit.ActionArtifactEdit, "123", "test", http.StatusNoContent)
+
+	model := new(mocks.AuditModel)
+	model.On("ListEntries", audit.Query{Actor: "user-1", Paging: *paging.NewQuery()}).
+		Return([]*audit.Entry{entry}, 1, nil)
+	model.On("ListEntries", audit.Query{Paging: *paging.NewQuery()}).
+		Return(nil, 0, errors.New("model error"))
+
+	router, err := rest.MakeRouter(
+		rest.Get("/api/0.0.1/audit", NewAuditController(model, new(view.RESTView)).ListEntries))
+	assert.NoError(t, err)
+	api := makeApi(router)
+
+	recorded := test.RunRequest(t, api.MakeHandler(),
+		test.MakeSimpleRequest("GET", "http://localhost/api/0.0.1/audit?actor=user-1", nil))
+	recorded.CodeIs(http.StatusOK)
+	recorded.HeaderIs(paging.HttpHeaderTotalCount, "1")
+
+	var list []*audit.Entry
+	assert.NoError(t, recorded.DecodeJsonPayload(&list))
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, entry.Id, list[0].Id)
+		assert.Equal(t, entry.Action, list[0].Action)
+	}
+
+	recorded = test.RunRequest(t, api.MakeHandler(),
+		test.MakeSimpleRequest("GET", "http://localhost/api/0.0.1/audit", nil))
+	recorded.CodeIs(http.StatusInternalServerError)
+
+	recorded = test.RunRequest(t, api.MakeHandler(),
+		test.MakeSimpleRequest("GET", "http://localhost/api/0.0.1/audit?outcome=maybe", nil))
+	recorded.CodeIs(http.StatusBadRequest)
+}
+
+func TestParseQuery(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputValues url.Values
+
+		OutputQuery audit.Query
+		OutputError error
+	}{
+		"empty": {
+			InputValues: url.Values{},
+			OutputQuery: audit.Query{Paging: *paging.NewQuery()},
+		},
+		"all filters": {
+			InputValues: url.Values{
+				"actor":          []string{"user-1"},
+				"action":         []string{audit.ActionDeploymentDelete},
+				"target_id":      []string{"123"},
+				"outcome":        []string{audit.OutcomeFailure},
+				"created_after":  []string{"2017-01-01T00:00:00Z"},
+				"created_before": []string{"2017-02-01T00:00:00Z"},
+				"page":           []string{"2"},
+				"per_page":       []string{"10"},
+			},
+			OutputQuery: audit.Query{
+				Actor:         "user-1",
+				Action:        audit.ActionDeploymentDelete,
+				TargetID:      "123",
+				Outcome:       audit.OutcomeFailure,
+				CreatedAfter:  TimeToPointer(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)),
+				CreatedBefore: TimeToPointer(time.Date(2017, 2, 1, 0, 0, 0, 0, time.UTC)),
+				Paging:        paging.Query{Page: 2, PerPage: 10},
+			},
+		},
+		"unknown action": {
+			InputValues: url.Values{"action": []string{"foo"}},
+			OutputError: errors.New("unknown action foo, use one of: " +
+				"deployment_create, deployment_retry, deployment_status_change, deployment_delete, " +
+				"device_deployment_abort, artifact_upload, artifact_edit, artifact_delete, " +
+				"webhook_create, webhook_delete"),
+		},
+		"unknown outcome": {
+			InputValues: url.Values{"outcome": []string{"maybe"}},
+			OutputError: errors.New("unknown outcome maybe, use one of: success, failure"),
+		},
+		"invalid time": {
+			InputValues: url.Values{"created_after": []string{"yesterday"}},
+			OutputError: errors.New("invalid created_after time yesterday, RFC 3339 format expected"),
+		},
+		"invalid page": {
+			InputValues: url.Values{"page": []string{"0"}},
+			OutputError: paging.ErrInvalidPage,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		query, err := ParseQuery(testCase.InputValues)
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.OutputQuery, query)
+		}
+	}
+}
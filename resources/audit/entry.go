@@ -0,0 +1,116 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package audit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mendersoftware/deployments/utils/paging"
+	"github.com/satori/go.uuid"
+)
+
+// Audited actions
+const (
+	ActionDeploymentCreate       = "deployment_create"
+	ActionDeploymentRetry        = "deployment_retry"
+	ActionDeploymentStatusChange = "deployment_status_change"
+	ActionDeploymentDelete       = "deployment_delete"
+	ActionDeviceDeploymentAbort  = "device_deployment_abort"
+	ActionArtifactUpload         = "artifact_upload"
+	ActionArtifactEdit           = "artifact_edit"
+	ActionArtifactDelete         = "artifact_delete"
+	ActionWebhookCreate          = "webhook_create"
+	ActionWebhookDelete          = "webhook_delete"
+)
+
+// Actions returns all audited actions.
+func Actions() []string {
+	return []string{
+		ActionDeploymentCreate,
+		ActionDeploymentRetry,
+		ActionDeploymentStatusChange,
+		ActionDeploymentDelete,
+		ActionDeviceDeploymentAbort,
+		ActionArtifactUpload,
+		ActionArtifactEdit,
+		ActionArtifactDelete,
+		ActionWebhookCreate,
+		ActionWebhookDelete,
+	}
+}
+
+// Outcomes of audited actions
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Entry records single management action.
+type Entry struct {
+	Id string `json:"id" bson:"_id"`
+
+	// Subject of the identity the action was requested with, empty if unknown
+	Actor string `json:"actor"`
+
+	Action string `json:"action"`
+
+	// ID of the resource acted upon, empty if not known (e.g. rejected creation)
+	TargetID string `json:"target_id"`
+
+	RequestID string `json:"request_id"`
+
+	Created time.Time `json:"created"`
+
+	Outcome string `json:"outcome"`
+
+	// HTTP status the action was responded with
+	Status int `json:"status"`
+}
+
+// NewEntry creates audit entry of the action, outcome is derived from
+// response status.
+func NewEntry(actor, action, targetID, requestID string, status int) *Entry {
+
+	outcome := OutcomeSuccess
+	if status >= http.StatusBadRequest {
+		outcome = OutcomeFailure
+	}
+
+	return &Entry{
+		Id:        uuid.NewV4().String(),
+		Actor:     actor,
+		Action:    action,
+		TargetID:  targetID,
+		RequestID: requestID,
+		Created:   time.Now(),
+		Outcome:   outcome,
+		Status:    status,
+	}
+}
+
+// Query selects audit entries, empty fields match any entry.
+type Query struct {
+	Actor    string
+	Action   string
+	TargetID string
+	Outcome  string
+	// match entries created at or after this time
+	CreatedAfter *time.Time
+	// match entries created before this time
+	CreatedBefore *time.Time
+	// page of matching entries, newest first
+	Paging paging.Query
+}
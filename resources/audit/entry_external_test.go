@@ -0,0 +1,67 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package audit_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/mendersoftware/deployments/resources/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEntry(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputStatus int
+
+		OutputOutcome string
+	}{
+		"created": {
+			InputStatus:   http.StatusCreated,
+			OutputOutcome: OutcomeSuccess,
+		},
+		"no content": {
+			InputStatus:   http.StatusNoContent,
+			OutputOutcome: OutcomeSuccess,
+		},
+		"bad request": {
+			InputStatus:   http.StatusBadRequest,
+			OutputOutcome: OutcomeFailure,
+		},
+		"internal error": {
+			InputStatus:   http.StatusInternalServerError,
+			OutputOutcome: OutcomeFailure,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		entry := NewEntry("user", ActionDeploymentCreate, "123", "req", testCase.InputStatus)
+
+		assert.NotEmpty(t, entry.Id)
+		assert.Equal(t, "user", entry.Actor)
+		assert.Equal(t, ActionDeploymentCreate, entry.Action)
+		assert.Equal(t, "123", entry.TargetID)
+		assert.Equal(t, "req", entry.RequestID)
+		assert.Equal(t, testCase.InputStatus, entry.Status)
+		assert.Equal(t, testCase.OutputOutcome, entry.Outcome)
+		assert.WithinDuration(t, time.Now(), entry.Created, time.Second)
+	}
+}
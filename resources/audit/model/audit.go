@@ -0,0 +1,56 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"github.com/mendersoftware/deployments/resources/audit"
+	"github.com/pkg/errors"
+)
+
+type AuditModel struct {
+	auditStorage AuditStorage
+}
+
+func NewAuditModel(auditStorage AuditStorage) *AuditModel {
+	return &AuditModel{
+		auditStorage: auditStorage,
+	}
+}
+
+// Record stores audit entry.
+func (a *AuditModel) Record(entry *audit.Entry) error {
+
+	if err := a.auditStorage.Insert(entry); err != nil {
+		return errors.Wrap(err, "Storing audit entry")
+	}
+
+	return nil
+}
+
+// ListEntries returns a page of audit entries matching the query, newest
+// first, and total number of matching entries.
+func (a *AuditModel) ListEntries(query audit.Query) ([]*audit.Entry, int, error) {
+
+	list, total, err := a.auditStorage.Find(query)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "Searching for audit entries")
+	}
+
+	if list == nil {
+		return make([]*audit.Entry, 0), total, nil
+	}
+
+	return list, total, nil
+}
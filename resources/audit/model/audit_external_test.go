@@ -0,0 +1,114 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/mendersoftware/deployments/resources/audit"
+	. "github.com/mendersoftware/deployments/resources/audit/model"
+	"github.com/mendersoftware/deployments/resources/audit/model/mocks"
+	"github.com/mendersoftware/deployments/utils/paging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditModelRecord(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputInsertError error
+
+		OutputError error
+	}{
+		"storage error": {
+			InputInsertError: errors.New("storage error"),
+			OutputError:      errors.New("Storing audit entry: storage error"),
+		},
+		"recorded": {},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		entry := audit.NewEntry("user", audit.ActionArtifactDelete, "123", "req", http.StatusNoContent)
+
+		storage := new(mocks.AuditStorage)
+		storage.On("Insert", entry).
+			Return(testCase.InputInsertError)
+
+		err := NewAuditModel(storage).Record(entry)
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+		storage.AssertCalled(t, "Insert", entry)
+	}
+}
+
+func TestAuditModelListEntries(t *testing.T) {
+
+	t.Parallel()
+
+	entry := audit.NewEntry("user", audit.ActionArtifactDelete, "123", "req", http.StatusNoContent)
+
+	testCases := map[string]struct {
+		InputQuery     audit.Query
+		InputList      []*audit.Entry
+		InputTotal     int
+		InputFindError error
+
+		OutputList  []*audit.Entry
+		OutputTotal int
+		OutputError error
+	}{
+		"storage error": {
+			InputQuery:     audit.Query{Paging: *paging.NewQuery()},
+			InputFindError: errors.New("storage error"),
+			OutputError:    errors.New("Searching for audit entries: storage error"),
+		},
+		"nothing found": {
+			InputQuery: audit.Query{Actor: "nobody", Paging: *paging.NewQuery()},
+			OutputList: []*audit.Entry{},
+		},
+		"found": {
+			InputQuery:  audit.Query{Actor: "user", Paging: *paging.NewQuery()},
+			InputList:   []*audit.Entry{entry},
+			InputTotal:  21,
+			OutputList:  []*audit.Entry{entry},
+			OutputTotal: 21,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		storage := new(mocks.AuditStorage)
+		storage.On("Find", testCase.InputQuery).
+			Return(testCase.InputList, testCase.InputTotal, testCase.InputFindError)
+
+		list, total, err := NewAuditModel(storage).ListEntries(testCase.InputQuery)
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.OutputList, list)
+			assert.Equal(t, testCase.OutputTotal, total)
+		}
+	}
+}
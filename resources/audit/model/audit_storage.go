@@ -0,0 +1,32 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"errors"
+
+	"github.com/mendersoftware/deployments/resources/audit"
+)
+
+// Common errors for interface AuditStorage
+var (
+	ErrAuditStorageInvalidEntry = errors.New("Invalid audit entry")
+)
+
+// AuditStorage allows to store and search audit entries
+type AuditStorage interface {
+	Insert(entry *audit.Entry) error
+	Find(query audit.Query) ([]*audit.Entry, int, error)
+}
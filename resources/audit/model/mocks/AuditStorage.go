@@ -0,0 +1,69 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mocks
+
+import (
+	"github.com/mendersoftware/deployments/resources/audit"
+	"github.com/stretchr/testify/mock"
+)
+
+// AuditStorage is an autogenerated mock type for the AuditStorage type
+type AuditStorage struct {
+	mock.Mock
+}
+
+// Find provides a mock function with given fields: query
+func (_m *AuditStorage) Find(query audit.Query) ([]*audit.Entry, int, error) {
+	ret := _m.Called(query)
+
+	var r0 []*audit.Entry
+	if rf, ok := ret.Get(0).(func(audit.Query) []*audit.Entry); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*audit.Entry)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(audit.Query) int); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(audit.Query) error); ok {
+		r2 = rf(query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Insert provides a mock function with given fields: entry
+func (_m *AuditStorage) Insert(entry *audit.Entry) error {
+	ret := _m.Called(entry)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*audit.Entry) error); ok {
+		r0 = rf(entry)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
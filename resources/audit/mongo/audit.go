@@ -0,0 +1,158 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mongo
+
+import (
+	"github.com/asaskevich/govalidator"
+	"github.com/mendersoftware/deployments/resources/audit"
+	"github.com/mendersoftware/deployments/resources/audit/model"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Database KEYS
+const (
+	// Need to be kept in sync with Entry structure field names
+	StorageKeyAuditId       = "_id"
+	StorageKeyAuditActor    = "actor"
+	StorageKeyAuditAction   = "action"
+	StorageKeyAuditTargetID = "targetid"
+	StorageKeyAuditOutcome  = "outcome"
+	StorageKeyAuditCreated  = "created"
+)
+
+// Indexes
+const (
+	IndexAuditCreatedStr       = "auditCreatedIndex"
+	IndexAuditActorCreatedStr  = "auditActorAndCreatedIndex"
+	IndexAuditTargetCreatedStr = "auditTargetAndCreatedIndex"
+)
+
+// Database
+const (
+	DatabaseName    = "deployment_service"
+	CollectionAudit = "audit"
+)
+
+// AuditStorage is a data layer for audit entries based on MongoDB
+// Implements model.AuditStorage
+type AuditStorage struct {
+	session *mgo.Session
+}
+
+// NewAuditStorage new data layer object
+func NewAuditStorage(session *mgo.Session) *AuditStorage {
+
+	return &AuditStorage{
+		session: session,
+	}
+}
+
+// IndexStorage set required indexes.
+// * Entries by creation time, for unfiltered listing.
+// * Entries of an actor and of a target by creation time.
+func (a *AuditStorage) IndexStorage() error {
+
+	session := a.session.Copy()
+	defer session.Close()
+
+	indexes := []mgo.Index{
+		{
+			Key:        []string{"-" + StorageKeyAuditCreated},
+			Name:       IndexAuditCreatedStr,
+			Background: false,
+		},
+		{
+			Key:        []string{StorageKeyAuditActor, "-" + StorageKeyAuditCreated},
+			Name:       IndexAuditActorCreatedStr,
+			Background: false,
+		},
+		{
+			Key:        []string{StorageKeyAuditTargetID, "-" + StorageKeyAuditCreated},
+			Name:       IndexAuditTargetCreatedStr,
+			Background: false,
+		},
+	}
+
+	for _, index := range indexes {
+		if err := session.DB(DatabaseName).C(CollectionAudit).EnsureIndex(index); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Insert persists object
+func (a *AuditStorage) Insert(entry *audit.Entry) error {
+
+	if entry == nil || govalidator.IsNull(entry.Id) {
+		return model.ErrAuditStorageInvalidEntry
+	}
+
+	session := a.session.Copy()
+	defer session.Close()
+
+	return session.DB(DatabaseName).C(CollectionAudit).Insert(entry)
+}
+
+// Find lists a page of audit entries matching the query, newest first,
+// together with total number of matching entries.
+func (a *AuditStorage) Find(match audit.Query) ([]*audit.Entry, int, error) {
+
+	session := a.session.Copy()
+	defer session.Close()
+
+	query := bson.M{}
+	if match.Actor != "" {
+		query[StorageKeyAuditActor] = match.Actor
+	}
+	if match.Action != "" {
+		query[StorageKeyAuditAction] = match.Action
+	}
+	if match.TargetID != "" {
+		query[StorageKeyAuditTargetID] = match.TargetID
+	}
+	if match.Outcome != "" {
+		query[StorageKeyAuditOutcome] = match.Outcome
+	}
+
+	created := bson.M{}
+	if match.CreatedAfter != nil {
+		created["$gte"] = *match.CreatedAfter
+	}
+	if match.CreatedBefore != nil {
+		created["$lt"] = *match.CreatedBefore
+	}
+	if len(created) != 0 {
+		query[StorageKeyAuditCreated] = created
+	}
+
+	total, err := session.DB(DatabaseName).C(CollectionAudit).Find(query).Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var list []*audit.Entry
+	err = session.DB(DatabaseName).C(CollectionAudit).Find(query).
+		Sort("-"+StorageKeyAuditCreated, StorageKeyAuditId).
+		Skip(match.Paging.Skip()).Limit(match.Paging.PerPage).
+		All(&list)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return list, total, nil
+}
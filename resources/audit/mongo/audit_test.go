@@ -0,0 +1,110 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mongo_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mendersoftware/deployments/resources/audit"
+	"github.com/mendersoftware/deployments/resources/audit/model"
+	. "github.com/mendersoftware/deployments/resources/audit/mongo"
+	"github.com/mendersoftware/deployments/utils/paging"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditStorageFind(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestAuditStorageFind in short mode.")
+	}
+
+	db.Wipe()
+	session := db.Session()
+	defer session.Close()
+
+	store := NewAuditStorage(session)
+	assert.NoError(t, store.IndexStorage())
+
+	now := time.Now().Round(time.Millisecond)
+
+	newEntry := func(actor, action, target string, status int, age time.Duration) *audit.Entry {
+		entry := audit.NewEntry(actor, action, target, "req", status)
+		entry.Created = now.Add(-age)
+		return entry
+	}
+
+	created := newEntry("alice", audit.ActionDeploymentCreate, "1", http.StatusCreated, 3*time.Hour)
+	aborted := newEntry("alice", audit.ActionDeploymentStatusChange, "1", http.StatusNoContent, 2*time.Hour)
+	rejected := newEntry("bob", audit.ActionArtifactDelete, "2", http.StatusConflict, time.Hour)
+	deleted := newEntry("bob", audit.ActionArtifactDelete, "2", http.StatusNoContent, 0)
+
+	assert.EqualError(t, store.Insert(nil), model.ErrAuditStorageInvalidEntry.Error())
+	for _, entry := range []*audit.Entry{created, aborted, rejected, deleted} {
+		assert.NoError(t, store.Insert(entry))
+	}
+
+	testCases := map[string]struct {
+		InputQuery audit.Query
+
+		OutputList  []*audit.Entry
+		OutputTotal int
+	}{
+		"all, newest first": {
+			InputQuery:  audit.Query{Paging: *paging.NewQuery()},
+			OutputList:  []*audit.Entry{deleted, rejected, aborted, created},
+			OutputTotal: 4,
+		},
+		"page": {
+			InputQuery:  audit.Query{Paging: paging.Query{Page: 2, PerPage: 3}},
+			OutputList:  []*audit.Entry{created},
+			OutputTotal: 4,
+		},
+		"actor": {
+			InputQuery:  audit.Query{Actor: "alice", Paging: *paging.NewQuery()},
+			OutputList:  []*audit.Entry{aborted, created},
+			OutputTotal: 2,
+		},
+		"action and outcome": {
+			InputQuery: audit.Query{
+				Action:  audit.ActionArtifactDelete,
+				Outcome: audit.OutcomeFailure,
+				Paging:  *paging.NewQuery(),
+			},
+			OutputList:  []*audit.Entry{rejected},
+			OutputTotal: 1,
+		},
+		"target and time range": {
+			InputQuery: audit.Query{
+				TargetID:      "1",
+				CreatedAfter:  TimeToPointer(now.Add(-2 * time.Hour)),
+				CreatedBefore: TimeToPointer(now),
+				Paging:        *paging.NewQuery(),
+			},
+			OutputList:  []*audit.Entry{aborted},
+			OutputTotal: 1,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		list, total, err := store.Find(testCase.InputQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.OutputTotal, total)
+		assert.Equal(t, testCase.OutputList, list)
+	}
+}
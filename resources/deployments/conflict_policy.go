@@ -0,0 +1,74 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Conflict policies, deciding what happens when deployment targets devices
+// which already have an active deployment
+const (
+	// New deployment waits until devices finish their older deployments
+	ConflictPolicyQueue = "queue"
+	// Older pending deployments of the devices are aborted
+	ConflictPolicySupersede = "supersede"
+	// Deployment is not created
+	ConflictPolicyReject = "reject"
+)
+
+// Errors
+var (
+	ErrInvalidConflictPolicy = errors.New("Conflict policy has to be one of queue, supersede or reject")
+)
+
+// ValidateConflictPolicy checks if conflict policy is known, empty policy
+// stands for queue.
+func ValidateConflictPolicy(policy string) error {
+	switch policy {
+	case "", ConflictPolicyQueue, ConflictPolicySupersede, ConflictPolicyReject:
+		return nil
+	}
+	return ErrInvalidConflictPolicy
+}
+
+// ConflictError is returned when deployment with reject conflict policy
+// targets devices which already have an active deployment.
+type ConflictError struct {
+	// Conflicting devices, sorted
+	Devices []string
+}
+
+// NewConflictError creates ConflictError listing devices of given device
+// deployments, each device once.
+func NewConflictError(deviceDeployments []*DeviceDeployment) *ConflictError {
+	seen := map[string]bool{}
+	devices := []string{}
+	for _, deviceDeployment := range deviceDeployments {
+		id := *deviceDeployment.DeviceId
+		if !seen[id] {
+			seen[id] = true
+			devices = append(devices, id)
+		}
+	}
+	sort.Strings(devices)
+	return &ConflictError{Devices: devices}
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%d device(s) already have an active deployment", len(e.Devices))
+}
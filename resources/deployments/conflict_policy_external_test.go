@@ -0,0 +1,72 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments_test
+
+import (
+	"testing"
+
+	. "github.com/mendersoftware/deployments/resources/deployments"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConflictPolicy(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]error{
+		"":                      nil,
+		ConflictPolicyQueue:     nil,
+		ConflictPolicySupersede: nil,
+		ConflictPolicyReject:    nil,
+		"replace":               ErrInvalidConflictPolicy,
+	}
+
+	for policy, expected := range testCases {
+		t.Logf("testing case '%s'", policy)
+
+		assert.Equal(t, expected, ValidateConflictPolicy(policy))
+	}
+}
+
+func TestDeploymentConstructorValidateConflictPolicy(t *testing.T) {
+
+	t.Parallel()
+
+	constructor := NewDeploymentConstructor()
+	constructor.Name = StringToPointer("foo")
+	constructor.ArtifactName = StringToPointer("bar")
+	constructor.Devices = []string{"a"}
+
+	constructor.ConflictPolicy = ConflictPolicyReject
+	assert.NoError(t, constructor.Validate())
+
+	constructor.ConflictPolicy = "replace"
+	assert.EqualError(t, constructor.Validate(), ErrInvalidConflictPolicy.Error())
+}
+
+func TestNewConflictError(t *testing.T) {
+
+	t.Parallel()
+
+	err := NewConflictError([]*DeviceDeployment{
+		NewDeviceDeployment("b", "1"),
+		NewDeviceDeployment("a", "1"),
+		NewDeviceDeployment("b", "2"),
+	})
+
+	assert.Equal(t, []string{"a", "b"}, err.Devices)
+	assert.EqualError(t, err, "2 device(s) already have an active deployment")
+}
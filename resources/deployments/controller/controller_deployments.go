@@ -20,11 +20,14 @@ import (
 	"github.com/asaskevich/govalidator"
 	"github.com/mendersoftware/deployments/resources/deployments"
 	"github.com/mendersoftware/deployments/utils/identity"
+	"github.com/mendersoftware/deployments/utils/paging"
+	"github.com/mendersoftware/deployments/utils/params"
 	"github.com/mendersoftware/go-lib-micro/requestid"
 	"github.com/mendersoftware/go-lib-micro/requestlog"
 	"github.com/pkg/errors"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 // Errors
@@ -48,6 +51,16 @@ func NewDeploymentsController(model DeploymentsModel, view RESTView) *Deployment
 	}
 }
 
+// requestContext carries request ID and identity of the requesting user, if
+// known, to the model.
+func requestContext(r *rest.Request) context.Context {
+	ctx := context.WithValue(context.Background(), requestid.RequestIdHeader, requestid.GetReqId(r))
+	if idata, err := identity.ExtractIdentityFromHeaders(r.Header); err == nil {
+		ctx = identity.WithContext(ctx, idata)
+	}
+	return ctx
+}
+
 func (d *DeploymentsController) PostDeployment(w rest.ResponseWriter, r *rest.Request) {
 	l := requestlog.GetRequestLogger(r.Env)
 
@@ -57,17 +70,100 @@ func (d *DeploymentsController) PostDeployment(w rest.ResponseWriter, r *rest.Re
 		return
 	}
 
-	reqId := requestid.GetReqId(r)
-	ctx := context.WithValue(context.Background(), requestid.RequestIdHeader, reqId)
+	ctx := requestContext(r)
 	id, err := d.model.CreateDeployment(ctx, constructor)
 	if err != nil {
-		d.view.RenderInternalError(w, r, err, l)
+		if cerr, ok := errors.Cause(err).(*deployments.ConflictError); ok {
+			d.view.RenderConflictError(w, r, cerr, l)
+			return
+		}
+		switch errors.Cause(err) {
+		case ErrModelNoDevicesMatched, deployments.ErrPhasesExceedDeploymentSize:
+			d.view.RenderError(w, r, err, http.StatusBadRequest, l)
+		default:
+			d.view.RenderInternalError(w, r, err, l)
+		}
 		return
 	}
 
 	d.view.RenderSuccessPost(w, r, id)
 }
 
+// PreviewDeployment shows which devices would receive which artifact if the
+// deployment was created, nothing is stored.
+func (d *DeploymentsController) PreviewDeployment(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	constructor, err := d.getDeploymentConstructorFromBody(r)
+	if err != nil {
+		d.view.RenderError(w, r, errors.Wrap(err, "Validating request body"), http.StatusBadRequest, l)
+		return
+	}
+
+	ctx := requestContext(r)
+	preview, err := d.model.PreviewDeployment(ctx, constructor)
+	if err != nil {
+		switch errors.Cause(err) {
+		case ErrModelNoDevicesMatched, deployments.ErrPhasesExceedDeploymentSize:
+			d.view.RenderError(w, r, err, http.StatusBadRequest, l)
+		default:
+			d.view.RenderInternalError(w, r, err, l)
+		}
+		return
+	}
+
+	d.view.RenderSuccessGet(w, preview)
+}
+
+// RetryDeployment creates a new deployment targeting devices of an existing
+// deployment, which ended up in one of requested statuses.
+func (d *DeploymentsController) RetryDeployment(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	id := r.PathParam("id")
+
+	if !govalidator.IsUUIDv4(id) {
+		d.view.RenderError(w, r, ErrIDNotUUIDv4, http.StatusBadRequest, l)
+		return
+	}
+
+	// body is optional, failed devices are retried by default
+	retry := deployments.NewRetryConstructor()
+	if r.ContentLength != 0 {
+		if err := r.DecodeJsonPayload(retry); err != nil {
+			d.view.RenderError(w, r, errors.Wrap(err, "Validating request body"), http.StatusBadRequest, l)
+			return
+		}
+		if len(retry.Statuses) == 0 {
+			retry.Statuses = deployments.NewRetryConstructor().Statuses
+		}
+	}
+	if err := retry.Validate(); err != nil {
+		d.view.RenderError(w, r, errors.Wrap(err, "Validating request body"), http.StatusBadRequest, l)
+		return
+	}
+
+	ctx := requestContext(r)
+	newID, err := d.model.RetryDeployment(ctx, id, retry)
+	if err != nil {
+		if cerr, ok := errors.Cause(err).(*deployments.ConflictError); ok {
+			d.view.RenderConflictError(w, r, cerr, l)
+			return
+		}
+		switch errors.Cause(err) {
+		case ErrModelDeploymentNotFound:
+			d.view.RenderError(w, r, err, http.StatusNotFound, l)
+		case ErrModelNoDevicesToRetry:
+			d.view.RenderError(w, r, err, http.StatusUnprocessableEntity, l)
+		default:
+			d.view.RenderInternalError(w, r, err, l)
+		}
+		return
+	}
+
+	d.view.RenderDeploymentCreated(w, newID)
+}
+
 func (d *DeploymentsController) getDeploymentConstructorFromBody(r *rest.Request) (*deployments.DeploymentConstructor, error) {
 	var constructor *deployments.DeploymentConstructor
 	if err := r.DecodeJsonPayload(&constructor); err != nil {
@@ -129,6 +225,90 @@ func (d *DeploymentsController) GetDeploymentStats(w rest.ResponseWriter, r *res
 	d.view.RenderSuccessGet(w, stats)
 }
 
+// GetDeploymentFailures returns failed devices of the deployment grouped by
+// failure reason code and device type.
+func (d *DeploymentsController) GetDeploymentFailures(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	id := r.PathParam("id")
+
+	if !govalidator.IsUUIDv4(id) {
+		d.view.RenderError(w, r, ErrIDNotUUIDv4, http.StatusBadRequest, l)
+		return
+	}
+
+	groups, err := d.model.GetDeploymentFailures(id)
+	if err != nil {
+		switch errors.Cause(err) {
+		case ErrModelDeploymentNotFound:
+			d.view.RenderErrorNotFound(w, r, l)
+		default:
+			d.view.RenderInternalError(w, r, err, l)
+		}
+		return
+	}
+
+	d.view.RenderSuccessGet(w, groups)
+}
+
+// Interval of keep-alive comments sent on idle event streams
+var EventStreamKeepAlive = 30 * time.Second
+
+// GetDeploymentEvents streams deployment progress as Server-Sent Events. The
+// stream starts with current statistics, followed by device status and
+// statistics changes, until the client disconnects.
+func (d *DeploymentsController) GetDeploymentEvents(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	id := r.PathParam("id")
+
+	if !govalidator.IsUUIDv4(id) {
+		d.view.RenderError(w, r, ErrIDNotUUIDv4, http.StatusBadRequest, l)
+		return
+	}
+
+	// subscribe first, so that no change is missed after reading stats
+	events, cancel := d.model.SubscribeDeploymentEvents(id)
+	defer cancel()
+
+	stats, err := d.model.GetDeploymentStats(id)
+	if err != nil {
+		d.view.RenderInternalError(w, r, err, l)
+		return
+	}
+
+	if stats == nil {
+		d.view.RenderErrorNotFound(w, r, l)
+		return
+	}
+
+	d.view.RenderEventStream(w)
+	if err := d.view.RenderEvent(w, deployments.NewStatsEvent(id, stats.Stats)); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(EventStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			err = d.view.RenderEvent(w, event)
+		case <-keepAlive.C:
+			err = d.view.RenderEventKeepAlive(w)
+		}
+		if err != nil {
+			l.Infof("event stream of deployment %s closed: %s", id, err)
+			return
+		}
+	}
+}
+
 func (d *DeploymentsController) AbortDeployment(w rest.ResponseWriter, r *rest.Request) {
 	l := requestlog.GetRequestLogger(r.Env)
 
@@ -149,9 +329,14 @@ func (d *DeploymentsController) AbortDeployment(w rest.ResponseWriter, r *rest.R
 		d.view.RenderError(w, r, err, http.StatusBadRequest, l)
 		return
 	}
-	// "aborted" is the only supported status
-	if status.Status != deployments.DeviceDeploymentStatusAborted {
+	// "aborted", "paused" and "running" are the only supported statuses
+	switch status.Status {
+	case deployments.DeviceDeploymentStatusAborted,
+		deployments.DeploymentStatusPaused,
+		deployments.DeploymentStatusRunning:
+	default:
 		d.view.RenderError(w, r, ErrUnexpectedDeploymentStatus, http.StatusBadRequest, l)
+		return
 	}
 
 	// Check if deployment is finished
@@ -165,9 +350,89 @@ func (d *DeploymentsController) AbortDeployment(w rest.ResponseWriter, r *rest.R
 		return
 	}
 
-	// Abort deployments for devices and update deployment stats
-	if err := d.model.AbortDeployment(id); err != nil {
+	switch status.Status {
+	case deployments.DeploymentStatusPaused:
+		err = d.model.PauseDeployment(id)
+	case deployments.DeploymentStatusRunning:
+		err = d.model.ResumeDeployment(id)
+	default:
+		// Abort deployments for devices and update deployment stats
+		err = d.model.AbortDeployment(id)
+	}
+	if err != nil {
 		d.view.RenderInternalError(w, r, err, l)
+		return
+	}
+
+	d.view.RenderEmptySuccessResponse(w)
+}
+
+// DeleteDeployment removes finished or aborted deployment, its device
+// deployments and logs.
+func (d *DeploymentsController) DeleteDeployment(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	id := r.PathParam("id")
+
+	if !govalidator.IsUUIDv4(id) {
+		d.view.RenderError(w, r, ErrIDNotUUIDv4, http.StatusBadRequest, l)
+		return
+	}
+
+	if err := d.model.DeleteDeployment(id); err != nil {
+		switch errors.Cause(err) {
+		case ErrModelDeploymentNotFound:
+			d.view.RenderErrorNotFound(w, r, l)
+		case ErrModelDeploymentNotFinished:
+			d.view.RenderError(w, r, err, http.StatusUnprocessableEntity, l)
+		default:
+			d.view.RenderInternalError(w, r, err, l)
+		}
+		return
+	}
+
+	d.view.RenderEmptySuccessResponse(w)
+}
+
+// AbortDeviceDeployment aborts deployment for a single device, leaving it
+// running for other devices. "aborted" is the only supported status.
+func (d *DeploymentsController) AbortDeviceDeployment(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	id := r.PathParam("id")
+	devid := r.PathParam("devid")
+
+	if !govalidator.IsUUIDv4(id) {
+		d.view.RenderError(w, r, ErrIDNotUUIDv4, http.StatusBadRequest, l)
+		return
+	}
+
+	// receive request body
+	var status struct {
+		Status string
+	}
+
+	err := r.DecodeJsonPayload(&status)
+	if err != nil {
+		d.view.RenderError(w, r, err, http.StatusBadRequest, l)
+		return
+	}
+	if status.Status != deployments.DeviceDeploymentStatusAborted {
+		d.view.RenderError(w, r, ErrUnexpectedDeploymentStatus, http.StatusBadRequest, l)
+		return
+	}
+
+	if err := d.model.AbortDeviceDeployment(id, devid); err != nil {
+		if err == ErrModelDeviceDeploymentNotFound {
+			d.view.RenderError(w, r, err, http.StatusNotFound, l)
+		} else if err == ErrDeploymentAborted {
+			d.view.RenderError(w, r, err, http.StatusConflict, l)
+		} else if terr, ok := errors.Cause(err).(*deployments.StatusTransitionError); ok {
+			d.view.RenderStatusTransitionError(w, r, terr, l)
+		} else {
+			d.view.RenderInternalError(w, r, err, l)
+		}
+		return
 	}
 
 	d.view.RenderEmptySuccessResponse(w)
@@ -233,10 +498,11 @@ func (d *DeploymentsController) PutDeploymentStatusForDevice(w rest.ResponseWrit
 		return
 	}
 
-	status := report.Status
-	if err := d.model.UpdateDeviceDeploymentStatus(did, idata.Subject, status); err != nil {
+	if err := d.model.UpdateDeviceDeploymentStatus(did, idata.Subject, report.State()); err != nil {
 		if err == ErrDeploymentAborted {
 			d.view.RenderError(w, r, err, http.StatusConflict, l)
+		} else if terr, ok := errors.Cause(err).(*deployments.StatusTransitionError); ok {
+			d.view.RenderStatusTransitionError(w, r, terr, l)
 		} else {
 			d.view.RenderInternalError(w, r, err, l)
 		}
@@ -256,7 +522,13 @@ func (d *DeploymentsController) GetDeviceStatusesForDeployment(w rest.ResponseWr
 		return
 	}
 
-	statuses, err := d.model.GetDeviceStatusesForDeployment(did)
+	page, err := paging.ParseQuery(r.URL.Query(), deployments.DeviceDeploymentSortFields()...)
+	if err != nil {
+		d.view.RenderError(w, r, err, http.StatusBadRequest, l)
+		return
+	}
+
+	statuses, total, err := d.model.GetDeviceStatusesForDeployment(did, page)
 	if err != nil {
 		switch err {
 		case ErrModelDeploymentNotFound:
@@ -268,7 +540,43 @@ func (d *DeploymentsController) GetDeviceStatusesForDeployment(w rest.ResponseWr
 		}
 	}
 
-	d.view.RenderSuccessGet(w, statuses)
+	d.view.RenderSuccessGetPage(w, r, statuses, page, total)
+}
+
+// ParseDeviceDeploymentHistoryQuery reads device deployment history query
+// from request parameters.
+func ParseDeviceDeploymentHistoryQuery(vals url.Values) (deployments.DeviceDeploymentHistoryQuery, error) {
+	query := *deployments.NewDeviceDeploymentHistoryQuery()
+
+	query.Status = vals.Get("status")
+
+	page, err := paging.ParsePagedQuery(vals)
+	if err != nil {
+		return query, err
+	}
+	query.Paging = page
+
+	return query, query.Validate()
+}
+
+func (d *DeploymentsController) GetDeviceDeploymentHistory(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	devid := r.PathParam("id")
+
+	query, err := ParseDeviceDeploymentHistoryQuery(r.URL.Query())
+	if err != nil {
+		d.view.RenderError(w, r, err, http.StatusBadRequest, l)
+		return
+	}
+
+	history, total, err := d.model.GetDeviceDeploymentHistory(devid, query)
+	if err != nil {
+		d.view.RenderInternalError(w, r, err, l)
+		return
+	}
+
+	d.view.RenderSuccessGetPage(w, r, history, query.Paging, total)
 }
 
 func ParseLookupQuery(vals url.Values) (deployments.Query, error) {
@@ -289,6 +597,10 @@ func ParseLookupQuery(vals url.Values) (deployments.Query, error) {
 		query.Status = deployments.StatusQueryPending
 	case "aborted":
 		query.Status = deployments.StatusQueryAborted
+	case "scheduled":
+		query.Status = deployments.StatusQueryScheduled
+	case "paused":
+		query.Status = deployments.StatusQueryPaused
 	case "":
 		query.Status = deployments.StatusQueryAny
 	default:
@@ -296,6 +608,30 @@ func ParseLookupQuery(vals url.Values) (deployments.Query, error) {
 
 	}
 
+	var err error
+	if query.CreatedAfter, err = params.ParseTime(vals, "created_after"); err != nil {
+		return query, err
+	}
+	if query.CreatedBefore, err = params.ParseTime(vals, "created_before"); err != nil {
+		return query, err
+	}
+	if query.FinishedAfter, err = params.ParseTime(vals, "finished_after"); err != nil {
+		return query, err
+	}
+	if query.FinishedBefore, err = params.ParseTime(vals, "finished_before"); err != nil {
+		return query, err
+	}
+
+	query.ArtifactName = vals.Get("artifact_name")
+	query.DeviceID = vals.Get("device_id")
+	query.CreatedBy = vals.Get("created_by")
+
+	page, err := paging.ParseQuery(vals, deployments.DeploymentSortFields()...)
+	if err != nil {
+		return query, err
+	}
+	query.Paging = page
+
 	return query, nil
 }
 
@@ -309,13 +645,13 @@ func (d *DeploymentsController) LookupDeployment(w rest.ResponseWriter, r *rest.
 		return
 	}
 
-	deps, err := d.model.LookupDeployment(query)
+	deps, total, err := d.model.LookupDeployment(query)
 	if err != nil {
 		d.view.RenderError(w, r, err, http.StatusBadRequest, l)
 		return
 	}
 
-	d.view.RenderSuccessGet(w, deps)
+	d.view.RenderSuccessGetPage(w, r, deps, query.Paging, total)
 }
 
 func (d *DeploymentsController) PutDeploymentLogForDevice(w rest.ResponseWriter, r *rest.Request) {
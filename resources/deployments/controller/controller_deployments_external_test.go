@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"testing"
 	"time"
 
@@ -32,6 +33,7 @@ import (
 	"github.com/mendersoftware/deployments/resources/deployments/controller/mocks"
 	"github.com/mendersoftware/deployments/resources/deployments/view"
 	"github.com/mendersoftware/deployments/resources/images"
+	"github.com/mendersoftware/deployments/utils/paging"
 	. "github.com/mendersoftware/deployments/utils/pointers"
 	"github.com/mendersoftware/go-lib-micro/requestid"
 	"github.com/mendersoftware/go-lib-micro/requestlog"
@@ -371,7 +373,7 @@ func TestControllerPostDeployment(t *testing.T) {
 			InputBodyObject: deployments.NewDeploymentConstructor(),
 			JSONResponseParams: h.JSONResponseParams{
 				OutputStatus:     http.StatusBadRequest,
-				OutputBodyObject: h.ErrorToErrStruct(errors.New(`Validating request body: Name: non zero value required;ArtifactName: non zero value required;Devices: non zero value required;`)),
+				OutputBodyObject: h.ErrorToErrStruct(errors.New(`Validating request body: Name: non zero value required;ArtifactName: non zero value required;`)),
 			},
 		},
 		{
@@ -399,6 +401,25 @@ func TestControllerPostDeployment(t *testing.T) {
 				OutputHeaders:    map[string]string{"Location": "./r/1234"},
 			},
 		},
+		{
+			InputBodyObject: &deployments.DeploymentConstructor{
+				Name:           StringToPointer("NYC Production"),
+				ArtifactName:   StringToPointer("App 123"),
+				Devices:        []string{"f826484e-1157-4109-af21-304e6d711560"},
+				ConflictPolicy: deployments.ConflictPolicyReject,
+			},
+			InputModelError: &deployments.ConflictError{
+				Devices: []string{"f826484e-1157-4109-af21-304e6d711560"},
+			},
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusConflict,
+				OutputBodyObject: map[string]interface{}{
+					"error":      "1 device(s) already have an active deployment",
+					"devices":    []string{"f826484e-1157-4109-af21-304e6d711560"},
+					"request_id": "test",
+				},
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -423,12 +444,213 @@ func TestControllerPostDeployment(t *testing.T) {
 	}
 }
 
+func TestControllerPreviewDeployment(t *testing.T) {
+
+	t.Parallel()
+
+	constructor := &deployments.DeploymentConstructor{
+		Name:         StringToPointer("NYC Production"),
+		ArtifactName: StringToPointer("App 123"),
+		Devices:      []string{"f826484e-1157-4109-af21-304e6d711560"},
+	}
+
+	preview := &deployments.DeploymentPreview{
+		Devices: []*deployments.DeviceDeploymentPreview{
+			{
+				DeviceId:   "f826484e-1157-4109-af21-304e6d711560",
+				DeviceType: "rpi",
+				Status:     deployments.DeviceDeploymentStatusNoArtifact,
+			},
+		},
+		DeviceTypes: map[string]int{"rpi": 1},
+		Stats: deployments.Stats{
+			deployments.DeviceDeploymentStatusNoArtifact: 1,
+		},
+	}
+
+	testCases := map[string]struct {
+		h.JSONResponseParams
+
+		InputBodyObject interface{}
+
+		InputModelPreview *deployments.DeploymentPreview
+		InputModelError   error
+	}{
+		"empty body": {
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("Validating request body: JSON payload is empty")),
+			},
+		},
+		"no devices matched": {
+			InputBodyObject: constructor,
+			InputModelError: ErrModelNoDevicesMatched,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(ErrModelNoDevicesMatched),
+			},
+		},
+		"model error": {
+			InputBodyObject: constructor,
+			InputModelError: errors.New("model error"),
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusInternalServerError,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("internal error")),
+			},
+		},
+		"ok": {
+			InputBodyObject:   constructor,
+			InputModelPreview: preview,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusOK,
+				OutputBodyObject: preview,
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deploymentModel := new(mocks.DeploymentsModel)
+
+		deploymentModel.On("PreviewDeployment", testCase.InputBodyObject).
+			Return(testCase.InputModelPreview, testCase.InputModelError)
+
+		router, err := rest.MakeRouter(
+			rest.Post("/r",
+				NewDeploymentsController(deploymentModel, new(view.DeploymentsView)).PreviewDeployment))
+		assert.NoError(t, err)
+
+		api := makeApi(router)
+
+		req := test.MakeSimpleRequest("POST", "http://localhost/r", testCase.InputBodyObject)
+		req.Header.Add(requestid.RequestIdHeader, "test")
+		recorded := test.RunRequest(t, api.MakeHandler(), req)
+
+		h.CheckRecordedResponse(t, recorded, testCase.JSONResponseParams)
+	}
+}
+
+func TestControllerRetryDeployment(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		h.JSONResponseParams
+
+		InputID         string
+		InputBodyObject interface{}
+
+		InputModelRetry *deployments.RetryConstructor
+		InputModelID    string
+		InputModelError error
+	}{
+		"invalid id": {
+			InputID: "not-uuid",
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(ErrIDNotUUIDv4),
+			},
+		},
+		"invalid status": {
+			InputID: "f826484e-1157-4109-af21-304e6d711560",
+			InputBodyObject: &deployments.RetryConstructor{
+				Statuses: []string{deployments.DeviceDeploymentStatusSuccess},
+			},
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(
+					errors.New("Validating request body: " + deployments.ErrRetryInvalidStatus.Error())),
+			},
+		},
+		"default statuses": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputModelRetry: deployments.NewRetryConstructor(),
+			InputModelID:    "23bbc7ba-3278-4b1c-a345-4080afe59e96",
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusCreated,
+				OutputHeaders: map[string]string{
+					"Location": "./deployments/23bbc7ba-3278-4b1c-a345-4080afe59e96",
+				},
+			},
+		},
+		"requested statuses": {
+			InputID: "f826484e-1157-4109-af21-304e6d711560",
+			InputBodyObject: &deployments.RetryConstructor{
+				Statuses: []string{deployments.DeviceDeploymentStatusAborted},
+			},
+			InputModelRetry: &deployments.RetryConstructor{
+				Statuses: []string{deployments.DeviceDeploymentStatusAborted},
+			},
+			InputModelID: "23bbc7ba-3278-4b1c-a345-4080afe59e96",
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusCreated,
+				OutputHeaders: map[string]string{
+					"Location": "./deployments/23bbc7ba-3278-4b1c-a345-4080afe59e96",
+				},
+			},
+		},
+		"deployment not found": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputModelRetry: deployments.NewRetryConstructor(),
+			InputModelError: ErrModelDeploymentNotFound,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusNotFound,
+				OutputBodyObject: h.ErrorToErrStruct(ErrModelDeploymentNotFound),
+			},
+		},
+		"no devices to retry": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputModelRetry: deployments.NewRetryConstructor(),
+			InputModelError: ErrModelNoDevicesToRetry,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusUnprocessableEntity,
+				OutputBodyObject: h.ErrorToErrStruct(ErrModelNoDevicesToRetry),
+			},
+		},
+		"model error": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputModelRetry: deployments.NewRetryConstructor(),
+			InputModelError: errors.New("model error"),
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusInternalServerError,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("internal error")),
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deploymentModel := new(mocks.DeploymentsModel)
+
+		deploymentModel.On("RetryDeployment", testCase.InputID, testCase.InputModelRetry).
+			Return(testCase.InputModelID, testCase.InputModelError)
+
+		router, err := rest.MakeRouter(
+			rest.Post("/r/:id",
+				NewDeploymentsController(deploymentModel, new(view.DeploymentsView)).RetryDeployment))
+		assert.NoError(t, err)
+
+		api := makeApi(router)
+
+		req := test.MakeSimpleRequest("POST", "http://localhost/r/"+testCase.InputID, testCase.InputBodyObject)
+		req.Header.Add(requestid.RequestIdHeader, "test")
+		recorded := test.RunRequest(t, api.MakeHandler(), req)
+
+		h.CheckRecordedResponse(t, recorded, testCase.JSONResponseParams)
+	}
+}
+
 func TestControllerPutDeploymentStatus(t *testing.T) {
 
 	t.Parallel()
 
 	type report struct {
-		Status string `json:"status"`
+		Status        string                     `json:"status"`
+		SubState      *string                    `json:"substate,omitempty"`
+		Progress      *int                       `json:"progress,omitempty"`
+		FailureReason *deployments.FailureReason `json:"failure_reason,omitempty"`
 	}
 
 	testCases := []struct {
@@ -436,10 +658,13 @@ func TestControllerPutDeploymentStatus(t *testing.T) {
 
 		InputBodyObject interface{}
 
-		InputModelDeploymentID string
-		InputModelDeviceID     string
-		InputModelStatus       string
-		InputModelError        error
+		InputModelDeploymentID  string
+		InputModelDeviceID      string
+		InputModelStatus        string
+		InputModelSubState      *string
+		InputModelProgress      *int
+		InputModelFailureReason *deployments.FailureReason
+		InputModelError         error
 
 		Headers map[string]string
 	}{
@@ -474,6 +699,67 @@ func TestControllerPutDeploymentStatus(t *testing.T) {
 				"Authorization": makeDeviceAuthHeader(`{"sub": "device-id-2"}`),
 			},
 		},
+		{
+			// substate and progress
+			InputBodyObject: &report{
+				Status:   "downloading",
+				SubState: StringToPointer("fetching rootfs"),
+				Progress: IntToPointer(42),
+			},
+			InputModelDeploymentID: "f826484e-1157-4109-af21-304e6d711560",
+			InputModelDeviceID:     "device-id-2",
+			InputModelStatus:       "downloading",
+			InputModelSubState:     StringToPointer("fetching rootfs"),
+			InputModelProgress:     IntToPointer(42),
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusNoContent,
+				OutputBodyObject: nil,
+			},
+			Headers: map[string]string{
+				"Authorization": makeDeviceAuthHeader(`{"sub": "device-id-2"}`),
+			},
+		},
+		{
+			// failure with reason
+			InputBodyObject: &report{
+				Status: "failure",
+				FailureReason: &deployments.FailureReason{
+					Code:    "disk_full",
+					Message: "No space left on device",
+				},
+			},
+			InputModelDeploymentID: "f826484e-1157-4109-af21-304e6d711560",
+			InputModelDeviceID:     "device-id-2",
+			InputModelStatus:       "failure",
+			InputModelFailureReason: &deployments.FailureReason{
+				Code:    "disk_full",
+				Message: "No space left on device",
+			},
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusNoContent,
+				OutputBodyObject: nil,
+			},
+			Headers: map[string]string{
+				"Authorization": makeDeviceAuthHeader(`{"sub": "device-id-2"}`),
+			},
+		},
+		{
+			// progress out of range
+			InputBodyObject:        &report{Status: "downloading", Progress: IntToPointer(420)},
+			InputModelDeploymentID: "f826484e-1157-4109-af21-304e6d711560",
+			InputModelDeviceID:     "device-id-2",
+			InputModelStatus:       "downloading",
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(ErrBadProgress),
+			},
+			Headers: map[string]string{
+				"Authorization": makeDeviceAuthHeader(`{"sub": "device-id-2"}`),
+			},
+		},
 		{
 			// no authorization
 			InputBodyObject:        &report{Status: "installing"},
@@ -518,6 +804,29 @@ func TestControllerPutDeploymentStatus(t *testing.T) {
 				"Authorization": makeDeviceAuthHeader(`{"sub": "device-id-2"}`),
 			},
 		},
+		{
+			// success -> downloading, invalid transition
+			InputBodyObject:        &report{Status: "downloading"},
+			InputModelDeploymentID: "f826484e-1157-4109-af21-304e6d711560",
+			InputModelDeviceID:     "device-id-2",
+			InputModelStatus:       "downloading",
+			InputModelError: &deployments.StatusTransitionError{
+				From: deployments.DeviceDeploymentStatusSuccess,
+				To:   deployments.DeviceDeploymentStatusDownloading,
+			},
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusConflict,
+				OutputBodyObject: map[string]string{
+					"error":      "Invalid device deployment status transition from success to downloading",
+					"status":     "success",
+					"request_id": "test",
+				},
+			},
+			Headers: map[string]string{
+				"Authorization": makeDeviceAuthHeader(`{"sub": "device-id-2"}`),
+			},
+		},
 		{
 			// change to aborted forbidden
 			InputBodyObject:        &report{Status: "aborted"},
@@ -544,7 +853,13 @@ func TestControllerPutDeploymentStatus(t *testing.T) {
 
 		deploymentModel.On("UpdateDeviceDeploymentStatus",
 			testCase.InputModelDeploymentID,
-			testCase.InputModelDeviceID, testCase.InputModelStatus).
+			testCase.InputModelDeviceID,
+			deployments.DeviceDeploymentState{
+				Status:        testCase.InputModelStatus,
+				SubState:      testCase.InputModelSubState,
+				Progress:      testCase.InputModelProgress,
+				FailureReason: testCase.InputModelFailureReason,
+			}).
 			Return(testCase.InputModelError)
 
 		router, err := rest.MakeRouter(
@@ -575,7 +890,7 @@ func TestControllerGetDeploymentStats(t *testing.T) {
 		h.JSONResponseParams
 
 		InputModelDeploymentID string
-		InputModelStats        deployments.Stats
+		InputModelStats        *deployments.DeploymentStatistics
 		InputModelError        error
 	}{
 		{
@@ -597,16 +912,19 @@ func TestControllerGetDeploymentStats(t *testing.T) {
 		},
 		{
 			InputModelDeploymentID: "23bbc7ba-3278-4b1c-a345-4080afe59e96",
-			InputModelStats: deployments.Stats{
-				deployments.DeviceDeploymentStatusSuccess:     12,
-				deployments.DeviceDeploymentStatusFailure:     2,
-				deployments.DeviceDeploymentStatusDownloading: 1,
-				deployments.DeviceDeploymentStatusRebooting:   3,
-				deployments.DeviceDeploymentStatusInstalling:  1,
-				deployments.DeviceDeploymentStatusPending:     2,
-				deployments.DeviceDeploymentStatusNoArtifact:  0,
-				deployments.DeviceDeploymentStatusAlreadyInst: 0,
-				deployments.DeviceDeploymentStatusAborted:     0,
+			InputModelStats: &deployments.DeploymentStatistics{
+				Stats: deployments.Stats{
+					deployments.DeviceDeploymentStatusSuccess:     12,
+					deployments.DeviceDeploymentStatusFailure:     2,
+					deployments.DeviceDeploymentStatusDownloading: 1,
+					deployments.DeviceDeploymentStatusRebooting:   3,
+					deployments.DeviceDeploymentStatusInstalling:  1,
+					deployments.DeviceDeploymentStatusPending:     2,
+					deployments.DeviceDeploymentStatusNoArtifact:  0,
+					deployments.DeviceDeploymentStatusAlreadyInst: 0,
+					deployments.DeviceDeploymentStatusAborted:     0,
+				},
+				Progress: deployments.DeploymentProgress{Retries: 2, Progress: 50},
 			},
 
 			JSONResponseParams: h.JSONResponseParams{
@@ -621,6 +939,8 @@ func TestControllerGetDeploymentStats(t *testing.T) {
 					deployments.DeviceDeploymentStatusNoArtifact:  0,
 					deployments.DeviceDeploymentStatusAlreadyInst: 0,
 					deployments.DeviceDeploymentStatusAborted:     0,
+					deployments.DeviceDeploymentStatsRetries:      2,
+					deployments.DeviceDeploymentStatsProgress:     50,
 				},
 			},
 		},
@@ -652,6 +972,192 @@ func TestControllerGetDeploymentStats(t *testing.T) {
 	}
 }
 
+func TestControllerGetDeploymentFailures(t *testing.T) {
+
+	t.Parallel()
+
+	groups := []*deployments.FailureGroup{
+		{Code: "disk_full", DeviceType: "beaglebone", Count: 7},
+		{Code: "", DeviceType: "raspberrypi3", Count: 1},
+	}
+
+	testCases := map[string]struct {
+		h.JSONResponseParams
+
+		InputDeploymentID string
+		InputModelGroups  []*deployments.FailureGroup
+		InputModelError   error
+	}{
+		"ok": {
+			InputDeploymentID: "23bbc7ba-3278-4b1c-a345-4080afe59e96",
+			InputModelGroups:  groups,
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusOK,
+				OutputBodyObject: groups,
+			},
+		},
+		"no failures": {
+			InputDeploymentID: "23bbc7ba-3278-4b1c-a345-4080afe59e96",
+			InputModelGroups:  []*deployments.FailureGroup{},
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusOK,
+				OutputBodyObject: []*deployments.FailureGroup{},
+			},
+		},
+		"deployment ID format error": {
+			InputDeploymentID: "23bbc7ba32784b1ca3454080afe59e96",
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(ErrIDNotUUIDv4),
+			},
+		},
+		"deployment not found": {
+			InputDeploymentID: "23bbc7ba-3278-4b1c-a345-4080afe59e96",
+			InputModelError:   ErrModelDeploymentNotFound,
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusNotFound,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("Resource not found")),
+			},
+		},
+		"model error": {
+			InputDeploymentID: "23bbc7ba-3278-4b1c-a345-4080afe59e96",
+			InputModelError:   errors.New("storage issue"),
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusInternalServerError,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("internal error")),
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deploymentModel := new(mocks.DeploymentsModel)
+		deploymentModel.On("GetDeploymentFailures", testCase.InputDeploymentID).
+			Return(testCase.InputModelGroups, testCase.InputModelError)
+
+		router, err := rest.MakeRouter(
+			rest.Get("/r/:id",
+				NewDeploymentsController(deploymentModel,
+					new(view.DeploymentsView)).GetDeploymentFailures))
+		assert.NoError(t, err)
+
+		req := test.MakeSimpleRequest("GET", "http://localhost/r/"+testCase.InputDeploymentID, nil)
+		req.Header.Add(requestid.RequestIdHeader, "test")
+		recorded := test.RunRequest(t, makeApi(router).MakeHandler(), req)
+
+		h.CheckRecordedResponse(t, recorded, testCase.JSONResponseParams)
+	}
+}
+
+func TestControllerGetDeploymentEvents(t *testing.T) {
+
+	t.Parallel()
+
+	tref := time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC)
+	event := deployments.NewDeviceStatusEvent("f826484e-1157-4109-af21-304e6d711560", "device-1",
+		deployments.DeviceDeploymentStatusPending, deployments.DeviceDeploymentStatusDownloading)
+	event.Time = tref
+
+	testCases := map[string]struct {
+		h.JSONResponseParams
+
+		InputID         string
+		InputModelStats *deployments.DeploymentStatistics
+		InputModelError error
+
+		OutputEvents []string
+	}{
+		"invalid id": {
+			InputID: "not-uuid",
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(ErrIDNotUUIDv4),
+			},
+		},
+		"not found": {
+			InputID: "f826484e-1157-4109-af21-304e6d711560",
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusNotFound,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("Resource not found")),
+			},
+		},
+		"model error": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputModelError: errors.New("storage issue"),
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusInternalServerError,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("internal error")),
+			},
+		},
+		"events": {
+			InputID: "f826484e-1157-4109-af21-304e6d711560",
+			InputModelStats: &deployments.DeploymentStatistics{
+				Stats: deployments.Stats{
+					deployments.DeviceDeploymentStatusPending: 1,
+				},
+				Progress: deployments.DeploymentProgress{Retries: 1},
+			},
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusOK,
+			},
+			OutputEvents: []string{
+				`event: stats`,
+				`"stats":{"pending":1}`,
+				`event: device_status` + "\n" +
+					`data: {"type":"device_status","deployment_id":"f826484e-1157-4109-af21-304e6d711560",` +
+					`"time":"2017-01-02T15:04:05Z","device_id":"device-1","status":"downloading",` +
+					`"previous_status":"pending"}` + "\n\n",
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		// stream ends once subscription is closed
+		events := make(chan *deployments.Event, 1)
+		events <- event
+		close(events)
+		cancelled := false
+
+		deploymentModel := new(mocks.DeploymentsModel)
+		deploymentModel.On("SubscribeDeploymentEvents", testCase.InputID).
+			Return((<-chan *deployments.Event)(events), func() { cancelled = true })
+		deploymentModel.On("GetDeploymentStats", testCase.InputID).
+			Return(testCase.InputModelStats, testCase.InputModelError)
+
+		router, err := rest.MakeRouter(
+			rest.Get("/r/:id/events",
+				NewDeploymentsController(deploymentModel,
+					new(view.DeploymentsView)).GetDeploymentEvents))
+		assert.NoError(t, err)
+
+		api := makeApi(router)
+
+		req := test.MakeSimpleRequest("GET", "http://localhost/r/"+testCase.InputID+"/events", nil)
+		req.Header.Add(requestid.RequestIdHeader, "test")
+		recorded := test.RunRequest(t, api.MakeHandler(), req)
+
+		if testCase.OutputEvents == nil {
+			h.CheckRecordedResponse(t, recorded, testCase.JSONResponseParams)
+			continue
+		}
+
+		recorded.CodeIs(testCase.OutputStatus)
+		recorded.HeaderIs("Content-Type", "text/event-stream")
+		for _, output := range testCase.OutputEvents {
+			assert.Contains(t, recorded.Recorder.Body.String(), output)
+		}
+		assert.True(t, cancelled)
+	}
+}
+
 func TestControllerGetDeviceStatusesForDeployment(t *testing.T) {
 	t.Parallel()
 
@@ -662,49 +1168,192 @@ func TestControllerGetDeviceStatusesForDeployment(t *testing.T) {
 		*deployments.NewDeviceDeployment("device0002", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"),
 		*deployments.NewDeviceDeployment("device0003", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"),
 	}
+	statuses[0].History = []deployments.DeviceDeploymentStatusChange{
+		{
+			Previous:  deployments.DeviceDeploymentStatusPending,
+			Status:    deployments.DeviceDeploymentStatusDownloading,
+			Timestamp: time.Now(),
+		},
+	}
+
+	testCases := map[string]struct {
+		h.JSONResponseParams
+
+		deploymentID  string
+		query         string
+		page          paging.Query
+		modelStatuses []deployments.DeviceDeployment
+		modelErr      error
+	}{
+		"existing deployment and statuses": {
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusOK,
+				OutputBodyObject: statuses,
+			},
+			deploymentID:  "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			page:          paging.Query{Page: 1},
+			modelStatuses: statuses,
+			modelErr:      nil,
+		},
+		"second page sorted by status": {
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusOK,
+				OutputBodyObject: statuses[2:],
+			},
+			deploymentID: "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			query:        "?page=2&per_page=2&sort=status",
+			page: paging.Query{Page: 2, PerPage: 2,
+				Sort: deployments.SortFieldStatus},
+			modelStatuses: statuses[2:],
+		},
+		"bad page": {
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(paging.ErrInvalidPage),
+			},
+			deploymentID: "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			query:        "?page=0",
+		},
+		"deployment ID format error": {
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("ID is not UUIDv4")),
+			},
+			deploymentID:  "30b3e62c9ec24312a7facff24cc7397a",
+			modelStatuses: nil,
+			modelErr:      nil,
+		},
+		"model error: deployment doesn't exist": {
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusNotFound,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("Deployment not found")),
+			},
+			deploymentID:  "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			page:          paging.Query{Page: 1},
+			modelStatuses: nil,
+			modelErr:      ErrModelDeploymentNotFound,
+		},
+		"unknown model error": {
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusInternalServerError,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("internal error")),
+			},
+			deploymentID:  "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			page:          paging.Query{Page: 1},
+			modelStatuses: nil,
+			modelErr:      errors.New("some unknown error"),
+		},
+	}
+
+	for id, tc := range testCases {
+		t.Logf("test case: %s", id)
+
+		deploymentModel := new(mocks.DeploymentsModel)
+		deploymentModel.On("GetDeviceStatusesForDeployment", tc.deploymentID, tc.page).
+			Return(tc.modelStatuses, len(tc.modelStatuses), tc.modelErr)
+
+		router, err := rest.MakeRouter(
+			rest.Get("/r/:id",
+				NewDeploymentsController(deploymentModel, new(view.DeploymentsView)).GetDeviceStatusesForDeployment))
+
+		assert.NoError(t, err)
+
+		api := makeApi(router)
+
+		req := test.MakeSimpleRequest("GET", "http://localhost/r/"+tc.deploymentID+tc.query, nil)
+		req.Header.Add(requestid.RequestIdHeader, "test")
+		recorded := test.RunRequest(t, api.MakeHandler(), req)
+
+		h.CheckRecordedResponse(t, recorded, tc.JSONResponseParams)
+	}
+}
+
+func TestControllerGetDeviceDeploymentHistory(t *testing.T) {
+	t.Parallel()
+
+	history := []*deployments.DeviceDeploymentHistoryEntry{
+		{
+			DeploymentId: "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			Name:         "foo",
+			ArtifactName: "bar",
+			Status:       deployments.DeviceDeploymentStatusSuccess,
+		},
+	}
 
 	testCases := map[string]struct {
 		h.JSONResponseParams
 
-		deploymentID  string
-		modelStatuses []deployments.DeviceDeployment
-		modelErr      error
+		query        string
+		modelQuery   deployments.DeviceDeploymentHistoryQuery
+		modelHistory []*deployments.DeviceDeploymentHistoryEntry
+		modelTotal   int
+		modelErr     error
+
+		outputLinks []string
 	}{
-		"existing deployment and statuses": {
+		"default query": {
 			JSONResponseParams: h.JSONResponseParams{
 				OutputStatus:     http.StatusOK,
-				OutputBodyObject: statuses,
+				OutputBodyObject: history,
+			},
+			modelQuery: deployments.DeviceDeploymentHistoryQuery{
+				Paging: *paging.NewQuery(),
+			},
+			modelHistory: history,
+			modelTotal:   1,
+			outputLinks: []string{
+				`</r/device0001?page=1&per_page=20>; rel="first"`,
+				`</r/device0001?page=1&per_page=20>; rel="last"`,
 			},
-			deploymentID:  "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
-			modelStatuses: statuses,
-			modelErr:      nil,
 		},
-		"deployment ID format error": {
+		"status and page": {
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusOK,
+				OutputBodyObject: []*deployments.DeviceDeploymentHistoryEntry{},
+			},
+			query: "?status=success&page=3&per_page=5",
+			modelQuery: deployments.DeviceDeploymentHistoryQuery{
+				Status: deployments.DeviceDeploymentStatusSuccess,
+				Paging: paging.Query{Page: 3, PerPage: 5},
+			},
+			modelHistory: []*deployments.DeviceDeploymentHistoryEntry{},
+			modelTotal:   12,
+			outputLinks: []string{
+				`</r/device0001?page=1&per_page=5&status=success>; rel="first"`,
+				`</r/device0001?page=2&per_page=5&status=success>; rel="prev"`,
+				`</r/device0001?page=3&per_page=5&status=success>; rel="last"`,
+			},
+		},
+		"invalid status": {
 			JSONResponseParams: h.JSONResponseParams{
 				OutputStatus:     http.StatusBadRequest,
-				OutputBodyObject: h.ErrorToErrStruct(errors.New("ID is not UUIDv4")),
+				OutputBodyObject: h.ErrorToErrStruct(deployments.ErrHistoryInvalidStatus),
 			},
-			deploymentID:  "30b3e62c9ec24312a7facff24cc7397a",
-			modelStatuses: nil,
-			modelErr:      nil,
+			query: "?status=bogus",
 		},
-		"model error: deployment doesn't exist": {
+		"invalid page": {
 			JSONResponseParams: h.JSONResponseParams{
-				OutputStatus:     http.StatusNotFound,
-				OutputBodyObject: h.ErrorToErrStruct(errors.New("Deployment not found")),
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(paging.ErrInvalidPage),
 			},
-			deploymentID:  "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
-			modelStatuses: nil,
-			modelErr:      ErrModelDeploymentNotFound,
+			query: "?page=first",
 		},
-		"unknown model error": {
+		"per page out of range": {
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(paging.ErrInvalidPerPage),
+			},
+			query: "?per_page=1000",
+		},
+		"model error": {
 			JSONResponseParams: h.JSONResponseParams{
 				OutputStatus:     http.StatusInternalServerError,
 				OutputBodyObject: h.ErrorToErrStruct(errors.New("internal error")),
 			},
-			deploymentID:  "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
-			modelStatuses: nil,
-			modelErr:      errors.New("some unknown error"),
+			modelQuery: deployments.DeviceDeploymentHistoryQuery{
+				Paging: *paging.NewQuery(),
+			},
+			modelErr: errors.New("storage error"),
 		},
 	}
 
@@ -712,22 +1361,26 @@ func TestControllerGetDeviceStatusesForDeployment(t *testing.T) {
 		t.Logf("test case: %s", id)
 
 		deploymentModel := new(mocks.DeploymentsModel)
-		deploymentModel.On("GetDeviceStatusesForDeployment", tc.deploymentID).
-			Return(tc.modelStatuses, tc.modelErr)
+		deploymentModel.On("GetDeviceDeploymentHistory", "device0001", tc.modelQuery).
+			Return(tc.modelHistory, tc.modelTotal, tc.modelErr)
 
 		router, err := rest.MakeRouter(
 			rest.Get("/r/:id",
-				NewDeploymentsController(deploymentModel, new(view.DeploymentsView)).GetDeviceStatusesForDeployment))
+				NewDeploymentsController(deploymentModel, new(view.DeploymentsView)).GetDeviceDeploymentHistory))
 
 		assert.NoError(t, err)
 
 		api := makeApi(router)
 
-		req := test.MakeSimpleRequest("GET", "http://localhost/r/"+tc.deploymentID, nil)
+		req := test.MakeSimpleRequest("GET", "http://localhost/r/device0001"+tc.query, nil)
 		req.Header.Add(requestid.RequestIdHeader, "test")
 		recorded := test.RunRequest(t, api.MakeHandler(), req)
 
 		h.CheckRecordedResponse(t, recorded, tc.JSONResponseParams)
+		if tc.outputLinks != nil {
+			recorded.HeaderIs(paging.HttpHeaderTotalCount, strconv.Itoa(tc.modelTotal))
+			assert.Equal(t, tc.outputLinks, recorded.Recorder.HeaderMap[paging.HttpHeaderLink])
+		}
 	}
 }
 
@@ -766,6 +1419,7 @@ func TestControllerLookupDeployment(t *testing.T) {
 		{
 			InputModelQuery: deployments.Query{
 				SearchText: " ",
+				Paging:     paging.Query{Page: 1},
 			},
 			InputModelError: errors.New("bad query"),
 
@@ -786,6 +1440,7 @@ func TestControllerLookupDeployment(t *testing.T) {
 		{
 			InputModelQuery: deployments.Query{
 				SearchText: "foo-not-found",
+				Paging:     paging.Query{Page: 1},
 			},
 			InputModelDeployments: []*deployments.Deployment{},
 
@@ -798,6 +1453,7 @@ func TestControllerLookupDeployment(t *testing.T) {
 			InputModelQuery: deployments.Query{
 				SearchText: "foo",
 				Status:     deployments.StatusQueryInProgress,
+				Paging:     paging.Query{Page: 1},
 			},
 			SearchStatus:          "inprogress",
 			InputModelDeployments: someDeployments,
@@ -839,7 +1495,8 @@ func TestControllerLookupDeployment(t *testing.T) {
 		deploymentModel := new(mocks.DeploymentsModel)
 
 		deploymentModel.On("LookupDeployment", testCase.InputModelQuery).
-			Return(testCase.InputModelDeployments, testCase.InputModelError)
+			Return(testCase.InputModelDeployments, len(testCase.InputModelDeployments),
+				testCase.InputModelError)
 
 		router, err := rest.MakeRouter(
 			rest.Get("/r",
@@ -867,6 +1524,10 @@ func TestControllerLookupDeployment(t *testing.T) {
 		recorded := test.RunRequest(t, api.MakeHandler(), req)
 
 		h.CheckRecordedResponse(t, recorded, testCase.JSONResponseParams)
+		if testCase.InputModelError == nil {
+			recorded.HeaderIs(paging.HttpHeaderTotalCount,
+				strconv.Itoa(len(testCase.InputModelDeployments)))
+		}
 	}
 }
 
@@ -884,6 +1545,7 @@ func TestParseLookupQuery(t *testing.T) {
 			query: deployments.Query{
 				SearchText: "foo",
 				Status:     deployments.StatusQueryInProgress,
+				Paging:     paging.Query{Page: 1},
 			},
 		},
 		{
@@ -901,6 +1563,7 @@ func TestParseLookupQuery(t *testing.T) {
 			query: deployments.Query{
 				SearchText: "foo",
 				Status:     deployments.StatusQueryFinished,
+				Paging:     paging.Query{Page: 1},
 			},
 		},
 		{
@@ -911,6 +1574,7 @@ func TestParseLookupQuery(t *testing.T) {
 			query: deployments.Query{
 				SearchText: "foo",
 				Status:     deployments.StatusQueryPending,
+				Paging:     paging.Query{Page: 1},
 			},
 		},
 		{
@@ -920,6 +1584,7 @@ func TestParseLookupQuery(t *testing.T) {
 			query: deployments.Query{
 				SearchText: "foo",
 				Status:     deployments.StatusQueryAny,
+				Paging:     paging.Query{Page: 1},
 			},
 		},
 		{
@@ -927,6 +1592,7 @@ func TestParseLookupQuery(t *testing.T) {
 			query: deployments.Query{
 				SearchText: "",
 				Status:     deployments.StatusQueryAny,
+				Paging:     paging.Query{Page: 1},
 			},
 		},
 		{
@@ -936,7 +1602,84 @@ func TestParseLookupQuery(t *testing.T) {
 			query: deployments.Query{
 				SearchText: "",
 				Status:     deployments.StatusQueryPending,
+				Paging:     paging.Query{Page: 1},
+			},
+		},
+		{
+			vals: url.Values{
+				"status": []string{"scheduled"},
+			},
+			query: deployments.Query{
+				SearchText: "",
+				Status:     deployments.StatusQueryScheduled,
+				Paging:     paging.Query{Page: 1},
+			},
+		},
+		{
+			vals: url.Values{
+				"status": []string{"paused"},
+			},
+			query: deployments.Query{
+				SearchText: "",
+				Status:     deployments.StatusQueryPaused,
+				Paging:     paging.Query{Page: 1},
+			},
+		},
+		{
+			vals: url.Values{
+				"page":     []string{"2"},
+				"per_page": []string{"50"},
+				"sort":     []string{"name:desc"},
+			},
+			query: deployments.Query{
+				Status: deployments.StatusQueryAny,
+				Paging: paging.Query{
+					Page:     2,
+					PerPage:  50,
+					Sort:     deployments.SortFieldName,
+					SortDesc: true,
+				},
+			},
+		},
+		{
+			vals: url.Values{
+				"per_page": []string{"0"},
+			},
+			err: paging.ErrInvalidPerPage,
+		},
+		{
+			vals: url.Values{
+				"created_after":   []string{"2016-10-03T00:00:00Z"},
+				"created_before":  []string{"2016-10-10T00:00:00Z"},
+				"finished_after":  []string{"2016-10-04T12:00:00+02:00"},
+				"finished_before": []string{"2016-10-11T00:00:00Z"},
+				"artifact_name":   []string{"release-1.0"},
+				"device_id":       []string{"b532b01a-9313-404f-8d19-e7fcbe5cc347"},
+				"created_by":      []string{"user-1"},
+			},
+			query: deployments.Query{
+				Status:         deployments.StatusQueryAny,
+				CreatedAfter:   TimeToPointer(time.Date(2016, 10, 3, 0, 0, 0, 0, time.UTC)),
+				CreatedBefore:  TimeToPointer(time.Date(2016, 10, 10, 0, 0, 0, 0, time.UTC)),
+				FinishedAfter:  TimeToPointer(time.Date(2016, 10, 4, 12, 0, 0, 0, time.FixedZone("", 2*60*60))),
+				FinishedBefore: TimeToPointer(time.Date(2016, 10, 11, 0, 0, 0, 0, time.UTC)),
+				ArtifactName:   "release-1.0",
+				DeviceID:       "b532b01a-9313-404f-8d19-e7fcbe5cc347",
+				CreatedBy:      "user-1",
+				Paging:         paging.Query{Page: 1},
+			},
+		},
+		{
+			vals: url.Values{
+				"finished_before": []string{"yesterday"},
 			},
+			err: errors.New("invalid finished_before time yesterday, RFC 3339 format expected"),
+		},
+		{
+			vals: url.Values{
+				"sort": []string{"size"},
+			},
+			err: errors.New("Sorting by size is not supported, use one of: created, name, artifact_name, finished"),
 		},
 	}
 	for _, tc := range testCases {
@@ -1308,6 +2051,53 @@ func TestControllerAbortDeployment(t *testing.T) {
 				OutputStatus: http.StatusNoContent,
 			},
 		},
+		{
+			// pause
+			InputBodyObject:                  &report{Status: "paused"},
+			InputModelDeploymentID:           "f826484e-1157-4109-af21-304e6d711560",
+			InputModelStatus:                 "paused",
+			InputModelDeploymentFinishedFlag: false,
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusNoContent,
+			},
+		},
+		{
+			// pause error
+			InputBodyObject:                  &report{Status: "paused"},
+			InputModelDeploymentID:           "f826484e-1157-4109-af21-304e6d711560",
+			InputModelStatus:                 "paused",
+			InputModelDeploymentFinishedFlag: false,
+			InputModelError:                  errors.New("pause error"),
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusInternalServerError,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("internal error")),
+			},
+		},
+		{
+			// resume
+			InputBodyObject:                  &report{Status: "running"},
+			InputModelDeploymentID:           "f826484e-1157-4109-af21-304e6d711560",
+			InputModelStatus:                 "running",
+			InputModelDeploymentFinishedFlag: false,
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusNoContent,
+			},
+		},
+		{
+			// resume finished deployment
+			InputBodyObject:                  &report{Status: "running"},
+			InputModelDeploymentID:           "f826484e-1157-4109-af21-304e6d711560",
+			InputModelStatus:                 "running",
+			InputModelDeploymentFinishedFlag: true,
+
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusUnprocessableEntity,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("Deployment already finished")),
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -1320,6 +2110,10 @@ func TestControllerAbortDeployment(t *testing.T) {
 
 		deploymentModel.On("AbortDeployment", testCase.InputModelDeploymentID).
 			Return(testCase.InputModelError)
+		deploymentModel.On("PauseDeployment", testCase.InputModelDeploymentID).
+			Return(testCase.InputModelError)
+		deploymentModel.On("ResumeDeployment", testCase.InputModelDeploymentID).
+			Return(testCase.InputModelError)
 
 		deploymentModel.On("IsDeploymentFinished", testCase.InputModelDeploymentID).
 			Return(testCase.InputModelDeploymentFinishedFlag, testCase.InputModelIsDeploymentFinishedError)
@@ -1340,3 +2134,193 @@ func TestControllerAbortDeployment(t *testing.T) {
 		h.CheckRecordedResponse(t, recorded, testCase.JSONResponseParams)
 	}
 }
+
+func TestControllerDeleteDeployment(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		h.JSONResponseParams
+
+		InputID         string
+		InputModelError error
+	}{
+		"invalid id": {
+			InputID: "not-uuid",
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(ErrIDNotUUIDv4),
+			},
+		},
+		"not found": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputModelError: ErrModelDeploymentNotFound,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusNotFound,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("Resource not found")),
+			},
+		},
+		"not finished": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputModelError: ErrModelDeploymentNotFinished,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusUnprocessableEntity,
+				OutputBodyObject: h.ErrorToErrStruct(ErrModelDeploymentNotFinished),
+			},
+		},
+		"model error": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputModelError: errors.New("model error"),
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusInternalServerError,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("internal error")),
+			},
+		},
+		"all correct": {
+			InputID: "f826484e-1157-4109-af21-304e6d711560",
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusNoContent,
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deploymentModel := new(mocks.DeploymentsModel)
+
+		deploymentModel.On("DeleteDeployment", testCase.InputID).
+			Return(testCase.InputModelError)
+
+		router, err := rest.MakeRouter(
+			rest.Delete("/r/:id",
+				NewDeploymentsController(deploymentModel,
+					new(view.DeploymentsView)).DeleteDeployment))
+		assert.NoError(t, err)
+
+		api := makeApi(router)
+
+		req := test.MakeSimpleRequest("DELETE", "http://localhost/r/"+testCase.InputID, nil)
+		req.Header.Add(requestid.RequestIdHeader, "test")
+		recorded := test.RunRequest(t, api.MakeHandler(), req)
+
+		h.CheckRecordedResponse(t, recorded, testCase.JSONResponseParams)
+	}
+}
+
+func TestControllerAbortDeviceDeployment(t *testing.T) {
+
+	t.Parallel()
+
+	type report struct {
+		Status string `json:"status"`
+	}
+
+	testCases := map[string]struct {
+		h.JSONResponseParams
+
+		InputID         string
+		InputBodyObject interface{}
+
+		InputModelError error
+	}{
+		"invalid id": {
+			InputID:         "not-uuid",
+			InputBodyObject: &report{Status: "aborted"},
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(ErrIDNotUUIDv4),
+			},
+		},
+		"empty body": {
+			InputID: "f826484e-1157-4109-af21-304e6d711560",
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("JSON payload is empty")),
+			},
+		},
+		"wrong status": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputBodyObject: &report{Status: "paused"},
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(ErrUnexpectedDeploymentStatus),
+			},
+		},
+		"device deployment not found": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputBodyObject: &report{Status: "aborted"},
+			InputModelError: ErrModelDeviceDeploymentNotFound,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusNotFound,
+				OutputBodyObject: h.ErrorToErrStruct(ErrModelDeviceDeploymentNotFound),
+			},
+		},
+		"already aborted": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputBodyObject: &report{Status: "aborted"},
+			InputModelError: ErrDeploymentAborted,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusConflict,
+				OutputBodyObject: h.ErrorToErrStruct(ErrDeploymentAborted),
+			},
+		},
+		"already finished": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputBodyObject: &report{Status: "aborted"},
+			InputModelError: &deployments.StatusTransitionError{
+				From: deployments.DeviceDeploymentStatusSuccess,
+				To:   deployments.DeviceDeploymentStatusAborted,
+			},
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusConflict,
+				OutputBodyObject: map[string]string{
+					"error":      "Invalid device deployment status transition from success to aborted",
+					"status":     "success",
+					"request_id": "test",
+				},
+			},
+		},
+		"model error": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputBodyObject: &report{Status: "aborted"},
+			InputModelError: errors.New("model error"),
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusInternalServerError,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("internal error")),
+			},
+		},
+		"all correct": {
+			InputID:         "f826484e-1157-4109-af21-304e6d711560",
+			InputBodyObject: &report{Status: "aborted"},
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusNoContent,
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deploymentModel := new(mocks.DeploymentsModel)
+
+		deploymentModel.On("AbortDeviceDeployment", testCase.InputID, "device-id").
+			Return(testCase.InputModelError)
+
+		router, err := rest.MakeRouter(
+			rest.Put("/r/:id/devices/:devid/status",
+				NewDeploymentsController(deploymentModel,
+					new(view.DeploymentsView)).AbortDeviceDeployment))
+		assert.NoError(t, err)
+
+		api := makeApi(router)
+
+		req := test.MakeSimpleRequest("PUT",
+			"http://localhost/r/"+testCase.InputID+"/devices/device-id/status",
+			testCase.InputBodyObject)
+		req.Header.Add(requestid.RequestIdHeader, "test")
+		recorded := test.RunRequest(t, api.MakeHandler(), req)
+
+		h.CheckRecordedResponse(t, recorded, testCase.JSONResponseParams)
+	}
+}
@@ -19,30 +19,44 @@ import (
 	"errors"
 
 	"github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/mendersoftware/deployments/utils/paging"
 )
 
 // Errors
 var (
-	ErrModelMissingInput       = errors.New("Missing input deployment data")
-	ErrModelInvalidDeviceID    = errors.New("Invalid device ID")
-	ErrModelDeploymentNotFound = errors.New("Deployment not found")
-	ErrModelInternal           = errors.New("Internal error")
-	ErrStorageInvalidLog       = errors.New("Invalid deployment log")
-	ErrDeploymentAborted       = errors.New("Deployment aborted")
+	ErrModelMissingInput             = errors.New("Missing input deployment data")
+	ErrModelInvalidDeviceID          = errors.New("Invalid device ID")
+	ErrModelDeploymentNotFound       = errors.New("Deployment not found")
+	ErrModelDeviceDeploymentNotFound = errors.New("Device deployment not found")
+	ErrModelInternal                 = errors.New("Internal error")
+	ErrStorageInvalidLog             = errors.New("Invalid deployment log")
+	ErrDeploymentAborted             = errors.New("Deployment aborted")
+	ErrModelNoDevicesMatched         = errors.New("No devices match deployment filter")
+	ErrModelNoDevicesToRetry         = errors.New("No devices with requested statuses")
+	ErrModelDeploymentNotFinished    = errors.New("Deployment not finished")
 )
 
 // Domain model for deployment
 type DeploymentsModel interface {
 	CreateDeployment(ctx context.Context, constructor *deployments.DeploymentConstructor) (string, error)
+	PreviewDeployment(ctx context.Context, constructor *deployments.DeploymentConstructor) (*deployments.DeploymentPreview, error)
+	RetryDeployment(ctx context.Context, deploymentID string, retry *deployments.RetryConstructor) (string, error)
 	GetDeployment(deploymentID string) (*deployments.Deployment, error)
 	IsDeploymentFinished(deploymentID string) (bool, error)
 	AbortDeployment(deploymentID string) error
-	GetDeploymentStats(deploymentID string) (deployments.Stats, error)
+	DeleteDeployment(deploymentID string) error
+	AbortDeviceDeployment(deploymentID string, deviceID string) error
+	PauseDeployment(deploymentID string) error
+	ResumeDeployment(deploymentID string) error
+	GetDeploymentStats(deploymentID string) (*deployments.DeploymentStatistics, error)
+	GetDeploymentFailures(deploymentID string) ([]*deployments.FailureGroup, error)
+	SubscribeDeploymentEvents(deploymentID string) (<-chan *deployments.Event, func())
 	GetDeploymentForDeviceWithCurrent(deviceID string, current deployments.InstalledDeviceDeployment) (*deployments.DeploymentInstructions, error)
 	HasDeploymentForDevice(deploymentID string, deviceID string) (bool, error)
-	UpdateDeviceDeploymentStatus(deploymentID string, deviceID string, status string) error
-	GetDeviceStatusesForDeployment(deploymentID string) ([]deployments.DeviceDeployment, error)
-	LookupDeployment(query deployments.Query) ([]*deployments.Deployment, error)
+	UpdateDeviceDeploymentStatus(deploymentID string, deviceID string, state deployments.DeviceDeploymentState) error
+	GetDeviceStatusesForDeployment(deploymentID string, page paging.Query) ([]deployments.DeviceDeployment, int, error)
+	GetDeviceDeploymentHistory(deviceID string, query deployments.DeviceDeploymentHistoryQuery) ([]*deployments.DeviceDeploymentHistoryEntry, int, error)
+	LookupDeployment(query deployments.Query) ([]*deployments.Deployment, int, error)
 	SaveDeviceDeploymentLog(deviceID string, deploymentID string, logs []deployments.LogMessage) error
 	GetDeviceDeploymentLog(deviceID, deploymentID string) (*deployments.DeploymentLog, error)
 }
@@ -17,6 +17,7 @@ package mocks
 import (
 	"context"
 	"github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -95,27 +96,45 @@ func (_m *DeploymentsModel) GetDeploymentForDeviceWithCurrent(deviceID string,
 }
 
 func (_m *DeploymentsModel) UpdateDeviceDeploymentStatus(deploymentID string,
-	deviceID string, status string) error {
+	deviceID string, state deployments.DeviceDeploymentState) error {
 
-	ret := _m.Called(deploymentID, deviceID, status)
+	ret := _m.Called(deploymentID, deviceID, state)
 	return ret.Error(0)
 }
 
-func (_m *DeploymentsModel) GetDeploymentStats(deploymentID string) (deployments.Stats, error) {
+func (_m *DeploymentsModel) GetDeploymentStats(deploymentID string) (*deployments.DeploymentStatistics, error) {
 	ret := _m.Called(deploymentID)
-	return ret.Get(0).(deployments.Stats), ret.Error(1)
-}
 
-func (_m *DeploymentsModel) GetDeviceStatusesForDeployment(deploymentID string) ([]deployments.DeviceDeployment, error) {
+	var r0 *deployments.DeploymentStatistics
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*deployments.DeploymentStatistics)
+	}
+
+	return r0, ret.Error(1)
+}
 
+func (_m *DeploymentsModel) GetDeploymentFailures(deploymentID string) ([]*deployments.FailureGroup, error) {
 	ret := _m.Called(deploymentID)
-	return ret.Get(0).([]deployments.DeviceDeployment), ret.Error(1)
+
+	var r0 []*deployments.FailureGroup
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*deployments.FailureGroup)
+	}
+
+	return r0, ret.Error(1)
 }
 
-func (_m *DeploymentsModel) LookupDeployment(query deployments.Query) ([]*deployments.Deployment, error) {
+func (_m *DeploymentsModel) GetDeviceStatusesForDeployment(deploymentID string,
+	page paging.Query) ([]deployments.DeviceDeployment, int, error) {
+
+	ret := _m.Called(deploymentID, page)
+	return ret.Get(0).([]deployments.DeviceDeployment), ret.Int(1), ret.Error(2)
+}
+
+func (_m *DeploymentsModel) LookupDeployment(query deployments.Query) ([]*deployments.Deployment, int, error) {
 
 	ret := _m.Called(query)
-	return ret.Get(0).([]*deployments.Deployment), ret.Error(1)
+	return ret.Get(0).([]*deployments.Deployment), ret.Int(1), ret.Error(2)
 }
 
 func (_m *DeploymentsModel) SaveDeviceDeploymentLog(deviceID string,
@@ -141,7 +160,86 @@ func (_m *DeploymentsModel) AbortDeployment(deploymentID string) error {
 	return ret.Error(0)
 }
 
+// SubscribeDeploymentEvents provides a mock function with given fields: deploymentID
+func (_m *DeploymentsModel) SubscribeDeploymentEvents(deploymentID string) (<-chan *deployments.Event, func()) {
+	ret := _m.Called(deploymentID)
+
+	var r0 <-chan *deployments.Event
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(<-chan *deployments.Event)
+	}
+
+	var r1 func()
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(func())
+	}
+
+	return r0, r1
+}
+
+// DeleteDeployment provides a mock function with given fields: deploymentID
+func (_m *DeploymentsModel) DeleteDeployment(deploymentID string) error {
+	ret := _m.Called(deploymentID)
+	return ret.Error(0)
+}
+
 func (_m *DeploymentsModel) IsDeploymentFinished(deploymentID string) (bool, error) {
 	ret := _m.Called(deploymentID)
 	return ret.Bool(0), ret.Error(1)
 }
+
+// AbortDeviceDeployment provides a mock function with given fields: deploymentID, deviceID
+func (_m *DeploymentsModel) AbortDeviceDeployment(deploymentID string, deviceID string) error {
+	ret := _m.Called(deploymentID, deviceID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(deploymentID, deviceID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PauseDeployment provides a mock function with given fields: deploymentID
+func (_m *DeploymentsModel) PauseDeployment(deploymentID string) error {
+	ret := _m.Called(deploymentID)
+	return ret.Error(0)
+}
+
+// ResumeDeployment provides a mock function with given fields: deploymentID
+func (_m *DeploymentsModel) ResumeDeployment(deploymentID string) error {
+	ret := _m.Called(deploymentID)
+	return ret.Error(0)
+}
+
+// GetDeviceDeploymentHistory provides a mock function with given fields: deviceID, query
+func (_m *DeploymentsModel) GetDeviceDeploymentHistory(deviceID string, query deployments.DeviceDeploymentHistoryQuery) ([]*deployments.DeviceDeploymentHistoryEntry, int, error) {
+	ret := _m.Called(deviceID, query)
+
+	var r0 []*deployments.DeviceDeploymentHistoryEntry
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*deployments.DeviceDeploymentHistoryEntry)
+	}
+
+	return r0, ret.Int(1), ret.Error(2)
+}
+
+// PreviewDeployment provides a mock function with given fields: constructor
+func (_m *DeploymentsModel) PreviewDeployment(ctx context.Context, constructor *deployments.DeploymentConstructor) (*deployments.DeploymentPreview, error) {
+	ret := _m.Called(constructor)
+
+	var r0 *deployments.DeploymentPreview
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*deployments.DeploymentPreview)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// RetryDeployment provides a mock function with given fields: deploymentID, retry
+func (_m *DeploymentsModel) RetryDeployment(ctx context.Context, deploymentID string, retry *deployments.RetryConstructor) (string, error) {
+	ret := _m.Called(deploymentID, retry)
+	return ret.String(0), ret.Error(1)
+}
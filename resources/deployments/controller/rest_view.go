@@ -17,16 +17,24 @@ package controller
 import (
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/mendersoftware/go-lib-micro/log"
 )
 
 type RESTView interface {
 	RenderNoUpdateForDevice(w rest.ResponseWriter)
 	RenderSuccessPost(w rest.ResponseWriter, r *rest.Request, id string)
+	RenderDeploymentCreated(w rest.ResponseWriter, id string)
 	RenderSuccessGet(w rest.ResponseWriter, object interface{})
+	RenderSuccessGetPage(w rest.ResponseWriter, r *rest.Request, object interface{}, query paging.Query, total int)
 	RenderEmptySuccessResponse(w rest.ResponseWriter)
 	RenderError(w rest.ResponseWriter, r *rest.Request, err error, status int, l *log.Logger)
 	RenderInternalError(w rest.ResponseWriter, r *rest.Request, err error, l *log.Logger)
 	RenderErrorNotFound(w rest.ResponseWriter, r *rest.Request, l *log.Logger)
 	RenderDeploymentLog(w rest.ResponseWriter, dlog deployments.DeploymentLog)
+	RenderStatusTransitionError(w rest.ResponseWriter, r *rest.Request, err *deployments.StatusTransitionError, l *log.Logger)
+	RenderConflictError(w rest.ResponseWriter, r *rest.Request, err *deployments.ConflictError, l *log.Logger)
+	RenderEventStream(w rest.ResponseWriter)
+	RenderEvent(w rest.ResponseWriter, event *deployments.Event) error
+	RenderEventKeepAlive(w rest.ResponseWriter) error
 }
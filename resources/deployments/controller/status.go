@@ -16,17 +16,32 @@ package controller
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/mendersoftware/deployments/resources/deployments"
 	"github.com/pkg/errors"
 )
 
 var (
-	ErrBadStatus = errors.New("unknown status value")
+	ErrBadStatus   = errors.New("unknown status value")
+	ErrBadSubState = fmt.Errorf("substate can be at most %d characters long",
+		deployments.DeviceDeploymentSubStateMaxLength)
+	ErrBadProgress = fmt.Errorf("progress has to be between 0 and %d",
+		deployments.DeviceDeploymentProgressMax)
+	ErrBadFailureReasonStatus = errors.New("failure reason can be reported only with failure status")
 )
 
 type statusReport struct {
 	Status string
+
+	// Optional device specific detail of the status
+	SubState *string `json:"substate"`
+
+	// Optional progress of the status in percent
+	Progress *int `json:"progress"`
+
+	// Optional reason of the failure, only with failure status
+	FailureReason *deployments.FailureReason `json:"failure_reason"`
 }
 
 func containsString(what string, in []string) bool {
@@ -62,8 +77,37 @@ func (s *statusReport) UnmarshalJSON(raw []byte) error {
 		return ErrBadStatus
 	}
 
+	if temp.SubState != nil &&
+		len(*temp.SubState) > deployments.DeviceDeploymentSubStateMaxLength {
+		return ErrBadSubState
+	}
+
+	if temp.Progress != nil &&
+		(*temp.Progress < 0 || *temp.Progress > deployments.DeviceDeploymentProgressMax) {
+		return ErrBadProgress
+	}
+
+	if temp.FailureReason != nil {
+		if temp.Status != deployments.DeviceDeploymentStatusFailure {
+			return ErrBadFailureReasonStatus
+		}
+		if err := temp.FailureReason.Validate(); err != nil {
+			return err
+		}
+	}
+
 	// all good
-	s.Status = temp.Status
+	*s = statusReport(temp)
 
 	return nil
 }
+
+// State returns device deployment state described by the report.
+func (s *statusReport) State() deployments.DeviceDeploymentState {
+	return deployments.DeviceDeploymentState{
+		Status:        s.Status,
+		SubState:      s.SubState,
+		Progress:      s.Progress,
+		FailureReason: s.FailureReason,
+	}
+}
@@ -16,9 +16,11 @@ package controller
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/mendersoftware/deployments/utils/pointers"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,6 +38,44 @@ func TestStatusUnmarshal(t *testing.T) {
 	assert.Equal(t,
 		statusReport{Status: deployments.DeviceDeploymentStatusInstalling},
 		report)
+
+	err = json.Unmarshal([]byte(`{"status": "downloading", "substate": "fetching rootfs", "progress": 42}`), &report)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		deployments.DeviceDeploymentState{
+			Status:   deployments.DeviceDeploymentStatusDownloading,
+			SubState: pointers.StringToPointer("fetching rootfs"),
+			Progress: pointers.IntToPointer(42),
+		},
+		report.State())
+
+	err = json.Unmarshal([]byte(`{"status": "downloading", "progress": 101}`), &report)
+	assert.EqualError(t, err, ErrBadProgress.Error())
+
+	err = json.Unmarshal([]byte(`{"status": "downloading", "progress": -1}`), &report)
+	assert.EqualError(t, err, ErrBadProgress.Error())
+
+	err = json.Unmarshal([]byte(`{"status": "downloading", "substate": "`+
+		strings.Repeat("x", deployments.DeviceDeploymentSubStateMaxLength+1)+`"}`), &report)
+	assert.EqualError(t, err, ErrBadSubState.Error())
+
+	err = json.Unmarshal([]byte(`{"status": "failure", "failure_reason": {"code": "disk_full", "message": "No space left"}}`), &report)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		deployments.DeviceDeploymentState{
+			Status: deployments.DeviceDeploymentStatusFailure,
+			FailureReason: &deployments.FailureReason{
+				Code:    "disk_full",
+				Message: "No space left",
+			},
+		},
+		report.State())
+
+	err = json.Unmarshal([]byte(`{"status": "installing", "failure_reason": {"code": "disk_full"}}`), &report)
+	assert.EqualError(t, err, ErrBadFailureReasonStatus.Error())
+
+	err = json.Unmarshal([]byte(`{"status": "failure", "failure_reason": {"message": "No space left"}}`), &report)
+	assert.EqualError(t, err, deployments.ErrFailureReasonInvalidCode.Error())
 }
 
 func TestContainsString(t *testing.T) {
@@ -20,12 +20,25 @@ import (
 	"time"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/mendersoftware/deployments/utils/paging"
+	"github.com/mendersoftware/deployments/utils/pointers"
 	"github.com/satori/go.uuid"
 )
 
 // Errors
 var (
-	ErrInvalidDeviceID = errors.New("Invalid device ID")
+	ErrInvalidDeviceID      = errors.New("Invalid device ID")
+	ErrInvalidMaxRetries    = errors.New("Max retries can not be negative")
+	ErrInvalidRetryBackoff  = errors.New("Retry backoff can not be negative")
+	ErrInvalidMaxInProgress = errors.New("Max in progress devices can not be negative")
+	ErrExpiresBeforeStart   = errors.New("Deployment has to expire after its start time")
+	ErrInvalidPriority      = errors.New("Priority can not be negative")
+)
+
+// Deployment status changes requested by the user, besides abort
+const (
+	DeploymentStatusPaused  = "paused"
+	DeploymentStatusRunning = "running"
 )
 
 // DeploymentConstructor represent input data needed for creating new Deployment (they differ in fields)
@@ -36,8 +49,45 @@ type DeploymentConstructor struct {
 	// Artifact name to be installed required, associated with image
 	ArtifactName *string `json:"artifact_name,omitempty" valid:"length(1|4096),required"`
 
-	// List of device id's targeted for deployments, required
-	Devices []string `json:"devices,omitempty" valid:"required" bson:"-"`
+	// List of device id's targeted for deployments, required unless filter is given
+	Devices []string `json:"devices,omitempty" valid:"optional" bson:"-"`
+
+	// Inventory filter selecting target devices, alternative to devices list
+	Filter *DeviceFilter `json:"filter,omitempty" valid:"-"`
+
+	// Rollout phases, optional; without phases all devices are targeted at once
+	Phases []*PhaseConstructor `json:"phases,omitempty" valid:"-" bson:"-"`
+
+	// Automatic abort policy, optional
+	FailurePolicy *FailurePolicy `json:"failure_policy,omitempty" valid:"-"`
+
+	// Devices do not receive the deployment before this time, optional
+	StartTime *time.Time `json:"start_time,omitempty" valid:"-"`
+
+	// Devices receive the deployment only within this window, optional
+	MaintenanceWindow *MaintenanceWindow `json:"maintenance_window,omitempty" valid:"-"`
+
+	// Devices which did not start the deployment by this time do not receive
+	// it, their status changes to expired, optional
+	ExpiresAt *time.Time `json:"expires_at,omitempty" valid:"-"`
+
+	// Number of times failed installation is retried on a device, optional
+	MaxRetries int `json:"max_retries,omitempty" valid:"-"`
+
+	// Delay in seconds before failed installation is retried, optional
+	RetryBackoff int `json:"retry_backoff,omitempty" valid:"-"`
+
+	// Maximum number of devices downloading, installing or rebooting at
+	// once, optional; no limit if 0
+	MaxInProgress int `json:"max_in_progress,omitempty" valid:"-"`
+
+	// Handling of devices which already have an active deployment, optional;
+	// queue if empty
+	ConflictPolicy string `json:"conflict_policy,omitempty" valid:"-"`
+
+	// Devices receive deployments of higher priority first, optional;
+	// deployments of the same priority are received in creation order
+	Priority int `json:"priority,omitempty" valid:"-"`
 }
 
 func NewDeploymentConstructor() *DeploymentConstructor {
@@ -51,12 +101,65 @@ func (c *DeploymentConstructor) Validate() error {
 		return err
 	}
 
+	if c.Filter != nil {
+		if len(c.Devices) != 0 {
+			return ErrDevicesAndFilterGiven
+		}
+		if err := c.Filter.Validate(); err != nil {
+			return err
+		}
+	} else if len(c.Devices) == 0 {
+		return ErrMissingTargetDevices
+	}
+
 	for _, id := range c.Devices {
 		if govalidator.IsNull(id) {
 			return ErrInvalidDeviceID
 		}
 	}
 
+	// Number of filtered devices is not known before the filter is resolved
+	if c.Filter != nil {
+		if err := validatePhaseSettings(c.Phases); err != nil {
+			return err
+		}
+	} else if err := ValidatePhases(c.Phases, len(c.Devices)); err != nil {
+		return err
+	}
+
+	if c.FailurePolicy != nil {
+		if err := c.FailurePolicy.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.MaintenanceWindow != nil {
+		if err := c.MaintenanceWindow.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.ExpiresAt != nil && c.StartTime != nil && !c.ExpiresAt.After(*c.StartTime) {
+		return ErrExpiresBeforeStart
+	}
+
+	if c.MaxRetries < 0 {
+		return ErrInvalidMaxRetries
+	}
+	if c.RetryBackoff < 0 {
+		return ErrInvalidRetryBackoff
+	}
+	if c.MaxInProgress < 0 {
+		return ErrInvalidMaxInProgress
+	}
+	if c.Priority < 0 {
+		return ErrInvalidPriority
+	}
+
+	if err := ValidateConflictPolicy(c.ConflictPolicy); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -77,6 +180,34 @@ type Deployment struct {
 	// Initialized with the "pending" counter set to total device count for deployment.
 	// Individual counter incremented/decremented according to device status updates.
 	Stats map[string]int `json:"-"`
+
+	// Rollout phases, devices of a phase get the deployment once the phase opens
+	Phases []*Phase `json:"phases,omitempty" valid:"-"`
+
+	// Reason of automatic abort
+	AbortReason *string `json:"abort_reason,omitempty" valid:"-"`
+
+	// Number of devices targeted by the deployment
+	DeviceCount int `json:"device_count,omitempty" valid:"-"`
+
+	// Paused deployment is not handed out to devices, devices already
+	// installing it may finish
+	Paused bool `json:"-" valid:"-"`
+
+	// Number of devices holding one of the slots limited by max in progress
+	// setting, maintained only if the limit is set
+	InProgress int `json:"-" valid:"-"`
+
+	// Id of the deployment this deployment retries devices of
+	RetryOf *string `json:"retry_of,omitempty" valid:"-"`
+
+	// Set when the whole deployment was aborted, as opposed to single devices
+	// being aborted. Missing for deployments created before devices could be
+	// aborted one by one.
+	Aborted *bool `json:"-" valid:"-"`
+
+	// Identity of the user who created the deployment, if known
+	CreatedBy *string `json:"created_by,omitempty" valid:"-"`
 }
 
 // NewDeployment creates new deployment object, sets create data by default.
@@ -89,6 +220,7 @@ func NewDeployment() *Deployment {
 		Id:      &id,
 		DeploymentConstructor: NewDeploymentConstructor(),
 		Stats: NewDeviceDeploymentStats(),
+		Aborted: pointers.BoolToPointer(false),
 	}
 }
 
@@ -98,13 +230,27 @@ func NewDeploymentFromConstructor(constructor *DeploymentConstructor) *Deploymen
 	deployment := NewDeployment()
 	deployment.DeploymentConstructor = constructor
 
+	if constructor != nil {
+		deployment.DeviceCount = len(constructor.Devices)
+		deployment.Phases = NewPhases(constructor.Phases, len(constructor.Devices))
+	}
+
 	return deployment
 }
 
 // Validate checkes structure according to valid tags
+// Deployment has to target at least one device, filters are expected to be
+// resolved at this point.
 func (d *Deployment) Validate() error {
-	_, err := govalidator.ValidateStruct(d)
-	return err
+	if _, err := govalidator.ValidateStruct(d); err != nil {
+		return err
+	}
+
+	if len(d.Devices) == 0 {
+		return ErrMissingTargetDevices
+	}
+
+	return nil
 }
 
 // To be able to hide devices field, from API output provice custom marshaler
@@ -144,8 +290,18 @@ func (d *Deployment) IsInProgress() bool {
 	return false
 }
 
+// HasInProgressLimit checks if deployment limits number of devices updating
+// at once.
+func (d *Deployment) HasInProgressLimit() bool {
+	return d.DeploymentConstructor != nil && d.MaxInProgress > 0
+}
+
 func (d *Deployment) IsAborted() bool {
-	// check if there are pending devices
+	if d.Aborted != nil {
+		return *d.Aborted
+	}
+
+	// legacy deployment, devices were only aborted together with the deployment
 	if d.Stats[DeviceDeploymentStatusAborted] != 0 {
 		return true
 	}
@@ -179,18 +335,62 @@ func (d *Deployment) IsPending() bool {
 	return true
 }
 
+// IsScheduled checks if deployment start time is still ahead
+func (d *Deployment) IsScheduled(now time.Time) bool {
+	if d.DeploymentConstructor == nil || d.StartTime == nil {
+		return false
+	}
+	return now.Before(*d.StartTime)
+}
+
+// IsExpired checks if deployment expiry time has passed
+func (d *Deployment) IsExpired(now time.Time) bool {
+	if d.DeploymentConstructor == nil || d.ExpiresAt == nil {
+		return false
+	}
+	return !now.Before(*d.ExpiresAt)
+}
+
+// IsWithinSchedule checks if devices can receive the deployment at given time,
+// according to deployment start time, expiry time and maintenance window.
+func (d *Deployment) IsWithinSchedule(now time.Time) bool {
+	if d.IsScheduled(now) || d.IsExpired(now) {
+		return false
+	}
+	if d.DeploymentConstructor != nil && d.MaintenanceWindow != nil {
+		return d.MaintenanceWindow.Contains(now)
+	}
+	return true
+}
+
 func (d *Deployment) GetStatus() string {
 	if d.IsAborted() {
 		return "aborted"
+	} else if d.Paused && !d.IsFinished() {
+		return DeploymentStatusPaused
 	} else if d.IsInProgress() {
 		return "inprogress"
 	} else if d.IsFinished() {
 		return "finished"
+	} else if d.IsScheduled(time.Now()) {
+		return "scheduled"
 	} else {
 		return "pending"
 	}
 }
 
+// PhaseOf returns index of the phase device at position `idx` of deployment
+// devices list belongs to.
+func (d *Deployment) PhaseOf(idx int) int {
+	for i, p := range d.Phases {
+		if idx < p.DeviceCount {
+			return i
+		}
+		idx -= p.DeviceCount
+	}
+	return 0
+}
+
 type StatusQuery int
 
 const (
@@ -199,6 +399,8 @@ const (
 	StatusQueryInProgress
 	StatusQueryFinished
 	StatusQueryAborted
+	StatusQueryScheduled
+	StatusQueryPaused
 )
 
 // Deployment lookup query
@@ -207,4 +409,33 @@ type Query struct {
 	SearchText string
 	// deployment status
 	Status StatusQuery
+	// match deployments created at or after this time
+	CreatedAfter *time.Time
+	// match deployments created before this time
+	CreatedBefore *time.Time
+	// match deployments finished at or after this time
+	FinishedAfter *time.Time
+	// match deployments finished before this time
+	FinishedBefore *time.Time
+	// match deployments of this artifact exactly
+	ArtifactName string
+	// match deployments targeting this device
+	DeviceID string
+	// match deployments created by this user
+	CreatedBy string
+	// page and order of matching deployments, all deployments if not set
+	Paging paging.Query
+}
+
+// Fields deployments can be sorted by
+const (
+	SortFieldCreated      = "created"
+	SortFieldName         = "name"
+	SortFieldArtifactName = "artifact_name"
+	SortFieldFinished     = "finished"
+)
+
+// DeploymentSortFields lists fields deployments can be sorted by.
+func DeploymentSortFields() []string {
+	return []string{SortFieldCreated, SortFieldName, SortFieldArtifactName, SortFieldFinished}
 }
@@ -259,6 +259,7 @@ func TestDeploymentGetStatus(t *testing.T) {
 
 	tests := map[string]struct {
 		Stats        map[string]int
+		Aborted      *bool
 		OutputStatus string
 	}{
 		"Single NoArtifact": {
@@ -295,6 +296,30 @@ func TestDeploymentGetStatus(t *testing.T) {
 			OutputStatus: "finished",
 		},
 		"Failed + Aborted": {
+			Stats: map[string]int{
+				DeviceDeploymentStatusFailure: 1,
+				DeviceDeploymentStatusAborted: 1,
+			},
+			Aborted:      BoolToPointer(true),
+			OutputStatus: "aborted",
+		},
+		"Failed + single device aborted": {
+			Stats: map[string]int{
+				DeviceDeploymentStatusFailure: 1,
+				DeviceDeploymentStatusAborted: 1,
+			},
+			Aborted:      BoolToPointer(false),
+			OutputStatus: "finished",
+		},
+		"Pending + single device aborted": {
+			Stats: map[string]int{
+				DeviceDeploymentStatusPending: 1,
+				DeviceDeploymentStatusAborted: 1,
+			},
+			Aborted:      BoolToPointer(false),
+			OutputStatus: "pending",
+		},
+		"Legacy Failed + Aborted": {
 			Stats: map[string]int{
 				DeviceDeploymentStatusFailure: 1,
 				DeviceDeploymentStatusAborted: 1,
@@ -339,8 +364,236 @@ func TestDeploymentGetStatus(t *testing.T) {
 
 		dep := NewDeployment()
 		dep.Stats = test.Stats
+		dep.Aborted = test.Aborted
 
 		assert.Equal(t, test.OutputStatus, dep.GetStatus())
 	}
 
 }
+
+func TestDeploymentSchedule(t *testing.T) {
+
+	t.Parallel()
+
+	now := time.Date(2016, 10, 17, 3, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	testCases := map[string]struct {
+		InputStartTime         *time.Time
+		InputExpiresAt         *time.Time
+		InputMaintenanceWindow *MaintenanceWindow
+
+		OutputScheduled      bool
+		OutputExpired        bool
+		OutputWithinSchedule bool
+	}{
+		"no schedule": {
+			OutputWithinSchedule: true,
+		},
+		"start time passed": {
+			InputStartTime:       &before,
+			OutputWithinSchedule: true,
+		},
+		"start time ahead": {
+			InputStartTime:  &after,
+			OutputScheduled: true,
+		},
+		"within window": {
+			InputMaintenanceWindow: &MaintenanceWindow{Start: "02:00", End: "05:00"},
+			OutputWithinSchedule:   true,
+		},
+		"outside window": {
+			InputMaintenanceWindow: &MaintenanceWindow{Start: "04:00", End: "05:00"},
+		},
+		"within window spanning midnight": {
+			InputMaintenanceWindow: &MaintenanceWindow{Start: "22:00", End: "03:30"},
+			OutputWithinSchedule:   true,
+		},
+		"outside window spanning midnight": {
+			InputMaintenanceWindow: &MaintenanceWindow{Start: "22:00", End: "03:00"},
+		},
+		"start time ahead, within window": {
+			InputStartTime:         &after,
+			InputMaintenanceWindow: &MaintenanceWindow{Start: "02:00", End: "05:00"},
+			OutputScheduled:        true,
+		},
+		"expiry ahead": {
+			InputExpiresAt:       &after,
+			OutputWithinSchedule: true,
+		},
+		"expired": {
+			InputExpiresAt: &before,
+			OutputExpired:  true,
+		},
+		"expires now": {
+			InputExpiresAt: &now,
+			OutputExpired:  true,
+		},
+		"expired, within window": {
+			InputExpiresAt:         &before,
+			InputMaintenanceWindow: &MaintenanceWindow{Start: "02:00", End: "05:00"},
+			OutputExpired:          true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		dep := NewDeployment()
+		dep.StartTime = testCase.InputStartTime
+		dep.ExpiresAt = testCase.InputExpiresAt
+		dep.MaintenanceWindow = testCase.InputMaintenanceWindow
+
+		assert.Equal(t, testCase.OutputScheduled, dep.IsScheduled(now))
+		assert.Equal(t, testCase.OutputExpired, dep.IsExpired(now))
+		assert.Equal(t, testCase.OutputWithinSchedule, dep.IsWithinSchedule(now))
+	}
+}
+
+func TestDeploymentGetStatusScheduled(t *testing.T) {
+
+	t.Parallel()
+
+	after := time.Now().Add(time.Hour)
+
+	dep := NewDeployment()
+	dep.StartTime = &after
+	dep.Stats[DeviceDeploymentStatusPending] = 1
+	assert.Equal(t, "scheduled", dep.GetStatus())
+
+	// start time passed
+	dep.StartTime = TimeToPointer(time.Now().Add(-time.Hour))
+	assert.Equal(t, "pending", dep.GetStatus())
+}
+
+func TestDeploymentGetStatusPaused(t *testing.T) {
+
+	t.Parallel()
+
+	dep := NewDeployment()
+	dep.Paused = true
+	dep.Stats[DeviceDeploymentStatusPending] = 1
+	dep.Stats[DeviceDeploymentStatusInstalling] = 1
+	assert.Equal(t, "paused", dep.GetStatus())
+
+	// devices that were already installing finished, pending ones remain
+	dep.Stats[DeviceDeploymentStatusInstalling] = 0
+	dep.Stats[DeviceDeploymentStatusSuccess] = 1
+	assert.Equal(t, "paused", dep.GetStatus())
+
+	// nothing left to deploy
+	dep.Stats[DeviceDeploymentStatusPending] = 0
+	assert.Equal(t, "finished", dep.GetStatus())
+
+	// single device aborted
+	dep.Stats[DeviceDeploymentStatusAborted] = 1
+	assert.Equal(t, "finished", dep.GetStatus())
+
+	// aborted takes precedence
+	dep.Aborted = BoolToPointer(true)
+	assert.Equal(t, "aborted", dep.GetStatus())
+}
+
+func TestMaintenanceWindowValidate(t *testing.T) {
+
+	t.Parallel()
+
+	assert.NoError(t, (&MaintenanceWindow{Start: "02:00", End: "05:00"}).Validate())
+	assert.NoError(t, (&MaintenanceWindow{Start: "23:00", End: "01:00"}).Validate())
+	assert.EqualError(t, (&MaintenanceWindow{Start: "2am", End: "05:00"}).Validate(),
+		ErrMaintenanceWindowInvalidTime.Error())
+	assert.EqualError(t, (&MaintenanceWindow{Start: "02:00", End: "25:00"}).Validate(),
+		ErrMaintenanceWindowInvalidTime.Error())
+	assert.EqualError(t, (&MaintenanceWindow{Start: "02:00", End: "02:00"}).Validate(),
+		ErrMaintenanceWindowEmpty.Error())
+}
+
+func TestDeploymentConstructorValidateRetries(t *testing.T) {
+
+	t.Parallel()
+
+	constructor := NewDeploymentConstructor()
+	constructor.Name = StringToPointer("foo")
+	constructor.ArtifactName = StringToPointer("bar")
+	constructor.Devices = []string{"a"}
+
+	constructor.MaxRetries = 3
+	constructor.RetryBackoff = 60
+	assert.NoError(t, constructor.Validate())
+
+	constructor.MaxRetries = -1
+	assert.EqualError(t, constructor.Validate(), ErrInvalidMaxRetries.Error())
+
+	constructor.MaxRetries = 3
+	constructor.RetryBackoff = -1
+	assert.EqualError(t, constructor.Validate(), ErrInvalidRetryBackoff.Error())
+}
+
+func TestDeploymentConstructorValidateMaxInProgress(t *testing.T) {
+
+	t.Parallel()
+
+	constructor := NewDeploymentConstructor()
+	constructor.Name = StringToPointer("foo")
+	constructor.ArtifactName = StringToPointer("bar")
+	constructor.Devices = []string{"a"}
+
+	constructor.MaxInProgress = 10
+	assert.NoError(t, constructor.Validate())
+
+	constructor.MaxInProgress = -1
+	assert.EqualError(t, constructor.Validate(), ErrInvalidMaxInProgress.Error())
+}
+
+func TestDeploymentConstructorValidatePriority(t *testing.T) {
+
+	t.Parallel()
+
+	constructor := NewDeploymentConstructor()
+	constructor.Name = StringToPointer("foo")
+	constructor.ArtifactName = StringToPointer("bar")
+	constructor.Devices = []string{"a"}
+
+	constructor.Priority = 100
+	assert.NoError(t, constructor.Validate())
+
+	constructor.Priority = -1
+	assert.EqualError(t, constructor.Validate(), ErrInvalidPriority.Error())
+}
+
+func TestDeploymentConstructorValidateExpiresAt(t *testing.T) {
+
+	t.Parallel()
+
+	start := time.Now().Add(time.Hour)
+
+	constructor := NewDeploymentConstructor()
+	constructor.Name = StringToPointer("foo")
+	constructor.ArtifactName = StringToPointer("bar")
+	constructor.Devices = []string{"a"}
+
+	constructor.ExpiresAt = TimeToPointer(start.Add(-time.Minute))
+	assert.NoError(t, constructor.Validate())
+
+	constructor.StartTime = &start
+	assert.EqualError(t, constructor.Validate(), ErrExpiresBeforeStart.Error())
+
+	constructor.ExpiresAt = &start
+	assert.EqualError(t, constructor.Validate(), ErrExpiresBeforeStart.Error())
+
+	constructor.ExpiresAt = TimeToPointer(start.Add(time.Minute))
+	assert.NoError(t, constructor.Validate())
+}
+
+func TestDeploymentHasInProgressLimit(t *testing.T) {
+
+	t.Parallel()
+
+	assert.False(t, (&Deployment{}).HasInProgressLimit())
+	assert.False(t, NewDeployment().HasInProgressLimit())
+
+	deployment := NewDeployment()
+	deployment.MaxInProgress = 5
+	assert.True(t, deployment.HasInProgressLimit())
+}
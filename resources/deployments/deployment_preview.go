@@ -0,0 +1,76 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments
+
+// DeviceDeploymentPreview describes how a device would take part in
+// a deployment.
+type DeviceDeploymentPreview struct {
+	// Device id
+	DeviceId string `json:"id"`
+
+	// Device type reported by inventory
+	DeviceType string `json:"device_type"`
+
+	// Image assigned to the device, empty if there is no matching artifact
+	ImageId string `json:"image_id,omitempty"`
+
+	// Initial device deployment status
+	Status string `json:"status"`
+
+	// Index of deployment phase the device belongs to
+	Phase int `json:"phase,omitempty"`
+}
+
+// DeploymentPreview is the outcome of deployment creation, computed without
+// storing anything.
+type DeploymentPreview struct {
+	// Per device details, in order devices were listed
+	Devices []*DeviceDeploymentPreview `json:"devices"`
+
+	// Number of devices by device type
+	DeviceTypes map[string]int `json:"device_types"`
+
+	// Number of devices by initial status
+	Stats Stats `json:"stats"`
+}
+
+// NewDeploymentPreview summarizes generated device deployments.
+func NewDeploymentPreview(deviceDeployments []*DeviceDeployment) *DeploymentPreview {
+	preview := &DeploymentPreview{
+		Devices:     make([]*DeviceDeploymentPreview, 0, len(deviceDeployments)),
+		DeviceTypes: make(map[string]int),
+		Stats:       NewDeviceDeploymentStats(),
+	}
+
+	for _, d := range deviceDeployments {
+		device := &DeviceDeploymentPreview{
+			DeviceId: *d.DeviceId,
+			Status:   *d.Status,
+			Phase:    d.Phase,
+		}
+		if d.DeviceType != nil {
+			device.DeviceType = *d.DeviceType
+		}
+		if d.Image != nil {
+			device.ImageId = d.Image.Id
+		}
+
+		preview.Devices = append(preview.Devices, device)
+		preview.DeviceTypes[device.DeviceType]++
+		preview.Stats[device.Status]++
+	}
+
+	return preview
+}
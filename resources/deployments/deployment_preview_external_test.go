@@ -0,0 +1,63 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments_test
+
+import (
+	"testing"
+
+	. "github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/mendersoftware/deployments/resources/images"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDeploymentPreview(t *testing.T) {
+
+	t.Parallel()
+
+	assigned := NewDeviceDeployment("device-1", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a")
+	assigned.DeviceType = StringToPointer("rpi")
+	assigned.Image = &images.SoftwareImage{Id: "image-1"}
+	assigned.Phase = 1
+
+	unassigned := NewDeviceDeployment("device-2", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a")
+	unassigned.DeviceType = StringToPointer("rpi")
+	unassigned.Status = StringToPointer(DeviceDeploymentStatusNoArtifact)
+
+	preview := NewDeploymentPreview([]*DeviceDeployment{assigned, unassigned})
+
+	assert.Equal(t, []*DeviceDeploymentPreview{
+		{
+			DeviceId:   "device-1",
+			DeviceType: "rpi",
+			ImageId:    "image-1",
+			Status:     DeviceDeploymentStatusPending,
+			Phase:      1,
+		},
+		{
+			DeviceId:   "device-2",
+			DeviceType: "rpi",
+			Status:     DeviceDeploymentStatusNoArtifact,
+		},
+	}, preview.Devices)
+	assert.Equal(t, map[string]int{"rpi": 2}, preview.DeviceTypes)
+	assert.Equal(t, 1, preview.Stats[DeviceDeploymentStatusPending])
+	assert.Equal(t, 1, preview.Stats[DeviceDeploymentStatusNoArtifact])
+	assert.Equal(t, 0, preview.Stats[DeviceDeploymentStatusSuccess])
+
+	empty := NewDeploymentPreview(nil)
+	assert.Empty(t, empty.Devices)
+	assert.Empty(t, empty.DeviceTypes)
+}
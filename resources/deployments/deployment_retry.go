@@ -0,0 +1,81 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments
+
+import (
+	"errors"
+)
+
+// Errors
+var (
+	ErrRetryInvalidStatus = errors.New("Only devices with failure, noartifact, aborted or expired status can be retried")
+)
+
+// RetryConstructor selects devices of a deployment to be targeted again by
+// a new deployment.
+type RetryConstructor struct {
+	// Statuses of devices to be retried, failure if not given
+	Statuses []string `json:"statuses,omitempty"`
+}
+
+func NewRetryConstructor() *RetryConstructor {
+	return &RetryConstructor{
+		Statuses: []string{DeviceDeploymentStatusFailure},
+	}
+}
+
+func (c *RetryConstructor) Validate() error {
+	for _, status := range c.Statuses {
+		switch status {
+		case DeviceDeploymentStatusFailure,
+			DeviceDeploymentStatusNoArtifact,
+			DeviceDeploymentStatusAborted,
+			DeviceDeploymentStatusExpired:
+		default:
+			return ErrRetryInvalidStatus
+		}
+	}
+	return nil
+}
+
+// Matches checks if device deployment has one of the selected statuses.
+func (c *RetryConstructor) Matches(deviceDeployment *DeviceDeployment) bool {
+	if deviceDeployment.Status == nil {
+		return false
+	}
+	for _, status := range c.Statuses {
+		if *deviceDeployment.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRetryDeploymentConstructor creates constructor of a deployment
+// installing the same artifact as `deployment` on given devices. Rollout
+// policies are carried over, schedule and phases are not.
+func NewRetryDeploymentConstructor(deployment *Deployment, devices []string) *DeploymentConstructor {
+	return &DeploymentConstructor{
+		Name:           deployment.Name,
+		ArtifactName:   deployment.ArtifactName,
+		Devices:        devices,
+		FailurePolicy:  deployment.FailurePolicy,
+		MaxRetries:     deployment.MaxRetries,
+		RetryBackoff:   deployment.RetryBackoff,
+		MaxInProgress:  deployment.MaxInProgress,
+		ConflictPolicy: deployment.ConflictPolicy,
+		Priority:       deployment.Priority,
+	}
+}
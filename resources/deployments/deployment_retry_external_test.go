@@ -0,0 +1,118 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments_test
+
+import (
+	"testing"
+
+	. "github.com/mendersoftware/deployments/resources/deployments"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryConstructorValidate(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputStatuses []string
+
+		OutputError error
+	}{
+		"default": {
+			InputStatuses: NewRetryConstructor().Statuses,
+		},
+		"final statuses": {
+			InputStatuses: []string{
+				DeviceDeploymentStatusFailure,
+				DeviceDeploymentStatusNoArtifact,
+				DeviceDeploymentStatusAborted,
+				DeviceDeploymentStatusExpired,
+			},
+		},
+		"success": {
+			InputStatuses: []string{DeviceDeploymentStatusSuccess},
+			OutputError:   ErrRetryInvalidStatus,
+		},
+		"active": {
+			InputStatuses: []string{DeviceDeploymentStatusFailure, DeviceDeploymentStatusInstalling},
+			OutputError:   ErrRetryInvalidStatus,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		err := (&RetryConstructor{Statuses: testCase.InputStatuses}).Validate()
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestRetryConstructorMatches(t *testing.T) {
+
+	t.Parallel()
+
+	retry := &RetryConstructor{
+		Statuses: []string{DeviceDeploymentStatusFailure, DeviceDeploymentStatusAborted},
+	}
+
+	failed := NewDeviceDeployment("a", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a")
+	failed.Status = StringToPointer(DeviceDeploymentStatusFailure)
+	assert.True(t, retry.Matches(failed))
+
+	succeeded := NewDeviceDeployment("b", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a")
+	succeeded.Status = StringToPointer(DeviceDeploymentStatusSuccess)
+	assert.False(t, retry.Matches(succeeded))
+
+	assert.False(t, retry.Matches(&DeviceDeployment{}))
+}
+
+func TestNewRetryDeploymentConstructor(t *testing.T) {
+
+	t.Parallel()
+
+	maxFailures := 1
+	constructor := NewDeploymentConstructor()
+	constructor.Name = StringToPointer("foo")
+	constructor.ArtifactName = StringToPointer("bar")
+	constructor.Devices = []string{"a", "b", "c"}
+	constructor.Phases = []*PhaseConstructor{{DeviceCount: 1}, {AfterPrevious: true}}
+	constructor.FailurePolicy = &FailurePolicy{MaxFailures: &maxFailures}
+	constructor.MaintenanceWindow = &MaintenanceWindow{Start: "22:00", End: "04:00"}
+	constructor.MaxRetries = 2
+	constructor.RetryBackoff = 60
+	constructor.MaxInProgress = 5
+	constructor.ConflictPolicy = ConflictPolicySupersede
+	constructor.Priority = 3
+
+	retry := NewRetryDeploymentConstructor(NewDeploymentFromConstructor(constructor), []string{"b"})
+
+	assert.NoError(t, retry.Validate())
+	assert.Equal(t, &DeploymentConstructor{
+		Name:           StringToPointer("foo"),
+		ArtifactName:   StringToPointer("bar"),
+		Devices:        []string{"b"},
+		FailurePolicy:  &FailurePolicy{MaxFailures: &maxFailures},
+		MaxRetries:     2,
+		RetryBackoff:   60,
+		MaxInProgress:  5,
+		ConflictPolicy: ConflictPolicySupersede,
+		Priority:       3,
+	}, retry)
+}
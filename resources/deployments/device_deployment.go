@@ -15,6 +15,7 @@
 package deployments
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/asaskevich/govalidator"
@@ -33,6 +34,21 @@ const (
 	DeviceDeploymentStatusNoArtifact  = "noartifact"
 	DeviceDeploymentStatusAlreadyInst = "already-installed"
 	DeviceDeploymentStatusAborted     = "aborted"
+	DeviceDeploymentStatusExpired     = "expired"
+)
+
+// Deployment statistics counter of retried installations, reported along
+// with status counters, see DeploymentStatistics
+const DeviceDeploymentStatsRetries = "retries"
+
+// Deployment statistics value of average progress, in percent, reported along
+// with status counters, see DeploymentStatistics
+const DeviceDeploymentStatsProgress = "progress"
+
+// Limits of substate and progress reported by devices
+const (
+	DeviceDeploymentSubStateMaxLength = 200
+	DeviceDeploymentProgressMax       = 100
 )
 
 type DeviceDeployment struct {
@@ -42,9 +58,21 @@ type DeviceDeployment struct {
 	// Update finish time
 	Finished *time.Time `json:"finished,omitempty" valid:"-"`
 
+	// Last status change time
+	Updated *time.Time `json:"updated,omitempty" valid:"-"`
+
 	// Status
 	Status *string `json:"status" valid:"required"`
 
+	// Device specific detail of the status, as last reported by the device
+	SubState *string `json:"substate,omitempty" valid:"-"`
+
+	// Progress of the status in percent, as last reported by the device
+	Progress *int `json:"progress,omitempty" valid:"-"`
+
+	// Reason of the failure, as reported by the device
+	FailureReason *FailureReason `json:"failure_reason,omitempty" valid:"-"`
+
 	// Device id
 	DeviceId *string `json:"id" valid:"required"`
 
@@ -62,6 +90,83 @@ type DeviceDeployment struct {
 
 	// Presence of deployment log
 	IsLogAvailable bool `json:"log" valid:"-"`
+
+	// Index of deployment phase the device belongs to
+	Phase int `json:"phase,omitempty" valid:"-"`
+
+	// Number of times failed installation was retried, attempt count is one more
+	Retries int `json:"retries" valid:"-"`
+
+	// Retried installation is not handed out to the device before this time
+	RetryAfter *time.Time `json:"retry_after,omitempty" valid:"-"`
+
+	// Priority of the deployment, copied so devices can be handed the most
+	// important deployment first
+	Priority int `json:"-" valid:"-"`
+
+	// Status changes, oldest first
+	History []DeviceDeploymentStatusChange `json:"history,omitempty" valid:"-"`
+}
+
+// DeviceDeploymentState describes status of device deployment to be set,
+// together with optional details reported by the device.
+type DeviceDeploymentState struct {
+	// Status
+	Status string
+
+	// Device specific detail of the status, e.g. name of the step being done
+	SubState *string
+
+	// Progress of the status in percent
+	Progress *int
+
+	// Reason of the failure, only with failure status
+	FailureReason *FailureReason
+}
+
+// DeviceDeploymentStatusChange records a single status change of device deployment.
+type DeviceDeploymentStatusChange struct {
+	// Status before the change
+	Previous string `json:"previous"`
+
+	// Status after the change
+	Status string `json:"status"`
+
+	// Time of the change
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Fields device deployments can be sorted by, besides creation and finish time
+const (
+	SortFieldDeviceID = "id"
+	SortFieldStatus   = "status"
+)
+
+// DeviceDeploymentSortFields lists fields device deployments can be sorted by.
+func DeviceDeploymentSortFields() []string {
+	return []string{SortFieldDeviceID, SortFieldStatus, SortFieldCreated, SortFieldFinished}
+}
+
+// Attempts returns number of installation attempts, including the current one.
+func (d *DeviceDeployment) Attempts() int {
+	return d.Retries + 1
+}
+
+// To be able to report attempt count in API output provide custom marshaler
+func (d *DeviceDeployment) MarshalJSON() ([]byte, error) {
+
+	//Prevents from inheriting original MarshalJSON (if would, infinite loop)
+	type Alias DeviceDeployment
+
+	withAttempts := struct {
+		*Alias
+		Attempts int `json:"attempts"`
+	}{
+		Alias:    (*Alias)(d),
+		Attempts: d.Attempts(),
+	}
+
+	return json.Marshal(&withAttempts)
 }
 
 func NewDeviceDeployment(deviceId, deploymentId string) *DeviceDeployment {
@@ -100,6 +205,7 @@ func NewDeviceDeploymentStats() Stats {
 		DeviceDeploymentStatusDownloading,
 		DeviceDeploymentStatusAlreadyInst,
 		DeviceDeploymentStatusAborted,
+		DeviceDeploymentStatusExpired,
 	}
 
 	s := make(Stats)
@@ -112,10 +218,38 @@ func NewDeviceDeploymentStats() Stats {
 	return s
 }
 
+// DeploymentProgress summarizes installation progress of deployment devices.
+type DeploymentProgress struct {
+	// Total number of retried installations
+	Retries int
+
+	// Average progress in percent reported by devices downloading,
+	// installing or rebooting, 0 if none of them reported progress
+	Progress int
+}
+
+// DeploymentStatistics combines status counters of deployment devices with
+// their installation progress. Reported as a single set of counters.
+type DeploymentStatistics struct {
+	Stats    Stats
+	Progress DeploymentProgress
+}
+
+func (s DeploymentStatistics) MarshalJSON() ([]byte, error) {
+	counters := make(map[string]int, len(s.Stats)+2)
+	for status, count := range s.Stats {
+		counters[status] = count
+	}
+	counters[DeviceDeploymentStatsRetries] = s.Progress.Retries
+	counters[DeviceDeploymentStatsProgress] = s.Progress.Progress
+
+	return json.Marshal(counters)
+}
+
 func IsDeviceDeploymentStatusFinished(status string) bool {
 	if status == DeviceDeploymentStatusFailure || status == DeviceDeploymentStatusSuccess ||
 		status == DeviceDeploymentStatusNoArtifact || status == DeviceDeploymentStatusAlreadyInst ||
-		status == DeviceDeploymentStatusAborted {
+		status == DeviceDeploymentStatusAborted || status == DeviceDeploymentStatusExpired {
 		return true
 	}
 	return false
@@ -131,6 +265,14 @@ func ActiveDeploymentStatuses() []string {
 	}
 }
 
+// IsDeviceDeploymentStatusInProgress checks if device is actively updating,
+// that is downloading, installing or rebooting.
+func IsDeviceDeploymentStatusInProgress(status string) bool {
+	return status == DeviceDeploymentStatusDownloading ||
+		status == DeviceDeploymentStatusInstalling ||
+		status == DeviceDeploymentStatusRebooting
+}
+
 // InstalledDeviceDeployment describes a deployment currently installed on the
 // device, usually reported by a device
 type InstalledDeviceDeployment struct {
@@ -15,6 +15,7 @@
 package deployments_test
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -37,6 +38,50 @@ func TestNewDeviceDeployment(t *testing.T) {
 	assert.Equal(t, false, dd.IsLogAvailable)
 }
 
+func TestDeviceDeploymentMarshalJSON(t *testing.T) {
+
+	t.Parallel()
+
+	dd := NewDeviceDeployment("device_123", "deployment_123")
+	dd.Retries = 2
+
+	// listings are rendered from slices of values
+	j, err := json.Marshal([]DeviceDeployment{*dd})
+	assert.NoError(t, err)
+
+	var out []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(j, &out))
+	if assert.Len(t, out, 1) {
+		assert.Equal(t, "device_123", out[0]["id"])
+		assert.Equal(t, float64(2), out[0]["retries"])
+		assert.Equal(t, float64(3), out[0]["attempts"])
+	}
+}
+
+func TestDeploymentStatisticsMarshalJSON(t *testing.T) {
+
+	t.Parallel()
+
+	stats := NewDeviceDeploymentStats()
+	stats[DeviceDeploymentStatusInstalling] = 2
+
+	j, err := json.Marshal(&DeploymentStatistics{
+		Stats:    stats,
+		Progress: DeploymentProgress{Retries: 1, Progress: 30},
+	})
+	assert.NoError(t, err)
+
+	var out map[string]int
+	assert.NoError(t, json.Unmarshal(j, &out))
+	assert.Len(t, out, len(stats)+2)
+	assert.Equal(t, 2, out[DeviceDeploymentStatusInstalling])
+	assert.Equal(t, 1, out[DeviceDeploymentStatsRetries])
+	assert.Equal(t, 30, out[DeviceDeploymentStatsProgress])
+
+	// status counters are left untouched
+	assert.NotContains(t, stats, DeviceDeploymentStatsRetries)
+}
+
 func TestDeviceDeploymentValidate(t *testing.T) {
 
 	t.Parallel()
@@ -137,6 +182,7 @@ func TestDeviceDeploymentStats(t *testing.T) {
 		DeviceDeploymentStatusDownloading,
 		DeviceDeploymentStatusAlreadyInst,
 		DeviceDeploymentStatusAborted,
+		DeviceDeploymentStatusExpired,
 	}
 	for _, f := range must {
 		assert.Contains(t, ds, f, "stats must contain status '%v'", f)
@@ -153,6 +199,7 @@ func TestDeviceDeploymentIsFinished(t *testing.T) {
 		{DeviceDeploymentStatusSuccess, true},
 		{DeviceDeploymentStatusAlreadyInst, true},
 		{DeviceDeploymentStatusAborted, true},
+		{DeviceDeploymentStatusExpired, true},
 		// statuses 'in progress'
 		{DeviceDeploymentStatusPending, false},
 		{DeviceDeploymentStatusRebooting, false},
@@ -0,0 +1,111 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mendersoftware/deployments/utils/paging"
+)
+
+// Errors
+var (
+	ErrHistoryInvalidStatus = errors.New("Unknown device deployment status")
+)
+
+// DeviceDeploymentHistoryQuery selects a page of deployments of a single device.
+type DeviceDeploymentHistoryQuery struct {
+	// Match only device deployments with this status, any status if empty
+	Status string
+
+	// Requested page
+	Paging paging.Query
+}
+
+// NewDeviceDeploymentHistoryQuery creates query for the first page of
+// entries with any status.
+func NewDeviceDeploymentHistoryQuery() *DeviceDeploymentHistoryQuery {
+	return &DeviceDeploymentHistoryQuery{
+		Paging: *paging.NewQuery(),
+	}
+}
+
+func (q *DeviceDeploymentHistoryQuery) Validate() error {
+	if q.Status != "" {
+		if _, ok := NewDeviceDeploymentStats()[q.Status]; !ok {
+			return ErrHistoryInvalidStatus
+		}
+	}
+	return nil
+}
+
+// DeviceDeploymentHistoryEntry describes a deployment of a device together
+// with details of the deployment it belongs to.
+type DeviceDeploymentHistoryEntry struct {
+	// Deployment id
+	DeploymentId string `json:"id"`
+
+	// Deployment name, empty if the deployment no longer exists
+	Name string `json:"name"`
+
+	// Deployed artifact name, empty if the deployment no longer exists
+	ArtifactName string `json:"artifact_name"`
+
+	// Device deployment status
+	Status string `json:"status"`
+
+	// Device deployment creation time
+	Created *time.Time `json:"created"`
+
+	// Last status change time
+	Updated *time.Time `json:"updated,omitempty"`
+
+	// Device deployment finish time
+	Finished *time.Time `json:"finished,omitempty"`
+
+	// Presence of deployment log
+	IsLogAvailable bool `json:"log"`
+}
+
+// NewDeviceDeploymentHistoryEntry combines device deployment with its
+// deployment, which can be nil.
+func NewDeviceDeploymentHistoryEntry(devDep *DeviceDeployment,
+	deployment *Deployment) *DeviceDeploymentHistoryEntry {
+
+	entry := &DeviceDeploymentHistoryEntry{
+		Created:        devDep.Created,
+		Updated:        devDep.Updated,
+		Finished:       devDep.Finished,
+		IsLogAvailable: devDep.IsLogAvailable,
+	}
+	if devDep.DeploymentId != nil {
+		entry.DeploymentId = *devDep.DeploymentId
+	}
+	if devDep.Status != nil {
+		entry.Status = *devDep.Status
+	}
+
+	if deployment != nil && deployment.DeploymentConstructor != nil {
+		if deployment.Name != nil {
+			entry.Name = *deployment.Name
+		}
+		if deployment.ArtifactName != nil {
+			entry.ArtifactName = *deployment.ArtifactName
+		}
+	}
+
+	return entry
+}
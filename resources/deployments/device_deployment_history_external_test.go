@@ -0,0 +1,96 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/mendersoftware/deployments/resources/deployments"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceDeploymentHistoryQueryValidate(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputQuery DeviceDeploymentHistoryQuery
+
+		OutputError error
+	}{
+		"default": {
+			InputQuery: *NewDeviceDeploymentHistoryQuery(),
+		},
+		"status": {
+			InputQuery: DeviceDeploymentHistoryQuery{
+				Status: DeviceDeploymentStatusFailure,
+			},
+		},
+		"unknown status": {
+			InputQuery: DeviceDeploymentHistoryQuery{
+				Status: "bogus",
+			},
+			OutputError: ErrHistoryInvalidStatus,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		err := testCase.InputQuery.Validate()
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestNewDeviceDeploymentHistoryEntry(t *testing.T) {
+
+	t.Parallel()
+
+	devDep := NewDeviceDeployment("device-1", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a")
+	finished := time.Now()
+	devDep.Finished = &finished
+	devDep.Status = StringToPointer(DeviceDeploymentStatusSuccess)
+	devDep.IsLogAvailable = true
+
+	constructor := NewDeploymentConstructor()
+	constructor.Name = StringToPointer("foo")
+	constructor.ArtifactName = StringToPointer("bar")
+	deployment := NewDeploymentFromConstructor(constructor)
+
+	assert.Equal(t, &DeviceDeploymentHistoryEntry{
+		DeploymentId:   "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+		Name:           "foo",
+		ArtifactName:   "bar",
+		Status:         DeviceDeploymentStatusSuccess,
+		Created:        devDep.Created,
+		Finished:       &finished,
+		IsLogAvailable: true,
+	}, NewDeviceDeploymentHistoryEntry(devDep, deployment))
+
+	// deployment removed in the meantime
+	assert.Equal(t, &DeviceDeploymentHistoryEntry{
+		DeploymentId:   "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+		Status:         DeviceDeploymentStatusSuccess,
+		Created:        devDep.Created,
+		Finished:       &finished,
+		IsLogAvailable: true,
+	}, NewDeviceDeploymentHistoryEntry(devDep, nil))
+}
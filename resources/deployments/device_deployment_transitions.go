@@ -0,0 +1,99 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments
+
+import (
+	"fmt"
+	"sort"
+)
+
+// deviceDeploymentTransitions lists statuses device deployment can move to
+// from given status. Active statuses can be reported repeatedly. Finished
+// statuses are final.
+var deviceDeploymentTransitions = map[string][]string{
+	DeviceDeploymentStatusPending: {
+		DeviceDeploymentStatusDownloading,
+		DeviceDeploymentStatusFailure,
+		DeviceDeploymentStatusAlreadyInst,
+		DeviceDeploymentStatusAborted,
+		DeviceDeploymentStatusExpired,
+	},
+	DeviceDeploymentStatusDownloading: {
+		DeviceDeploymentStatusDownloading,
+		DeviceDeploymentStatusInstalling,
+		DeviceDeploymentStatusFailure,
+		DeviceDeploymentStatusAborted,
+	},
+	DeviceDeploymentStatusInstalling: {
+		DeviceDeploymentStatusInstalling,
+		DeviceDeploymentStatusRebooting,
+		DeviceDeploymentStatusSuccess,
+		DeviceDeploymentStatusFailure,
+		DeviceDeploymentStatusAborted,
+	},
+	DeviceDeploymentStatusRebooting: {
+		DeviceDeploymentStatusRebooting,
+		DeviceDeploymentStatusSuccess,
+		DeviceDeploymentStatusFailure,
+		DeviceDeploymentStatusAborted,
+	},
+}
+
+// StatusTransitionError is returned for device deployment status changes not
+// allowed by the transition table.
+type StatusTransitionError struct {
+	// Current status
+	From string
+
+	// Requested status
+	To string
+}
+
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("Invalid device deployment status transition from %s to %s", e.From, e.To)
+}
+
+// IsValidDeviceDeploymentStatusTransition checks if device deployment can
+// move from status `from` to status `to`.
+func IsValidDeviceDeploymentStatusTransition(from, to string) bool {
+	for _, s := range deviceDeploymentTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateDeviceDeploymentStatusTransition returns StatusTransitionError if
+// device deployment can not move from status `from` to status `to`.
+func ValidateDeviceDeploymentStatusTransition(from, to string) error {
+	if !IsValidDeviceDeploymentStatusTransition(from, to) {
+		return &StatusTransitionError{From: from, To: to}
+	}
+	return nil
+}
+
+// DeviceDeploymentStatusesAllowingTransitionTo lists statuses device
+// deployment can move to status `to` from, sorted.
+func DeviceDeploymentStatusesAllowingTransitionTo(to string) []string {
+	statuses := []string{}
+	for from := range deviceDeploymentTransitions {
+		if IsValidDeviceDeploymentStatusTransition(from, to) {
+			statuses = append(statuses, from)
+		}
+	}
+	sort.Strings(statuses)
+	return statuses
+}
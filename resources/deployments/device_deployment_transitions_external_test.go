@@ -0,0 +1,132 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments_test
+
+import (
+	"testing"
+
+	. "github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceDeploymentStatusTransitions(t *testing.T) {
+
+	t.Parallel()
+
+	all := []string{
+		DeviceDeploymentStatusPending,
+		DeviceDeploymentStatusDownloading,
+		DeviceDeploymentStatusInstalling,
+		DeviceDeploymentStatusRebooting,
+		DeviceDeploymentStatusSuccess,
+		DeviceDeploymentStatusFailure,
+		DeviceDeploymentStatusNoArtifact,
+		DeviceDeploymentStatusAlreadyInst,
+		DeviceDeploymentStatusAborted,
+		DeviceDeploymentStatusExpired,
+	}
+
+	allowed := map[string][]string{
+		DeviceDeploymentStatusPending: {
+			DeviceDeploymentStatusDownloading,
+			DeviceDeploymentStatusFailure,
+			DeviceDeploymentStatusAlreadyInst,
+			DeviceDeploymentStatusAborted,
+			DeviceDeploymentStatusExpired,
+		},
+		DeviceDeploymentStatusDownloading: {
+			DeviceDeploymentStatusDownloading,
+			DeviceDeploymentStatusInstalling,
+			DeviceDeploymentStatusFailure,
+			DeviceDeploymentStatusAborted,
+		},
+		DeviceDeploymentStatusInstalling: {
+			DeviceDeploymentStatusInstalling,
+			DeviceDeploymentStatusRebooting,
+			DeviceDeploymentStatusSuccess,
+			DeviceDeploymentStatusFailure,
+			DeviceDeploymentStatusAborted,
+		},
+		DeviceDeploymentStatusRebooting: {
+			DeviceDeploymentStatusRebooting,
+			DeviceDeploymentStatusSuccess,
+			DeviceDeploymentStatusFailure,
+			DeviceDeploymentStatusAborted,
+		},
+	}
+
+	for _, from := range all {
+		for _, to := range all {
+			expected := false
+			for _, s := range allowed[from] {
+				if s == to {
+					expected = true
+				}
+			}
+
+			assert.Equal(t, expected, IsValidDeviceDeploymentStatusTransition(from, to),
+				"transition from %s to %s", from, to)
+
+			err := ValidateDeviceDeploymentStatusTransition(from, to)
+			if expected {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, &StatusTransitionError{From: from, To: to}, err)
+			}
+		}
+	}
+}
+
+func TestStatusTransitionError(t *testing.T) {
+
+	t.Parallel()
+
+	err := &StatusTransitionError{
+		From: DeviceDeploymentStatusSuccess,
+		To:   DeviceDeploymentStatusDownloading,
+	}
+	assert.EqualError(t, err, "Invalid device deployment status transition from success to downloading")
+}
+
+func TestDeviceDeploymentStatusesAllowingTransitionTo(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string][]string{
+		DeviceDeploymentStatusPending: {},
+		DeviceDeploymentStatusInstalling: {
+			DeviceDeploymentStatusDownloading,
+			DeviceDeploymentStatusInstalling,
+		},
+		DeviceDeploymentStatusFailure: {
+			DeviceDeploymentStatusDownloading,
+			DeviceDeploymentStatusInstalling,
+			DeviceDeploymentStatusPending,
+			DeviceDeploymentStatusRebooting,
+		},
+		DeviceDeploymentStatusAlreadyInst: {
+			DeviceDeploymentStatusPending,
+		},
+		DeviceDeploymentStatusExpired: {
+			DeviceDeploymentStatusPending,
+		},
+	}
+
+	for to, expected := range testCases {
+		t.Logf("testing case %s", to)
+
+		assert.Equal(t, expected, DeviceDeploymentStatusesAllowingTransitionTo(to))
+	}
+}
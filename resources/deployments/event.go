@@ -0,0 +1,62 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments
+
+import (
+	"time"
+)
+
+// Deployment event types
+const (
+	EventTypeStats        = "stats"
+	EventTypeDeviceStatus = "device_status"
+)
+
+// Event notifies about deployment progress.
+type Event struct {
+	Type         string    `json:"type"`
+	DeploymentID string    `json:"deployment_id"`
+	Time         time.Time `json:"time"`
+
+	// Deployment statistics, set for stats events
+	Stats Stats `json:"stats,omitempty"`
+
+	// Device status transition, set for device status events
+	DeviceID       string `json:"device_id,omitempty"`
+	Status         string `json:"status,omitempty"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+}
+
+// NewStatsEvent creates event carrying current deployment statistics.
+func NewStatsEvent(deploymentID string, stats Stats) *Event {
+	return &Event{
+		Type:         EventTypeStats,
+		DeploymentID: deploymentID,
+		Time:         time.Now(),
+		Stats:        stats,
+	}
+}
+
+// NewDeviceStatusEvent creates event carrying device deployment status transition.
+func NewDeviceStatusEvent(deploymentID, deviceID, from, to string) *Event {
+	return &Event{
+		Type:           EventTypeDeviceStatus,
+		DeploymentID:   deploymentID,
+		Time:           time.Now(),
+		DeviceID:       deviceID,
+		Status:         to,
+		PreviousStatus: from,
+	}
+}
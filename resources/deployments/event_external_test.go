@@ -0,0 +1,66 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventJSON(t *testing.T) {
+
+	t.Parallel()
+
+	tref := time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	testCases := map[string]struct {
+		InputEvent *Event
+		OutputJSON string
+	}{
+		"stats": {
+			InputEvent: NewStatsEvent("30b3e62c-9ec2-4312-a7fa-cff24cc7397a", Stats{
+				DeviceDeploymentStatusSuccess: 2,
+			}),
+			OutputJSON: `{"type":"stats","deployment_id":"30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+				"time":"2017-01-02T15:04:05Z","stats":{"success":2}}`,
+		},
+		"device status": {
+			InputEvent: NewDeviceStatusEvent("30b3e62c-9ec2-4312-a7fa-cff24cc7397a", "device-1",
+				DeviceDeploymentStatusDownloading, DeviceDeploymentStatusInstalling),
+			OutputJSON: `{"type":"device_status","deployment_id":"30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+				"time":"2017-01-02T15:04:05Z","device_id":"device-1","status":"installing",
+				"previous_status":"downloading"}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		assert.WithinDuration(t, time.Now(), testCase.InputEvent.Time, time.Minute)
+		testCase.InputEvent.Time = tref
+
+		data, err := json.Marshal(testCase.InputEvent)
+		assert.NoError(t, err)
+
+		var expected, actual map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(testCase.OutputJSON), &expected))
+		assert.NoError(t, json.Unmarshal(data, &actual))
+		assert.Equal(t, expected, actual)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/mendersoftware/go-lib-micro/log"
+)
+
+// Defaults
+const (
+	DefaultSubscriberBuffer = 64
+	DefaultRetryInterval    = 5 * time.Second
+)
+
+// EventsStorage stores events shared by all service instances.
+type EventsStorage interface {
+	Insert(event *deployments.Event) error
+	// Tail calls handler for each event stored after tailing started, until
+	// stop is closed.
+	Tail(handler func(event *deployments.Event), stop <-chan struct{}) error
+}
+
+// Hub fans deployment events out to subscribers. Published events go through
+// the storage, which each service instance tails and dispatches events to
+// its own subscribers, so subscribers see events published by all instances.
+type Hub struct {
+	storage EventsStorage
+	log     *log.Logger
+
+	lock        sync.Mutex
+	subscribers map[string]map[chan *deployments.Event]struct{}
+}
+
+// NewHub creates events hub on top of events storage. Run has to be started
+// for subscribers to receive events.
+func NewHub(storage EventsStorage) *Hub {
+	return &Hub{
+		storage:     storage,
+		log:         log.New(log.Ctx{"module": "events"}),
+		subscribers: map[string]map[chan *deployments.Event]struct{}{},
+	}
+}
+
+// Publish stores event for delivery to subscribers. Events are best effort,
+// failure to publish is logged and does not affect the caller.
+func (h *Hub) Publish(event *deployments.Event) {
+	if err := h.storage.Insert(event); err != nil {
+		h.log.Errorf("publishing %s event of deployment %s: %s",
+			event.Type, event.DeploymentID, err)
+	}
+}
+
+// Subscribe returns channel receiving events of given deployment and function
+// cancelling the subscription. Events are dropped for subscribers which do not
+// keep up.
+func (h *Hub) Subscribe(deploymentID string) (<-chan *deployments.Event, func()) {
+	events := make(chan *deployments.Event, DefaultSubscriberBuffer)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.subscribers[deploymentID] == nil {
+		h.subscribers[deploymentID] = map[chan *deployments.Event]struct{}{}
+	}
+	h.subscribers[deploymentID][events] = struct{}{}
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.lock.Lock()
+			defer h.lock.Unlock()
+
+			delete(h.subscribers[deploymentID], events)
+			if len(h.subscribers[deploymentID]) == 0 {
+				delete(h.subscribers, deploymentID)
+			}
+			close(events)
+		})
+	}
+
+	return events, cancel
+}
+
+// Dispatch delivers event to local subscribers of its deployment.
+func (h *Hub) Dispatch(event *deployments.Event) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for events := range h.subscribers[event.DeploymentID] {
+		select {
+		case events <- event:
+		default:
+			// slow subscriber, stats of following events catch it up
+		}
+	}
+}
+
+// Run tails events storage and dispatches events to subscribers until stop
+// is closed. Tailing is restarted on storage errors.
+func (h *Hub) Run(retryInterval time.Duration, stop <-chan struct{}) {
+	for {
+		err := h.storage.Tail(h.Dispatch, stop)
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if err != nil {
+			h.log.Errorf("tailing events: %s", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
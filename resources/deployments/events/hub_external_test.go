@@ -0,0 +1,149 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package events_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mendersoftware/deployments/resources/deployments"
+	. "github.com/mendersoftware/deployments/resources/deployments/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// channelStorage passes events from Insert to Tail in memory
+type channelStorage struct {
+	events    chan *deployments.Event
+	insertErr error
+	tailErrs  chan error
+}
+
+func newChannelStorage() *channelStorage {
+	return &channelStorage{
+		events:   make(chan *deployments.Event, 10),
+		tailErrs: make(chan error, 10),
+	}
+}
+
+func (s *channelStorage) Insert(event *deployments.Event) error {
+	if s.insertErr != nil {
+		return s.insertErr
+	}
+	s.events <- event
+	return nil
+}
+
+func (s *channelStorage) Tail(handler func(event *deployments.Event), stop <-chan struct{}) error {
+	for {
+		select {
+		case <-stop:
+			return nil
+		case err := <-s.tailErrs:
+			return err
+		case event := <-s.events:
+			handler(event)
+		}
+	}
+}
+
+func receive(t *testing.T, events <-chan *deployments.Event) *deployments.Event {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+	return nil
+}
+
+func TestHub(t *testing.T) {
+
+	t.Parallel()
+
+	storage := newChannelStorage()
+	hub := NewHub(storage)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		hub.Run(time.Millisecond, stop)
+		close(done)
+	}()
+
+	first, cancelFirst := hub.Subscribe("123")
+	second, cancelSecond := hub.Subscribe("123")
+	other, cancelOther := hub.Subscribe("234")
+	defer cancelOther()
+
+	hub.Publish(deployments.NewStatsEvent("123", deployments.Stats{}))
+	assert.Equal(t, "123", receive(t, first).DeploymentID)
+	assert.Equal(t, "123", receive(t, second).DeploymentID)
+
+	// tailing restarts after errors
+	storage.tailErrs <- errors.New("storage error")
+
+	cancelFirst()
+	// cancelling twice is fine
+	cancelFirst()
+	_, open := <-first
+	assert.False(t, open)
+
+	hub.Publish(deployments.NewDeviceStatusEvent("123", "device-1",
+		deployments.DeviceDeploymentStatusPending, deployments.DeviceDeploymentStatusDownloading))
+	assert.Equal(t, "device-1", receive(t, second).DeviceID)
+	cancelSecond()
+
+	select {
+	case event := <-other:
+		t.Fatalf("unexpected event of deployment %s", event.DeploymentID)
+	default:
+	}
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("hub not stopped")
+	}
+}
+
+func TestHubSlowSubscriber(t *testing.T) {
+
+	t.Parallel()
+
+	hub := NewHub(newChannelStorage())
+
+	events, cancel := hub.Subscribe("123")
+	defer cancel()
+
+	// dispatching does not block on full subscriber
+	for i := 0; i < DefaultSubscriberBuffer+1; i++ {
+		hub.Dispatch(deployments.NewStatsEvent("123", deployments.Stats{}))
+	}
+	assert.Len(t, events, DefaultSubscriberBuffer)
+}
+
+func TestHubPublishError(t *testing.T) {
+
+	t.Parallel()
+
+	storage := newChannelStorage()
+	storage.insertErr = errors.New("storage error")
+
+	// failing publish does not affect the caller
+	NewHub(storage).Publish(deployments.NewStatsEvent("123", deployments.Stats{}))
+	assert.Len(t, storage.events, 0)
+}
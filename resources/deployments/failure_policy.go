@@ -0,0 +1,91 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors
+var (
+	ErrFailurePolicyEmpty          = errors.New("Failure policy needs to specify max failures or max failure rate")
+	ErrFailurePolicyInvalidCount   = errors.New("Failure policy max failures can not be negative")
+	ErrFailurePolicyInvalidPercent = errors.New("Failure policy max failure rate has to be between 0 and 100 percent")
+)
+
+// Number of finished devices needed before failure rate is checked
+const failureRateMinFinished = 10
+
+// FailurePolicy defines when deployment is automatically aborted due to
+// failing devices.
+type FailurePolicy struct {
+	// Maximum number of failed devices tolerated
+	MaxFailures *int `json:"max_failures,omitempty"`
+
+	// Maximum percentage of failed devices among finished ones tolerated
+	MaxFailureRate *int `json:"max_failure_rate,omitempty"`
+}
+
+func (p *FailurePolicy) Validate() error {
+	if p.MaxFailures == nil && p.MaxFailureRate == nil {
+		return ErrFailurePolicyEmpty
+	}
+	if p.MaxFailures != nil && *p.MaxFailures < 0 {
+		return ErrFailurePolicyInvalidCount
+	}
+	if p.MaxFailureRate != nil && (*p.MaxFailureRate < 0 || *p.MaxFailureRate > 100) {
+		return ErrFailurePolicyInvalidPercent
+	}
+	return nil
+}
+
+// Violation checks deployment stats against the policy. Returns description of
+// the violation or empty string if stats are within policy limits.
+func (p *FailurePolicy) Violation(stats Stats) string {
+	failures := stats[DeviceDeploymentStatusFailure]
+	if failures == 0 {
+		return ""
+	}
+
+	if p.MaxFailures != nil && failures > *p.MaxFailures {
+		return fmt.Sprintf("failure policy: %d devices failed, at most %d allowed",
+			failures, *p.MaxFailures)
+	}
+
+	if p.MaxFailureRate != nil {
+		// devices which had nothing to install are not counted
+		finished := 0
+		for status := range NewDeviceDeploymentStats() {
+			if IsDeviceDeploymentStatusFinished(status) &&
+				status != DeviceDeploymentStatusNoArtifact &&
+				status != DeviceDeploymentStatusAlreadyInst {
+				finished += stats[status]
+			}
+		}
+
+		// do not abort on the first few reports alone
+		if finished < failureRateMinFinished {
+			return ""
+		}
+
+		if failures*100 > *p.MaxFailureRate*finished {
+			return fmt.Sprintf("failure policy: %d of %d finished devices failed, at most %d%% allowed",
+				failures, finished, *p.MaxFailureRate)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,154 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments_test
+
+import (
+	"testing"
+
+	. "github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/stretchr/testify/assert"
+)
+
+func intToPointer(i int) *int {
+	return &i
+}
+
+func TestFailurePolicyValidate(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputPolicy FailurePolicy
+		OutputError error
+	}{
+		"empty": {
+			OutputError: ErrFailurePolicyEmpty,
+		},
+		"negative count": {
+			InputPolicy: FailurePolicy{MaxFailures: intToPointer(-1)},
+			OutputError: ErrFailurePolicyInvalidCount,
+		},
+		"rate out of range": {
+			InputPolicy: FailurePolicy{MaxFailureRate: intToPointer(101)},
+			OutputError: ErrFailurePolicyInvalidPercent,
+		},
+		"zero failures": {
+			InputPolicy: FailurePolicy{MaxFailures: intToPointer(0)},
+		},
+		"both": {
+			InputPolicy: FailurePolicy{
+				MaxFailures:    intToPointer(10),
+				MaxFailureRate: intToPointer(20),
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		err := testCase.InputPolicy.Validate()
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestFailurePolicyViolation(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputPolicy FailurePolicy
+		InputStats  Stats
+
+		OutputViolated bool
+	}{
+		"no failures": {
+			InputPolicy: FailurePolicy{MaxFailures: intToPointer(0)},
+			InputStats:  Stats{DeviceDeploymentStatusSuccess: 10},
+		},
+		"count within limit": {
+			InputPolicy: FailurePolicy{MaxFailures: intToPointer(2)},
+			InputStats:  Stats{DeviceDeploymentStatusFailure: 2},
+		},
+		"count exceeded": {
+			InputPolicy:    FailurePolicy{MaxFailures: intToPointer(2)},
+			InputStats:     Stats{DeviceDeploymentStatusFailure: 3},
+			OutputViolated: true,
+		},
+		"rate within limit": {
+			InputPolicy: FailurePolicy{MaxFailureRate: intToPointer(20)},
+			InputStats: Stats{
+				DeviceDeploymentStatusFailure: 2,
+				DeviceDeploymentStatusSuccess: 8,
+				DeviceDeploymentStatusPending: 90,
+			},
+		},
+		"rate exceeded": {
+			InputPolicy: FailurePolicy{MaxFailureRate: intToPointer(20)},
+			InputStats: Stats{
+				DeviceDeploymentStatusFailure: 3,
+				DeviceDeploymentStatusSuccess: 7,
+				// not finished, not counted
+				DeviceDeploymentStatusPending: 990,
+			},
+			OutputViolated: true,
+		},
+		"all finished devices failed": {
+			InputPolicy: FailurePolicy{MaxFailureRate: intToPointer(10)},
+			InputStats: Stats{
+				DeviceDeploymentStatusFailure: 10,
+				DeviceDeploymentStatusPending: 990,
+			},
+			OutputViolated: true,
+		},
+		"too few finished devices": {
+			InputPolicy: FailurePolicy{MaxFailureRate: intToPointer(20)},
+			InputStats: Stats{
+				DeviceDeploymentStatusFailure:     1,
+				DeviceDeploymentStatusDownloading: 2,
+				DeviceDeploymentStatusPending:     97,
+			},
+		},
+		"untargeted devices not counted": {
+			InputPolicy: FailurePolicy{MaxFailureRate: intToPointer(20)},
+			InputStats: Stats{
+				DeviceDeploymentStatusFailure:     3,
+				DeviceDeploymentStatusSuccess:     7,
+				DeviceDeploymentStatusNoArtifact:  50,
+				DeviceDeploymentStatusAlreadyInst: 50,
+			},
+			OutputViolated: true,
+		},
+		"non-status counters not counted": {
+			InputPolicy: FailurePolicy{MaxFailureRate: intToPointer(20)},
+			InputStats: Stats{
+				DeviceDeploymentStatusFailure: 3,
+				DeviceDeploymentStatusSuccess: 7,
+				"retries":                     100,
+			},
+			OutputViolated: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		reason := testCase.InputPolicy.Violation(testCase.InputStats)
+		assert.Equal(t, testCase.OutputViolated, reason != "")
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Limits of failure reason reported by devices
+const (
+	FailureReasonCodeMaxLength    = 64
+	FailureReasonMessageMaxLength = 1024
+)
+
+// Errors
+var (
+	ErrFailureReasonInvalidCode = fmt.Errorf("Failure reason code has to be 1 to %d letters, digits, '_', '-' or '.'",
+		FailureReasonCodeMaxLength)
+	ErrFailureReasonMessageTooLong = fmt.Errorf("Failure reason message can be at most %d characters long",
+		FailureReasonMessageMaxLength)
+)
+
+var failureReasonCodeRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// FailureReason is a machine readable description of device deployment
+// failure reported by the device.
+type FailureReason struct {
+	// Error code, e.g. disk_full
+	Code string `json:"code"`
+
+	// Human readable error description
+	Message string `json:"message,omitempty"`
+}
+
+func (f *FailureReason) Validate() error {
+	if len(f.Code) > FailureReasonCodeMaxLength || !failureReasonCodeRegexp.MatchString(f.Code) {
+		return ErrFailureReasonInvalidCode
+	}
+	if len(f.Message) > FailureReasonMessageMaxLength {
+		return ErrFailureReasonMessageTooLong
+	}
+	return nil
+}
+
+// FailureGroup counts failed devices of a deployment sharing failure reason
+// code and device type.
+type FailureGroup struct {
+	// Failure reason code, empty for failures reported without reason
+	Code string `json:"code"`
+
+	// Device type of failed devices
+	DeviceType string `json:"device_type"`
+
+	// Number of failed devices
+	Count int `json:"count"`
+}
@@ -0,0 +1,73 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureReasonValidate(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputReason FailureReason
+		OutputError error
+	}{
+		"code only": {
+			InputReason: FailureReason{Code: "disk_full"},
+		},
+		"code and message": {
+			InputReason: FailureReason{
+				Code:    "ERR-42.1",
+				Message: "No space left on device",
+			},
+		},
+		"empty code": {
+			InputReason: FailureReason{Message: "No space left on device"},
+			OutputError: ErrFailureReasonInvalidCode,
+		},
+		"invalid code": {
+			InputReason: FailureReason{Code: "disk full"},
+			OutputError: ErrFailureReasonInvalidCode,
+		},
+		"code too long": {
+			InputReason: FailureReason{Code: strings.Repeat("x", FailureReasonCodeMaxLength+1)},
+			OutputError: ErrFailureReasonInvalidCode,
+		},
+		"message too long": {
+			InputReason: FailureReason{
+				Code:    "disk_full",
+				Message: strings.Repeat("x", FailureReasonMessageMaxLength+1),
+			},
+			OutputError: ErrFailureReasonMessageTooLong,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		err := testCase.InputReason.Validate()
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
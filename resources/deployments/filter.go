@@ -0,0 +1,81 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments
+
+import (
+	"errors"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/mendersoftware/deployments/utils/paging"
+)
+
+// Errors
+var (
+	ErrFilterEmpty           = errors.New("Device filter needs at least one attribute")
+	ErrFilterDuplicate       = errors.New("Device filter attribute specified more than once")
+	ErrFilterReservedName    = errors.New("Device filter attribute can not be named page or per_page")
+	ErrMissingTargetDevices  = errors.New("Deployment needs to specify devices or device filter")
+	ErrDevicesAndFilterGiven = errors.New("Deployment can specify either devices or device filter, not both")
+)
+
+// AttributeFilter matches devices by inventory attribute value.
+type AttributeFilter struct {
+	// Inventory attribute name, e.g. device_type
+	Name string `json:"name" valid:"length(1|4096),required"`
+
+	// Required attribute value
+	Value string `json:"value" valid:"length(1|4096),required"`
+}
+
+// DeviceFilter selects deployment target devices by inventory attributes.
+// Devices need to match all attributes.
+type DeviceFilter struct {
+	Attributes []*AttributeFilter `json:"attributes" valid:"-"`
+}
+
+func (f *DeviceFilter) Validate() error {
+	if len(f.Attributes) == 0 {
+		return ErrFilterEmpty
+	}
+
+	names := make(map[string]bool, len(f.Attributes))
+	for _, a := range f.Attributes {
+		if a == nil {
+			return ErrFilterEmpty
+		}
+		if _, err := govalidator.ValidateStruct(a); err != nil {
+			return err
+		}
+		// attributes are sent to inventory together with paging parameters
+		if a.Name == paging.ParamPage || a.Name == paging.ParamPerPage {
+			return ErrFilterReservedName
+		}
+		if names[a.Name] {
+			return ErrFilterDuplicate
+		}
+		names[a.Name] = true
+	}
+
+	return nil
+}
+
+// AsMap returns filter attributes as name to value map.
+func (f *DeviceFilter) AsMap() map[string]string {
+	attributes := make(map[string]string, len(f.Attributes))
+	for _, a := range f.Attributes {
+		attributes[a.Name] = a.Value
+	}
+	return attributes
+}
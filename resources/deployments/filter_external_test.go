@@ -0,0 +1,114 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/mendersoftware/deployments/resources/deployments"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploymentConstructorValidateFilter(t *testing.T) {
+
+	t.Parallel()
+
+	rpi := &AttributeFilter{Name: "device_type", Value: "rpi3"}
+
+	testCases := map[string]struct {
+		InputDevices []string
+		InputFilter  *DeviceFilter
+		InputPhases  []*PhaseConstructor
+
+		OutputError error
+	}{
+		"no target devices": {
+			OutputError: ErrMissingTargetDevices,
+		},
+		"devices and filter": {
+			InputDevices: []string{"a"},
+			InputFilter:  &DeviceFilter{Attributes: []*AttributeFilter{rpi}},
+			OutputError:  ErrDevicesAndFilterGiven,
+		},
+		"empty filter": {
+			InputFilter: &DeviceFilter{},
+			OutputError: ErrFilterEmpty,
+		},
+		"duplicate attribute": {
+			InputFilter: &DeviceFilter{Attributes: []*AttributeFilter{rpi, rpi}},
+			OutputError: ErrFilterDuplicate,
+		},
+		"reserved attribute name": {
+			InputFilter: &DeviceFilter{Attributes: []*AttributeFilter{
+				{Name: "per_page", Value: "10"},
+			}},
+			OutputError: ErrFilterReservedName,
+		},
+		"missing attribute value": {
+			InputFilter: &DeviceFilter{Attributes: []*AttributeFilter{{Name: "device_type"}}},
+			OutputError: errors.New("Value: non zero value required;"),
+		},
+		"filter": {
+			InputFilter: &DeviceFilter{Attributes: []*AttributeFilter{rpi}},
+		},
+		"filter with phases": {
+			InputFilter: &DeviceFilter{Attributes: []*AttributeFilter{rpi}},
+			InputPhases: []*PhaseConstructor{
+				{DeviceCount: 10},
+				{AfterPrevious: true},
+			},
+		},
+		"filter with invalid phases": {
+			InputFilter: &DeviceFilter{Attributes: []*AttributeFilter{rpi}},
+			InputPhases: []*PhaseConstructor{
+				{DeviceCount: 10},
+				{},
+			},
+			OutputError: ErrPhaseStartMissing,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		constructor := NewDeploymentConstructor()
+		constructor.Name = StringToPointer("foo")
+		constructor.ArtifactName = StringToPointer("bar")
+		constructor.Devices = testCase.InputDevices
+		constructor.Filter = testCase.InputFilter
+		constructor.Phases = testCase.InputPhases
+
+		err := constructor.Validate()
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestDeviceFilterAsMap(t *testing.T) {
+
+	t.Parallel()
+
+	filter := &DeviceFilter{Attributes: []*AttributeFilter{
+		{Name: "device_type", Value: "rpi3"},
+		{Name: "region", Value: "eu"},
+	}}
+
+	assert.Equal(t, map[string]string{"device_type": "rpi3", "region": "eu"}, filter.AsMap())
+}
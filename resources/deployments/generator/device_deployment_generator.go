@@ -63,6 +63,7 @@ func (d *ImageBasedDeviceDeployment) Generate(ctx context.Context, deviceID stri
 	deviceDeployment.DeviceType = &deviceType
 	deviceDeployment.Image = image
 	deviceDeployment.Created = deployment.Created
+	deviceDeployment.Priority = deployment.Priority
 
 	// If not having appropriate image, set noartifact status
 	if deviceDeployment.Image == nil {
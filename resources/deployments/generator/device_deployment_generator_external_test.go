@@ -108,6 +108,27 @@ func TestImageBasedDeviceDeploymentGenerate(t *testing.T) {
 				Image:      &images.SoftwareImage{},
 			},
 		},
+		// Case: Priority copied from deployment
+		{
+			InputID: "b532b01a-9313-404f-8d19-e7fcbe5cc347",
+			InputDeployment: deployments.NewDeploymentFromConstructor(&deployments.DeploymentConstructor{
+				Name:         StringToPointer("Production"),
+				ArtifactName: StringToPointer("App 123"),
+				Devices:      []string{"275547d3-68da-4558-86fa-b1c2a2bd3d46"},
+				Priority:     10,
+			}),
+			InputGetDeviceType:            "BBB",
+			InputImageByNameAndDeviceType: &images.SoftwareImage{},
+
+			OutputDeviceDeplyment: &deployments.DeviceDeployment{
+				Created:    TimeToPointer(time.Now()),
+				Status:     StringToPointer(deployments.DeviceDeploymentStatusPending),
+				DeviceId:   StringToPointer("b532b01a-9313-404f-8d19-e7fcbe5cc347"),
+				DeviceType: StringToPointer("BBB"),
+				Image:      &images.SoftwareImage{},
+				Priority:   10,
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -137,6 +158,7 @@ func TestImageBasedDeviceDeploymentGenerate(t *testing.T) {
 			assert.Equal(t, testCase.OutputDeviceDeplyment.Image, deviceDeployment.Image)
 			assert.WithinDuration(t, *testCase.OutputDeviceDeplyment.Created, *deviceDeployment.Created, time.Minute)
 			assert.Equal(t, testCase.OutputDeviceDeplyment.Status, deviceDeployment.Status)
+			assert.Equal(t, testCase.OutputDeviceDeplyment.Priority, deviceDeployment.Priority)
 		}
 	}
 
@@ -18,6 +18,7 @@ import (
 	"context"
 
 	"github.com/mendersoftware/deployments/integration"
+	"github.com/mendersoftware/deployments/resources/deployments"
 	"github.com/pkg/errors"
 )
 
@@ -29,6 +30,7 @@ const (
 
 type APIClient interface {
 	GetDeviceInventory(ctx context.Context, device integration.DeviceID) (*integration.Device, error)
+	GetDevices(ctx context.Context, attributes map[string]string) ([]*integration.Device, error)
 }
 
 type Inventory struct {
@@ -61,3 +63,18 @@ func (i *Inventory) GetDeviceType(ctx context.Context, deviceID string) (string,
 
 	return "", errors.New(AttributeNameDeviceType + " inventory attribute not found")
 }
+
+// SearchDevices returns IDs of devices matching the filter.
+func (i *Inventory) SearchDevices(ctx context.Context, filter *deployments.DeviceFilter) ([]string, error) {
+	devices, err := i.api.GetDevices(ctx, filter.AsMap())
+	if err != nil {
+		return nil, errors.Wrap(err, "searching inventory for devices")
+	}
+
+	ids := make([]string, 0, len(devices))
+	for _, device := range devices {
+		ids = append(ids, device.ID.String())
+	}
+
+	return ids, nil
+}
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/mendersoftware/deployments/integration"
+	"github.com/mendersoftware/deployments/resources/deployments"
 	. "github.com/mendersoftware/deployments/resources/deployments/generator"
 	"github.com/mendersoftware/deployments/resources/deployments/generator/mocks"
 	"github.com/stretchr/testify/assert"
@@ -90,3 +91,57 @@ func TestInventoryGetDeviceType(t *testing.T) {
 	}
 
 }
+
+func TestInventorySearchDevices(t *testing.T) {
+
+	t.Parallel()
+
+	filter := &deployments.DeviceFilter{
+		Attributes: []*deployments.AttributeFilter{
+			{Name: AttributeNameDeviceType, Value: "BBB"},
+		},
+	}
+
+	cases := map[string]struct {
+		GetDevices    []*integration.Device
+		GetDevicesErr error
+
+		OutIDs []string
+		OutErr error
+	}{
+		"remote error": {
+			GetDevicesErr: errors.New("remote failed"),
+			OutErr:        errors.New("searching inventory for devices: remote failed"),
+		},
+		"no devices": {
+			GetDevices: []*integration.Device{},
+			OutIDs:     []string{},
+		},
+		"found": {
+			GetDevices: []*integration.Device{{ID: "a"}, {ID: "b"}},
+			OutIDs:     []string{"a", "b"},
+		},
+	}
+
+	for name, test := range cases {
+
+		t.Logf("Case: %s\n", name)
+
+		api := new(mocks.APIClient)
+		api.On("GetDevices", mock.AnythingOfType("*context.emptyCtx"), map[string]string{AttributeNameDeviceType: "BBB"}).
+			Return(test.GetDevices, test.GetDevicesErr)
+
+		inv := NewInventory(api)
+
+		ids, err := inv.SearchDevices(context.TODO(), filter)
+
+		if test.OutErr != nil {
+			assert.EqualError(t, err, test.OutErr.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, test.OutIDs, ids)
+	}
+
+}
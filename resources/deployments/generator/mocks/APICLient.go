@@ -47,3 +47,26 @@ func (_m *APIClient) GetDeviceInventory(ctx context.Context, device integration.
 
 	return r0, r1
 }
+
+// GetDevices provides a mock function with given fields: ctx, attributes
+func (_m *APIClient) GetDevices(ctx context.Context, attributes map[string]string) ([]*integration.Device, error) {
+	ret := _m.Called(ctx, attributes)
+
+	var r0 []*integration.Device
+	if rf, ok := ret.Get(0).(func(context.Context, map[string]string) []*integration.Device); ok {
+		r0 = rf(ctx, attributes)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*integration.Device)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, map[string]string) error); ok {
+		r1 = rf(ctx, attributes)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
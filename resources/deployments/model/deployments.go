@@ -20,6 +20,9 @@ import (
 
 	"github.com/mendersoftware/deployments/resources/deployments"
 	"github.com/mendersoftware/deployments/resources/deployments/controller"
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/utils/identity"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/pkg/errors"
 )
 
@@ -34,6 +37,9 @@ type DeploymentsModel struct {
 	deviceDeploymentLogsStorage DeviceDeploymentLogsStorage
 	imageLinker                 GetRequester
 	deviceDeploymentGenerator   Generator
+	deviceSearcher              DeviceSearcher
+	eventsHub                   EventsHub
+	notifier                    Notifier
 	imageContentType            string
 }
 
@@ -43,6 +49,9 @@ type DeploymentsModelConfig struct {
 	DeviceDeploymentLogsStorage DeviceDeploymentLogsStorage
 	ImageLinker                 GetRequester
 	DeviceDeploymentGenerator   Generator
+	DeviceSearcher              DeviceSearcher
+	EventsHub                   EventsHub
+	Notifier                    Notifier
 	ImageContentType            string
 }
 
@@ -53,6 +62,9 @@ func NewDeploymentModel(config DeploymentsModelConfig) *DeploymentsModel {
 		deviceDeploymentLogsStorage: config.DeviceDeploymentLogsStorage,
 		imageLinker:                 config.ImageLinker,
 		deviceDeploymentGenerator:   config.DeviceDeploymentGenerator,
+		deviceSearcher:              config.DeviceSearcher,
+		eventsHub:                   config.EventsHub,
+		notifier:                    config.Notifier,
 		imageContentType:            config.ImageContentType,
 	}
 }
@@ -63,26 +75,236 @@ func NewDeploymentModel(config DeploymentsModelConfig) *DeploymentsModel {
 // TODO: check if specified devices are bootstrapped (when have a way to do this)
 func (d *DeploymentsModel) CreateDeployment(ctx context.Context, constructor *deployments.DeploymentConstructor) (string, error) {
 
-	if constructor == nil {
+	deployment, deviceDeployments, err := d.prepareDeployment(ctx, constructor)
+	if err != nil {
+		return "", err
+	}
+
+	if err := d.resolveConflicts(deployment, deviceDeployments); err != nil {
+		return "", err
+	}
+
+	if err := d.storeDeployment(deployment, deviceDeployments); err != nil {
+		return "", err
+	}
+
+	if err := d.supersedeConflicts(deployment, deviceDeployments); err != nil {
+		return "", err
+	}
+
+	d.notify(webhooks.EventDeploymentCreated, deployment)
+
+	return *deployment.Id, nil
+}
+
+// RetryDeployment creates a new deployment of the same artifact, targeting
+// devices of deployment `deploymentID` with selected statuses.
+func (d *DeploymentsModel) RetryDeployment(ctx context.Context, deploymentID string,
+	retry *deployments.RetryConstructor) (string, error) {
+
+	if retry == nil {
 		return "", controller.ErrModelMissingInput
 	}
 
+	original, err := d.deploymentsStorage.FindByID(deploymentID)
+	if err != nil {
+		return "", errors.Wrap(err, "Searching for deployment by ID")
+	}
+	if original == nil || original.DeploymentConstructor == nil {
+		return "", controller.ErrModelDeploymentNotFound
+	}
+
+	statuses, _, err := d.deviceDeploymentsStorage.GetDeviceStatusesForDeployment(deploymentID,
+		paging.Query{})
+	if err != nil {
+		return "", errors.Wrap(err, "Searching for device deployments")
+	}
+
+	devices := []string{}
+	for i := range statuses {
+		if retry.Matches(&statuses[i]) {
+			devices = append(devices, *statuses[i].DeviceId)
+		}
+	}
+	if len(devices) == 0 {
+		return "", controller.ErrModelNoDevicesToRetry
+	}
+
+	constructor := deployments.NewRetryDeploymentConstructor(original, devices)
+	deployment, deviceDeployments, err := d.prepareDeployment(ctx, constructor)
+	if err != nil {
+		return "", err
+	}
+	deployment.RetryOf = original.Id
+
+	if err := d.resolveConflicts(deployment, deviceDeployments); err != nil {
+		return "", err
+	}
+
+	if err := d.storeDeployment(deployment, deviceDeployments); err != nil {
+		return "", err
+	}
+
+	if err := d.supersedeConflicts(deployment, deviceDeployments); err != nil {
+		return "", err
+	}
+
+	d.notify(webhooks.EventDeploymentCreated, deployment)
+
+	return *deployment.Id, nil
+}
+
+// resolveConflicts applies reject conflict policy to devices which are
+// going to receive the deployment while they still have an active one.
+// Queued devices get the new deployment once they finish older ones, superseded
+// ones are handled by supersedeConflicts once the deployment is stored.
+func (d *DeploymentsModel) resolveConflicts(deployment *deployments.Deployment,
+	deviceDeployments []*deployments.DeviceDeployment) error {
+
+	if deployment.ConflictPolicy != deployments.ConflictPolicyReject {
+		return nil
+	}
+
+	devices := pendingDevices(deviceDeployments)
+	if len(devices) == 0 {
+		return nil
+	}
+
+	active, err := d.deviceDeploymentsStorage.FindDeviceDeploymentsForDevices(devices,
+		deployments.ActiveDeploymentStatuses()...)
+	if err != nil {
+		return errors.Wrap(err, "Searching for active device deployments")
+	}
+	if len(active) != 0 {
+		return deployments.NewConflictError(active)
+	}
+
+	return nil
+}
+
+// supersedeConflicts applies supersede conflict policy, aborting older pending
+// deployments of devices receiving already stored deployment. Devices already
+// installing older deployment are left to finish it. In case of failure the
+// deployment stays stored and the remaining conflicts behave as queued.
+func (d *DeploymentsModel) supersedeConflicts(deployment *deployments.Deployment,
+	deviceDeployments []*deployments.DeviceDeployment) error {
+
+	if deployment.ConflictPolicy != deployments.ConflictPolicySupersede {
+		return nil
+	}
+
+	devices := pendingDevices(deviceDeployments)
+	if len(devices) == 0 {
+		return nil
+	}
+
+	pending, err := d.deviceDeploymentsStorage.FindDeviceDeploymentsForDevices(devices,
+		deployments.DeviceDeploymentStatusPending)
+	if err != nil {
+		return errors.Wrap(err, "Searching for pending device deployments")
+	}
+
+	for _, deviceDeployment := range pending {
+		if *deviceDeployment.DeploymentId == *deployment.Id {
+			continue
+		}
+
+		err := d.AbortDeviceDeployment(*deviceDeployment.DeploymentId, *deviceDeployment.DeviceId)
+		if _, ok := errors.Cause(err).(*deployments.StatusTransitionError); ok ||
+			err == controller.ErrDeploymentAborted {
+			// finished or aborted in the meantime
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Superseding deployment %s for device %s",
+				*deviceDeployment.DeploymentId, *deviceDeployment.DeviceId)
+		}
+	}
+
+	return nil
+}
+
+// pendingDevices lists devices which are going to receive the deployment.
+func pendingDevices(deviceDeployments []*deployments.DeviceDeployment) []string {
+	devices := []string{}
+	for _, deviceDeployment := range deviceDeployments {
+		if deviceDeployment.Status != nil &&
+			*deviceDeployment.Status == deployments.DeviceDeploymentStatusPending {
+			devices = append(devices, *deviceDeployment.DeviceId)
+		}
+	}
+	return devices
+}
+
+// storeDeployment stores deployment along with its device deployments.
+func (d *DeploymentsModel) storeDeployment(deployment *deployments.Deployment,
+	deviceDeployments []*deployments.DeviceDeployment) error {
+
+	if err := d.deploymentsStorage.Insert(deployment); err != nil {
+		return errors.Wrap(err, "Storing deployment data")
+	}
+
+	if err := d.deviceDeploymentsStorage.InsertMany(deviceDeployments...); err != nil {
+		if errCleanup := d.deploymentsStorage.Delete(*deployment.Id); errCleanup != nil {
+			err = errors.Wrap(err, errCleanup.Error())
+		}
+
+		return errors.Wrap(err, "Storing assigned deployments to devices")
+	}
+
+	return nil
+}
+
+// PreviewDeployment computes the outcome of deployment creation without
+// storing anything.
+func (d *DeploymentsModel) PreviewDeployment(ctx context.Context,
+	constructor *deployments.DeploymentConstructor) (*deployments.DeploymentPreview, error) {
+
+	_, deviceDeployments, err := d.prepareDeployment(ctx, constructor)
+	if err != nil {
+		return nil, err
+	}
+
+	return deployments.NewDeploymentPreview(deviceDeployments), nil
+}
+
+// prepareDeployment creates deployment and device deployments for each of its
+// devices, without storing them.
+func (d *DeploymentsModel) prepareDeployment(ctx context.Context,
+	constructor *deployments.DeploymentConstructor) (*deployments.Deployment, []*deployments.DeviceDeployment, error) {
+
+	if constructor == nil {
+		return nil, nil, controller.ErrModelMissingInput
+	}
+
 	if err := constructor.Validate(); err != nil {
-		return "", errors.Wrap(err, "Validating deployment")
+		return nil, nil, errors.Wrap(err, "Validating deployment")
+	}
+
+	if constructor.Filter != nil {
+		if err := d.resolveDeviceFilter(ctx, constructor); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	deployment := deployments.NewDeploymentFromConstructor(constructor)
+	if creator, ok := identity.FromContext(ctx); ok && creator.Subject != "" {
+		deployment.CreatedBy = &creator.Subject
+	}
 
 	// Generate deployment for each specified device.
 	unassigned := 0
 	deviceDeployments := make([]*deployments.DeviceDeployment, 0, len(constructor.Devices))
-	for _, id := range constructor.Devices {
+	for i, id := range constructor.Devices {
 
 		deviceDeployment, err := d.deviceDeploymentGenerator.Generate(ctx, id, deployment)
 		if err != nil {
-			return "", errors.Wrap(err, "Preparing deployment for device")
+			return nil, nil, errors.Wrap(err, "Preparing deployment for device")
 		}
 
+		// Devices are assigned to rollout phases in order they were listed
+		deviceDeployment.Phase = deployment.PhaseOf(i)
+
 		// // Check how many devices are not going to be deployed
 		if deviceDeployment.Status != nil && *(deviceDeployment.Status) == deployments.DeviceDeploymentStatusNoArtifact {
 			unassigned++
@@ -95,19 +317,28 @@ func (d *DeploymentsModel) CreateDeployment(ctx context.Context, constructor *de
 	deployment.Stats[deployments.DeviceDeploymentStatusNoArtifact] = unassigned
 	deployment.Stats[deployments.DeviceDeploymentStatusPending] = len(constructor.Devices) - unassigned
 
-	if err := d.deploymentsStorage.Insert(deployment); err != nil {
-		return "", errors.Wrap(err, "Storing deployment data")
+	return deployment, deviceDeployments, nil
+}
+
+// resolveDeviceFilter sets constructor devices to devices currently matching
+// the constructor filter. Filter is kept as a record of how devices were selected.
+func (d *DeploymentsModel) resolveDeviceFilter(ctx context.Context, constructor *deployments.DeploymentConstructor) error {
+
+	devices, err := d.deviceSearcher.SearchDevices(ctx, constructor.Filter)
+	if err != nil {
+		return errors.Wrap(err, "Resolving device filter")
 	}
 
-	if err := d.deviceDeploymentsStorage.InsertMany(deviceDeployments...); err != nil {
-		if errCleanup := d.deploymentsStorage.Delete(*deployment.Id); errCleanup != nil {
-			err = errors.Wrap(err, errCleanup.Error())
-		}
+	if len(devices) == 0 {
+		return controller.ErrModelNoDevicesMatched
+	}
 
-		return "", errors.Wrap(err, "Storing assigned deployments to devices")
+	if err := deployments.ValidatePhases(constructor.Phases, len(devices)); err != nil {
+		return errors.Wrap(err, "Validating deployment")
 	}
 
-	return *deployment.Id, nil
+	constructor.Devices = devices
+	return nil
 }
 
 // IsDeploymentFinished checks if there is unfinished deployment with given ID
@@ -132,9 +363,129 @@ func (d *DeploymentsModel) GetDeployment(deploymentID string) (*deployments.Depl
 		return nil, errors.Wrap(err, "Searching for deployment by ID")
 	}
 
+	if deployment != nil && len(deployment.Phases) != 0 {
+		if err := d.setPhasesStats(deployment); err != nil {
+			return nil, err
+		}
+	}
+
 	return deployment, nil
 }
 
+// setPhasesStats fills deployment phases with current statistics and status.
+func (d *DeploymentsModel) setPhasesStats(deployment *deployments.Deployment) error {
+
+	now := time.Now()
+	var previous *deployments.Phase
+	for i, phase := range deployment.Phases {
+		stats, err := d.deviceDeploymentsStorage.AggregateDeviceDeploymentByStatusForPhase(*deployment.Id, i)
+		if err != nil {
+			return errors.Wrap(err, "Counting phase device deployments")
+		}
+
+		phase.SetStats(stats, now, previous)
+		previous = phase
+	}
+
+	return nil
+}
+
+// isDeploymentOpenForDevice checks if the device can receive its deployment
+// now, according to deployment schedule, device rollout phase and retry backoff.
+// Paused deployments are not handed out.
+func (d *DeploymentsModel) isDeploymentOpenForDevice(deviceDeployment *deployments.DeviceDeployment) (bool, error) {
+
+	now := time.Now()
+	if deviceDeployment.RetryAfter != nil && now.Before(*deviceDeployment.RetryAfter) {
+		return false, nil
+	}
+
+	deployment, err := d.deploymentsStorage.FindByID(*deviceDeployment.DeploymentId)
+	if err != nil {
+		return false, errors.Wrap(err, "Searching for deployment by ID")
+	}
+
+	if deployment == nil {
+		return true, nil
+	}
+
+	if deployment.Paused || !deployment.IsWithinSchedule(now) {
+		return false, nil
+	}
+
+	phase := deviceDeployment.Phase
+	if phase >= len(deployment.Phases) {
+		return true, nil
+	}
+
+	var previous *deployments.Phase
+	if phase > 0 && deployment.Phases[phase].AfterPrevious {
+		previous = deployment.Phases[phase-1]
+		previous.Stats, err = d.deviceDeploymentsStorage.AggregateDeviceDeploymentByStatusForPhase(*deployment.Id, phase-1)
+		if err != nil {
+			return false, errors.Wrap(err, "Counting phase device deployments")
+		}
+	}
+
+	return deployment.Phases[phase].IsOpen(now, previous), nil
+}
+
+// startDeviceDeployment reserves a slot for the device if deployment limits
+// number of devices updating at once. Device holding the slot is moved to
+// downloading status right away, so that concurrent polls see the slot taken.
+// Returns false if there is no slot available.
+func (d *DeploymentsModel) startDeviceDeployment(deviceDeployment *deployments.DeviceDeployment) (bool, error) {
+
+	deploymentID := *deviceDeployment.DeploymentId
+
+	deployment, err := d.deploymentsStorage.FindByID(deploymentID)
+	if err != nil {
+		return false, errors.Wrap(err, "Searching for deployment by ID")
+	}
+
+	if deployment == nil || !deployment.HasInProgressLimit() {
+		return true, nil
+	}
+
+	acquired, err := d.deploymentsStorage.AcquireInProgressSlot(deploymentID, deployment.MaxInProgress)
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	err = d.UpdateDeviceDeploymentStatus(deploymentID, *deviceDeployment.DeviceId,
+		deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusDownloading})
+	if err != nil {
+		if errRelease := d.deploymentsStorage.ReleaseInProgressSlot(deploymentID); errRelease != nil {
+			return false, errors.Wrap(err, errRelease.Error())
+		}
+		// deployment got aborted in the meantime
+		if err == controller.ErrDeploymentAborted {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// releaseInProgressSlot frees the slot held by a device which stopped
+// actively updating.
+func (d *DeploymentsModel) releaseInProgressSlot(deployment *deployments.Deployment,
+	from string, to string) error {
+
+	if deployment == nil || !deployment.HasInProgressLimit() ||
+		!deployments.IsDeviceDeploymentStatusInProgress(from) ||
+		deployments.IsDeviceDeploymentStatusInProgress(to) {
+		return nil
+	}
+
+	if err := d.deploymentsStorage.ReleaseInProgressSlot(*deployment.Id); err != nil {
+		return errors.Wrap(err, "failed to release in progress slot")
+	}
+
+	return nil
+}
+
 // ImageUsedInActiveDeployment checks if specified image is in use by deployments
 // Image is considered to be in use if it's participating in at lest one non success/error deployment.
 func (d *DeploymentsModel) ImageUsedInActiveDeployment(imageID string) (bool, error) {
@@ -163,15 +514,45 @@ func (d *DeploymentsModel) ImageUsedInDeployment(imageID string) (bool, error) {
 func (d *DeploymentsModel) GetDeploymentForDeviceWithCurrent(deviceID string,
 	installed deployments.InstalledDeviceDeployment) (*deployments.DeploymentInstructions, error) {
 
-	deployment, err := d.deviceDeploymentsStorage.FindOldestDeploymentForDeviceIDWithStatuses(deviceID,
+	deviceDeployments, err := d.deviceDeploymentsStorage.FindDeploymentsForDeviceIDWithStatuses(deviceID,
 		deployments.ActiveDeploymentStatuses()...)
 
 	if err != nil {
-		return nil, errors.Wrap(err, "Searching for oldest active deployment for the device")
+		return nil, errors.Wrap(err, "Searching for active deployments for the device")
 	}
 
-	if deployment == nil {
-		return nil, nil
+	// Deployments the device can not receive yet do not hold back the ones
+	// queued behind them
+	for _, deployment := range deviceDeployments {
+		ready, err := d.isDeviceDeploymentReady(deviceID, deployment, installed)
+		if err != nil {
+			return nil, err
+		}
+		if ready {
+			return d.getDeploymentInstructions(deployment)
+		}
+	}
+
+	return nil, nil
+}
+
+// isDeviceDeploymentReady checks if the device deployment can be handed out
+// to the device now.
+func (d *DeploymentsModel) isDeviceDeploymentReady(deviceID string,
+	deployment *deployments.DeviceDeployment, installed deployments.InstalledDeviceDeployment) (bool, error) {
+
+	pending := deployment.Status != nil && *deployment.Status == deployments.DeviceDeploymentStatusPending
+
+	// Pending devices wait for deployment start time, maintenance window
+	// and their rollout phase
+	if pending {
+		open, err := d.isDeploymentOpenForDevice(deployment)
+		if err != nil {
+			return false, errors.Wrap(err, "Checking deployment availability")
+		}
+		if !open {
+			return false, nil
+		}
 	}
 
 	if installed.Artifact != "" && deployment.Image.ArtifactName == installed.Artifact {
@@ -179,14 +560,34 @@ func (d *DeploymentsModel) GetDeploymentForDeviceWithCurrent(deviceID string,
 		// its status to already installed first
 
 		if err := d.UpdateDeviceDeploymentStatus(*deployment.DeploymentId, deviceID,
-			deployments.DeviceDeploymentStatusAlreadyInst); err != nil {
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusAlreadyInst}); err != nil {
 
-			return nil, errors.Wrap(err, "Failed to update deployment status")
+			return false, errors.Wrap(err, "Failed to update deployment status")
 		}
 
-		return nil, nil
+		return false, nil
+	}
+
+	// Devices starting the deployment may need to wait for devices already
+	// updating
+	if pending {
+		started, err := d.startDeviceDeployment(deployment)
+		if err != nil {
+			return false, errors.Wrap(err, "Starting device deployment")
+		}
+		if !started {
+			return false, nil
+		}
 	}
 
+	return true, nil
+}
+
+// getDeploymentInstructions generates instructions for the device to install
+// deployment artifact.
+func (d *DeploymentsModel) getDeploymentInstructions(
+	deployment *deployments.DeviceDeployment) (*deployments.DeploymentInstructions, error) {
+
 	link, err := d.imageLinker.GetRequest(deployment.Image.Id,
 		DefaultUpdateDownloadLinkExpire, d.imageContentType)
 	if err != nil {
@@ -206,9 +607,12 @@ func (d *DeploymentsModel) GetDeploymentForDeviceWithCurrent(deviceID string,
 }
 
 // UpdateDeviceDeploymentStatus will update the deployment status for device of
-// ID `deviceID`. Returns nil if update was successful.
+// ID `deviceID`, together with substate and progress reported by the device.
+// Returns nil if update was successful.
 func (d *DeploymentsModel) UpdateDeviceDeploymentStatus(deploymentID string,
-	deviceID string, status string) error {
+	deviceID string, state deployments.DeviceDeploymentState) error {
+
+	status := state.Status
 
 	var finishTime *time.Time = nil
 	if deployments.IsDeviceDeploymentStatusFinished(status) {
@@ -223,9 +627,22 @@ func (d *DeploymentsModel) UpdateDeviceDeploymentStatus(deploymentID string,
 	if currentStatus == deployments.DeviceDeploymentStatusAborted {
 		return controller.ErrDeploymentAborted
 	}
+	// missing device deployment is reported by the storage update
+	if currentStatus != "" {
+		if err := deployments.ValidateDeviceDeploymentStatusTransition(currentStatus, status); err != nil {
+			return err
+		}
+	}
+
+	if status == deployments.DeviceDeploymentStatusFailure {
+		retried, err := d.retryDeviceDeployment(deploymentID, deviceID)
+		if err != nil || retried {
+			return err
+		}
+	}
 
 	old, err := d.deviceDeploymentsStorage.UpdateDeviceDeploymentStatus(deviceID, deploymentID,
-		status, finishTime)
+		state, finishTime)
 	if err != nil {
 		return err
 	}
@@ -240,18 +657,112 @@ func (d *DeploymentsModel) UpdateDeviceDeploymentStatus(deploymentID string,
 		return errors.Wrap(err, "failed when searching for deployment")
 	}
 
+	d.publishEvent(deployments.NewDeviceStatusEvent(deploymentID, deviceID, old, status))
+	if deployment != nil {
+		d.publishEvent(deployments.NewStatsEvent(deploymentID, deployment.Stats))
+	}
+
+	if err := d.releaseInProgressSlot(deployment, old, status); err != nil {
+		return err
+	}
+
+	if status == deployments.DeviceDeploymentStatusFailure {
+		aborted, err := d.enforceFailurePolicy(deployment)
+		if err != nil || aborted {
+			return err
+		}
+	}
+
 	if deployment.IsFinished() {
 		// TODO: Make this part of UpdateStats() call as currently we are doing two
 		// write operations on DB - as well as it's saver to keep them in single transaction.
-		if err := d.deploymentsStorage.Finish(deploymentID, time.Now()); err != nil {
+		now := time.Now()
+		if err := d.deploymentsStorage.Finish(deploymentID, now); err != nil {
 			return errors.Wrap(err, "failed to mark deployment as finished")
 		}
+
+		deployment.Finished = &now
+		d.notify(webhooks.EventDeploymentFinished, deployment)
 	}
 
 	return nil
 }
 
-func (d *DeploymentsModel) GetDeploymentStats(deploymentID string) (deployments.Stats, error) {
+// retryDeviceDeployment puts failed device deployment back to pending if
+// deployment retry policy allows it. Returns true if installation is retried.
+func (d *DeploymentsModel) retryDeviceDeployment(deploymentID string,
+	deviceID string) (bool, error) {
+
+	deployment, err := d.deploymentsStorage.FindByID(deploymentID)
+	if err != nil {
+		return false, errors.Wrap(err, "failed when searching for deployment")
+	}
+
+	if deployment == nil || deployment.DeploymentConstructor == nil || deployment.MaxRetries == 0 {
+		return false, nil
+	}
+
+	var retryAfter *time.Time
+	if deployment.RetryBackoff != 0 {
+		after := time.Now().Add(time.Duration(deployment.RetryBackoff) * time.Second)
+		retryAfter = &after
+	}
+
+	// status might have changed since it was checked, stats are updated
+	// from the one actually retried
+	previous, err := d.deviceDeploymentsStorage.RetryDeviceDeployment(deviceID, deploymentID,
+		deployment.MaxRetries, retryAfter)
+	if err != nil || previous == "" {
+		return false, err
+	}
+
+	if err := d.deploymentsStorage.UpdateStats(deploymentID, previous,
+		deployments.DeviceDeploymentStatusPending); err != nil {
+		return true, err
+	}
+
+	d.publishEvent(deployments.NewDeviceStatusEvent(deploymentID, deviceID, previous,
+		deployments.DeviceDeploymentStatusPending))
+
+	if err := d.releaseInProgressSlot(deployment, previous,
+		deployments.DeviceDeploymentStatusPending); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// enforceFailurePolicy aborts deployment if its failure policy was violated.
+// Returns true if deployment got aborted.
+func (d *DeploymentsModel) enforceFailurePolicy(deployment *deployments.Deployment) (bool, error) {
+
+	if deployment.DeploymentConstructor == nil || deployment.FailurePolicy == nil ||
+		deployment.IsAborted() {
+		return false, nil
+	}
+
+	reason := deployment.FailurePolicy.Violation(deployment.Stats)
+	if reason == "" {
+		return false, nil
+	}
+
+	if err := d.AbortDeployment(*deployment.Id); err != nil {
+		return false, errors.Wrap(err, "failed to abort deployment due to failure policy")
+	}
+
+	if err := d.deploymentsStorage.UpdateAbortReason(*deployment.Id, reason); err != nil {
+		return true, errors.Wrap(err, "failed to record deployment abort reason")
+	}
+
+	deployment.AbortReason = &reason
+	d.notify(webhooks.EventDeploymentFailureThreshold, deployment)
+
+	return true, nil
+}
+
+// GetDeploymentStats returns status counters and installation progress of
+// deployment devices, nil if the deployment does not exist.
+func (d *DeploymentsModel) GetDeploymentStats(deploymentID string) (*deployments.DeploymentStatistics, error) {
 	deployment, err := d.deploymentsStorage.FindByID(deploymentID)
 
 	if err != nil {
@@ -262,40 +773,105 @@ func (d *DeploymentsModel) GetDeploymentStats(deploymentID string) (deployments.
 		return nil, nil
 	}
 
-	return d.deviceDeploymentsStorage.AggregateDeviceDeploymentByStatus(deploymentID)
+	return d.deviceDeploymentsStorage.AggregateDeviceDeploymentStatistics(deploymentID)
 }
 
-//GetDeviceStatusesForDeployment retrieve device deployment statuses for a given deployment.
-func (d *DeploymentsModel) GetDeviceStatusesForDeployment(deploymentID string) ([]deployments.DeviceDeployment, error) {
+// GetDeploymentFailures returns failed devices of the deployment grouped by
+// failure reason code and device type.
+func (d *DeploymentsModel) GetDeploymentFailures(deploymentID string) ([]*deployments.FailureGroup, error) {
+
 	deployment, err := d.deploymentsStorage.FindByID(deploymentID)
 	if err != nil {
-		return nil, controller.ErrModelInternal
+		return nil, errors.Wrap(err, "Searching for deployment by ID")
 	}
 
 	if deployment == nil {
 		return nil, controller.ErrModelDeploymentNotFound
 	}
 
-	statuses, err := d.deviceDeploymentsStorage.GetDeviceStatusesForDeployment(deploymentID)
+	groups, err := d.deviceDeploymentsStorage.AggregateDeviceDeploymentFailures(deploymentID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Aggregating failed device deployments")
+	}
+
+	if groups == nil {
+		return make([]*deployments.FailureGroup, 0), nil
+	}
+
+	return groups, nil
+}
+
+//GetDeviceStatusesForDeployment retrieve a page of device deployment statuses for a given deployment,
+// together with total number of devices of the deployment.
+func (d *DeploymentsModel) GetDeviceStatusesForDeployment(deploymentID string,
+	page paging.Query) ([]deployments.DeviceDeployment, int, error) {
+
+	deployment, err := d.deploymentsStorage.FindByID(deploymentID)
 	if err != nil {
-		return nil, controller.ErrModelInternal
+		return nil, 0, controller.ErrModelInternal
+	}
+
+	if deployment == nil {
+		return nil, 0, controller.ErrModelDeploymentNotFound
+	}
+
+	statuses, total, err := d.deviceDeploymentsStorage.GetDeviceStatusesForDeployment(deploymentID, page)
+	if err != nil {
+		return nil, 0, controller.ErrModelInternal
+	}
+
+	if statuses == nil {
+		return make([]deployments.DeviceDeployment, 0), total, nil
+	}
+
+	return statuses, total, nil
+}
+
+// GetDeviceDeploymentHistory lists a page of deployments of a device, newest
+// first, together with names of the deployments and artifacts, and the total
+// number of matching deployments of the device.
+func (d *DeploymentsModel) GetDeviceDeploymentHistory(deviceID string,
+	query deployments.DeviceDeploymentHistoryQuery) ([]*deployments.DeviceDeploymentHistoryEntry, int, error) {
+
+	devDeps, total, err := d.deviceDeploymentsStorage.FindDeviceDeploymentsForDevice(deviceID, query)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "searching for device deployments")
+	}
+
+	// device is likely to get a couple of retries of the same deployment
+	found := make(map[string]*deployments.Deployment)
+
+	history := make([]*deployments.DeviceDeploymentHistoryEntry, 0, len(devDeps))
+	for _, devDep := range devDeps {
+		deployment, ok := found[*devDep.DeploymentId]
+		if !ok {
+			deployment, err = d.deploymentsStorage.FindByID(*devDep.DeploymentId)
+			if err != nil {
+				return nil, 0, errors.Wrap(err, "searching for deployment")
+			}
+			found[*devDep.DeploymentId] = deployment
+		}
+
+		history = append(history, deployments.NewDeviceDeploymentHistoryEntry(devDep, deployment))
 	}
 
-	return statuses, nil
+	return history, total, nil
 }
 
-func (d *DeploymentsModel) LookupDeployment(query deployments.Query) ([]*deployments.Deployment, error) {
-	list, err := d.deploymentsStorage.Find(query)
+// LookupDeployment returns a page of deployments matching the query, together
+// with total number of matching deployments.
+func (d *DeploymentsModel) LookupDeployment(query deployments.Query) ([]*deployments.Deployment, int, error) {
+	list, total, err := d.deploymentsStorage.Find(query)
 
 	if err != nil {
-		return nil, errors.Wrap(err, "searching for deployments")
+		return nil, 0, errors.Wrap(err, "searching for deployments")
 	}
 
 	if list == nil {
-		return make([]*deployments.Deployment, 0), nil
+		return make([]*deployments.Deployment, 0), total, nil
 	}
 
-	return list, nil
+	return list, total, nil
 }
 
 // SaveDeviceDeploymentLog will save the deployment log for device of
@@ -310,7 +886,7 @@ func (d *DeploymentsModel) SaveDeviceDeploymentLog(deviceID string,
 		Messages:     logs,
 	}
 	if err := dlog.Validate(); err != nil {
-		return errors.Wrapf(err, controller.ErrStorageInvalidLog.Error())
+		return errors.Wrap(err, controller.ErrStorageInvalidLog.Error())
 	}
 
 	if has, err := d.HasDeploymentForDevice(deploymentID, deviceID); !has {
@@ -340,6 +916,17 @@ func (d *DeploymentsModel) HasDeploymentForDevice(deploymentID string, deviceID
 // AbortDeployment aborts deployment for devices and updates deployment stats
 func (d *DeploymentsModel) AbortDeployment(deploymentID string) error {
 
+	// devices getting aborted are only needed for events
+	var active []*deployments.DeviceDeployment
+	if d.eventsHub != nil {
+		var err error
+		active, err = d.deviceDeploymentsStorage.FindDeviceDeploymentsWithStatuses(deploymentID,
+			deployments.ActiveDeploymentStatuses()...)
+		if err != nil {
+			return errors.Wrap(err, "Searching for active device deployments")
+		}
+	}
+
 	if err := d.deviceDeploymentsStorage.AbortDeviceDeployments(deploymentID); err != nil {
 		return err
 	}
@@ -352,5 +939,217 @@ func (d *DeploymentsModel) AbortDeployment(deploymentID string) error {
 	// Update deployment stats and finish deployment (set finished timestamp to current time)
 	// Aborted deployment is considered to be finished even if some devices are
 	// still processing this deployment.
-	return d.deploymentsStorage.UpdateStatsAndFinishDeployment(deploymentID, stats)
+	if err := d.deploymentsStorage.UpdateStatsAndFinishDeployment(deploymentID, stats); err != nil {
+		return err
+	}
+
+	for _, deviceDeployment := range active {
+		d.publishEvent(deployments.NewDeviceStatusEvent(deploymentID, *deviceDeployment.DeviceId,
+			*deviceDeployment.Status, deployments.DeviceDeploymentStatusAborted))
+	}
+	d.publishEvent(deployments.NewStatsEvent(deploymentID, stats))
+
+	if d.notifier != nil {
+		deployment, err := d.deploymentsStorage.FindByID(deploymentID)
+		if err != nil {
+			return errors.Wrap(err, "Searching for deployment by ID")
+		}
+		if deployment != nil {
+			d.notify(webhooks.EventDeploymentAborted, deployment)
+		}
+	}
+
+	return nil
+}
+
+// SubscribeDeploymentEvents returns channel receiving progress events of the
+// deployment and function cancelling the subscription. Without events hub the
+// channel is closed right away.
+func (d *DeploymentsModel) SubscribeDeploymentEvents(deploymentID string) (<-chan *deployments.Event, func()) {
+
+	if d.eventsHub == nil {
+		events := make(chan *deployments.Event)
+		close(events)
+		return events, func() {}
+	}
+
+	return d.eventsHub.Subscribe(deploymentID)
+}
+
+// publishEvent passes event to events hub, if there is one.
+func (d *DeploymentsModel) publishEvent(event *deployments.Event) {
+	if d.eventsHub != nil {
+		d.eventsHub.Publish(event)
+	}
+}
+
+// notify passes lifecycle event to notifier, if there is one.
+func (d *DeploymentsModel) notify(event string, data interface{}) {
+	if d.notifier != nil {
+		d.notifier.Notify(event, data)
+	}
+}
+
+// DeleteDeployment removes finished or aborted deployment together with its
+// device deployments and logs.
+func (d *DeploymentsModel) DeleteDeployment(deploymentID string) error {
+
+	deployment, err := d.deploymentsStorage.FindByID(deploymentID)
+	if err != nil {
+		return errors.Wrap(err, "Searching for deployment by ID")
+	}
+	if deployment == nil {
+		return controller.ErrModelDeploymentNotFound
+	}
+	if deployment.Finished == nil {
+		return controller.ErrModelDeploymentNotFinished
+	}
+
+	return d.removeDeployment(deploymentID)
+}
+
+// removeDeployment deletes deployment data. Deployment itself goes last, so
+// that partially removed deployment can be found and removed again.
+func (d *DeploymentsModel) removeDeployment(deploymentID string) error {
+
+	if err := d.deviceDeploymentLogsStorage.DeleteDeviceDeploymentLogs(deploymentID); err != nil {
+		return errors.Wrap(err, "Deleting device deployment logs")
+	}
+
+	if err := d.deviceDeploymentsStorage.DeleteDeviceDeployments(deploymentID); err != nil {
+		return errors.Wrap(err, "Deleting device deployments")
+	}
+
+	if err := d.deploymentsStorage.Delete(deploymentID); err != nil {
+		return errors.Wrap(err, "Deleting deployment")
+	}
+
+	return nil
+}
+
+// AbortDeviceDeployment aborts deployment for a single device, the deployment
+// goes on for remaining devices. Deployment stats and finished flag are updated
+// as if the device reported aborted status.
+func (d *DeploymentsModel) AbortDeviceDeployment(deploymentID string, deviceID string) error {
+
+	found, err := d.deviceDeploymentsStorage.HasDeploymentForDevice(deploymentID, deviceID)
+	if err != nil {
+		return errors.Wrap(err, "searching for device deployment")
+	}
+	if !found {
+		return controller.ErrModelDeviceDeploymentNotFound
+	}
+
+	return d.UpdateDeviceDeploymentStatus(deploymentID, deviceID,
+		deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusAborted})
+}
+
+// PauseDeployment stops handing out the deployment to devices. Devices already
+// installing the deployment are not affected.
+func (d *DeploymentsModel) PauseDeployment(deploymentID string) error {
+	return d.deploymentsStorage.UpdatePaused(deploymentID, true)
+}
+
+// ResumeDeployment resumes paused deployment.
+func (d *DeploymentsModel) ResumeDeployment(deploymentID string) error {
+	return d.deploymentsStorage.UpdatePaused(deploymentID, false)
+}
+
+// ExpireDeployments moves pending devices of deployments past their expiry
+// time to expired status, so that the deployments can finish. Returns number
+// of expired device deployments.
+func (d *DeploymentsModel) ExpireDeployments() (int, error) {
+
+	expired, err := d.deploymentsStorage.FindExpired(time.Now())
+	if err != nil {
+		return 0, errors.Wrap(err, "Searching for expired deployments")
+	}
+
+	count := 0
+	for _, deployment := range expired {
+		pending, err := d.deviceDeploymentsStorage.FindDeviceDeploymentsWithStatuses(*deployment.Id,
+			deployments.DeviceDeploymentStatusPending)
+		if err != nil {
+			return count, errors.Wrapf(err, "Searching for pending devices of deployment %s",
+				*deployment.Id)
+		}
+
+		for _, deviceDeployment := range pending {
+			err := d.UpdateDeviceDeploymentStatus(*deployment.Id, *deviceDeployment.DeviceId,
+				deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusExpired})
+			if _, ok := errors.Cause(err).(*deployments.StatusTransitionError); ok ||
+				err == controller.ErrDeploymentAborted {
+				// started or aborted in the meantime
+				continue
+			}
+			if err != nil {
+				return count, errors.Wrapf(err, "Expiring deployment %s for device %s",
+					*deployment.Id, *deviceDeployment.DeviceId)
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// FailStaleDeviceDeployments marks device deployments which stayed in
+// downloading, installing or rebooting status for longer than timeout as failed.
+// Devices which went silent would otherwise keep their deployments in progress
+// forever. Returns number of failed device deployments.
+func (d *DeploymentsModel) FailStaleDeviceDeployments(timeout time.Duration) (int, error) {
+
+	stale, err := d.deviceDeploymentsStorage.FindStaleDeviceDeployments(time.Now().Add(-timeout),
+		deployments.DeviceDeploymentStatusDownloading,
+		deployments.DeviceDeploymentStatusInstalling,
+		deployments.DeviceDeploymentStatusRebooting)
+	if err != nil {
+		return 0, errors.Wrap(err, "Searching for stale device deployments")
+	}
+
+	failed := 0
+	for _, deviceDeployment := range stale {
+		err := d.UpdateDeviceDeploymentStatus(*deviceDeployment.DeploymentId, *deviceDeployment.DeviceId,
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusFailure})
+		if _, ok := errors.Cause(err).(*deployments.StatusTransitionError); ok ||
+			err == controller.ErrDeploymentAborted {
+			// finished or aborted in the meantime
+			continue
+		}
+		if err != nil {
+			return failed, errors.Wrapf(err, "Failing deployment %s for device %s",
+				*deviceDeployment.DeploymentId, *deviceDeployment.DeviceId)
+		}
+		failed++
+	}
+
+	return failed, nil
+}
+
+// PurgeDeployments removes deployments which finished longer than `age` ago,
+// together with their device deployments and logs. With archive set,
+// deployments, including their final statistics, are moved to the archive
+// first. Returns number of purged deployments.
+func (d *DeploymentsModel) PurgeDeployments(age time.Duration, archive bool) (int, error) {
+
+	old, err := d.deploymentsStorage.FindFinishedBefore(time.Now().Add(-age))
+	if err != nil {
+		return 0, errors.Wrap(err, "Searching for finished deployments")
+	}
+
+	purged := 0
+	for _, deployment := range old {
+		if archive {
+			if err := d.deploymentsStorage.Archive(deployment); err != nil {
+				return purged, errors.Wrapf(err, "Archiving deployment %s", *deployment.Id)
+			}
+		}
+
+		if err := d.removeDeployment(*deployment.Id); err != nil {
+			return purged, errors.Wrapf(err, "Purging deployment %s", *deployment.Id)
+		}
+		purged++
+	}
+
+	return purged, nil
 }
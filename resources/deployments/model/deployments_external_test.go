@@ -25,6 +25,9 @@ import (
 	. "github.com/mendersoftware/deployments/resources/deployments/model"
 	"github.com/mendersoftware/deployments/resources/deployments/model/mocks"
 	"github.com/mendersoftware/deployments/resources/images"
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/utils/identity"
+	"github.com/mendersoftware/deployments/utils/paging"
 	. "github.com/mendersoftware/deployments/utils/pointers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -221,7 +224,7 @@ func TestDeploymentModelGetDeploymentForDevice(t *testing.T) {
 			InputID: "ID:123",
 			InputOlderstDeviceDeploymentError: errors.New("storage issue"),
 
-			OutputError: errors.New("Searching for oldest active deployment for the device: storage issue"),
+			OutputError: errors.New("Searching for active deployments for the device: storage issue"),
 		},
 		{
 			InputID: "ID:123",
@@ -286,24 +289,27 @@ func TestDeploymentModelGetDeploymentForDevice(t *testing.T) {
 		deploymentStorage := new(mocks.DeploymentsStorage)
 
 		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
-		deviceDeploymentStorage.On("FindOldestDeploymentForDeviceIDWithStatuses",
+		var deviceDeployments []*deployments.DeviceDeployment
+		if testCase.InputOlderstDeviceDeployment != nil {
+			deviceDeployments = append(deviceDeployments, testCase.InputOlderstDeviceDeployment)
+		}
+		deviceDeploymentStorage.On("FindDeploymentsForDeviceIDWithStatuses",
 			testCase.InputID, mock.AnythingOfType("[]string")).
-			Return(testCase.InputOlderstDeviceDeployment,
-				testCase.InputOlderstDeviceDeploymentError)
+			Return(deviceDeployments, testCase.InputOlderstDeviceDeploymentError)
 		// if UpdateDeviceDeploymentStatus is ever called, the status
 		// will be already-installed
 		deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus",
 			mock.AnythingOfType("string"), mock.AnythingOfType("string"),
-			deployments.DeviceDeploymentStatusAlreadyInst, mock.AnythingOfType("*time.Time")).
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusAlreadyInst}, mock.AnythingOfType("*time.Time")).
 			Return("dontcare", nil)
 		deviceDeploymentStorage.On("GetDeviceDeploymentStatus",
 			mock.AnythingOfType("string"), mock.AnythingOfType("string")).
-			Return("dontcare", nil)
+			Return(deployments.DeviceDeploymentStatusPending, nil)
 
 		imageLinker := new(mocks.GetRequester)
 		if testCase.InputOlderstDeviceDeployment != nil {
 			// Notice: force GetRequest to expect image id returned
-			// by FindOldestDeploymentForDeviceIDWithStatuses Just
+			// by FindDeploymentsForDeviceIDWithStatuses Just
 			// as implementation does, if this changes test will
 			// break by panic ;)
 			imageLinker.On("GetRequest", testCase.InputOlderstDeviceDeployment.Image.Id,
@@ -379,7 +385,7 @@ func TestDeploymentModelCreateDeployment(t *testing.T) {
 		},
 		{
 			InputConstructor: deployments.NewDeploymentConstructor(),
-			OutputError:      errors.New("Validating deployment: Name: non zero value required;ArtifactName: non zero value required;Devices: non zero value required;"),
+			OutputError:      errors.New("Validating deployment: Name: non zero value required;ArtifactName: non zero value required;"),
 		},
 		{
 			InputConstructor: &deployments.DeploymentConstructor{
@@ -465,17 +471,25 @@ func TestDeploymentModelCreateDeployment(t *testing.T) {
 		deviceDeploymentStorage.On("InsertMany", mock.AnythingOfType("[]*deployments.DeviceDeployment")).
 			Return(testCase.InputDeviceDeploymentStorageInsertManyError)
 
+		notifier := new(mocks.Notifier)
+		notifier.On("Notify", webhooks.EventDeploymentCreated, mock.AnythingOfType("*deployments.Deployment"))
+
 		model := NewDeploymentModel(DeploymentsModelConfig{
 			DeploymentsStorage:        deploymentStorage,
 			DeviceDeploymentGenerator: generator,
 			DeviceDeploymentsStorage:  deviceDeploymentStorage,
+			Notifier:                  notifier,
 		})
 
 		out, err := model.CreateDeployment(context.Background(), testCase.InputConstructor)
 		if testCase.OutputError != nil {
 			assert.EqualError(t, err, testCase.OutputError.Error())
+			notifier.AssertNotCalled(t, "Notify", webhooks.EventDeploymentCreated,
+				mock.AnythingOfType("*deployments.Deployment"))
 		} else {
 			assert.NoError(t, err)
+			notifier.AssertCalled(t, "Notify", webhooks.EventDeploymentCreated,
+				mock.AnythingOfType("*deployments.Deployment"))
 		}
 		if testCase.OutputBody {
 			assert.NotNil(t, out)
@@ -484,6 +498,83 @@ func TestDeploymentModelCreateDeployment(t *testing.T) {
 
 }
 
+func TestDeploymentModelPreviewDeployment(t *testing.T) {
+
+	t.Parallel()
+
+	constructor := &deployments.DeploymentConstructor{
+		Name:         StringToPointer("NYC Production"),
+		ArtifactName: StringToPointer("App 123"),
+		Devices:      []string{"device-1", "device-2", "device-3"},
+	}
+
+	image := &images.SoftwareImage{Id: "image-1"}
+
+	pending := deployments.NewDeviceDeployment("device-1", validUUIDv4)
+	pending.DeviceType = StringToPointer("rpi")
+	pending.Image = image
+	other := deployments.NewDeviceDeployment("device-2", validUUIDv4)
+	other.DeviceType = StringToPointer("rpi")
+	other.Image = image
+	noArtifact := deployments.NewDeviceDeployment("device-3", validUUIDv4)
+	noArtifact.DeviceType = StringToPointer("bbb")
+	noArtifact.Status = StringToPointer(deployments.DeviceDeploymentStatusNoArtifact)
+
+	generator := new(mocks.Generator)
+	generator.On("Generate", mock.AnythingOfType("*context.emptyCtx"), "device-1",
+		mock.AnythingOfType("*deployments.Deployment")).
+		Return(pending, nil)
+	generator.On("Generate", mock.AnythingOfType("*context.emptyCtx"), "device-2",
+		mock.AnythingOfType("*deployments.Deployment")).
+		Return(other, nil)
+	generator.On("Generate", mock.AnythingOfType("*context.emptyCtx"), "device-3",
+		mock.AnythingOfType("*deployments.Deployment")).
+		Return(noArtifact, nil)
+
+	// nothing is stored
+	deploymentStorage := new(mocks.DeploymentsStorage)
+	deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+
+	model := NewDeploymentModel(DeploymentsModelConfig{
+		DeploymentsStorage:        deploymentStorage,
+		DeviceDeploymentGenerator: generator,
+		DeviceDeploymentsStorage:  deviceDeploymentStorage,
+	})
+
+	preview, err := model.PreviewDeployment(context.Background(), constructor)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []*deployments.DeviceDeploymentPreview{
+		{
+			DeviceId:   "device-1",
+			DeviceType: "rpi",
+			ImageId:    "image-1",
+			Status:     deployments.DeviceDeploymentStatusPending,
+		},
+		{
+			DeviceId:   "device-2",
+			DeviceType: "rpi",
+			ImageId:    "image-1",
+			Status:     deployments.DeviceDeploymentStatusPending,
+		},
+		{
+			DeviceId:   "device-3",
+			DeviceType: "bbb",
+			Status:     deployments.DeviceDeploymentStatusNoArtifact,
+		},
+	}, preview.Devices)
+	assert.Equal(t, map[string]int{"rpi": 2, "bbb": 1}, preview.DeviceTypes)
+	assert.Equal(t, 2, preview.Stats[deployments.DeviceDeploymentStatusPending])
+	assert.Equal(t, 1, preview.Stats[deployments.DeviceDeploymentStatusNoArtifact])
+
+	deploymentStorage.AssertNotCalled(t, "Insert", mock.AnythingOfType("*deployments.Deployment"))
+	deviceDeploymentStorage.AssertNotCalled(t, "InsertMany", mock.AnythingOfType("[]*deployments.DeviceDeployment"))
+
+	// errors are reported the same way as for deployment creation
+	_, err = model.PreviewDeployment(context.Background(), nil)
+	assert.EqualError(t, err, controller.ErrModelMissingInput.Error())
+}
+
 func TestDeploymentModelUpdateDeviceDeploymentStatus(t *testing.T) {
 
 	t.Parallel()
@@ -493,6 +584,8 @@ func TestDeploymentModelUpdateDeviceDeploymentStatus(t *testing.T) {
 		InputDeviceID   string
 		OldStatus       string
 		InputStatus     string
+		InputSubState   *string
+		InputProgress   *int
 
 		InputDevsStorageError error
 		InputDepsStorageError error
@@ -527,7 +620,7 @@ func TestDeploymentModelUpdateDeviceDeploymentStatus(t *testing.T) {
 				},
 			},
 			InputDeviceID: "234",
-			InputStatus:   "none",
+			InputStatus:   "downloading",
 			OldStatus:     "pending",
 
 			InputDevsStorageError: nil,
@@ -535,6 +628,19 @@ func TestDeploymentModelUpdateDeviceDeploymentStatus(t *testing.T) {
 
 			OutputError: errors.New("deployments storage issue"),
 		},
+		{
+			InputDeployment: &deployments.Deployment{
+				Id: StringToPointer("234"),
+				Stats: deployments.Stats{
+					deployments.DeviceDeploymentStatusDownloading: 1,
+				},
+			},
+			InputDeviceID: "234",
+			InputStatus:   "downloading",
+			InputSubState: StringToPointer("fetching rootfs"),
+			InputProgress: IntToPointer(42),
+			OldStatus:     "downloading",
+		},
 		{
 			isFinished: true,
 			InputDeployment: &deployments.Deployment{
@@ -604,6 +710,22 @@ func TestDeploymentModelUpdateDeviceDeploymentStatus(t *testing.T) {
 
 			OutputError: controller.ErrDeploymentAborted,
 		},
+		{
+			InputDeployment: &deployments.Deployment{
+				Id: StringToPointer("890"),
+				Stats: deployments.Stats{
+					deployments.DeviceDeploymentStatusSuccess: 1,
+				},
+			},
+			InputDeviceID: "890",
+			InputStatus:   "downloading",
+			OldStatus:     "success",
+
+			OutputError: &deployments.StatusTransitionError{
+				From: deployments.DeviceDeploymentStatusSuccess,
+				To:   deployments.DeviceDeploymentStatusDownloading,
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -611,10 +733,16 @@ func TestDeploymentModelUpdateDeviceDeploymentStatus(t *testing.T) {
 		t.Logf("testing %s %s %s %v %v", *testCase.InputDeployment.Id, testCase.InputDeviceID,
 			testCase.InputStatus, testCase.InputDevsStorageError, testCase.isFinished)
 
+		state := deployments.DeviceDeploymentState{
+			Status:   testCase.InputStatus,
+			SubState: testCase.InputSubState,
+			Progress: testCase.InputProgress,
+		}
+
 		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
 		deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus",
 			testCase.InputDeviceID, *testCase.InputDeployment.Id,
-			testCase.InputStatus, mock.AnythingOfType("*time.Time")).
+			state, mock.AnythingOfType("*time.Time")).
 			Return("dontcare", testCase.InputDevsStorageError)
 		deviceDeploymentStorage.On("GetDeviceDeploymentStatus",
 			*testCase.InputDeployment.Id, testCase.InputDeviceID).
@@ -640,7 +768,7 @@ func TestDeploymentModelUpdateDeviceDeploymentStatus(t *testing.T) {
 		})
 
 		err := model.UpdateDeviceDeploymentStatus(*testCase.InputDeployment.Id,
-			testCase.InputDeviceID, testCase.InputStatus)
+			testCase.InputDeviceID, state)
 		if testCase.OutputError != nil {
 			assert.EqualError(t, err, testCase.OutputError.Error())
 		} else {
@@ -674,13 +802,13 @@ func TestGetDeploymentStats(t *testing.T) {
 
 	testCases := []struct {
 		InputDeploymentID         string
-		InputModelDeploymentStats deployments.Stats
+		InputModelDeploymentStats *deployments.DeploymentStatistics
 		InputModelError           error
 
 		InoutFindByIDDeployment *deployments.Deployment
 		InoutFindByIDError      error
 
-		OutputStats deployments.Stats
+		OutputStats *deployments.DeploymentStatistics
 		OutputError error
 	}{
 		{
@@ -717,24 +845,30 @@ func TestGetDeploymentStats(t *testing.T) {
 		{
 			InputDeploymentID:       "ID:345",
 			InoutFindByIDDeployment: new(deployments.Deployment),
-			InputModelDeploymentStats: deployments.Stats{
-				deployments.DeviceDeploymentStatusPending:     2,
-				deployments.DeviceDeploymentStatusSuccess:     4,
-				deployments.DeviceDeploymentStatusFailure:     1,
-				deployments.DeviceDeploymentStatusInstalling:  3,
-				deployments.DeviceDeploymentStatusRebooting:   3,
-				deployments.DeviceDeploymentStatusDownloading: 3,
-				deployments.DeviceDeploymentStatusAlreadyInst: 0,
+			InputModelDeploymentStats: &deployments.DeploymentStatistics{
+				Stats: deployments.Stats{
+					deployments.DeviceDeploymentStatusPending:     2,
+					deployments.DeviceDeploymentStatusSuccess:     4,
+					deployments.DeviceDeploymentStatusFailure:     1,
+					deployments.DeviceDeploymentStatusInstalling:  3,
+					deployments.DeviceDeploymentStatusRebooting:   3,
+					deployments.DeviceDeploymentStatusDownloading: 3,
+					deployments.DeviceDeploymentStatusAlreadyInst: 0,
+				},
+				Progress: deployments.DeploymentProgress{Retries: 1, Progress: 40},
 			},
 
-			OutputStats: deployments.Stats{
-				deployments.DeviceDeploymentStatusDownloading: 3,
-				deployments.DeviceDeploymentStatusRebooting:   3,
-				deployments.DeviceDeploymentStatusInstalling:  3,
-				deployments.DeviceDeploymentStatusSuccess:     4,
-				deployments.DeviceDeploymentStatusFailure:     1,
-				deployments.DeviceDeploymentStatusPending:     2,
-				deployments.DeviceDeploymentStatusAlreadyInst: 0,
+			OutputStats: &deployments.DeploymentStatistics{
+				Stats: deployments.Stats{
+					deployments.DeviceDeploymentStatusDownloading: 3,
+					deployments.DeviceDeploymentStatusRebooting:   3,
+					deployments.DeviceDeploymentStatusInstalling:  3,
+					deployments.DeviceDeploymentStatusSuccess:     4,
+					deployments.DeviceDeploymentStatusFailure:     1,
+					deployments.DeviceDeploymentStatusPending:     2,
+					deployments.DeviceDeploymentStatusAlreadyInst: 0,
+				},
+				Progress: deployments.DeploymentProgress{Retries: 1, Progress: 40},
 			},
 		},
 	}
@@ -744,7 +878,7 @@ func TestGetDeploymentStats(t *testing.T) {
 			testCase.InputModelDeploymentStats, testCase.InputModelError)
 
 		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
-		deviceDeploymentStorage.On("AggregateDeviceDeploymentByStatus",
+		deviceDeploymentStorage.On("AggregateDeviceDeploymentStatistics",
 			testCase.InputDeploymentID).
 			Return(testCase.InputModelDeploymentStats, testCase.InputModelError)
 
@@ -769,6 +903,72 @@ func TestGetDeploymentStats(t *testing.T) {
 	}
 }
 
+func TestDeploymentModelGetDeploymentFailures(t *testing.T) {
+
+	t.Parallel()
+
+	groups := []*deployments.FailureGroup{
+		{Code: "disk_full", DeviceType: "beaglebone", Count: 7},
+	}
+
+	testCases := map[string]struct {
+		InputFindByIDDeployment *deployments.Deployment
+		InputFindByIDError      error
+		InputGroups             []*deployments.FailureGroup
+		InputAggregateError     error
+
+		OutputGroups []*deployments.FailureGroup
+		OutputError  error
+	}{
+		"ok": {
+			InputFindByIDDeployment: new(deployments.Deployment),
+			InputGroups:             groups,
+			OutputGroups:            groups,
+		},
+		"no failures": {
+			InputFindByIDDeployment: new(deployments.Deployment),
+			OutputGroups:            []*deployments.FailureGroup{},
+		},
+		"deployment not found": {
+			OutputError: controller.ErrModelDeploymentNotFound,
+		},
+		"find error": {
+			InputFindByIDError: errors.New("storage issue"),
+			OutputError:        errors.New("Searching for deployment by ID: storage issue"),
+		},
+		"aggregate error": {
+			InputFindByIDDeployment: new(deployments.Deployment),
+			InputAggregateError:     errors.New("storage issue"),
+			OutputError:             errors.New("Aggregating failed device deployments: storage issue"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindByID", "123").
+			Return(testCase.InputFindByIDDeployment, testCase.InputFindByIDError)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("AggregateDeviceDeploymentFailures", "123").
+			Return(testCase.InputGroups, testCase.InputAggregateError)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:       deploymentStorage,
+			DeviceDeploymentsStorage: deviceDeploymentStorage,
+		})
+
+		groups, err := model.GetDeploymentFailures("123")
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.OutputGroups, groups)
+		}
+	}
+}
+
 func TestDeploymentModelGetDeviceStatusesForDeployment(t *testing.T) {
 	t.Parallel()
 
@@ -840,8 +1040,10 @@ func TestDeploymentModelGetDeviceStatusesForDeployment(t *testing.T) {
 
 		devsDb := new(mocks.DeviceDeploymentStorage)
 
-		devsDb.On("GetDeviceStatusesForDeployment", tc.inDeploymentId).
-			Return(tc.devsStorageStatuses, tc.devsStorageErr)
+		page := paging.Query{Page: 2, PerPage: 3}
+
+		devsDb.On("GetDeviceStatusesForDeployment", tc.inDeploymentId, page).
+			Return(tc.devsStorageStatuses, 10, tc.devsStorageErr)
 
 		depsDb := new(mocks.DeploymentsStorage)
 
@@ -852,12 +1054,13 @@ func TestDeploymentModelGetDeviceStatusesForDeployment(t *testing.T) {
 			DeploymentsStorage:       depsDb,
 			DeviceDeploymentsStorage: devsDb,
 		})
-		statuses, err := model.GetDeviceStatusesForDeployment(tc.inDeploymentId)
+		statuses, total, err := model.GetDeviceStatusesForDeployment(tc.inDeploymentId, page)
 
 		if tc.modelErr != nil {
 			assert.EqualError(t, err, tc.modelErr.Error())
 		} else {
 			assert.NoError(t, err)
+			assert.Equal(t, 10, total)
 
 			for i, expected := range tc.devsStorageStatuses {
 				assert.Equal(t, expected, statuses[i])
@@ -978,10 +1181,12 @@ func TestDeploymentModelLookupDeployment(t *testing.T) {
 
 	testCases := map[string]struct {
 		MockDeployments []*deployments.Deployment
+		MockTotal       int
 		MockError       error
 
 		OutputError       error
 		OutputDeployments []*deployments.Deployment
+		OutputTotal       int
 	}{
 		"nothing found": {
 			MockDeployments:   nil,
@@ -993,7 +1198,9 @@ func TestDeploymentModelLookupDeployment(t *testing.T) {
 		},
 		"found deployments": {
 			MockDeployments:   []*deployments.Deployment{&deployments.Deployment{Id: StringToPointer("lala")}},
+			MockTotal:         21,
 			OutputDeployments: []*deployments.Deployment{&deployments.Deployment{Id: StringToPointer("lala")}},
+			OutputTotal:       21,
 		},
 	}
 
@@ -1003,17 +1210,18 @@ func TestDeploymentModelLookupDeployment(t *testing.T) {
 
 		deploymentStorage := new(mocks.DeploymentsStorage)
 		deploymentStorage.On("Find", mock.AnythingOfType("deployments.Query")).
-			Return(testCase.MockDeployments, testCase.MockError)
+			Return(testCase.MockDeployments, testCase.MockTotal, testCase.MockError)
 
 		model := NewDeploymentModel(DeploymentsModelConfig{DeploymentsStorage: deploymentStorage})
 
-		deployments, err := model.LookupDeployment(deployments.Query{})
+		deployments, total, err := model.LookupDeployment(deployments.Query{})
 		if testCase.OutputError != nil {
 			assert.EqualError(t, err, testCase.OutputError.Error())
 		} else {
 			assert.NoError(t, err)
 		}
 		assert.Equal(t, testCase.OutputDeployments, deployments)
+		assert.Equal(t, testCase.OutputTotal, total)
 	}
 }
 
@@ -1129,3 +1337,1903 @@ func TestDeploymentModelAbortDeployment(t *testing.T) {
 		}
 	}
 }
+
+func TestDeploymentModelPublishEvents(t *testing.T) {
+
+	t.Parallel()
+
+	var published []*deployments.Event
+	hub := new(mocks.EventsHub)
+	hub.On("Publish", mock.AnythingOfType("*deployments.Event")).
+		Run(func(args mock.Arguments) {
+			published = append(published, args.Get(0).(*deployments.Event))
+		})
+
+	installing := deployments.NewDeviceDeployment("device-1", "123")
+	installing.Status = StringToPointer(deployments.DeviceDeploymentStatusInstalling)
+	pending := deployments.NewDeviceDeployment("device-2", "123")
+
+	deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+	deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "123", "device-1").
+		Return(deployments.DeviceDeploymentStatusDownloading, nil)
+	deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus", "device-1", "123",
+		deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusInstalling}, mock.AnythingOfType("*time.Time")).
+		Return(deployments.DeviceDeploymentStatusDownloading, nil)
+	deviceDeploymentStorage.On("FindDeviceDeploymentsWithStatuses", "123",
+		deployments.ActiveDeploymentStatuses()).
+		Return([]*deployments.DeviceDeployment{installing, pending}, nil)
+	deviceDeploymentStorage.On("AbortDeviceDeployments", "123").
+		Return(nil)
+	deviceDeploymentStorage.On("AggregateDeviceDeploymentByStatus", "123").
+		Return(deployments.Stats{deployments.DeviceDeploymentStatusAborted: 2}, nil)
+
+	deploymentStorage := new(mocks.DeploymentsStorage)
+	deploymentStorage.On("UpdateStats", "123", deployments.DeviceDeploymentStatusDownloading,
+		deployments.DeviceDeploymentStatusInstalling).
+		Return(nil)
+	deploymentStorage.On("FindByID", "123").
+		Return(&deployments.Deployment{
+			Id: StringToPointer("123"),
+			Stats: deployments.Stats{
+				deployments.DeviceDeploymentStatusInstalling: 1,
+				deployments.DeviceDeploymentStatusPending:    1,
+			},
+		}, nil)
+	deploymentStorage.On("UpdateStatsAndFinishDeployment", "123",
+		deployments.Stats{deployments.DeviceDeploymentStatusAborted: 2}).
+		Return(nil)
+
+	model := NewDeploymentModel(DeploymentsModelConfig{
+		DeploymentsStorage:       deploymentStorage,
+		DeviceDeploymentsStorage: deviceDeploymentStorage,
+		EventsHub:                hub,
+	})
+
+	assert.NoError(t, model.UpdateDeviceDeploymentStatus("123", "device-1",
+		deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusInstalling}))
+	assert.NoError(t, model.AbortDeployment("123"))
+
+	expected := []*deployments.Event{
+		deployments.NewDeviceStatusEvent("123", "device-1",
+			deployments.DeviceDeploymentStatusDownloading, deployments.DeviceDeploymentStatusInstalling),
+		deployments.NewStatsEvent("123", deployments.Stats{
+			deployments.DeviceDeploymentStatusInstalling: 1,
+			deployments.DeviceDeploymentStatusPending:    1,
+		}),
+		deployments.NewDeviceStatusEvent("123", "device-1",
+			deployments.DeviceDeploymentStatusInstalling, deployments.DeviceDeploymentStatusAborted),
+		deployments.NewDeviceStatusEvent("123", "device-2",
+			deployments.DeviceDeploymentStatusPending, deployments.DeviceDeploymentStatusAborted),
+		deployments.NewStatsEvent("123", deployments.Stats{deployments.DeviceDeploymentStatusAborted: 2}),
+	}
+	if assert.Len(t, published, len(expected)) {
+		for i := range expected {
+			published[i].Time = expected[i].Time
+			assert.Equal(t, expected[i], published[i])
+		}
+	}
+}
+
+func TestDeploymentModelNotifyFinished(t *testing.T) {
+
+	t.Parallel()
+
+	deployment := &deployments.Deployment{
+		Id:    StringToPointer("123"),
+		Stats: deployments.Stats{deployments.DeviceDeploymentStatusSuccess: 1},
+	}
+
+	deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+	deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "123", "device").
+		Return(deployments.DeviceDeploymentStatusRebooting, nil)
+	deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus", "device", "123",
+		deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusSuccess}, mock.AnythingOfType("*time.Time")).
+		Return(deployments.DeviceDeploymentStatusRebooting, nil)
+
+	deploymentStorage := new(mocks.DeploymentsStorage)
+	deploymentStorage.On("UpdateStats", "123", deployments.DeviceDeploymentStatusRebooting,
+		deployments.DeviceDeploymentStatusSuccess).
+		Return(nil)
+	deploymentStorage.On("FindByID", "123").
+		Return(deployment, nil)
+	deploymentStorage.On("Finish", "123", mock.AnythingOfType("time.Time")).
+		Return(nil)
+
+	notifier := new(mocks.Notifier)
+	notifier.On("Notify", webhooks.EventDeploymentFinished, deployment)
+
+	model := NewDeploymentModel(DeploymentsModelConfig{
+		DeploymentsStorage:       deploymentStorage,
+		DeviceDeploymentsStorage: deviceDeploymentStorage,
+		Notifier:                 notifier,
+	})
+
+	assert.NoError(t, model.UpdateDeviceDeploymentStatus("123", "device",
+		deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusSuccess}))
+
+	notifier.AssertCalled(t, "Notify", webhooks.EventDeploymentFinished, deployment)
+	assert.NotNil(t, deployment.Finished)
+}
+
+func TestDeploymentModelSubscribeDeploymentEvents(t *testing.T) {
+
+	t.Parallel()
+
+	// without events hub there are no events
+	events, cancel := NewDeploymentModel(DeploymentsModelConfig{}).SubscribeDeploymentEvents("123")
+	_, open := <-events
+	assert.False(t, open)
+	cancel()
+
+	var hubEvents <-chan *deployments.Event = make(chan *deployments.Event)
+	hub := new(mocks.EventsHub)
+	hub.On("Subscribe", "123").
+		Return(hubEvents, func() {})
+
+	events, _ = NewDeploymentModel(DeploymentsModelConfig{
+		EventsHub: hub,
+	}).SubscribeDeploymentEvents("123")
+	assert.Equal(t, hubEvents, events)
+}
+
+func TestDeploymentModelAbortDeviceDeployment(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputHasDeployment      bool
+		InputHasDeploymentError error
+		InputOldStatus          string
+		InputStats              deployments.Stats
+
+		OutputFinish bool
+		OutputError  error
+	}{
+		"device deployment not found": {
+			OutputError: controller.ErrModelDeviceDeploymentNotFound,
+		},
+		"storage error": {
+			InputHasDeploymentError: errors.New("storage error"),
+			OutputError:             errors.New("searching for device deployment: storage error"),
+		},
+		"already aborted": {
+			InputHasDeployment: true,
+			InputOldStatus:     deployments.DeviceDeploymentStatusAborted,
+			OutputError:        controller.ErrDeploymentAborted,
+		},
+		"already finished": {
+			InputHasDeployment: true,
+			InputOldStatus:     deployments.DeviceDeploymentStatusSuccess,
+			OutputError: &deployments.StatusTransitionError{
+				From: deployments.DeviceDeploymentStatusSuccess,
+				To:   deployments.DeviceDeploymentStatusAborted,
+			},
+		},
+		"other devices pending": {
+			InputHasDeployment: true,
+			InputOldStatus:     deployments.DeviceDeploymentStatusDownloading,
+			InputStats: deployments.Stats{
+				deployments.DeviceDeploymentStatusPending: 1,
+				deployments.DeviceDeploymentStatusAborted: 1,
+			},
+		},
+		"last device": {
+			InputHasDeployment: true,
+			InputOldStatus:     deployments.DeviceDeploymentStatusPending,
+			InputStats: deployments.Stats{
+				deployments.DeviceDeploymentStatusSuccess: 1,
+				deployments.DeviceDeploymentStatusAborted: 1,
+			},
+			OutputFinish: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("HasDeploymentForDevice", "123", "device").
+			Return(testCase.InputHasDeployment, testCase.InputHasDeploymentError)
+		deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "123", "device").
+			Return(testCase.InputOldStatus, nil)
+		deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus", "device", "123",
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusAborted}, mock.AnythingOfType("*time.Time")).
+			Return(testCase.InputOldStatus, nil)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("UpdateStats", "123", testCase.InputOldStatus,
+			deployments.DeviceDeploymentStatusAborted).
+			Return(nil)
+		deploymentStorage.On("FindByID", "123").
+			Return(&deployments.Deployment{
+				Id:                    StringToPointer("123"),
+				DeploymentConstructor: deployments.NewDeploymentConstructor(),
+				Stats:                 testCase.InputStats,
+				Aborted:               BoolToPointer(false),
+			}, nil)
+		deploymentStorage.On("Finish", "123", mock.AnythingOfType("time.Time")).
+			Return(nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:       deploymentStorage,
+			DeviceDeploymentsStorage: deviceDeploymentStorage,
+		})
+
+		err := model.AbortDeviceDeployment("123", "device")
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+			deviceDeploymentStorage.AssertNotCalled(t, "UpdateDeviceDeploymentStatus",
+				"device", "123", deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusAborted},
+				mock.AnythingOfType("*time.Time"))
+			continue
+		}
+
+		assert.NoError(t, err)
+		if testCase.OutputFinish {
+			deploymentStorage.AssertCalled(t, "Finish", "123", mock.AnythingOfType("time.Time"))
+		} else {
+			deploymentStorage.AssertNotCalled(t, "Finish", "123", mock.AnythingOfType("time.Time"))
+		}
+	}
+}
+
+func TestDeploymentModelCreateDeploymentPhases(t *testing.T) {
+
+	t.Parallel()
+
+	generator := new(mocks.Generator)
+	generator.On("Generate", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("string"), mock.AnythingOfType("*deployments.Deployment")).
+		Return(func(ctx context.Context, deviceID string, deployment *deployments.Deployment) *deployments.DeviceDeployment {
+			return deployments.NewDeviceDeployment(deviceID, *deployment.Id)
+		}, nil)
+
+	deploymentStorage := new(mocks.DeploymentsStorage)
+	deploymentStorage.On("Insert", mock.AnythingOfType("*deployments.Deployment")).
+		Return(nil)
+
+	deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+	deviceDeploymentStorage.On("InsertMany", mock.AnythingOfType("[]*deployments.DeviceDeployment")).
+		Return(nil)
+
+	model := NewDeploymentModel(DeploymentsModelConfig{
+		DeploymentsStorage:        deploymentStorage,
+		DeviceDeploymentGenerator: generator,
+		DeviceDeploymentsStorage:  deviceDeploymentStorage,
+	})
+
+	_, err := model.CreateDeployment(context.Background(), &deployments.DeploymentConstructor{
+		Name:         StringToPointer("NYC Production"),
+		ArtifactName: StringToPointer("App 123"),
+		Devices:      []string{"a", "b", "c", "d"},
+		Phases: []*deployments.PhaseConstructor{
+			{BatchSize: 25},
+			{AfterPrevious: true},
+		},
+	})
+	assert.NoError(t, err)
+
+	deployment := deploymentStorage.Calls[0].Arguments.Get(0).(*deployments.Deployment)
+	assert.Len(t, deployment.Phases, 2)
+	assert.Equal(t, 1, deployment.Phases[0].DeviceCount)
+	assert.Equal(t, 3, deployment.Phases[1].DeviceCount)
+
+	deviceDeployments := deviceDeploymentStorage.Calls[0].Arguments.Get(0).([]*deployments.DeviceDeployment)
+	phases := []int{}
+	for _, dd := range deviceDeployments {
+		phases = append(phases, dd.Phase)
+	}
+	assert.Equal(t, []int{0, 1, 1, 1}, phases)
+}
+
+func TestDeploymentModelGetDeploymentForDevicePhases(t *testing.T) {
+
+	t.Parallel()
+
+	image := images.NewSoftwareImage(
+		validUUIDv4,
+		&images.SoftwareImageMetaConstructor{
+			Name: "foo",
+		},
+		&images.SoftwareImageMetaArtifactConstructor{
+			ArtifactName: "foo-artifact",
+		})
+
+	earlier := time.Now().Add(-time.Hour)
+	later := time.Now().Add(time.Hour)
+
+	testCases := map[string]struct {
+		InputPhase         int
+		InputPhases        []*deployments.Phase
+		InputPreviousStats deployments.Stats
+
+		OutputInstructions bool
+	}{
+		"first phase start time not reached": {
+			InputPhases: []*deployments.Phase{
+				{DeviceCount: 1, StartTime: &later},
+				{DeviceCount: 1, AfterPrevious: true},
+			},
+		},
+		"first phase started": {
+			InputPhases: []*deployments.Phase{
+				{DeviceCount: 1, StartTime: &earlier},
+				{DeviceCount: 1, AfterPrevious: true},
+			},
+
+			OutputInstructions: true,
+		},
+		"start time not reached": {
+			InputPhase: 1,
+			InputPhases: []*deployments.Phase{
+				{DeviceCount: 1},
+				{DeviceCount: 1, StartTime: &later},
+			},
+		},
+		"previous phase in progress": {
+			InputPhase: 1,
+			InputPhases: []*deployments.Phase{
+				{DeviceCount: 1},
+				{DeviceCount: 1, AfterPrevious: true},
+			},
+			InputPreviousStats: deployments.Stats{
+				deployments.DeviceDeploymentStatusInstalling: 1,
+			},
+		},
+		"previous phase succeeded": {
+			InputPhase: 1,
+			InputPhases: []*deployments.Phase{
+				{DeviceCount: 1},
+				{DeviceCount: 1, AfterPrevious: true},
+			},
+			InputPreviousStats: deployments.Stats{
+				deployments.DeviceDeploymentStatusSuccess: 1,
+			},
+
+			OutputInstructions: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deviceDeployment := deployments.NewDeviceDeployment("ID:123", "ID:678")
+		deviceDeployment.Image = image
+		deviceDeployment.Phase = testCase.InputPhase
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("FindDeploymentsForDeviceIDWithStatuses",
+			"ID:123", mock.AnythingOfType("[]string")).
+			Return([]*deployments.DeviceDeployment{deviceDeployment}, nil)
+		deviceDeploymentStorage.On("AggregateDeviceDeploymentByStatusForPhase", "ID:678", 0).
+			Return(testCase.InputPreviousStats, nil)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindByID", "ID:678").
+			Return(&deployments.Deployment{
+				Id:     StringToPointer("ID:678"),
+				Stats:  deployments.NewDeviceDeploymentStats(),
+				Phases: testCase.InputPhases,
+			}, nil)
+
+		imageLinker := new(mocks.GetRequester)
+		imageLinker.On("GetRequest", image.Id, DefaultUpdateDownloadLinkExpire).
+			Return(&images.Link{}, nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeviceDeploymentsStorage: deviceDeploymentStorage,
+			DeploymentsStorage:       deploymentStorage,
+			ImageLinker:              imageLinker,
+		})
+
+		out, err := model.GetDeploymentForDeviceWithCurrent("ID:123",
+			deployments.InstalledDeviceDeployment{})
+		assert.NoError(t, err)
+		if testCase.OutputInstructions {
+			assert.NotNil(t, out)
+		} else {
+			assert.Nil(t, out)
+		}
+	}
+}
+
+func TestDeploymentModelUpdateDeviceDeploymentStatusFailurePolicy(t *testing.T) {
+
+	t.Parallel()
+
+	maxFailures := 1
+
+	testCases := map[string]struct {
+		InputStats deployments.Stats
+
+		OutputAborted bool
+	}{
+		"within policy": {
+			InputStats: deployments.Stats{
+				deployments.DeviceDeploymentStatusFailure: 1,
+				deployments.DeviceDeploymentStatusPending: 2,
+			},
+		},
+		"policy violated": {
+			InputStats: deployments.Stats{
+				deployments.DeviceDeploymentStatusFailure: 2,
+				deployments.DeviceDeploymentStatusPending: 1,
+			},
+			OutputAborted: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deployment := &deployments.Deployment{
+			Id: StringToPointer("123"),
+			DeploymentConstructor: &deployments.DeploymentConstructor{
+				FailurePolicy: &deployments.FailurePolicy{MaxFailures: &maxFailures},
+			},
+			Stats: testCase.InputStats,
+		}
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "123", "device").
+			Return(deployments.DeviceDeploymentStatusInstalling, nil)
+		deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus", "device", "123",
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusFailure}, mock.AnythingOfType("*time.Time")).
+			Return(deployments.DeviceDeploymentStatusInstalling, nil)
+		deviceDeploymentStorage.On("AbortDeviceDeployments", "123").
+			Return(nil)
+		deviceDeploymentStorage.On("AggregateDeviceDeploymentByStatus", "123").
+			Return(deployments.Stats{}, nil)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("UpdateStats", "123", deployments.DeviceDeploymentStatusInstalling,
+			deployments.DeviceDeploymentStatusFailure).
+			Return(nil)
+		deploymentStorage.On("FindByID", "123").
+			Return(deployment, nil)
+		deploymentStorage.On("UpdateStatsAndFinishDeployment", "123", mock.AnythingOfType("deployments.Stats")).
+			Return(nil)
+		deploymentStorage.On("UpdateAbortReason", "123", mock.AnythingOfType("string")).
+			Return(nil)
+
+		notifier := new(mocks.Notifier)
+		notifier.On("Notify", mock.AnythingOfType("string"), deployment)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:       deploymentStorage,
+			DeviceDeploymentsStorage: deviceDeploymentStorage,
+			Notifier:                 notifier,
+		})
+
+		err := model.UpdateDeviceDeploymentStatus("123", "device",
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusFailure})
+		assert.NoError(t, err)
+
+		if testCase.OutputAborted {
+			deviceDeploymentStorage.AssertCalled(t, "AbortDeviceDeployments", "123")
+			deploymentStorage.AssertCalled(t, "UpdateAbortReason", "123", mock.AnythingOfType("string"))
+			notifier.AssertCalled(t, "Notify", webhooks.EventDeploymentAborted, deployment)
+			notifier.AssertCalled(t, "Notify", webhooks.EventDeploymentFailureThreshold, deployment)
+			assert.NotNil(t, deployment.AbortReason)
+		} else {
+			deviceDeploymentStorage.AssertNotCalled(t, "AbortDeviceDeployments", "123")
+			deploymentStorage.AssertNotCalled(t, "UpdateAbortReason", "123", mock.AnythingOfType("string"))
+			notifier.AssertNotCalled(t, "Notify", mock.AnythingOfType("string"), deployment)
+		}
+	}
+}
+
+func TestDeploymentModelGetDeploymentForDeviceSchedule(t *testing.T) {
+
+	t.Parallel()
+
+	image := images.NewSoftwareImage(
+		validUUIDv4,
+		&images.SoftwareImageMetaConstructor{
+			Name: "foo",
+		},
+		&images.SoftwareImageMetaArtifactConstructor{
+			ArtifactName: "foo-artifact",
+		})
+
+	later := time.Now().Add(time.Hour)
+	// window that closed a couple of minutes ago
+	closed := &deployments.MaintenanceWindow{
+		Start: time.Now().UTC().Add(-2 * time.Hour).Format(deployments.MaintenanceWindowTimeFormat),
+		End:   time.Now().UTC().Add(-time.Minute).Format(deployments.MaintenanceWindowTimeFormat),
+	}
+	open := &deployments.MaintenanceWindow{
+		Start: time.Now().UTC().Add(-time.Hour).Format(deployments.MaintenanceWindowTimeFormat),
+		End:   time.Now().UTC().Add(time.Hour).Format(deployments.MaintenanceWindowTimeFormat),
+	}
+
+	testCases := map[string]struct {
+		InputDeviceStatus string
+		InputConstructor  *deployments.DeploymentConstructor
+		InputPaused       bool
+
+		OutputInstructions bool
+	}{
+		"paused": {
+			InputDeviceStatus: deployments.DeviceDeploymentStatusPending,
+			InputConstructor:  &deployments.DeploymentConstructor{},
+			InputPaused:       true,
+		},
+		"paused, already started device": {
+			InputDeviceStatus:  deployments.DeviceDeploymentStatusInstalling,
+			InputConstructor:   &deployments.DeploymentConstructor{},
+			InputPaused:        true,
+			OutputInstructions: true,
+		},
+		"start time ahead": {
+			InputDeviceStatus: deployments.DeviceDeploymentStatusPending,
+			InputConstructor: &deployments.DeploymentConstructor{
+				StartTime: &later,
+			},
+		},
+		"outside maintenance window": {
+			InputDeviceStatus: deployments.DeviceDeploymentStatusPending,
+			InputConstructor: &deployments.DeploymentConstructor{
+				MaintenanceWindow: closed,
+			},
+		},
+		"within maintenance window": {
+			InputDeviceStatus: deployments.DeviceDeploymentStatusPending,
+			InputConstructor: &deployments.DeploymentConstructor{
+				MaintenanceWindow: open,
+			},
+			OutputInstructions: true,
+		},
+		"already started device": {
+			InputDeviceStatus: deployments.DeviceDeploymentStatusDownloading,
+			InputConstructor: &deployments.DeploymentConstructor{
+				MaintenanceWindow: closed,
+			},
+			OutputInstructions: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deviceDeployment := deployments.NewDeviceDeployment("ID:123", "ID:678")
+		deviceDeployment.Image = image
+		deviceDeployment.Status = StringToPointer(testCase.InputDeviceStatus)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("FindDeploymentsForDeviceIDWithStatuses",
+			"ID:123", mock.AnythingOfType("[]string")).
+			Return([]*deployments.DeviceDeployment{deviceDeployment}, nil)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindByID", "ID:678").
+			Return(&deployments.Deployment{
+				Id:                    StringToPointer("ID:678"),
+				Stats:                 deployments.NewDeviceDeploymentStats(),
+				DeploymentConstructor: testCase.InputConstructor,
+				Paused:                testCase.InputPaused,
+			}, nil)
+
+		imageLinker := new(mocks.GetRequester)
+		imageLinker.On("GetRequest", image.Id, DefaultUpdateDownloadLinkExpire).
+			Return(&images.Link{}, nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeviceDeploymentsStorage: deviceDeploymentStorage,
+			DeploymentsStorage:       deploymentStorage,
+			ImageLinker:              imageLinker,
+		})
+
+		out, err := model.GetDeploymentForDeviceWithCurrent("ID:123",
+			deployments.InstalledDeviceDeployment{})
+		assert.NoError(t, err)
+		if testCase.OutputInstructions {
+			assert.NotNil(t, out)
+		} else {
+			assert.Nil(t, out)
+		}
+	}
+}
+
+func TestDeploymentModelGetDeploymentForDeviceQueued(t *testing.T) {
+
+	t.Parallel()
+
+	image := images.NewSoftwareImage(
+		validUUIDv4,
+		&images.SoftwareImageMetaConstructor{
+			Name: "foo",
+		},
+		&images.SoftwareImageMetaArtifactConstructor{
+			ArtifactName: "foo-artifact",
+		})
+
+	later := time.Now().Add(time.Hour)
+
+	testCases := map[string]struct {
+		InputConstructor *deployments.DeploymentConstructor
+		InputPaused      bool
+	}{
+		"paused deployment ahead": {
+			InputConstructor: &deployments.DeploymentConstructor{},
+			InputPaused:      true,
+		},
+		"scheduled deployment ahead": {
+			InputConstructor: &deployments.DeploymentConstructor{
+				StartTime: &later,
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		blocked := deployments.NewDeviceDeployment("ID:123", "ID:678")
+		blocked.Image = image
+		queued := deployments.NewDeviceDeployment("ID:123", "ID:679")
+		queued.Image = image
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("FindDeploymentsForDeviceIDWithStatuses",
+			"ID:123", mock.AnythingOfType("[]string")).
+			Return([]*deployments.DeviceDeployment{blocked, queued}, nil)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindByID", "ID:678").
+			Return(&deployments.Deployment{
+				Id:                    StringToPointer("ID:678"),
+				Stats:                 deployments.NewDeviceDeploymentStats(),
+				DeploymentConstructor: testCase.InputConstructor,
+				Paused:                testCase.InputPaused,
+			}, nil)
+		deploymentStorage.On("FindByID", "ID:679").
+			Return(&deployments.Deployment{
+				Id:                    StringToPointer("ID:679"),
+				Stats:                 deployments.NewDeviceDeploymentStats(),
+				DeploymentConstructor: &deployments.DeploymentConstructor{},
+			}, nil)
+
+		imageLinker := new(mocks.GetRequester)
+		imageLinker.On("GetRequest", image.Id, DefaultUpdateDownloadLinkExpire).
+			Return(&images.Link{}, nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeviceDeploymentsStorage: deviceDeploymentStorage,
+			DeploymentsStorage:       deploymentStorage,
+			ImageLinker:              imageLinker,
+		})
+
+		out, err := model.GetDeploymentForDeviceWithCurrent("ID:123",
+			deployments.InstalledDeviceDeployment{})
+		assert.NoError(t, err)
+		if assert.NotNil(t, out) {
+			assert.Equal(t, "ID:679", out.ID)
+		}
+	}
+}
+
+func TestDeploymentModelGetDeploymentForDeviceInProgressLimit(t *testing.T) {
+
+	t.Parallel()
+
+	image := images.NewSoftwareImage(
+		validUUIDv4,
+		&images.SoftwareImageMetaConstructor{
+			Name: "foo",
+		},
+		&images.SoftwareImageMetaArtifactConstructor{
+			ArtifactName: "foo-artifact",
+		})
+
+	testCases := map[string]struct {
+		InputDeviceStatus  string
+		InputMaxInProgress int
+		InputAcquired      bool
+		InputAcquireError  error
+		InputUpdateStatus  string
+
+		OutputInstructions bool
+		OutputAcquire      bool
+		OutputRelease      bool
+		OutputError        error
+	}{
+		"no limit": {
+			InputDeviceStatus:  deployments.DeviceDeploymentStatusPending,
+			OutputInstructions: true,
+		},
+		"slot acquired": {
+			InputDeviceStatus:  deployments.DeviceDeploymentStatusPending,
+			InputMaxInProgress: 2,
+			InputAcquired:      true,
+			InputUpdateStatus:  deployments.DeviceDeploymentStatusPending,
+			OutputInstructions: true,
+			OutputAcquire:      true,
+		},
+		"no slot available": {
+			InputDeviceStatus:  deployments.DeviceDeploymentStatusPending,
+			InputMaxInProgress: 2,
+			OutputAcquire:      true,
+		},
+		"acquire error": {
+			InputDeviceStatus:  deployments.DeviceDeploymentStatusPending,
+			InputMaxInProgress: 2,
+			InputAcquireError:  errors.New("storage error"),
+			OutputAcquire:      true,
+			OutputError:        errors.New("Starting device deployment: storage error"),
+		},
+		"aborted in the meantime": {
+			InputDeviceStatus:  deployments.DeviceDeploymentStatusPending,
+			InputMaxInProgress: 2,
+			InputAcquired:      true,
+			InputUpdateStatus:  deployments.DeviceDeploymentStatusAborted,
+			OutputAcquire:      true,
+			OutputRelease:      true,
+		},
+		"already holding a slot": {
+			InputDeviceStatus:  deployments.DeviceDeploymentStatusDownloading,
+			InputMaxInProgress: 2,
+			OutputInstructions: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deviceDeployment := deployments.NewDeviceDeployment("ID:123", "ID:678")
+		deviceDeployment.Image = image
+		deviceDeployment.Status = StringToPointer(testCase.InputDeviceStatus)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("FindDeploymentsForDeviceIDWithStatuses",
+			"ID:123", mock.AnythingOfType("[]string")).
+			Return([]*deployments.DeviceDeployment{deviceDeployment}, nil)
+		deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "ID:678", "ID:123").
+			Return(testCase.InputUpdateStatus, nil)
+		deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus", "ID:123", "ID:678",
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusDownloading}, mock.AnythingOfType("*time.Time")).
+			Return(deployments.DeviceDeploymentStatusPending, nil)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindByID", "ID:678").
+			Return(&deployments.Deployment{
+				Id: StringToPointer("ID:678"),
+				Stats: deployments.Stats{
+					deployments.DeviceDeploymentStatusPending: 2,
+				},
+				DeploymentConstructor: &deployments.DeploymentConstructor{
+					MaxInProgress: testCase.InputMaxInProgress,
+				},
+			}, nil)
+		deploymentStorage.On("AcquireInProgressSlot", "ID:678", testCase.InputMaxInProgress).
+			Return(testCase.InputAcquired, testCase.InputAcquireError)
+		deploymentStorage.On("ReleaseInProgressSlot", "ID:678").
+			Return(nil)
+		deploymentStorage.On("UpdateStats", "ID:678", deployments.DeviceDeploymentStatusPending,
+			deployments.DeviceDeploymentStatusDownloading).
+			Return(nil)
+
+		imageLinker := new(mocks.GetRequester)
+		imageLinker.On("GetRequest", image.Id, DefaultUpdateDownloadLinkExpire).
+			Return(&images.Link{}, nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeviceDeploymentsStorage: deviceDeploymentStorage,
+			DeploymentsStorage:       deploymentStorage,
+			ImageLinker:              imageLinker,
+		})
+
+		out, err := model.GetDeploymentForDeviceWithCurrent("ID:123",
+			deployments.InstalledDeviceDeployment{})
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+		if testCase.OutputInstructions {
+			assert.NotNil(t, out)
+		} else {
+			assert.Nil(t, out)
+		}
+
+		if testCase.OutputAcquire {
+			deploymentStorage.AssertCalled(t, "AcquireInProgressSlot", "ID:678", testCase.InputMaxInProgress)
+		} else {
+			deploymentStorage.AssertNotCalled(t, "AcquireInProgressSlot", "ID:678", testCase.InputMaxInProgress)
+		}
+		if testCase.OutputRelease {
+			deploymentStorage.AssertCalled(t, "ReleaseInProgressSlot", "ID:678")
+		} else {
+			deploymentStorage.AssertNotCalled(t, "ReleaseInProgressSlot", "ID:678")
+		}
+		if testCase.InputAcquired && testCase.OutputInstructions {
+			// device holding the slot is moved to downloading right away
+			deploymentStorage.AssertCalled(t, "UpdateStats", "ID:678",
+				deployments.DeviceDeploymentStatusPending, deployments.DeviceDeploymentStatusDownloading)
+		}
+	}
+}
+
+func TestDeploymentModelUpdateDeviceDeploymentStatusInProgressLimit(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputMaxInProgress int
+		InputOldStatus     string
+		InputStatus        string
+
+		OutputRelease bool
+	}{
+		"no limit": {
+			InputOldStatus: deployments.DeviceDeploymentStatusRebooting,
+			InputStatus:    deployments.DeviceDeploymentStatusSuccess,
+		},
+		"finished": {
+			InputMaxInProgress: 1,
+			InputOldStatus:     deployments.DeviceDeploymentStatusRebooting,
+			InputStatus:        deployments.DeviceDeploymentStatusSuccess,
+			OutputRelease:      true,
+		},
+		"failed": {
+			InputMaxInProgress: 1,
+			InputOldStatus:     deployments.DeviceDeploymentStatusDownloading,
+			InputStatus:        deployments.DeviceDeploymentStatusFailure,
+			OutputRelease:      true,
+		},
+		"still updating": {
+			InputMaxInProgress: 1,
+			InputOldStatus:     deployments.DeviceDeploymentStatusDownloading,
+			InputStatus:        deployments.DeviceDeploymentStatusInstalling,
+		},
+		"never started": {
+			InputMaxInProgress: 1,
+			InputOldStatus:     deployments.DeviceDeploymentStatusPending,
+			InputStatus:        deployments.DeviceDeploymentStatusAlreadyInst,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "123", "device").
+			Return(testCase.InputOldStatus, nil)
+		deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus", "device", "123",
+			deployments.DeviceDeploymentState{Status: testCase.InputStatus}, mock.AnythingOfType("*time.Time")).
+			Return(testCase.InputOldStatus, nil)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindByID", "123").
+			Return(&deployments.Deployment{
+				Id: StringToPointer("123"),
+				DeploymentConstructor: &deployments.DeploymentConstructor{
+					MaxInProgress: testCase.InputMaxInProgress,
+				},
+				Stats: deployments.Stats{
+					deployments.DeviceDeploymentStatusPending: 1,
+				},
+			}, nil)
+		deploymentStorage.On("UpdateStats", "123", testCase.InputOldStatus, testCase.InputStatus).
+			Return(nil)
+		deploymentStorage.On("ReleaseInProgressSlot", "123").
+			Return(nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:       deploymentStorage,
+			DeviceDeploymentsStorage: deviceDeploymentStorage,
+		})
+
+		err := model.UpdateDeviceDeploymentStatus("123", "device", deployments.DeviceDeploymentState{Status: testCase.InputStatus})
+		assert.NoError(t, err)
+
+		if testCase.OutputRelease {
+			deploymentStorage.AssertCalled(t, "ReleaseInProgressSlot", "123")
+		} else {
+			deploymentStorage.AssertNotCalled(t, "ReleaseInProgressSlot", "123")
+		}
+	}
+}
+
+func TestDeploymentModelCreateDeploymentFilter(t *testing.T) {
+
+	t.Parallel()
+
+	filter := &deployments.DeviceFilter{
+		Attributes: []*deployments.AttributeFilter{
+			{Name: "device_type", Value: "rpi3"},
+		},
+	}
+
+	testCases := map[string]struct {
+		InputPhases      []*deployments.PhaseConstructor
+		InputDevices     []string
+		InputSearchError error
+
+		OutputError error
+	}{
+		"search error": {
+			InputSearchError: errors.New("inventory down"),
+			OutputError:      errors.New("Resolving device filter: inventory down"),
+		},
+		"no devices matched": {
+			InputDevices: []string{},
+			OutputError:  controller.ErrModelNoDevicesMatched,
+		},
+		"phases exceed matched devices": {
+			InputDevices: []string{"a", "b"},
+			InputPhases: []*deployments.PhaseConstructor{
+				{DeviceCount: 3},
+				{AfterPrevious: true},
+			},
+			OutputError: errors.New("Validating deployment: " + deployments.ErrPhasesExceedDeploymentSize.Error()),
+		},
+		"ok": {
+			InputDevices: []string{"a", "b", "c"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		searcher := new(mocks.DeviceSearcher)
+		searcher.On("SearchDevices", mock.AnythingOfType("*context.emptyCtx"), filter).
+			Return(testCase.InputDevices, testCase.InputSearchError)
+
+		generator := new(mocks.Generator)
+		generator.On("Generate", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("string"), mock.AnythingOfType("*deployments.Deployment")).
+			Return(func(ctx context.Context, deviceID string, deployment *deployments.Deployment) *deployments.DeviceDeployment {
+				return deployments.NewDeviceDeployment(deviceID, *deployment.Id)
+			}, nil)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("Insert", mock.AnythingOfType("*deployments.Deployment")).
+			Return(nil)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("InsertMany", mock.AnythingOfType("[]*deployments.DeviceDeployment")).
+			Return(nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:        deploymentStorage,
+			DeviceDeploymentGenerator: generator,
+			DeviceDeploymentsStorage:  deviceDeploymentStorage,
+			DeviceSearcher:            searcher,
+		})
+
+		_, err := model.CreateDeployment(context.Background(), &deployments.DeploymentConstructor{
+			Name:         StringToPointer("NYC Production"),
+			ArtifactName: StringToPointer("App 123"),
+			Filter:       filter,
+			Phases:       testCase.InputPhases,
+		})
+
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+			deploymentStorage.AssertNotCalled(t, "Insert", mock.Anything)
+			continue
+		}
+
+		assert.NoError(t, err)
+
+		deployment := deploymentStorage.Calls[0].Arguments.Get(0).(*deployments.Deployment)
+		assert.Equal(t, filter, deployment.Filter)
+		assert.Equal(t, len(testCase.InputDevices), deployment.DeviceCount)
+		assert.Equal(t, len(testCase.InputDevices), deployment.Stats[deployments.DeviceDeploymentStatusPending])
+	}
+}
+
+func TestDeploymentModelCreateDeploymentCreatedBy(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputContext context.Context
+
+		OutputCreatedBy *string
+	}{
+		"no identity": {
+			InputContext: context.Background(),
+		},
+		"user identity": {
+			InputContext:    identity.WithContext(context.Background(), identity.Identity{Subject: "user-1"}),
+			OutputCreatedBy: StringToPointer("user-1"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		generator := new(mocks.Generator)
+		generator.On("Generate", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*deployments.Deployment")).
+			Return(func(ctx context.Context, deviceID string, deployment *deployments.Deployment) *deployments.DeviceDeployment {
+				return deployments.NewDeviceDeployment(deviceID, *deployment.Id)
+			}, nil)
+
+		var stored *deployments.Deployment
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("Insert", mock.MatchedBy(func(deployment *deployments.Deployment) bool {
+			stored = deployment
+			return true
+		})).Return(nil)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("InsertMany", mock.AnythingOfType("[]*deployments.DeviceDeployment")).
+			Return(nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:        deploymentStorage,
+			DeviceDeploymentGenerator: generator,
+			DeviceDeploymentsStorage:  deviceDeploymentStorage,
+		})
+
+		_, err := model.CreateDeployment(testCase.InputContext, &deployments.DeploymentConstructor{
+			Name:         StringToPointer("NYC Production"),
+			ArtifactName: StringToPointer("App 123"),
+			Devices:      []string{"a"},
+		})
+		assert.NoError(t, err)
+		if assert.NotNil(t, stored) {
+			assert.Equal(t, testCase.OutputCreatedBy, stored.CreatedBy)
+		}
+	}
+}
+
+func TestDeploymentModelCreateDeploymentConflictPolicy(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputPolicy    string
+		InputConflicts []*deployments.DeviceDeployment
+		InputFindError error
+
+		OutputFindStatuses []string
+		OutputSuperseded   []string
+		OutputStored       bool
+		OutputError        error
+	}{
+		"default": {},
+		"queue": {
+			InputPolicy: deployments.ConflictPolicyQueue,
+			InputConflicts: []*deployments.DeviceDeployment{
+				deployments.NewDeviceDeployment("a", "old"),
+			},
+		},
+		"reject, no conflicts": {
+			InputPolicy:        deployments.ConflictPolicyReject,
+			OutputFindStatuses: deployments.ActiveDeploymentStatuses(),
+		},
+		"reject": {
+			InputPolicy: deployments.ConflictPolicyReject,
+			InputConflicts: []*deployments.DeviceDeployment{
+				deployments.NewDeviceDeployment("b", "old"),
+				deployments.NewDeviceDeployment("a", "old"),
+				deployments.NewDeviceDeployment("b", "older"),
+			},
+			OutputFindStatuses: deployments.ActiveDeploymentStatuses(),
+			OutputError:        &deployments.ConflictError{Devices: []string{"a", "b"}},
+		},
+		"reject, find error": {
+			InputPolicy:        deployments.ConflictPolicyReject,
+			InputFindError:     errors.New("storage error"),
+			OutputFindStatuses: deployments.ActiveDeploymentStatuses(),
+			OutputError:        errors.New("Searching for active device deployments: storage error"),
+		},
+		"supersede": {
+			InputPolicy: deployments.ConflictPolicySupersede,
+			InputConflicts: []*deployments.DeviceDeployment{
+				deployments.NewDeviceDeployment("a", "old"),
+				// finished in the meantime
+				deployments.NewDeviceDeployment("b", "old"),
+				// the stored deployment itself
+				deployments.NewDeviceDeployment("a", "new"),
+			},
+			OutputFindStatuses: []string{deployments.DeviceDeploymentStatusPending},
+			OutputSuperseded:   []string{"a"},
+		},
+		"supersede, find error": {
+			InputPolicy:        deployments.ConflictPolicySupersede,
+			InputFindError:     errors.New("storage error"),
+			OutputFindStatuses: []string{deployments.DeviceDeploymentStatusPending},
+			OutputStored:       true,
+			OutputError:        errors.New("Searching for pending device deployments: storage error"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		// device c gets no artifact, it does not conflict
+		generator := new(mocks.Generator)
+		generator.On("Generate", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("string"), mock.AnythingOfType("*deployments.Deployment")).
+			Return(func(ctx context.Context, deviceID string, deployment *deployments.Deployment) *deployments.DeviceDeployment {
+				deviceDeployment := deployments.NewDeviceDeployment(deviceID, *deployment.Id)
+				if deviceID == "c" {
+					deviceDeployment.Status = StringToPointer(deployments.DeviceDeploymentStatusNoArtifact)
+				}
+				return deviceDeployment
+			}, nil)
+
+		// older deployments are superseded only once the new one is stored
+		stored := false
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("Insert", mock.AnythingOfType("*deployments.Deployment")).
+			Run(func(args mock.Arguments) {
+				stored = true
+				args.Get(0).(*deployments.Deployment).Id = StringToPointer("new")
+			}).
+			Return(nil)
+		deploymentStorage.On("UpdateStats", "old", deployments.DeviceDeploymentStatusPending,
+			deployments.DeviceDeploymentStatusAborted).
+			Return(nil)
+		deploymentStorage.On("FindByID", "old").
+			Return(&deployments.Deployment{
+				Id:                    StringToPointer("old"),
+				DeploymentConstructor: deployments.NewDeploymentConstructor(),
+				Stats: deployments.Stats{
+					deployments.DeviceDeploymentStatusDownloading: 1,
+					deployments.DeviceDeploymentStatusAborted:     1,
+				},
+				Aborted: BoolToPointer(false),
+			}, nil)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("InsertMany", mock.AnythingOfType("[]*deployments.DeviceDeployment")).
+			Return(nil)
+		deviceDeploymentStorage.On("FindDeviceDeploymentsForDevices", []string{"a", "b"},
+			testCase.OutputFindStatuses).
+			Return(testCase.InputConflicts, testCase.InputFindError)
+		deviceDeploymentStorage.On("HasDeploymentForDevice", "old", mock.AnythingOfType("string")).
+			Return(true, nil)
+		deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "old", "a").
+			Return(deployments.DeviceDeploymentStatusPending, nil)
+		deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "old", "b").
+			Return(deployments.DeviceDeploymentStatusSuccess, nil)
+		deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus", "a", "old",
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusAborted}, mock.AnythingOfType("*time.Time")).
+			Run(func(args mock.Arguments) {
+				assert.True(t, stored, "superseded before storing the deployment")
+			}).
+			Return(deployments.DeviceDeploymentStatusPending, nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:        deploymentStorage,
+			DeviceDeploymentGenerator: generator,
+			DeviceDeploymentsStorage:  deviceDeploymentStorage,
+		})
+
+		_, err := model.CreateDeployment(context.Background(), &deployments.DeploymentConstructor{
+			Name:           StringToPointer("NYC Production"),
+			ArtifactName:   StringToPointer("App 123"),
+			Devices:        []string{"a", "b", "c"},
+			ConflictPolicy: testCase.InputPolicy,
+		})
+
+		if testCase.OutputFindStatuses != nil {
+			deviceDeploymentStorage.AssertCalled(t, "FindDeviceDeploymentsForDevices",
+				[]string{"a", "b"}, testCase.OutputFindStatuses)
+		} else {
+			deviceDeploymentStorage.AssertNotCalled(t, "FindDeviceDeploymentsForDevices",
+				mock.Anything, mock.Anything)
+		}
+
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+			assert.Equal(t, testCase.OutputStored, stored)
+			continue
+		}
+
+		assert.NoError(t, err)
+		deploymentStorage.AssertCalled(t, "Insert", mock.AnythingOfType("*deployments.Deployment"))
+		for _, device := range testCase.OutputSuperseded {
+			deviceDeploymentStorage.AssertCalled(t, "UpdateDeviceDeploymentStatus", device, "old",
+				deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusAborted}, mock.AnythingOfType("*time.Time"))
+		}
+		if testCase.OutputSuperseded == nil {
+			deviceDeploymentStorage.AssertNotCalled(t, "UpdateDeviceDeploymentStatus",
+				mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	}
+}
+
+func TestDeploymentModelUpdateDeviceDeploymentStatusRetry(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputMaxRetries   int
+		InputRetryBackoff int
+		InputRetriedFrom  string
+
+		OutputRetried bool
+	}{
+		"no retry policy": {},
+		"retries exhausted": {
+			InputMaxRetries: 2,
+		},
+		"retried": {
+			InputMaxRetries:  2,
+			InputRetriedFrom: deployments.DeviceDeploymentStatusInstalling,
+			OutputRetried:    true,
+		},
+		"retried with backoff": {
+			InputMaxRetries:   2,
+			InputRetryBackoff: 60,
+			InputRetriedFrom:  deployments.DeviceDeploymentStatusInstalling,
+			OutputRetried:     true,
+		},
+		"status changed before retry": {
+			InputMaxRetries:  2,
+			InputRetriedFrom: deployments.DeviceDeploymentStatusRebooting,
+			OutputRetried:    true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deployment := &deployments.Deployment{
+			Id: StringToPointer("123"),
+			DeploymentConstructor: &deployments.DeploymentConstructor{
+				MaxRetries:   testCase.InputMaxRetries,
+				RetryBackoff: testCase.InputRetryBackoff,
+			},
+			Stats: deployments.Stats{
+				deployments.DeviceDeploymentStatusPending: 1,
+			},
+		}
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "123", "device").
+			Return(deployments.DeviceDeploymentStatusInstalling, nil)
+		deviceDeploymentStorage.On("RetryDeviceDeployment", "device", "123", testCase.InputMaxRetries,
+			mock.MatchedBy(func(after *time.Time) bool {
+				if testCase.InputRetryBackoff == 0 {
+					return after == nil
+				}
+				return after != nil && after.After(time.Now())
+			})).
+			Return(testCase.InputRetriedFrom, nil)
+		deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus", "device", "123",
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusFailure}, mock.AnythingOfType("*time.Time")).
+			Return(deployments.DeviceDeploymentStatusInstalling, nil)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindByID", "123").
+			Return(deployment, nil)
+		deploymentStorage.On("UpdateStats", "123", mock.AnythingOfType("string"),
+			mock.AnythingOfType("string")).
+			Return(nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:       deploymentStorage,
+			DeviceDeploymentsStorage: deviceDeploymentStorage,
+		})
+
+		err := model.UpdateDeviceDeploymentStatus("123", "device",
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusFailure})
+		assert.NoError(t, err)
+
+		if testCase.OutputRetried {
+			deploymentStorage.AssertCalled(t, "UpdateStats", "123", testCase.InputRetriedFrom,
+				deployments.DeviceDeploymentStatusPending)
+			deviceDeploymentStorage.AssertNotCalled(t, "UpdateDeviceDeploymentStatus", "device", "123",
+				deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusFailure}, mock.AnythingOfType("*time.Time"))
+		} else {
+			deploymentStorage.AssertCalled(t, "UpdateStats", "123", deployments.DeviceDeploymentStatusInstalling,
+				deployments.DeviceDeploymentStatusFailure)
+			deviceDeploymentStorage.AssertCalled(t, "UpdateDeviceDeploymentStatus", "device", "123",
+				deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusFailure}, mock.AnythingOfType("*time.Time"))
+		}
+	}
+}
+
+func TestDeploymentModelPauseResumeDeployment(t *testing.T) {
+
+	t.Parallel()
+
+	deploymentStorage := new(mocks.DeploymentsStorage)
+	deploymentStorage.On("UpdatePaused", "123", true).
+		Return(nil)
+	deploymentStorage.On("UpdatePaused", "234", false).
+		Return(errors.New("storage error"))
+
+	model := NewDeploymentModel(DeploymentsModelConfig{
+		DeploymentsStorage: deploymentStorage,
+	})
+
+	assert.NoError(t, model.PauseDeployment("123"))
+	assert.EqualError(t, model.ResumeDeployment("234"), "storage error")
+	deploymentStorage.AssertExpectations(t)
+}
+
+func TestDeploymentModelFailStaleDeviceDeployments(t *testing.T) {
+
+	t.Parallel()
+
+	stale := []*deployments.DeviceDeployment{
+		deployments.NewDeviceDeployment("device-1", "123"),
+		deployments.NewDeviceDeployment("device-2", "123"),
+	}
+
+	testCases := map[string]struct {
+		InputFindError          error
+		InputDevice2Status      string
+		InputDevice2UpdateError error
+
+		OutputFailed int
+		OutputError  error
+	}{
+		"find error": {
+			InputFindError: errors.New("storage error"),
+			OutputError:    errors.New("Searching for stale device deployments: storage error"),
+		},
+		"failed": {
+			InputDevice2Status: deployments.DeviceDeploymentStatusRebooting,
+			OutputFailed:       2,
+		},
+		"aborted in the meantime": {
+			InputDevice2Status: deployments.DeviceDeploymentStatusAborted,
+			OutputFailed:       1,
+		},
+		"finished in the meantime": {
+			InputDevice2Status: deployments.DeviceDeploymentStatusSuccess,
+			OutputFailed:       1,
+		},
+		"finished during update": {
+			InputDevice2Status: deployments.DeviceDeploymentStatusRebooting,
+			InputDevice2UpdateError: &deployments.StatusTransitionError{
+				From: deployments.DeviceDeploymentStatusSuccess,
+				To:   deployments.DeviceDeploymentStatusFailure,
+			},
+			OutputFailed: 1,
+		},
+		"update error": {
+			InputDevice2Status:      deployments.DeviceDeploymentStatusRebooting,
+			InputDevice2UpdateError: errors.New("storage error"),
+			OutputFailed:            1,
+			OutputError:             errors.New("Failing deployment 123 for device device-2: storage error"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("FindStaleDeviceDeployments", mock.AnythingOfType("time.Time"),
+			[]string{
+				deployments.DeviceDeploymentStatusDownloading,
+				deployments.DeviceDeploymentStatusInstalling,
+				deployments.DeviceDeploymentStatusRebooting,
+			}).
+			Return(stale, testCase.InputFindError)
+		deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "123", "device-1").
+			Return(deployments.DeviceDeploymentStatusDownloading, nil)
+		deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "123", "device-2").
+			Return(testCase.InputDevice2Status, nil)
+		deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus", "device-1", "123",
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusFailure}, mock.AnythingOfType("*time.Time")).
+			Return(deployments.DeviceDeploymentStatusDownloading, nil)
+		deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus", "device-2", "123",
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusFailure}, mock.AnythingOfType("*time.Time")).
+			Return(deployments.DeviceDeploymentStatusDownloading, testCase.InputDevice2UpdateError)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindByID", "123").
+			Return(&deployments.Deployment{
+				Id:                    StringToPointer("123"),
+				DeploymentConstructor: &deployments.DeploymentConstructor{},
+				Stats: deployments.Stats{
+					deployments.DeviceDeploymentStatusPending: 1,
+				},
+			}, nil)
+		deploymentStorage.On("UpdateStats", "123", deployments.DeviceDeploymentStatusDownloading,
+			deployments.DeviceDeploymentStatusFailure).
+			Return(nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:       deploymentStorage,
+			DeviceDeploymentsStorage: deviceDeploymentStorage,
+		})
+
+		failed, err := model.FailStaleDeviceDeployments(time.Hour)
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, testCase.OutputFailed, failed)
+	}
+}
+
+func TestDeploymentModelExpireDeployments(t *testing.T) {
+
+	t.Parallel()
+
+	expired := []*deployments.Deployment{
+		&deployments.Deployment{
+			Id: StringToPointer("123"),
+		},
+	}
+	pending := []*deployments.DeviceDeployment{
+		deployments.NewDeviceDeployment("device-1", "123"),
+		deployments.NewDeviceDeployment("device-2", "123"),
+	}
+
+	testCases := map[string]struct {
+		InputFindError        error
+		InputFindDevicesError error
+		InputDevice2Status    string
+		InputStats            deployments.Stats
+
+		OutputExpired int
+		OutputFinish  bool
+		OutputError   error
+	}{
+		"find error": {
+			InputFindError: errors.New("storage error"),
+			OutputError:    errors.New("Searching for expired deployments: storage error"),
+		},
+		"find devices error": {
+			InputFindDevicesError: errors.New("storage error"),
+			OutputError:           errors.New("Searching for pending devices of deployment 123: storage error"),
+		},
+		"expired": {
+			InputDevice2Status: deployments.DeviceDeploymentStatusPending,
+			InputStats: deployments.Stats{
+				deployments.DeviceDeploymentStatusExpired: 2,
+			},
+			OutputExpired: 2,
+			OutputFinish:  true,
+		},
+		"started in the meantime": {
+			InputDevice2Status: deployments.DeviceDeploymentStatusDownloading,
+			InputStats: deployments.Stats{
+				deployments.DeviceDeploymentStatusDownloading: 1,
+				deployments.DeviceDeploymentStatusExpired:     1,
+			},
+			OutputExpired: 1,
+		},
+		"aborted in the meantime": {
+			InputDevice2Status: deployments.DeviceDeploymentStatusAborted,
+			InputStats: deployments.Stats{
+				deployments.DeviceDeploymentStatusAborted: 1,
+				deployments.DeviceDeploymentStatusExpired: 1,
+			},
+			OutputExpired: 1,
+			OutputFinish:  true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("FindDeviceDeploymentsWithStatuses", "123",
+			[]string{deployments.DeviceDeploymentStatusPending}).
+			Return(pending, testCase.InputFindDevicesError)
+		deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "123", "device-1").
+			Return(deployments.DeviceDeploymentStatusPending, nil)
+		deviceDeploymentStorage.On("GetDeviceDeploymentStatus", "123", "device-2").
+			Return(testCase.InputDevice2Status, nil)
+		deviceDeploymentStorage.On("UpdateDeviceDeploymentStatus", mock.AnythingOfType("string"), "123",
+			deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusExpired}, mock.AnythingOfType("*time.Time")).
+			Return(deployments.DeviceDeploymentStatusPending, nil)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindExpired", mock.AnythingOfType("time.Time")).
+			Return(expired, testCase.InputFindError)
+		deploymentStorage.On("FindByID", "123").
+			Return(&deployments.Deployment{
+				Id:                    StringToPointer("123"),
+				DeploymentConstructor: &deployments.DeploymentConstructor{},
+				Stats:                 testCase.InputStats,
+			}, nil)
+		deploymentStorage.On("UpdateStats", "123", deployments.DeviceDeploymentStatusPending,
+			deployments.DeviceDeploymentStatusExpired).
+			Return(nil)
+		deploymentStorage.On("Finish", "123", mock.AnythingOfType("time.Time")).
+			Return(nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:       deploymentStorage,
+			DeviceDeploymentsStorage: deviceDeploymentStorage,
+		})
+
+		count, err := model.ExpireDeployments()
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, testCase.OutputExpired, count)
+		if testCase.OutputFinish {
+			deploymentStorage.AssertCalled(t, "Finish", "123", mock.AnythingOfType("time.Time"))
+		} else {
+			deploymentStorage.AssertNotCalled(t, "Finish", "123", mock.AnythingOfType("time.Time"))
+		}
+	}
+}
+
+func TestDeploymentModelDeleteDeployment(t *testing.T) {
+
+	t.Parallel()
+
+	now := time.Now()
+
+	testCases := map[string]struct {
+		InputDeployment *deployments.Deployment
+		InputFindError  error
+		InputLogsError  error
+
+		OutputDeleted bool
+		OutputError   error
+	}{
+		"find error": {
+			InputFindError: errors.New("storage error"),
+			OutputError:    errors.New("Searching for deployment by ID: storage error"),
+		},
+		"not found": {
+			OutputError: controller.ErrModelDeploymentNotFound,
+		},
+		"not finished": {
+			InputDeployment: &deployments.Deployment{
+				Id: StringToPointer("123"),
+			},
+			OutputError: controller.ErrModelDeploymentNotFinished,
+		},
+		"logs error": {
+			InputDeployment: &deployments.Deployment{
+				Id:       StringToPointer("123"),
+				Finished: &now,
+			},
+			InputLogsError: errors.New("storage error"),
+			OutputError:    errors.New("Deleting device deployment logs: storage error"),
+		},
+		"deleted": {
+			InputDeployment: &deployments.Deployment{
+				Id:       StringToPointer("123"),
+				Finished: &now,
+			},
+			OutputDeleted: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindByID", "123").
+			Return(testCase.InputDeployment, testCase.InputFindError)
+		deploymentStorage.On("Delete", "123").
+			Return(nil)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("DeleteDeviceDeployments", "123").
+			Return(nil)
+
+		deviceDeploymentLogStorage := new(mocks.DeviceDeploymentLogStorage)
+		deviceDeploymentLogStorage.On("DeleteDeviceDeploymentLogs", "123").
+			Return(testCase.InputLogsError)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:          deploymentStorage,
+			DeviceDeploymentsStorage:    deviceDeploymentStorage,
+			DeviceDeploymentLogsStorage: deviceDeploymentLogStorage,
+		})
+
+		err := model.DeleteDeployment("123")
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+		if testCase.OutputDeleted {
+			deviceDeploymentStorage.AssertCalled(t, "DeleteDeviceDeployments", "123")
+			deploymentStorage.AssertCalled(t, "Delete", "123")
+		} else {
+			deploymentStorage.AssertNotCalled(t, "Delete", "123")
+		}
+	}
+}
+
+func TestDeploymentModelPurgeDeployments(t *testing.T) {
+
+	t.Parallel()
+
+	old := []*deployments.Deployment{
+		&deployments.Deployment{
+			Id: StringToPointer("123"),
+		},
+		&deployments.Deployment{
+			Id: StringToPointer("234"),
+		},
+	}
+
+	testCases := map[string]struct {
+		InputArchive      bool
+		InputFindError    error
+		InputArchiveError error
+		InputDeleteError  error
+
+		OutputPurged int
+		OutputError  error
+	}{
+		"find error": {
+			InputFindError: errors.New("storage error"),
+			OutputError:    errors.New("Searching for finished deployments: storage error"),
+		},
+		"purged": {
+			OutputPurged: 2,
+		},
+		"archived": {
+			InputArchive: true,
+			OutputPurged: 2,
+		},
+		"archive error": {
+			InputArchive:      true,
+			InputArchiveError: errors.New("storage error"),
+			OutputError:       errors.New("Archiving deployment 123: storage error"),
+		},
+		"delete error": {
+			InputDeleteError: errors.New("storage error"),
+			OutputError:      errors.New("Purging deployment 123: Deleting deployment: storage error"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindFinishedBefore", mock.AnythingOfType("time.Time")).
+			Return(old, testCase.InputFindError)
+		deploymentStorage.On("Archive", mock.AnythingOfType("*deployments.Deployment")).
+			Return(testCase.InputArchiveError)
+		deploymentStorage.On("Delete", mock.AnythingOfType("string")).
+			Return(testCase.InputDeleteError)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("DeleteDeviceDeployments", mock.AnythingOfType("string")).
+			Return(nil)
+
+		deviceDeploymentLogStorage := new(mocks.DeviceDeploymentLogStorage)
+		deviceDeploymentLogStorage.On("DeleteDeviceDeploymentLogs", mock.AnythingOfType("string")).
+			Return(nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:          deploymentStorage,
+			DeviceDeploymentsStorage:    deviceDeploymentStorage,
+			DeviceDeploymentLogsStorage: deviceDeploymentLogStorage,
+		})
+
+		purged, err := model.PurgeDeployments(30*24*time.Hour, testCase.InputArchive)
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, testCase.OutputPurged, purged)
+		if !testCase.InputArchive {
+			deploymentStorage.AssertNotCalled(t, "Archive", mock.AnythingOfType("*deployments.Deployment"))
+		}
+	}
+}
+
+func TestDeploymentModelGetDeviceDeploymentHistory(t *testing.T) {
+
+	t.Parallel()
+
+	first := deployments.NewDeviceDeployment("device-1", "123")
+	second := deployments.NewDeviceDeployment("device-1", "234")
+	removed := deployments.NewDeviceDeployment("device-1", "345")
+
+	deployment := &deployments.Deployment{
+		Id: StringToPointer("123"),
+		DeploymentConstructor: &deployments.DeploymentConstructor{
+			Name:         StringToPointer("foo"),
+			ArtifactName: StringToPointer("bar"),
+		},
+	}
+
+	query := *deployments.NewDeviceDeploymentHistoryQuery()
+
+	testCases := map[string]struct {
+		InputDevDeps       []*deployments.DeviceDeployment
+		InputTotal         int
+		InputDevDepsError  error
+		InputFindByIDError error
+
+		OutputHistory []*deployments.DeviceDeploymentHistoryEntry
+		OutputError   error
+	}{
+		"device deployments storage error": {
+			InputDevDepsError: errors.New("storage error"),
+			OutputError:       errors.New("searching for device deployments: storage error"),
+		},
+		"deployments storage error": {
+			InputDevDeps:       []*deployments.DeviceDeployment{first},
+			InputFindByIDError: errors.New("storage error"),
+			OutputError:        errors.New("searching for deployment: storage error"),
+		},
+		"no deployments": {
+			InputDevDeps:  []*deployments.DeviceDeployment{},
+			OutputHistory: []*deployments.DeviceDeploymentHistoryEntry{},
+		},
+		"history": {
+			InputDevDeps: []*deployments.DeviceDeployment{first, second, removed},
+			InputTotal:   5,
+			OutputHistory: []*deployments.DeviceDeploymentHistoryEntry{
+				{
+					DeploymentId: "123",
+					Name:         "foo",
+					ArtifactName: "bar",
+					Status:       deployments.DeviceDeploymentStatusPending,
+					Created:      first.Created,
+				},
+				{
+					DeploymentId: "234",
+					Name:         "foo",
+					ArtifactName: "bar",
+					Status:       deployments.DeviceDeploymentStatusPending,
+					Created:      second.Created,
+				},
+				{
+					DeploymentId: "345",
+					Status:       deployments.DeviceDeploymentStatusPending,
+					Created:      removed.Created,
+				},
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("FindDeviceDeploymentsForDevice", "device-1", query).
+			Return(testCase.InputDevDeps, testCase.InputTotal, testCase.InputDevDepsError)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindByID", "123").
+			Return(deployment, testCase.InputFindByIDError)
+		deploymentStorage.On("FindByID", "234").
+			Return(deployment, testCase.InputFindByIDError)
+		deploymentStorage.On("FindByID", "345").
+			Return(nil, testCase.InputFindByIDError)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:       deploymentStorage,
+			DeviceDeploymentsStorage: deviceDeploymentStorage,
+		})
+
+		history, total, err := model.GetDeviceDeploymentHistory("device-1", query)
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.OutputHistory, history)
+			assert.Equal(t, testCase.InputTotal, total)
+		}
+	}
+}
+
+func TestDeploymentModelRetryDeployment(t *testing.T) {
+
+	t.Parallel()
+
+	original := &deployments.Deployment{
+		Id: StringToPointer(validUUIDv4),
+		DeploymentConstructor: &deployments.DeploymentConstructor{
+			Name:         StringToPointer("NYC Production"),
+			ArtifactName: StringToPointer("App 123"),
+			MaxRetries:   2,
+		},
+	}
+
+	deviceDeployment := func(device, status string) deployments.DeviceDeployment {
+		d := deployments.NewDeviceDeployment(device, validUUIDv4)
+		d.Status = StringToPointer(status)
+		return *d
+	}
+	statuses := []deployments.DeviceDeployment{
+		deviceDeployment("device-1", deployments.DeviceDeploymentStatusSuccess),
+		deviceDeployment("device-2", deployments.DeviceDeploymentStatusFailure),
+		deviceDeployment("device-3", deployments.DeviceDeploymentStatusAborted),
+		deviceDeployment("device-4", deployments.DeviceDeploymentStatusFailure),
+	}
+
+	testCases := map[string]struct {
+		InputRetry         *deployments.RetryConstructor
+		InputDeployment    *deployments.Deployment
+		InputFindByIDError error
+		InputStatusesError error
+
+		OutputDevices []string
+		OutputError   error
+	}{
+		"missing input": {
+			OutputError: controller.ErrModelMissingInput,
+		},
+		"deployment storage error": {
+			InputRetry:         deployments.NewRetryConstructor(),
+			InputFindByIDError: errors.New("storage error"),
+			OutputError:        errors.New("Searching for deployment by ID: storage error"),
+		},
+		"deployment not found": {
+			InputRetry:  deployments.NewRetryConstructor(),
+			OutputError: controller.ErrModelDeploymentNotFound,
+		},
+		"device deployments storage error": {
+			InputRetry:         deployments.NewRetryConstructor(),
+			InputDeployment:    original,
+			InputStatusesError: errors.New("storage error"),
+			OutputError:        errors.New("Searching for device deployments: storage error"),
+		},
+		"no devices to retry": {
+			InputRetry: &deployments.RetryConstructor{
+				Statuses: []string{deployments.DeviceDeploymentStatusNoArtifact},
+			},
+			InputDeployment: original,
+			OutputError:     controller.ErrModelNoDevicesToRetry,
+		},
+		"failed devices": {
+			InputRetry:      deployments.NewRetryConstructor(),
+			InputDeployment: original,
+			OutputDevices:   []string{"device-2", "device-4"},
+		},
+		"failed and aborted devices": {
+			InputRetry: &deployments.RetryConstructor{
+				Statuses: []string{
+					deployments.DeviceDeploymentStatusFailure,
+					deployments.DeviceDeploymentStatusAborted,
+				},
+			},
+			InputDeployment: original,
+			OutputDevices:   []string{"device-2", "device-3", "device-4"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		generator := new(mocks.Generator)
+		generator.On("Generate", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("string"),
+			mock.AnythingOfType("*deployments.Deployment")).
+			Return(&deployments.DeviceDeployment{}, nil)
+
+		deploymentStorage := new(mocks.DeploymentsStorage)
+		deploymentStorage.On("FindByID", validUUIDv4).
+			Return(testCase.InputDeployment, testCase.InputFindByIDError)
+		deploymentStorage.On("Insert", mock.AnythingOfType("*deployments.Deployment")).
+			Return(nil)
+
+		deviceDeploymentStorage := new(mocks.DeviceDeploymentStorage)
+		deviceDeploymentStorage.On("GetDeviceStatusesForDeployment", validUUIDv4, paging.Query{}).
+			Return(statuses, len(statuses), testCase.InputStatusesError)
+		deviceDeploymentStorage.On("InsertMany", mock.AnythingOfType("[]*deployments.DeviceDeployment")).
+			Return(nil)
+
+		model := NewDeploymentModel(DeploymentsModelConfig{
+			DeploymentsStorage:        deploymentStorage,
+			DeviceDeploymentGenerator: generator,
+			DeviceDeploymentsStorage:  deviceDeploymentStorage,
+		})
+
+		id, err := model.RetryDeployment(context.Background(), validUUIDv4, testCase.InputRetry)
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+			deploymentStorage.AssertNotCalled(t, "Insert", mock.AnythingOfType("*deployments.Deployment"))
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, validUUIDv4, id)
+
+		deployment := deploymentStorage.Calls[1].Arguments.Get(0).(*deployments.Deployment)
+		assert.Equal(t, id, *deployment.Id)
+		assert.Equal(t, validUUIDv4, *deployment.RetryOf)
+		assert.Equal(t, testCase.OutputDevices, deployment.Devices)
+		assert.Equal(t, "App 123", *deployment.ArtifactName)
+		assert.Equal(t, 2, deployment.MaxRetries)
+	}
+}
@@ -28,6 +28,13 @@ type DeploymentsStorage interface {
 	FindUnfinishedByID(id string) (*deployments.Deployment, error)
 	UpdateStats(id string, state_from, state_to string) error
 	UpdateStatsAndFinishDeployment(id string, stats deployments.Stats) error
-	Find(query deployments.Query) ([]*deployments.Deployment, error)
+	Find(query deployments.Query) ([]*deployments.Deployment, int, error)
 	Finish(id string, when time.Time) error
+	UpdateAbortReason(id string, reason string) error
+	UpdatePaused(id string, paused bool) error
+	AcquireInProgressSlot(id string, max int) (bool, error)
+	ReleaseInProgressSlot(id string) error
+	FindExpired(now time.Time) ([]*deployments.Deployment, error)
+	FindFinishedBefore(before time.Time) ([]*deployments.Deployment, error)
+	Archive(deployment *deployments.Deployment) error
 }
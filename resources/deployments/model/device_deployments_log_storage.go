@@ -22,4 +22,5 @@ import (
 type DeviceDeploymentLogsStorage interface {
 	SaveDeviceDeploymentLog(log deployments.DeploymentLog) error
 	GetDeviceDeploymentLog(deviceID, deploymentID string) (*deployments.DeploymentLog, error)
+	DeleteDeviceDeploymentLogs(deploymentID string) error
 }
@@ -18,18 +18,28 @@ import (
 	"time"
 
 	"github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/mendersoftware/deployments/utils/paging"
 )
 
 // Device deployment storage
 type DeviceDeploymentStorage interface {
 	InsertMany(deployment ...*deployments.DeviceDeployment) error
 	ExistAssignedImageWithIDAndStatuses(id string, statuses ...string) (bool, error)
-	FindOldestDeploymentForDeviceIDWithStatuses(deviceID string, statuses ...string) (*deployments.DeviceDeployment, error)
-	UpdateDeviceDeploymentStatus(deviceID string, deploymentID string, status string, finishTime *time.Time) (string, error)
+	FindDeploymentsForDeviceIDWithStatuses(deviceID string, statuses ...string) ([]*deployments.DeviceDeployment, error)
+	UpdateDeviceDeploymentStatus(deviceID string, deploymentID string, state deployments.DeviceDeploymentState, finishTime *time.Time) (string, error)
 	UpdateDeviceDeploymentLogAvailability(deviceID string, deploymentID string, log bool) error
 	AggregateDeviceDeploymentByStatus(id string) (deployments.Stats, error)
-	GetDeviceStatusesForDeployment(deploymentID string) ([]deployments.DeviceDeployment, error)
+	AggregateDeviceDeploymentStatistics(id string) (*deployments.DeploymentStatistics, error)
+	AggregateDeviceDeploymentByStatusForPhase(id string, phase int) (deployments.Stats, error)
+	AggregateDeviceDeploymentFailures(id string) ([]*deployments.FailureGroup, error)
+	GetDeviceStatusesForDeployment(deploymentID string, page paging.Query) ([]deployments.DeviceDeployment, int, error)
 	HasDeploymentForDevice(deploymentID string, deviceID string) (bool, error)
 	GetDeviceDeploymentStatus(deploymentID string, deviceID string) (string, error)
 	AbortDeviceDeployments(deploymentID string) error
+	FindStaleDeviceDeployments(before time.Time, statuses ...string) ([]*deployments.DeviceDeployment, error)
+	FindDeviceDeploymentsWithStatuses(deploymentID string, statuses ...string) ([]*deployments.DeviceDeployment, error)
+	FindDeviceDeploymentsForDevices(deviceIDs []string, statuses ...string) ([]*deployments.DeviceDeployment, error)
+	RetryDeviceDeployment(deviceID string, deploymentID string, maxRetries int, retryAfter *time.Time) (string, error)
+	FindDeviceDeploymentsForDevice(deviceID string, query deployments.DeviceDeploymentHistoryQuery) ([]*deployments.DeviceDeployment, int, error)
+	DeleteDeviceDeployments(deploymentID string) error
 }
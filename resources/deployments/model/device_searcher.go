@@ -0,0 +1,26 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"context"
+
+	"github.com/mendersoftware/deployments/resources/deployments"
+)
+
+// Resolve device filter into IDs of matching devices.
+type DeviceSearcher interface {
+	SearchDevices(ctx context.Context, filter *deployments.DeviceFilter) ([]string, error)
+}
@@ -63,10 +63,10 @@ func (_m *DeploymentsStorage) FindByID(id string) (*deployments.Deployment, erro
 	return r0, r1
 }
 
-func (_m *DeploymentsStorage) Find(query deployments.Query) ([]*deployments.Deployment, error) {
+func (_m *DeploymentsStorage) Find(query deployments.Query) ([]*deployments.Deployment, int, error) {
 	ret := _m.Called(query)
 
-	return ret.Get(0).([]*deployments.Deployment), ret.Error(1)
+	return ret.Get(0).([]*deployments.Deployment), ret.Int(1), ret.Error(2)
 }
 
 // Insert provides a mock function with given fields: deployment
@@ -101,6 +101,12 @@ func (_m *DeploymentsStorage) Finish(id string, when time.Time) error {
 	return ret.Error(0)
 }
 
+func (_m *DeploymentsStorage) UpdateAbortReason(id string, reason string) error {
+	ret := _m.Called(id, reason)
+
+	return ret.Error(0)
+}
+
 func (_m *DeploymentsStorage) FindUnfinishedByID(id string) (*deployments.Deployment, error) {
 	ret := _m.Called(id)
 
@@ -122,3 +128,52 @@ func (_m *DeploymentsStorage) FindUnfinishedByID(id string) (*deployments.Deploy
 
 	return r0, r1
 }
+
+// UpdatePaused provides a mock function with given fields: id, paused
+func (_m *DeploymentsStorage) UpdatePaused(id string, paused bool) error {
+	ret := _m.Called(id, paused)
+	return ret.Error(0)
+}
+
+// AcquireInProgressSlot provides a mock function with given fields: id, max
+func (_m *DeploymentsStorage) AcquireInProgressSlot(id string, max int) (bool, error) {
+	ret := _m.Called(id, max)
+	return ret.Bool(0), ret.Error(1)
+}
+
+// ReleaseInProgressSlot provides a mock function with given fields: id
+func (_m *DeploymentsStorage) ReleaseInProgressSlot(id string) error {
+	ret := _m.Called(id)
+	return ret.Error(0)
+}
+
+// FindExpired provides a mock function with given fields: now
+func (_m *DeploymentsStorage) FindExpired(now time.Time) ([]*deployments.Deployment, error) {
+	ret := _m.Called(now)
+
+	var r0 []*deployments.Deployment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*deployments.Deployment)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// FindFinishedBefore provides a mock function with given fields: before
+func (_m *DeploymentsStorage) FindFinishedBefore(before time.Time) ([]*deployments.Deployment, error) {
+	ret := _m.Called(before)
+
+	var r0 []*deployments.Deployment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*deployments.Deployment)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// Archive provides a mock function with given fields: deployment
+func (_m *DeploymentsStorage) Archive(deployment *deployments.Deployment) error {
+	ret := _m.Called(deployment)
+
+	return ret.Error(0)
+}
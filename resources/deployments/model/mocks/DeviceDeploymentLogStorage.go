@@ -35,3 +35,9 @@ func (_m *DeviceDeploymentLogStorage) GetDeviceDeploymentLog(deviceID, deploymen
 
 	return ret.Get(0).(*deployments.DeploymentLog), ret.Error(1)
 }
+
+func (_m *DeviceDeploymentLogStorage) DeleteDeviceDeploymentLogs(deploymentID string) error {
+	ret := _m.Called(deploymentID)
+
+	return ret.Error(0)
+}
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -47,16 +48,16 @@ func (_m *DeviceDeploymentStorage) ExistAssignedImageWithIDAndStatuses(id string
 	return r0, r1
 }
 
-// FindOldestDeploymentForDeviceIDWithStatuses provides a mock function with given fields: deviceID, statuses
-func (_m *DeviceDeploymentStorage) FindOldestDeploymentForDeviceIDWithStatuses(deviceID string, statuses ...string) (*deployments.DeviceDeployment, error) {
+// FindDeploymentsForDeviceIDWithStatuses provides a mock function with given fields: deviceID, statuses
+func (_m *DeviceDeploymentStorage) FindDeploymentsForDeviceIDWithStatuses(deviceID string, statuses ...string) ([]*deployments.DeviceDeployment, error) {
 	ret := _m.Called(deviceID, statuses)
 
-	var r0 *deployments.DeviceDeployment
-	if rf, ok := ret.Get(0).(func(string, ...string) *deployments.DeviceDeployment); ok {
+	var r0 []*deployments.DeviceDeployment
+	if rf, ok := ret.Get(0).(func(string, ...string) []*deployments.DeviceDeployment); ok {
 		r0 = rf(deviceID, statuses...)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*deployments.DeviceDeployment)
+			r0 = ret.Get(0).([]*deployments.DeviceDeployment)
 		}
 	}
 
@@ -84,8 +85,8 @@ func (_m *DeviceDeploymentStorage) InsertMany(deployment ...*deployments.DeviceD
 	return r0
 }
 
-func (_m *DeviceDeploymentStorage) UpdateDeviceDeploymentStatus(deviceID string, deploymentID string, status string, finishTime *time.Time) (string, error) {
-	ret := _m.Called(deviceID, deploymentID, status, finishTime)
+func (_m *DeviceDeploymentStorage) UpdateDeviceDeploymentStatus(deviceID string, deploymentID string, state deployments.DeviceDeploymentState, finishTime *time.Time) (string, error) {
+	ret := _m.Called(deviceID, deploymentID, state, finishTime)
 
 	return ret.Get(0).(string), ret.Error(1)
 }
@@ -102,10 +103,39 @@ func (_m *DeviceDeploymentStorage) AggregateDeviceDeploymentByStatus(deploymentI
 	return ret.Get(0).(deployments.Stats), ret.Error(1)
 }
 
-func (_m *DeviceDeploymentStorage) GetDeviceStatusesForDeployment(deploymentID string) ([]deployments.DeviceDeployment, error) {
+func (_m *DeviceDeploymentStorage) AggregateDeviceDeploymentStatistics(deploymentID string) (*deployments.DeploymentStatistics, error) {
 	ret := _m.Called(deploymentID)
 
-	return ret.Get(0).([]deployments.DeviceDeployment), ret.Error(1)
+	var r0 *deployments.DeploymentStatistics
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*deployments.DeploymentStatistics)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *DeviceDeploymentStorage) AggregateDeviceDeploymentByStatusForPhase(deploymentID string, phase int) (deployments.Stats, error) {
+	ret := _m.Called(deploymentID, phase)
+
+	return ret.Get(0).(deployments.Stats), ret.Error(1)
+}
+
+func (_m *DeviceDeploymentStorage) AggregateDeviceDeploymentFailures(deploymentID string) ([]*deployments.FailureGroup, error) {
+	ret := _m.Called(deploymentID)
+
+	var r0 []*deployments.FailureGroup
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*deployments.FailureGroup)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *DeviceDeploymentStorage) GetDeviceStatusesForDeployment(deploymentID string,
+	page paging.Query) ([]deployments.DeviceDeployment, int, error) {
+	ret := _m.Called(deploymentID, page)
+
+	return ret.Get(0).([]deployments.DeviceDeployment), ret.Int(1), ret.Error(2)
 }
 
 func (_m *DeviceDeploymentStorage) HasDeploymentForDevice(deploymentID string, deviceID string) (bool, error) {
@@ -125,3 +155,64 @@ func (_m *DeviceDeploymentStorage) AbortDeviceDeployments(deploymentID string) e
 
 	return ret.Error(0)
 }
+
+// RetryDeviceDeployment provides a mock function with given fields: deviceID, deploymentID, maxRetries, retryAfter
+func (_m *DeviceDeploymentStorage) RetryDeviceDeployment(deviceID string, deploymentID string, maxRetries int, retryAfter *time.Time) (string, error) {
+	ret := _m.Called(deviceID, deploymentID, maxRetries, retryAfter)
+	return ret.String(0), ret.Error(1)
+}
+
+// FindStaleDeviceDeployments provides a mock function with given fields: before, statuses
+func (_m *DeviceDeploymentStorage) FindStaleDeviceDeployments(before time.Time, statuses ...string) ([]*deployments.DeviceDeployment, error) {
+	ret := _m.Called(before, statuses)
+
+	var r0 []*deployments.DeviceDeployment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*deployments.DeviceDeployment)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// FindDeviceDeploymentsWithStatuses provides a mock function with given fields: deploymentID, statuses
+func (_m *DeviceDeploymentStorage) FindDeviceDeploymentsWithStatuses(deploymentID string, statuses ...string) ([]*deployments.DeviceDeployment, error) {
+	ret := _m.Called(deploymentID, statuses)
+
+	var r0 []*deployments.DeviceDeployment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*deployments.DeviceDeployment)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// FindDeviceDeploymentsForDevices provides a mock function with given fields: deviceIDs, statuses
+func (_m *DeviceDeploymentStorage) FindDeviceDeploymentsForDevices(deviceIDs []string, statuses ...string) ([]*deployments.DeviceDeployment, error) {
+	ret := _m.Called(deviceIDs, statuses)
+
+	var r0 []*deployments.DeviceDeployment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*deployments.DeviceDeployment)
+	}
+
+	return r0, ret.Error(1)
+}
+
+// FindDeviceDeploymentsForDevice provides a mock function with given fields: deviceID, query
+func (_m *DeviceDeploymentStorage) FindDeviceDeploymentsForDevice(deviceID string, query deployments.DeviceDeploymentHistoryQuery) ([]*deployments.DeviceDeployment, int, error) {
+	ret := _m.Called(deviceID, query)
+
+	var r0 []*deployments.DeviceDeployment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*deployments.DeviceDeployment)
+	}
+
+	return r0, ret.Int(1), ret.Error(2)
+}
+
+// DeleteDeviceDeployments provides a mock function with given fields: deploymentID
+func (_m *DeviceDeploymentStorage) DeleteDeviceDeployments(deploymentID string) error {
+	ret := _m.Called(deploymentID)
+
+	return ret.Error(0)
+}
@@ -0,0 +1,50 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mocks
+
+import (
+	"context"
+
+	"github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/stretchr/testify/mock"
+)
+
+// DeviceSearcher is an autogenerated mock type for the DeviceSearcher type
+type DeviceSearcher struct {
+	mock.Mock
+}
+
+// SearchDevices provides a mock function with given fields: ctx, filter
+func (_m *DeviceSearcher) SearchDevices(ctx context.Context, filter *deployments.DeviceFilter) ([]string, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, *deployments.DeviceFilter) []string); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *deployments.DeviceFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -0,0 +1,47 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mocks
+
+import (
+	"github.com/mendersoftware/deployments/resources/deployments"
+	"github.com/stretchr/testify/mock"
+)
+
+// EventsHub is an autogenerated mock type for the EventsHub type
+type EventsHub struct {
+	mock.Mock
+}
+
+// Publish provides a mock function with given fields: event
+func (_m *EventsHub) Publish(event *deployments.Event) {
+	_m.Called(event)
+}
+
+// Subscribe provides a mock function with given fields: deploymentID
+func (_m *EventsHub) Subscribe(deploymentID string) (<-chan *deployments.Event, func()) {
+	ret := _m.Called(deploymentID)
+
+	var r0 <-chan *deployments.Event
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(<-chan *deployments.Event)
+	}
+
+	var r1 func()
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(func())
+	}
+
+	return r0, r1
+}
@@ -27,8 +27,9 @@ import (
 
 // Database settings
 const (
-	DatabaseName          = "deployment_service"
-	CollectionDeployments = "deployments"
+	DatabaseName                 = "deployment_service"
+	CollectionDeployments        = "deployments"
+	CollectionDeploymentsArchive = "deployments.archive"
 )
 
 // Errors
@@ -44,13 +45,38 @@ const (
 	StorageKeyDeploymentArtifactName = "deploymentconstructor.artifactname"
 	StorageKeyDeploymentStats        = "stats"
 	StorageKeyDeploymentFinished     = "finished"
+	StorageKeyDeploymentAbortReason  = "abortreason"
+	StorageKeyDeploymentStartTime    = "deploymentconstructor.starttime"
+	StorageKeyDeploymentPaused       = "paused"
+	StorageKeyDeploymentInProgress   = "inprogress"
+	StorageKeyDeploymentAborted      = "aborted"
+	StorageKeyDeploymentExpiresAt    = "deploymentconstructor.expiresat"
+	StorageKeyDeploymentCreated      = "created"
+	StorageKeyDeploymentId           = "_id"
+	StorageKeyDeploymentCreatedBy    = "createdby"
 )
 
+// Storage keys of fields deployments can be sorted by
+var deploymentSortKeys = map[string]string{
+	deployments.SortFieldCreated:      StorageKeyDeploymentCreated,
+	deployments.SortFieldName:         StorageKeyDeploymentName,
+	deployments.SortFieldArtifactName: StorageKeyDeploymentArtifactName,
+	deployments.SortFieldFinished:     StorageKeyDeploymentFinished,
+}
+
 var (
 	StorageIndexes = []string{
 		"$text:" + StorageKeyDeploymentName,
 		"$text:" + StorageKeyDeploymentArtifactName,
 	}
+
+	// Keys deployments are looked up by, besides the text search
+	StorageLookupIndexes = []string{
+		StorageKeyDeploymentCreated,
+		StorageKeyDeploymentFinished,
+		StorageKeyDeploymentArtifactName,
+		StorageKeyDeploymentCreatedBy,
+	}
 )
 
 // DeploymentsStorage is a data layer for deployments based on MongoDB
@@ -65,6 +91,27 @@ func NewDeploymentsStorage(session *mgo.Session) *DeploymentsStorage {
 	}
 }
 
+// IndexStorage set required indexes.
+// * Set indexes supporting deployment lookup by time ranges, artifact name and creator.
+// Lookup by device is backed by indexes of device deployments.
+func (d *DeploymentsStorage) IndexStorage() error {
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	for _, key := range StorageLookupIndexes {
+		index := mgo.Index{
+			Key:        []string{key},
+			Background: true,
+		}
+		if err := session.DB(DatabaseName).C(CollectionDeployments).EnsureIndex(index); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (d *DeploymentsStorage) ensureIndexing(session *mgo.Session) error {
 	return session.DB(DatabaseName).C(CollectionDeployments).
 		EnsureIndexKey(StorageIndexes...)
@@ -185,6 +232,8 @@ func (d *DeploymentsStorage) FindUnfinishedByID(id string) (*deployments.Deploym
 	return deployment, nil
 }
 
+// UpdateStatsAndFinishDeployment sets stats of the aborted deployment and
+// marks it as aborted and finished.
 func (d *DeploymentsStorage) UpdateStatsAndFinishDeployment(id string, stats deployments.Stats) error {
 	if govalidator.IsNull(id) {
 		return ErrStorageInvalidID
@@ -198,6 +247,7 @@ func (d *DeploymentsStorage) UpdateStatsAndFinishDeployment(id string, stats dep
 		"$set": bson.M{
 			StorageKeyDeploymentStats:    stats,
 			StorageKeyDeploymentFinished: &now,
+			StorageKeyDeploymentAborted:  true,
 		},
 	}
 
@@ -238,6 +288,55 @@ func buildStatusKey(status string) string {
 	return StorageKeyDeploymentStats + "." + status
 }
 
+// buildNotAbortedQuery matches deployments which were not aborted as a whole;
+// deployments missing the aborted flag are aborted if any device is.
+func buildNotAbortedQuery() bson.M {
+	return bson.M{
+		"$or": []bson.M{
+			bson.M{
+				StorageKeyDeploymentAborted: false,
+			},
+			bson.M{
+				// matches both missing and null flag
+				StorageKeyDeploymentAborted:                               nil,
+				buildStatusKey(deployments.DeviceDeploymentStatusAborted): bson.M{"$eq": 0},
+			},
+		},
+	}
+}
+
+// buildAbortedQuery matches deployments which were aborted as a whole, see
+// buildNotAbortedQuery.
+func buildAbortedQuery() bson.M {
+	return bson.M{
+		"$or": []bson.M{
+			bson.M{
+				StorageKeyDeploymentAborted: true,
+			},
+			bson.M{
+				StorageKeyDeploymentAborted:                               nil,
+				buildStatusKey(deployments.DeviceDeploymentStatusAborted): bson.M{"$gt": 0},
+			},
+		},
+	}
+}
+
+// buildTimeRangeQuery matches key at or after `after` and before `before`,
+// either bound is optional. Returns nil if no bound is given.
+func buildTimeRangeQuery(key string, after, before *time.Time) bson.M {
+	rq := bson.M{}
+	if after != nil {
+		rq["$gte"] = *after
+	}
+	if before != nil {
+		rq["$lt"] = *before
+	}
+	if len(rq) == 0 {
+		return nil
+	}
+	return bson.M{key: rq}
+}
+
 func buildStatusQuery(status deployments.StatusQuery) bson.M {
 
 	gt0 := bson.M{"$gt": 0}
@@ -264,11 +363,13 @@ func buildStatusQuery(status deployments.StatusQuery) bson.M {
 				},
 			}
 		}
-	case deployments.StatusQueryPending:
+	case deployments.StatusQueryPending, deployments.StatusQueryScheduled:
 		{
-			// all status counters, except for pending, are 0
-			stq = bson.M{
+			// all status counters, except for pending and aborted, are 0;
+			// devices aborted one by one do not make the deployment aborted
+			pending := bson.M{
 				"$and": []bson.M{
+					buildNotAbortedQuery(),
 					bson.M{
 						buildStatusKey(deployments.DeviceDeploymentStatusDownloading): eq0,
 					},
@@ -284,20 +385,66 @@ func buildStatusQuery(status deployments.StatusQuery) bson.M {
 					bson.M{
 						buildStatusKey(deployments.DeviceDeploymentStatusAlreadyInst): eq0,
 					},
-					bson.M{
-						buildStatusKey(deployments.DeviceDeploymentStatusAborted): eq0,
-					},
 					bson.M{
 						buildStatusKey(deployments.DeviceDeploymentStatusFailure): eq0,
 					},
 					bson.M{
 						buildStatusKey(deployments.DeviceDeploymentStatusNoArtifact): eq0,
 					},
+					bson.M{
+						// counter missing in deployments created before expiry
+						buildStatusKey(deployments.DeviceDeploymentStatusExpired): bson.M{"$not": gt0},
+					},
 					bson.M{
 						buildStatusKey(deployments.DeviceDeploymentStatusPending): gt0,
 					},
 				},
 			}
+
+			// scheduled deployments are pending ones with start time still ahead
+			afterStart := bson.M{"$gt": time.Now()}
+			start := bson.M{
+				StorageKeyDeploymentStartTime: afterStart,
+			}
+			if status == deployments.StatusQueryPending {
+				start = bson.M{
+					StorageKeyDeploymentStartTime: bson.M{"$not": afterStart},
+				}
+			}
+
+			stq = bson.M{
+				"$and": []bson.M{pending, start},
+			}
+		}
+	case deployments.StatusQueryAborted:
+		{
+			stq = buildAbortedQuery()
+		}
+	case deployments.StatusQueryPaused:
+		{
+			// paused and some devices are still pending or in progress
+			stq = bson.M{
+				StorageKeyDeploymentPaused: true,
+				"$and": []bson.M{
+					buildNotAbortedQuery(),
+					bson.M{
+						"$or": []bson.M{
+							bson.M{
+								buildStatusKey(deployments.DeviceDeploymentStatusPending): gt0,
+							},
+							bson.M{
+								buildStatusKey(deployments.DeviceDeploymentStatusDownloading): gt0,
+							},
+							bson.M{
+								buildStatusKey(deployments.DeviceDeploymentStatusInstalling): gt0,
+							},
+							bson.M{
+								buildStatusKey(deployments.DeviceDeploymentStatusRebooting): gt0,
+							},
+						},
+					},
+				},
+			}
 		}
 	case deployments.StatusQueryFinished:
 		{
@@ -337,6 +484,9 @@ func buildStatusQuery(status deployments.StatusQuery) bson.M {
 							bson.M{
 								buildStatusKey(deployments.DeviceDeploymentStatusAborted): gt0,
 							},
+							bson.M{
+								buildStatusKey(deployments.DeviceDeploymentStatusExpired): gt0,
+							},
 						},
 					},
 				},
@@ -344,10 +494,24 @@ func buildStatusQuery(status deployments.StatusQuery) bson.M {
 		}
 	}
 
+	// paused deployments are reported as paused until finished
+	switch status {
+	case deployments.StatusQueryInProgress, deployments.StatusQueryPending, deployments.StatusQueryScheduled:
+		stq = bson.M{
+			"$and": []bson.M{
+				stq,
+				bson.M{StorageKeyDeploymentPaused: bson.M{"$ne": true}},
+			},
+		}
+	}
+
 	return stq
 }
 
-func (d *DeploymentsStorage) Find(match deployments.Query) ([]*deployments.Deployment, error) {
+// Find lists deployments matching the query, together with total number of
+// matching deployments. Deployments are ordered by creation time unless other
+// sort is requested.
+func (d *DeploymentsStorage) Find(match deployments.Query) ([]*deployments.Deployment, int, error) {
 
 	session := d.session.Copy()
 	defer session.Close()
@@ -358,7 +522,7 @@ func (d *DeploymentsStorage) Find(match deployments.Query) ([]*deployments.Deplo
 	if match.SearchText != "" {
 		// we must have indexing for text search
 		if !d.hasIndexing(session) {
-			return nil, ErrDeploymentStorageCannotExecQuery
+			return nil, 0, ErrDeploymentStorageCannotExecQuery
 		}
 
 		tq := bson.M{
@@ -376,6 +540,39 @@ func (d *DeploymentsStorage) Find(match deployments.Query) ([]*deployments.Deplo
 		andq = append(andq, stq)
 	}
 
+	// build deployment by time ranges part of the query
+	if tq := buildTimeRangeQuery(StorageKeyDeploymentCreated,
+		match.CreatedAfter, match.CreatedBefore); tq != nil {
+		andq = append(andq, tq)
+	}
+	if tq := buildTimeRangeQuery(StorageKeyDeploymentFinished,
+		match.FinishedAfter, match.FinishedBefore); tq != nil {
+		andq = append(andq, tq)
+	}
+
+	if match.ArtifactName != "" {
+		andq = append(andq, bson.M{StorageKeyDeploymentArtifactName: match.ArtifactName})
+	}
+
+	if match.CreatedBy != "" {
+		andq = append(andq, bson.M{StorageKeyDeploymentCreatedBy: match.CreatedBy})
+	}
+
+	// device list is not kept with the deployment, look at device deployments
+	if match.DeviceID != "" {
+		ids := []string{}
+		err := session.DB(DatabaseName).C(CollectionDevices).
+			Find(bson.M{StorageKeyDeviceDeploymentDeviceId: match.DeviceID}).
+			Distinct(StorageKeyDeviceDeploymentDeploymentID, &ids)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ids == nil {
+			ids = []string{}
+		}
+		andq = append(andq, bson.M{StorageKeyDeploymentId: bson.M{"$in": ids}})
+	}
+
 	query := bson.M{}
 	if len(andq) != 0 {
 		// use search criteria if any
@@ -383,14 +580,24 @@ func (d *DeploymentsStorage) Find(match deployments.Query) ([]*deployments.Deplo
 			"$and": andq,
 		}
 	}
+	total, err := session.DB(DatabaseName).C(CollectionDeployments).
+		Find(&query).Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
 	var deployment []*deployments.Deployment
-	err := session.DB(DatabaseName).C(CollectionDeployments).
-		Find(&query).All(&deployment)
+	err = session.DB(DatabaseName).C(CollectionDeployments).
+		Find(&query).
+		Sort(match.Paging.SortKey(deploymentSortKeys, deployments.SortFieldCreated),
+			StorageKeyDeploymentId).
+		Skip(match.Paging.Skip()).Limit(match.Paging.PerPage).
+		All(&deployment)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return deployment, nil
+	return deployment, total, nil
 }
 
 func (d *DeploymentsStorage) Finish(id string, when time.Time) error {
@@ -416,3 +623,163 @@ func (d *DeploymentsStorage) Finish(id string, when time.Time) error {
 
 	return err
 }
+
+// UpdateAbortReason records why deployment was aborted
+func (d *DeploymentsStorage) UpdateAbortReason(id string, reason string) error {
+	if govalidator.IsNull(id) {
+		return ErrStorageInvalidID
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	update := bson.M{
+		"$set": bson.M{
+			StorageKeyDeploymentAbortReason: reason,
+		},
+	}
+
+	err := session.DB(DatabaseName).C(CollectionDeployments).UpdateId(id, update)
+
+	if err == mgo.ErrNotFound {
+		return ErrStorageInvalidID
+	}
+
+	return err
+}
+
+// UpdatePaused pauses or resumes the deployment
+func (d *DeploymentsStorage) UpdatePaused(id string, paused bool) error {
+	if govalidator.IsNull(id) {
+		return ErrStorageInvalidID
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	update := bson.M{
+		"$set": bson.M{
+			StorageKeyDeploymentPaused: paused,
+		},
+	}
+
+	err := session.DB(DatabaseName).C(CollectionDeployments).UpdateId(id, update)
+
+	if err == mgo.ErrNotFound {
+		return ErrStorageInvalidID
+	}
+
+	return err
+}
+
+// AcquireInProgressSlot atomically increments number of devices updating
+// at once, unless it already reached `max`. Returns false if there is no
+// slot available.
+func (d *DeploymentsStorage) AcquireInProgressSlot(id string, max int) (bool, error) {
+	if govalidator.IsNull(id) {
+		return false, ErrStorageInvalidID
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	selector := bson.M{
+		"_id":                          id,
+		StorageKeyDeploymentInProgress: bson.M{"$lt": max},
+	}
+	update := bson.M{
+		"$inc": bson.M{
+			StorageKeyDeploymentInProgress: 1,
+		},
+	}
+
+	err := session.DB(DatabaseName).C(CollectionDeployments).Update(selector, update)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ReleaseInProgressSlot decrements number of devices updating at once.
+func (d *DeploymentsStorage) ReleaseInProgressSlot(id string) error {
+	if govalidator.IsNull(id) {
+		return ErrStorageInvalidID
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	selector := bson.M{
+		"_id":                          id,
+		StorageKeyDeploymentInProgress: bson.M{"$gt": 0},
+	}
+	update := bson.M{
+		"$inc": bson.M{
+			StorageKeyDeploymentInProgress: -1,
+		},
+	}
+
+	err := session.DB(DatabaseName).C(CollectionDeployments).Update(selector, update)
+	if err == mgo.ErrNotFound {
+		// no slot held, nothing to release
+		return nil
+	}
+
+	return err
+}
+
+// FindExpired returns deployments with expiry time before `now` that still
+// have pending devices.
+func (d *DeploymentsStorage) FindExpired(now time.Time) ([]*deployments.Deployment, error) {
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	query := bson.M{
+		StorageKeyDeploymentExpiresAt:                             bson.M{"$lte": now},
+		buildStatusKey(deployments.DeviceDeploymentStatusPending): bson.M{"$gt": 0},
+	}
+
+	var expired []*deployments.Deployment
+	if err := session.DB(DatabaseName).C(CollectionDeployments).Find(query).All(&expired); err != nil {
+		return nil, err
+	}
+
+	return expired, nil
+}
+
+// FindFinishedBefore returns deployments which finished before given time.
+func (d *DeploymentsStorage) FindFinishedBefore(before time.Time) ([]*deployments.Deployment, error) {
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	query := bson.M{
+		StorageKeyDeploymentFinished: bson.M{"$lt": before},
+	}
+
+	var finished []*deployments.Deployment
+	if err := session.DB(DatabaseName).C(CollectionDeployments).Find(query).All(&finished); err != nil {
+		return nil, err
+	}
+
+	return finished, nil
+}
+
+// Archive stores deployment in the archive collection. Archiving the same
+// deployment again overwrites the archived copy.
+func (d *DeploymentsStorage) Archive(deployment *deployments.Deployment) error {
+
+	if deployment == nil || deployment.Id == nil || govalidator.IsNull(*deployment.Id) {
+		return ErrDeploymentStorageInvalidDeployment
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	_, err := session.DB(DatabaseName).C(CollectionDeploymentsArchive).UpsertId(*deployment.Id, deployment)
+	return err
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/mendersoftware/deployments/resources/deployments"
 	. "github.com/mendersoftware/deployments/resources/deployments/mongo"
+	"github.com/mendersoftware/deployments/utils/paging"
 	. "github.com/mendersoftware/deployments/utils/pointers"
 	"github.com/stretchr/testify/assert"
 )
@@ -660,6 +661,7 @@ func TestDeploymentStorageUpdateStatsAndFinishDeployment(t *testing.T) {
 			err := session.DB(DatabaseName).C(CollectionDeployments).FindId(tc.InputID).One(&deployment)
 			assert.NoError(t, err)
 			assert.Equal(t, tc.InputStats, deployment.Stats)
+			assert.Equal(t, BoolToPointer(true), deployment.Aborted)
 		}
 
 		// Need to close all sessions to be able to call wipe at next test case
@@ -783,6 +785,74 @@ func TestDeploymentStorageFindBy(t *testing.T) {
 				deployments.DeviceDeploymentStatusAborted: 1,
 			}),
 		},
+		&deployments.Deployment{
+			DeploymentConstructor: &deployments.DeploymentConstructor{
+				Name:         StringToPointer("zed"),
+				ArtifactName: StringToPointer("daz"),
+				Devices:      []string{"b532b01a-9313-404f-8d19-e7fcbe5cc347"},
+				StartTime:    TimeToPointer(time.Now().Add(time.Hour)),
+			},
+			Id: StringToPointer("3fe15222-1234-401f-8f5e-582aba2a0030"),
+			Stats: newTestStats(deployments.Stats{
+				deployments.DeviceDeploymentStatusPending: 1,
+			}),
+		},
+		// paused
+		&deployments.Deployment{
+			DeploymentConstructor: &deployments.DeploymentConstructor{
+				Name:         StringToPointer("zed"),
+				ArtifactName: StringToPointer("daz"),
+				Devices:      []string{"b532b01a-9313-404f-8d19-e7fcbe5cc347"},
+			},
+			Id: StringToPointer("3fe15222-1234-401f-8f5e-582aba2a0031"),
+			Stats: newTestStats(deployments.Stats{
+				deployments.DeviceDeploymentStatusPending:     1,
+				deployments.DeviceDeploymentStatusDownloading: 1,
+			}),
+			Paused: true,
+		},
+		// paused, single device aborted
+		&deployments.Deployment{
+			DeploymentConstructor: &deployments.DeploymentConstructor{
+				Name:         StringToPointer("zed"),
+				ArtifactName: StringToPointer("daz"),
+				Devices:      []string{"b532b01a-9313-404f-8d19-e7fcbe5cc347"},
+			},
+			Id: StringToPointer("3fe15222-1234-401f-8f5e-582aba2a0032"),
+			Stats: newTestStats(deployments.Stats{
+				deployments.DeviceDeploymentStatusPending: 1,
+				deployments.DeviceDeploymentStatusAborted: 1,
+			}),
+			Paused:  true,
+			Aborted: BoolToPointer(false),
+		},
+		// pending, single device aborted
+		&deployments.Deployment{
+			DeploymentConstructor: &deployments.DeploymentConstructor{
+				Name:         StringToPointer("zed"),
+				ArtifactName: StringToPointer("daz"),
+				Devices:      []string{"b532b01a-9313-404f-8d19-e7fcbe5cc347"},
+			},
+			Id: StringToPointer("3fe15222-1234-401f-8f5e-582aba2a0033"),
+			Stats: newTestStats(deployments.Stats{
+				deployments.DeviceDeploymentStatusPending: 1,
+				deployments.DeviceDeploymentStatusAborted: 1,
+			}),
+			Aborted: BoolToPointer(false),
+		},
+		// aborted as a whole
+		&deployments.Deployment{
+			DeploymentConstructor: &deployments.DeploymentConstructor{
+				Name:         StringToPointer("zed"),
+				ArtifactName: StringToPointer("daz"),
+				Devices:      []string{"b532b01a-9313-404f-8d19-e7fcbe5cc347"},
+			},
+			Id: StringToPointer("3fe15222-1234-401f-8f5e-582aba2a0034"),
+			Stats: newTestStats(deployments.Stats{
+				deployments.DeviceDeploymentStatusAborted: 2,
+			}),
+			Aborted: BoolToPointer(true),
+		},
 	}
 
 	testCases := []struct {
@@ -875,6 +945,33 @@ func TestDeploymentStorageFindBy(t *testing.T) {
 			OutputError:                nil,
 			OutputID: []string{
 				"3fe15222-1234-401f-8f5e-582aba2a002f",
+				"3fe15222-1234-401f-8f5e-582aba2a0033",
+			},
+		},
+		{
+			InputStatus:                deployments.StatusQueryAborted,
+			InputDeploymentsCollection: someDeployments,
+			OutputError:                nil,
+			OutputID: []string{
+				"3fe15222-1234-401f-8f5e-582aba2a002a",
+				"3fe15222-1234-401f-8f5e-582aba2a0034",
+			},
+		},
+		{
+			InputStatus:                deployments.StatusQueryScheduled,
+			InputDeploymentsCollection: someDeployments,
+			OutputError:                nil,
+			OutputID: []string{
+				"3fe15222-1234-401f-8f5e-582aba2a0030",
+			},
+		},
+		{
+			InputStatus:                deployments.StatusQueryPaused,
+			InputDeploymentsCollection: someDeployments,
+			OutputError:                nil,
+			OutputID: []string{
+				"3fe15222-1234-401f-8f5e-582aba2a0031",
+				"3fe15222-1234-401f-8f5e-582aba2a0032",
 			},
 		},
 		{
@@ -888,6 +985,7 @@ func TestDeploymentStorageFindBy(t *testing.T) {
 				"3fe15222-0a41-401f-8f5e-582aba2a002c",
 				"44dd8822-eeb1-44db-a18e-f4f5acc43796",
 				"3fe15222-1234-401f-8f5e-582aba2a002a",
+				"3fe15222-1234-401f-8f5e-582aba2a0034",
 			},
 		},
 		{
@@ -907,6 +1005,11 @@ func TestDeploymentStorageFindBy(t *testing.T) {
 				"3fe15222-1234-401f-8f5e-582aba2a002f",
 				"44dd8822-eeb1-44db-a18e-f4f5acc43796",
 				"3fe15222-1234-401f-8f5e-582aba2a002a",
+				"3fe15222-1234-401f-8f5e-582aba2a0030",
+				"3fe15222-1234-401f-8f5e-582aba2a0031",
+				"3fe15222-1234-401f-8f5e-582aba2a0032",
+				"3fe15222-1234-401f-8f5e-582aba2a0033",
+				"3fe15222-1234-401f-8f5e-582aba2a0034",
 			},
 		},
 	}
@@ -930,7 +1033,7 @@ func TestDeploymentStorageFindBy(t *testing.T) {
 			assert.NoError(t, store.Insert(d))
 		}
 
-		deployments, err := store.Find(deployments.Query{
+		deployments, total, err := store.Find(deployments.Query{
 			SearchText: testCase.InputName,
 			Status:     testCase.InputStatus,
 		})
@@ -940,6 +1043,7 @@ func TestDeploymentStorageFindBy(t *testing.T) {
 		} else {
 			assert.NoError(t, err)
 			assert.Len(t, deployments, len(testCase.OutputID))
+			assert.Equal(t, len(testCase.OutputID), total)
 			for _, dep := range deployments {
 				assert.Contains(t, testCase.OutputID, *dep.Id,
 					"got unexpected deployment %s", *dep.Id)
@@ -1008,3 +1112,502 @@ func TestDeploymentFinish(t *testing.T) {
 		session.Close()
 	}
 }
+
+func TestDeploymentUpdateAbortReason(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestDeploymentUpdateAbortReason in short mode.")
+	}
+
+	testCases := map[string]struct {
+		InputID         string
+		InputDeployment *deployments.Deployment
+
+		OutputError error
+	}{
+		"updated": {
+			InputID: "a108ae14-bb4e-455f-9b40-2ef4bab97bb7",
+			InputDeployment: &deployments.Deployment{
+				Id: StringToPointer("a108ae14-bb4e-455f-9b40-2ef4bab97bb7"),
+			},
+		},
+		"nonexistent": {
+			InputID:     "a108ae14-bb4e-455f-9b40-2ef4bab97bb7",
+			OutputError: ErrStorageInvalidID,
+		},
+		"empty id": {
+			OutputError: ErrStorageInvalidID,
+		},
+	}
+
+	for id, tc := range testCases {
+		t.Logf("testing case %s", id)
+
+		db.Wipe()
+
+		session := db.Session()
+		store := NewDeploymentsStorage(session)
+
+		dep := session.DB(DatabaseName).C(CollectionDeployments)
+		if tc.InputDeployment != nil {
+			assert.NoError(t, dep.Insert(tc.InputDeployment))
+		}
+
+		err := store.UpdateAbortReason(tc.InputID, "too many failures")
+
+		if tc.OutputError != nil {
+			assert.EqualError(t, err, tc.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+
+			var deployment *deployments.Deployment
+			err := dep.FindId(tc.InputID).One(&deployment)
+			assert.NoError(t, err)
+
+			if assert.NotNil(t, deployment.AbortReason) {
+				assert.Equal(t, "too many failures", *deployment.AbortReason)
+			}
+		}
+
+		// Need to close all sessions to be able to call wipe at next test case
+		session.Close()
+	}
+}
+
+func TestDeploymentUpdatePaused(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestDeploymentUpdatePaused in short mode.")
+	}
+
+	testCases := map[string]struct {
+		InputID         string
+		InputDeployment *deployments.Deployment
+
+		OutputError error
+	}{
+		"updated": {
+			InputID: "a108ae14-bb4e-455f-9b40-2ef4bab97bb7",
+			InputDeployment: &deployments.Deployment{
+				Id: StringToPointer("a108ae14-bb4e-455f-9b40-2ef4bab97bb7"),
+			},
+		},
+		"nonexistent": {
+			InputID:     "a108ae14-bb4e-455f-9b40-2ef4bab97bb7",
+			OutputError: ErrStorageInvalidID,
+		},
+		"empty id": {
+			OutputError: ErrStorageInvalidID,
+		},
+	}
+
+	for id, tc := range testCases {
+		t.Logf("testing case %s", id)
+
+		db.Wipe()
+
+		session := db.Session()
+		store := NewDeploymentsStorage(session)
+
+		dep := session.DB(DatabaseName).C(CollectionDeployments)
+		if tc.InputDeployment != nil {
+			assert.NoError(t, dep.Insert(tc.InputDeployment))
+		}
+
+		err := store.UpdatePaused(tc.InputID, true)
+
+		if tc.OutputError != nil {
+			assert.EqualError(t, err, tc.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+
+			var deployment *deployments.Deployment
+			assert.NoError(t, dep.FindId(tc.InputID).One(&deployment))
+			assert.True(t, deployment.Paused)
+
+			assert.NoError(t, store.UpdatePaused(tc.InputID, false))
+			assert.NoError(t, dep.FindId(tc.InputID).One(&deployment))
+			assert.False(t, deployment.Paused)
+		}
+
+		// Need to close all sessions to be able to call wipe at next test case
+		session.Close()
+	}
+}
+
+func TestDeploymentInProgressSlots(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestDeploymentInProgressSlots in short mode.")
+	}
+
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeploymentsStorage(session)
+
+	id := "a108ae14-bb4e-455f-9b40-2ef4bab97bb7"
+	dep := session.DB(DatabaseName).C(CollectionDeployments)
+	assert.NoError(t, dep.Insert(&deployments.Deployment{
+		Id: StringToPointer(id),
+	}))
+
+	// slots are taken until the limit is reached
+	for i := 0; i < 2; i++ {
+		acquired, err := store.AcquireInProgressSlot(id, 2)
+		assert.NoError(t, err)
+		assert.True(t, acquired)
+	}
+	acquired, err := store.AcquireInProgressSlot(id, 2)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	// released slot can be taken again
+	assert.NoError(t, store.ReleaseInProgressSlot(id))
+	acquired, err = store.AcquireInProgressSlot(id, 2)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	// counter does not go below zero
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, store.ReleaseInProgressSlot(id))
+	}
+	var deployment *deployments.Deployment
+	assert.NoError(t, dep.FindId(id).One(&deployment))
+	assert.Equal(t, 0, deployment.InProgress)
+
+	// unknown deployment has no slots
+	acquired, err = store.AcquireInProgressSlot("b108ae14-bb4e-455f-9b40-2ef4bab97bb7", 2)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+
+	_, err = store.AcquireInProgressSlot("", 2)
+	assert.EqualError(t, err, ErrStorageInvalidID.Error())
+	assert.EqualError(t, store.ReleaseInProgressSlot(""), ErrStorageInvalidID.Error())
+}
+
+func TestDeploymentStorageFindExpired(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestDeploymentStorageFindExpired in short mode.")
+	}
+
+	now := time.Now()
+
+	newDeployment := func(id string, expiresAt *time.Time, stats deployments.Stats) *deployments.Deployment {
+		return &deployments.Deployment{
+			DeploymentConstructor: &deployments.DeploymentConstructor{
+				Name:         StringToPointer("foo"),
+				ArtifactName: StringToPointer("bar"),
+				Devices:      []string{"b532b01a-9313-404f-8d19-e7fcbe5cc347"},
+				ExpiresAt:    expiresAt,
+			},
+			Id:      StringToPointer(id),
+			Created: &now,
+			Stats:   newTestStats(stats),
+		}
+	}
+
+	input := []*deployments.Deployment{
+		// no expiry
+		newDeployment("a108ae14-bb4e-455f-9b40-2ef4bab97bb7", nil, deployments.Stats{
+			deployments.DeviceDeploymentStatusPending: 1,
+		}),
+		// expiry ahead
+		newDeployment("d1804903-5caa-4a73-a3ae-0efcc3205405", TimeToPointer(now.Add(time.Hour)),
+			deployments.Stats{
+				deployments.DeviceDeploymentStatusPending: 1,
+			}),
+		// expired
+		newDeployment("e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130", TimeToPointer(now.Add(-time.Hour)),
+			deployments.Stats{
+				deployments.DeviceDeploymentStatusPending:     1,
+				deployments.DeviceDeploymentStatusDownloading: 1,
+			}),
+		// expired, no pending devices left
+		newDeployment("3fe15222-0a41-401f-8f5e-582aba2a002c", TimeToPointer(now.Add(-time.Hour)),
+			deployments.Stats{
+				deployments.DeviceDeploymentStatusExpired: 1,
+				deployments.DeviceDeploymentStatusSuccess: 1,
+			}),
+	}
+
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeploymentsStorage(session)
+
+	for _, d := range input {
+		assert.NoError(t, store.Insert(d))
+	}
+
+	expired, err := store.FindExpired(now)
+	assert.NoError(t, err)
+	if assert.Len(t, expired, 1) {
+		assert.Equal(t, "e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130", *expired[0].Id)
+	}
+
+	// deployment with expired devices only is finished
+	finished, _, err := store.Find(deployments.Query{Status: deployments.StatusQueryFinished})
+	assert.NoError(t, err)
+	if assert.Len(t, finished, 1) {
+		assert.Equal(t, "3fe15222-0a41-401f-8f5e-582aba2a002c", *finished[0].Id)
+	}
+}
+
+func TestDeploymentStorageFindPaging(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestDeploymentStorageFindPaging in short mode.")
+	}
+
+	now := time.Now()
+
+	newDeployment := func(id string, name string, created time.Time) *deployments.Deployment {
+		return &deployments.Deployment{
+			DeploymentConstructor: &deployments.DeploymentConstructor{
+				Name:         StringToPointer(name),
+				ArtifactName: StringToPointer("bar"),
+				Devices:      []string{"b532b01a-9313-404f-8d19-e7fcbe5cc347"},
+			},
+			Id:      StringToPointer(id),
+			Created: &created,
+			Stats:   newTestStats(deployments.Stats{}),
+		}
+	}
+
+	input := []*deployments.Deployment{
+		newDeployment("a108ae14-bb4e-455f-9b40-2ef4bab97bb7", "b", now.Add(-time.Hour)),
+		newDeployment("d1804903-5caa-4a73-a3ae-0efcc3205405", "c", now.Add(-2*time.Hour)),
+		newDeployment("e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130", "a", now),
+	}
+
+	testCases := map[string]struct {
+		page paging.Query
+
+		ids []string
+	}{
+		"all, oldest first": {
+			ids: []string{
+				"d1804903-5caa-4a73-a3ae-0efcc3205405",
+				"a108ae14-bb4e-455f-9b40-2ef4bab97bb7",
+				"e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130",
+			},
+		},
+		"first page, newest first": {
+			page: paging.Query{Page: 1, PerPage: 2,
+				Sort: deployments.SortFieldCreated, SortDesc: true},
+			ids: []string{
+				"e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130",
+				"a108ae14-bb4e-455f-9b40-2ef4bab97bb7",
+			},
+		},
+		"second page by name": {
+			page: paging.Query{Page: 2, PerPage: 2, Sort: deployments.SortFieldName},
+			ids: []string{
+				"d1804903-5caa-4a73-a3ae-0efcc3205405",
+			},
+		},
+	}
+
+	db.Wipe()
+	session := db.Session()
+	defer session.Close()
+	store := NewDeploymentsStorage(session)
+
+	for _, d := range input {
+		assert.NoError(t, store.Insert(d))
+	}
+
+	for name, tc := range testCases {
+		t.Logf("testing case %s", name)
+
+		found, total, err := store.Find(deployments.Query{Paging: tc.page})
+		assert.NoError(t, err)
+		assert.Equal(t, len(input), total)
+
+		ids := []string{}
+		for _, d := range found {
+			ids = append(ids, *d.Id)
+		}
+		assert.Equal(t, tc.ids, ids)
+	}
+}
+
+func TestDeploymentStorageFindFilters(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestDeploymentStorageFindFilters in short mode.")
+	}
+
+	now := time.Now().Round(time.Millisecond)
+	weekAgo := now.Add(-7 * 24 * time.Hour)
+
+	newDeployment := func(id string, artifact string, created time.Time,
+		finished *time.Time, creator *string) *deployments.Deployment {
+
+		return &deployments.Deployment{
+			DeploymentConstructor: &deployments.DeploymentConstructor{
+				Name:         StringToPointer("foo"),
+				ArtifactName: StringToPointer(artifact),
+				Devices:      []string{"b532b01a-9313-404f-8d19-e7fcbe5cc347"},
+			},
+			Id:        StringToPointer(id),
+			Created:   &created,
+			Finished:  finished,
+			CreatedBy: creator,
+			Stats:     newTestStats(deployments.Stats{}),
+		}
+	}
+
+	input := []*deployments.Deployment{
+		newDeployment("a108ae14-bb4e-455f-9b40-2ef4bab97bb7", "release-1",
+			weekAgo.Add(-time.Hour), TimeToPointer(weekAgo), StringToPointer("alice")),
+		newDeployment("d1804903-5caa-4a73-a3ae-0efcc3205405", "release-2",
+			weekAgo.Add(time.Hour), TimeToPointer(now.Add(-time.Hour)), StringToPointer("bob")),
+		newDeployment("e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130", "release-2",
+			now.Add(-time.Hour), nil, nil),
+	}
+
+	db.Wipe()
+	session := db.Session()
+	defer session.Close()
+	store := NewDeploymentsStorage(session)
+	devStore := NewDeviceDeploymentsStorage(session)
+
+	assert.NoError(t, store.IndexStorage())
+	for _, d := range input {
+		assert.NoError(t, store.Insert(d))
+	}
+	assert.NoError(t, devStore.InsertMany(
+		deployments.NewDeviceDeployment("device-1", "a108ae14-bb4e-455f-9b40-2ef4bab97bb7"),
+		deployments.NewDeviceDeployment("device-1", "e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130"),
+		deployments.NewDeviceDeployment("device-2", "d1804903-5caa-4a73-a3ae-0efcc3205405"),
+	))
+
+	testCases := map[string]struct {
+		query deployments.Query
+
+		ids []string
+	}{
+		"created last week": {
+			query: deployments.Query{CreatedAfter: &weekAgo},
+			ids: []string{
+				"d1804903-5caa-4a73-a3ae-0efcc3205405",
+				"e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130",
+			},
+		},
+		"created before a week": {
+			query: deployments.Query{CreatedBefore: &weekAgo},
+			ids: []string{
+				"a108ae14-bb4e-455f-9b40-2ef4bab97bb7",
+			},
+		},
+		"finished within range": {
+			query: deployments.Query{
+				FinishedAfter:  &weekAgo,
+				FinishedBefore: &now,
+			},
+			ids: []string{
+				"a108ae14-bb4e-455f-9b40-2ef4bab97bb7",
+				"d1804903-5caa-4a73-a3ae-0efcc3205405",
+			},
+		},
+		"artifact name": {
+			query: deployments.Query{ArtifactName: "release-2"},
+			ids: []string{
+				"d1804903-5caa-4a73-a3ae-0efcc3205405",
+				"e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130",
+			},
+		},
+		"device": {
+			query: deployments.Query{DeviceID: "device-1"},
+			ids: []string{
+				"a108ae14-bb4e-455f-9b40-2ef4bab97bb7",
+				"e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130",
+			},
+		},
+		"unknown device": {
+			query: deployments.Query{DeviceID: "device-3"},
+			ids:   []string{},
+		},
+		"creator and artifact": {
+			query: deployments.Query{CreatedBy: "bob", ArtifactName: "release-2"},
+			ids: []string{
+				"d1804903-5caa-4a73-a3ae-0efcc3205405",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Logf("testing case %s", name)
+
+		found, total, err := store.Find(tc.query)
+		assert.NoError(t, err)
+		assert.Equal(t, len(tc.ids), total)
+
+		ids := []string{}
+		for _, d := range found {
+			ids = append(ids, *d.Id)
+		}
+		assert.Equal(t, tc.ids, ids)
+	}
+}
+
+func TestDeploymentStorageFindFinishedBeforeAndArchive(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestDeploymentStorageFindFinishedBeforeAndArchive in short mode.")
+	}
+
+	now := time.Now()
+
+	newDeployment := func(id string, finished *time.Time) *deployments.Deployment {
+		return &deployments.Deployment{
+			DeploymentConstructor: &deployments.DeploymentConstructor{
+				Name:         StringToPointer("foo"),
+				ArtifactName: StringToPointer("bar"),
+				Devices:      []string{"b532b01a-9313-404f-8d19-e7fcbe5cc347"},
+			},
+			Id:       StringToPointer(id),
+			Created:  &now,
+			Finished: finished,
+			Stats:    newTestStats(deployments.Stats{}),
+		}
+	}
+
+	input := []*deployments.Deployment{
+		// unfinished
+		newDeployment("a108ae14-bb4e-455f-9b40-2ef4bab97bb7", nil),
+		// finished recently
+		newDeployment("d1804903-5caa-4a73-a3ae-0efcc3205405", TimeToPointer(now.Add(-time.Hour))),
+		// finished long ago
+		newDeployment("e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130", TimeToPointer(now.Add(-100*24*time.Hour))),
+	}
+
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeploymentsStorage(session)
+
+	for _, d := range input {
+		assert.NoError(t, store.Insert(d))
+	}
+
+	old, err := store.FindFinishedBefore(now.Add(-24 * time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, old, 1) {
+		assert.Equal(t, "e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130", *old[0].Id)
+
+		// archiving is repeatable
+		assert.NoError(t, store.Archive(old[0]))
+		assert.NoError(t, store.Archive(old[0]))
+
+		var archived []*deployments.Deployment
+		err := session.DB(DatabaseName).C(CollectionDeploymentsArchive).Find(nil).All(&archived)
+		assert.NoError(t, err)
+		if assert.Len(t, archived, 1) {
+			assert.Equal(t, "e8c32ff6-7c1b-43c7-aa31-2e4fc3a3c130", *archived[0].Id)
+			assert.Equal(t, "foo", *archived[0].Name)
+		}
+	}
+
+	assert.EqualError(t, store.Archive(&deployments.Deployment{}),
+		ErrDeploymentStorageInvalidDeployment.Error())
+}
@@ -15,6 +15,7 @@
 package mongo
 
 import (
+	"github.com/asaskevich/govalidator"
 	"github.com/mendersoftware/deployments/resources/deployments"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -89,3 +90,20 @@ func (d *DeviceDeploymentLogsStorage) GetDeviceDeploymentLog(deviceID, deploymen
 
 	return &depl, nil
 }
+
+// DeleteDeviceDeploymentLogs removes logs of all devices of a deployment.
+func (d *DeviceDeploymentLogsStorage) DeleteDeviceDeploymentLogs(deploymentID string) error {
+	if govalidator.IsNull(deploymentID) {
+		return ErrStorageInvalidID
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	query := bson.M{
+		StorageKeyDeviceDeploymentDeploymentID: deploymentID,
+	}
+
+	_, err := session.DB(DatabaseName).C(CollectionDeviceDeploymentLogs).RemoveAll(query)
+	return err
+}
@@ -238,3 +238,47 @@ func TestGetDeviceDeploymentLog(t *testing.T) {
 
 	db.Wipe()
 }
+
+func TestDeleteDeviceDeploymentLogs(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestDeleteDeviceDeploymentLogs in short mode.")
+	}
+
+	deploymentID := "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"
+	otherDeploymentID := "30b3e62c-9ec2-4312-a7fa-cff24cc7397b"
+
+	messages := []deployments.LogMessage{
+		{
+			Level:     "notice",
+			Message:   "foo",
+			Timestamp: parseTime(t, "2006-01-02T15:04:05-07:00"),
+		},
+	}
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentLogsStorage(session)
+
+	for _, log := range []deployments.DeploymentLog{
+		{DeviceID: "123", DeploymentID: deploymentID, Messages: messages},
+		{DeviceID: "234", DeploymentID: deploymentID, Messages: messages},
+		{DeviceID: "123", DeploymentID: otherDeploymentID, Messages: messages},
+	} {
+		assert.NoError(t, store.SaveDeviceDeploymentLog(log))
+	}
+
+	assert.EqualError(t, store.DeleteDeviceDeploymentLogs(""), ErrStorageInvalidID.Error())
+	assert.NoError(t, store.DeleteDeviceDeploymentLogs(deploymentID))
+
+	log, err := store.GetDeviceDeploymentLog("123", deploymentID)
+	assert.NoError(t, err)
+	assert.Nil(t, log)
+
+	log, err = store.GetDeviceDeploymentLog("123", otherDeploymentID)
+	assert.NoError(t, err)
+	assert.NotNil(t, log)
+}
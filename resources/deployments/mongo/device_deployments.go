@@ -20,6 +20,7 @@ import (
 	"github.com/asaskevich/govalidator"
 	"github.com/mendersoftware/deployments/resources/deployments"
 	imagesMongo "github.com/mendersoftware/deployments/resources/images/mongo"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/pkg/errors"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
@@ -39,6 +40,31 @@ const (
 	StorageKeyDeviceDeploymentDeploymentID    = "deploymentid"
 	StorageKeyDeviceDeploymentFinished        = "finished"
 	StorageKeyDeviceDeploymentIsLogAvailable  = "log"
+	StorageKeyDeviceDeploymentPhase           = "phase"
+	StorageKeyDeviceDeploymentRetries         = "retries"
+	StorageKeyDeviceDeploymentRetryAfter      = "retryafter"
+	StorageKeyDeviceDeploymentCreated         = "created"
+	StorageKeyDeviceDeploymentUpdated         = "updated"
+	StorageKeyDeviceDeploymentPriority        = "priority"
+	StorageKeyDeviceDeploymentHistory         = "history"
+	StorageKeyDeviceDeploymentSubState        = "substate"
+	StorageKeyDeviceDeploymentProgress        = "progress"
+	StorageKeyDeviceDeploymentFailureReason   = "failurereason"
+	StorageKeyDeviceDeploymentFailureCode     = StorageKeyDeviceDeploymentFailureReason + ".code"
+	StorageKeyDeviceDeploymentDeviceType      = "devicetype"
+)
+
+// Storage keys of fields device deployments can be sorted by
+var deviceDeploymentSortKeys = map[string]string{
+	deployments.SortFieldDeviceID: StorageKeyDeviceDeploymentDeviceId,
+	deployments.SortFieldStatus:   StorageKeyDeviceDeploymentStatus,
+	deployments.SortFieldCreated:  StorageKeyDeviceDeploymentCreated,
+	deployments.SortFieldFinished: StorageKeyDeviceDeploymentFinished,
+}
+
+// Indexes
+const (
+	IndexDeviceIDStatusPriorityStr = "deviceIdStatusPriorityIndex"
 )
 
 // Errors
@@ -119,7 +145,47 @@ func (d *DeviceDeploymentsStorage) ExistAssignedImageWithIDAndStatuses(imageID s
 	return true, nil
 }
 
-// FindOldestDeploymentForDeviceIDWithStatuses find oldest deployment matching device id and one of specified statuses.
+// IndexStorage set required indexes.
+// * Set index supporting search for the next deployment of a device.
+func (d *DeviceDeploymentsStorage) IndexStorage() error {
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	deviceIDStatusPriorityIndex := mgo.Index{
+		Key: []string{
+			StorageKeyDeviceDeploymentDeviceId,
+			StorageKeyDeviceDeploymentStatus,
+			"-" + StorageKeyDeviceDeploymentPriority,
+			StorageKeyDeviceDeploymentCreated,
+		},
+		Name:       IndexDeviceIDStatusPriorityStr,
+		Background: true,
+	}
+
+	return session.DB(DatabaseName).C(CollectionDevices).EnsureIndex(deviceIDStatusPriorityIndex)
+}
+
+// MigrateMissingPriority sets the default priority on device deployments
+// created before priorities were introduced, as missing priority would sort
+// below any set one. Returns number of updated device deployments.
+func (d *DeviceDeploymentsStorage) MigrateMissingPriority() (int, error) {
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	info, err := session.DB(DatabaseName).C(CollectionDevices).UpdateAll(
+		bson.M{StorageKeyDeviceDeploymentPriority: bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{StorageKeyDeviceDeploymentPriority: 0}})
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Updated, nil
+}
+
+// FindOldestDeploymentForDeviceIDWithStatuses find deployment matching device id and one of specified statuses
+// with the highest priority, oldest one among deployments of the same priority.
 func (d *DeviceDeploymentsStorage) FindOldestDeploymentForDeviceIDWithStatuses(deviceID string, statuses ...string) (*deployments.DeviceDeployment, error) {
 
 	// Verify ID formatting
@@ -136,9 +202,11 @@ func (d *DeviceDeploymentsStorage) FindOldestDeploymentForDeviceIDWithStatuses(d
 		StorageKeyDeviceDeploymentStatus:   bson.M{"$in": statuses},
 	}
 
-	// Select only the oldest one that have not been finished yet.
+	// Select only the most important one that have not been finished yet.
 	var deployment *deployments.DeviceDeployment
-	if err := session.DB(DatabaseName).C(CollectionDevices).Find(query).Sort("created").One(&deployment); err != nil {
+	if err := session.DB(DatabaseName).C(CollectionDevices).Find(query).
+		Sort("-"+StorageKeyDeviceDeploymentPriority, StorageKeyDeviceDeploymentCreated).
+		One(&deployment); err != nil {
 		if err.Error() == mgo.ErrNotFound.Error() {
 			return nil, nil
 		}
@@ -148,7 +216,37 @@ func (d *DeviceDeploymentsStorage) FindOldestDeploymentForDeviceIDWithStatuses(d
 	return deployment, nil
 }
 
-func (d *DeviceDeploymentsStorage) UpdateDeviceDeploymentStatus(deviceID string, deploymentID string, status string, finishTime *time.Time) (string, error) {
+// FindDeploymentsForDeviceIDWithStatuses finds deployments matching device id
+// and one of specified statuses, ordered the same way as
+// FindOldestDeploymentForDeviceIDWithStatuses does.
+func (d *DeviceDeploymentsStorage) FindDeploymentsForDeviceIDWithStatuses(deviceID string, statuses ...string) ([]*deployments.DeviceDeployment, error) {
+
+	// Verify ID formatting
+	if govalidator.IsNull(deviceID) {
+		return nil, ErrStorageInvalidID
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	query := bson.M{
+		StorageKeyDeviceDeploymentDeviceId: deviceID,
+		StorageKeyDeviceDeploymentStatus:   bson.M{"$in": statuses},
+	}
+
+	var deviceDeployments []*deployments.DeviceDeployment
+	if err := session.DB(DatabaseName).C(CollectionDevices).Find(query).
+		Sort("-"+StorageKeyDeviceDeploymentPriority, StorageKeyDeviceDeploymentCreated).
+		All(&deviceDeployments); err != nil {
+		return nil, err
+	}
+
+	return deviceDeployments, nil
+}
+
+func (d *DeviceDeploymentsStorage) UpdateDeviceDeploymentStatus(deviceID string, deploymentID string, state deployments.DeviceDeploymentState, finishTime *time.Time) (string, error) {
+
+	status := state.Status
 
 	// Verify ID formatting
 	if govalidator.IsNull(deviceID) ||
@@ -161,42 +259,119 @@ func (d *DeviceDeploymentsStorage) UpdateDeviceDeploymentStatus(deviceID string,
 	defer session.Close()
 
 	// Device should know only about deployments that are not finished
+	// Only statuses allowed by the transition table can be changed.
 	query := bson.M{
 		StorageKeyDeviceDeploymentDeviceId:     deviceID,
 		StorageKeyDeviceDeploymentDeploymentID: deploymentID,
+		StorageKeyDeviceDeploymentStatus: bson.M{
+			"$in": deployments.DeviceDeploymentStatusesAllowingTransitionTo(status),
+		},
 	}
 
+	now := time.Now()
+
 	// update status field
 	set := bson.M{
-		StorageKeyDeviceDeploymentStatus: status,
+		StorageKeyDeviceDeploymentStatus:  status,
+		StorageKeyDeviceDeploymentUpdated: now,
 	}
 	// and finish time if provided
 	if finishTime != nil {
 		set[StorageKeyDeviceDeploymentFinished] = finishTime
 	}
 
+	// substate, progress and failure reason are replaced by every update,
+	// details of previous report are removed if the new one does not carry them
+	unset := bson.M{}
+	if state.SubState != nil {
+		set[StorageKeyDeviceDeploymentSubState] = *state.SubState
+	} else {
+		unset[StorageKeyDeviceDeploymentSubState] = ""
+	}
+	if state.Progress != nil {
+		set[StorageKeyDeviceDeploymentProgress] = *state.Progress
+	} else {
+		unset[StorageKeyDeviceDeploymentProgress] = ""
+	}
+	if state.FailureReason != nil {
+		set[StorageKeyDeviceDeploymentFailureReason] = state.FailureReason
+	} else {
+		unset[StorageKeyDeviceDeploymentFailureReason] = ""
+	}
+
 	update := bson.M{
 		"$set": set,
 	}
-
-	var old deployments.DeviceDeployment
-
-	// update and return the old status in one go
-	change := mgo.Change{
-		Update: update,
+	if len(unset) > 0 {
+		update["$unset"] = unset
 	}
 
-	chi, err := session.DB(DatabaseName).C(CollectionDevices).Find(query).Apply(change, &old)
-
+	previous, err := d.updateStatus(session, deviceID, deploymentID, query, update, status, now)
+	if err == mgo.ErrNotFound && previous != "" {
+		// distinguish missing device deployment from invalid transition
+		return "", &deployments.StatusTransitionError{From: previous, To: status}
+	}
 	if err != nil {
 		return "", err
 	}
 
-	if chi.Updated == 0 {
-		return "", mgo.ErrNotFound
-	}
+	return previous, nil
+}
+
+// updateStatus applies update moving device deployment matching the selector
+// to status `to`, and records the change in its history by the same write.
+// The status being replaced is matched exactly, so that the recorded change
+// is the one applied; it is read again if it changes in the meantime.
+// Returns the status the device deployment was updated from. If the selector
+// does not match, returns mgo.ErrNotFound together with the current status,
+// empty if the device deployment does not exist.
+func (d *DeviceDeploymentsStorage) updateStatus(session *mgo.Session, deviceID, deploymentID string,
+	selector bson.M, update bson.M, to string, now time.Time) (string, error) {
+
+	current, err := d.GetDeviceDeploymentStatus(deploymentID, deviceID)
+	for {
+		if err != nil {
+			return "", err
+		}
+		if current == "" {
+			return "", mgo.ErrNotFound
+		}
+
+		query := bson.M{
+			"$and": []bson.M{
+				selector,
+				bson.M{StorageKeyDeviceDeploymentStatus: current},
+			},
+		}
 
-	return *old.Status, nil
+		change := bson.M{}
+		for op, fields := range update {
+			change[op] = fields
+		}
+		// repeated reports of the same status are not a change
+		if current != to {
+			change["$push"] = bson.M{
+				StorageKeyDeviceDeploymentHistory: deployments.DeviceDeploymentStatusChange{
+					Previous:  current,
+					Status:    to,
+					Timestamp: now,
+				},
+			}
+		}
+
+		err = session.DB(DatabaseName).C(CollectionDevices).Update(query, change)
+		if err != mgo.ErrNotFound {
+			return current, err
+		}
+
+		// either the selector does not match, or the status changed
+		var latest string
+		latest, err = d.GetDeviceDeploymentStatus(deploymentID, deviceID)
+		if err == nil && latest == current {
+			return current, mgo.ErrNotFound
+		}
+		current = latest
+	}
 }
 
 func (d *DeviceDeploymentsStorage) UpdateDeviceDeploymentLogAvailability(
@@ -228,19 +403,226 @@ func (d *DeviceDeploymentsStorage) UpdateDeviceDeploymentLogAvailability(
 	return nil
 }
 
+// RetryDeviceDeployment puts device deployment back to pending status and
+// increments its retry counter, unless the deployment can not fail anymore
+// (e.g. was aborted) or `maxRetries` retries were already done.
+// Returns status the device deployment was retried from, or empty string if
+// not retried.
+func (d *DeviceDeploymentsStorage) RetryDeviceDeployment(deviceID string, deploymentID string,
+	maxRetries int, retryAfter *time.Time) (string, error) {
+
+	// Verify ID formatting
+	if govalidator.IsNull(deviceID) ||
+		govalidator.IsNull(deploymentID) {
+		return "", ErrStorageInvalidID
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	selector := bson.M{
+		StorageKeyDeviceDeploymentDeviceId:     deviceID,
+		StorageKeyDeviceDeploymentDeploymentID: deploymentID,
+		// retry replaces transition to failure
+		StorageKeyDeviceDeploymentStatus: bson.M{
+			"$in": deployments.DeviceDeploymentStatusesAllowingTransitionTo(
+				deployments.DeviceDeploymentStatusFailure),
+		},
+		// matches also deployments created before retry counter was introduced
+		StorageKeyDeviceDeploymentRetries: bson.M{
+			"$not": bson.M{"$gte": maxRetries},
+		},
+	}
+
+	now := time.Now()
+
+	update := bson.M{
+		"$set": bson.M{
+			StorageKeyDeviceDeploymentStatus:     deployments.DeviceDeploymentStatusPending,
+			StorageKeyDeviceDeploymentRetryAfter: retryAfter,
+			StorageKeyDeviceDeploymentUpdated:    now,
+		},
+		"$inc": bson.M{
+			StorageKeyDeviceDeploymentRetries: 1,
+		},
+	}
+
+	previous, err := d.updateStatus(session, deviceID, deploymentID, selector, update,
+		deployments.DeviceDeploymentStatusPending, now)
+	if err == mgo.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return previous, nil
+}
+
+// FindStaleDeviceDeployments returns device deployments with one of given
+// statuses, which did not change status since `before`.
+func (d *DeviceDeploymentsStorage) FindStaleDeviceDeployments(before time.Time,
+	statuses ...string) ([]*deployments.DeviceDeployment, error) {
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	query := bson.M{
+		StorageKeyDeviceDeploymentStatus: bson.M{"$in": statuses},
+		"$or": []bson.M{
+			bson.M{
+				StorageKeyDeviceDeploymentUpdated: bson.M{"$lt": before},
+			},
+			// status never updated
+			bson.M{
+				StorageKeyDeviceDeploymentUpdated: bson.M{"$exists": false},
+				StorageKeyDeviceDeploymentCreated: bson.M{"$lt": before},
+			},
+		},
+	}
+
+	var stale []*deployments.DeviceDeployment
+	if err := session.DB(DatabaseName).C(CollectionDevices).Find(query).All(&stale); err != nil {
+		return nil, err
+	}
+
+	return stale, nil
+}
+
+// FindDeviceDeploymentsWithStatuses returns device deployments of given
+// deployment with one of given statuses.
+func (d *DeviceDeploymentsStorage) FindDeviceDeploymentsWithStatuses(deploymentID string,
+	statuses ...string) ([]*deployments.DeviceDeployment, error) {
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	query := bson.M{
+		StorageKeyDeviceDeploymentDeploymentID: deploymentID,
+		StorageKeyDeviceDeploymentStatus:       bson.M{"$in": statuses},
+	}
+
+	var found []*deployments.DeviceDeployment
+	if err := session.DB(DatabaseName).C(CollectionDevices).Find(query).All(&found); err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// FindDeviceDeploymentsForDevices returns device deployments of any of given
+// devices with one of given statuses.
+func (d *DeviceDeploymentsStorage) FindDeviceDeploymentsForDevices(deviceIDs []string,
+	statuses ...string) ([]*deployments.DeviceDeployment, error) {
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	query := bson.M{
+		StorageKeyDeviceDeploymentDeviceId: bson.M{"$in": deviceIDs},
+		StorageKeyDeviceDeploymentStatus:   bson.M{"$in": statuses},
+	}
+
+	var found []*deployments.DeviceDeployment
+	if err := session.DB(DatabaseName).C(CollectionDevices).Find(query).All(&found); err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// FindDeviceDeploymentsForDevice returns a page of device deployments of
+// given device, newest first, together with total number of matching ones.
+func (d *DeviceDeploymentsStorage) FindDeviceDeploymentsForDevice(deviceID string,
+	query deployments.DeviceDeploymentHistoryQuery) ([]*deployments.DeviceDeployment, int, error) {
+
+	if govalidator.IsNull(deviceID) {
+		return nil, 0, ErrStorageInvalidID
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	filter := bson.M{
+		StorageKeyDeviceDeploymentDeviceId: deviceID,
+	}
+	if query.Status != "" {
+		filter[StorageKeyDeviceDeploymentStatus] = query.Status
+	}
+
+	total, err := session.DB(DatabaseName).C(CollectionDevices).Find(filter).Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var devDeps []*deployments.DeviceDeployment
+	err = session.DB(DatabaseName).C(CollectionDevices).Find(filter).
+		Sort("-" + StorageKeyDeviceDeploymentCreated).
+		Skip(query.Paging.Skip()).Limit(query.Paging.PerPage).
+		All(&devDeps)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return devDeps, total, nil
+}
+
 func (d *DeviceDeploymentsStorage) AggregateDeviceDeploymentByStatus(id string) (deployments.Stats, error) {
 
+	statistics, err := d.AggregateDeviceDeploymentStatistics(id)
+	if err != nil || statistics == nil {
+		return nil, err
+	}
+
+	return statistics.Stats, nil
+}
+
+// AggregateDeviceDeploymentStatistics aggregates statuses of device
+// deployments of the deployment together with their installation progress.
+func (d *DeviceDeploymentsStorage) AggregateDeviceDeploymentStatistics(id string) (*deployments.DeploymentStatistics, error) {
+
+	if govalidator.IsNull(id) {
+		return nil, ErrStorageInvalidID
+	}
+
+	return d.aggregateDeviceDeployments(bson.M{
+		StorageKeyDeviceDeploymentDeploymentID: id,
+	})
+}
+
+// AggregateDeviceDeploymentByStatusForPhase aggregates statuses of device
+// deployments belonging to given phase of the deployment.
+func (d *DeviceDeploymentsStorage) AggregateDeviceDeploymentByStatusForPhase(id string, phase int) (deployments.Stats, error) {
+
 	if govalidator.IsNull(id) {
 		return nil, ErrStorageInvalidID
 	}
 
+	filter := bson.M{
+		StorageKeyDeviceDeploymentDeploymentID: id,
+		StorageKeyDeviceDeploymentPhase:        phase,
+	}
+	if phase == 0 {
+		// device deployments created before phases were introduced
+		// have no phase set
+		filter[StorageKeyDeviceDeploymentPhase] = bson.M{"$in": []interface{}{0, nil}}
+	}
+
+	statistics, err := d.aggregateDeviceDeployments(filter)
+	if err != nil || statistics == nil {
+		return nil, err
+	}
+
+	return statistics.Stats, nil
+}
+
+func (d *DeviceDeploymentsStorage) aggregateDeviceDeployments(filter bson.M) (*deployments.DeploymentStatistics, error) {
+
 	session := d.session.Copy()
 	defer session.Close()
 
 	match := bson.M{
-		"$match": bson.M{
-			StorageKeyDeviceDeploymentDeploymentID: id,
-		},
+		"$match": filter,
 	}
 	group := bson.M{
 		"$group": bson.M{
@@ -248,6 +630,25 @@ func (d *DeviceDeploymentsStorage) AggregateDeviceDeploymentByStatus(id string)
 			"count": bson.M{
 				"$sum": 1,
 			},
+			"retries": bson.M{
+				"$sum": "$" + StorageKeyDeviceDeploymentRetries,
+			},
+			// missing progress is skipped by $sum, count reporting devices separately
+			"progress": bson.M{
+				"$sum": "$" + StorageKeyDeviceDeploymentProgress,
+			},
+			"reporting": bson.M{
+				"$sum": bson.M{
+					"$cond": []interface{}{
+						bson.M{"$eq": []interface{}{
+							bson.M{"$ifNull": []interface{}{"$" + StorageKeyDeviceDeploymentProgress, -1}},
+							-1,
+						}},
+						0,
+						1,
+					},
+				},
+			},
 		},
 	}
 	pipe := []bson.M{
@@ -255,8 +656,11 @@ func (d *DeviceDeploymentsStorage) AggregateDeviceDeploymentByStatus(id string)
 		group,
 	}
 	var results []struct {
-		Name  string `bson:"_id"`
-		Count int
+		Name      string `bson:"_id"`
+		Count     int
+		Retries   int
+		Progress  int
+		Reporting int
 	}
 	err := session.DB(DatabaseName).C(CollectionDevices).Pipe(&pipe).All(&results)
 	if err != nil {
@@ -266,15 +670,91 @@ func (d *DeviceDeploymentsStorage) AggregateDeviceDeploymentByStatus(id string)
 		return nil, err
 	}
 
-	raw := deployments.NewDeviceDeploymentStats()
+	statistics := &deployments.DeploymentStatistics{
+		Stats: deployments.NewDeviceDeploymentStats(),
+	}
+	progress, reporting := 0, 0
 	for _, res := range results {
-		raw[res.Name] = res.Count
+		statistics.Stats[res.Name] = res.Count
+		statistics.Progress.Retries += res.Retries
+		if deployments.IsDeviceDeploymentStatusInProgress(res.Name) {
+			progress += res.Progress
+			reporting += res.Reporting
+		}
 	}
-	return raw, nil
+	if reporting > 0 {
+		statistics.Progress.Progress = progress / reporting
+	}
+	return statistics, nil
+}
+
+// AggregateDeviceDeploymentFailures counts failed device deployments of the
+// deployment grouped by failure reason code and device type, most common
+// failures first.
+func (d *DeviceDeploymentsStorage) AggregateDeviceDeploymentFailures(id string) ([]*deployments.FailureGroup, error) {
+
+	if govalidator.IsNull(id) {
+		return nil, ErrStorageInvalidID
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	pipe := []bson.M{
+		{
+			"$match": bson.M{
+				StorageKeyDeviceDeploymentDeploymentID: id,
+				StorageKeyDeviceDeploymentStatus:       deployments.DeviceDeploymentStatusFailure,
+			},
+		},
+		{
+			"$group": bson.M{
+				"_id": bson.M{
+					"code":       "$" + StorageKeyDeviceDeploymentFailureCode,
+					"devicetype": "$" + StorageKeyDeviceDeploymentDeviceType,
+				},
+				"count": bson.M{
+					"$sum": 1,
+				},
+			},
+		},
+		{
+			"$sort": bson.D{
+				{Name: "count", Value: -1},
+				{Name: "_id.code", Value: 1},
+				{Name: "_id.devicetype", Value: 1},
+			},
+		},
+	}
+	var results []struct {
+		Group struct {
+			Code       string
+			DeviceType string
+		} `bson:"_id"`
+		Count int
+	}
+	if err := session.DB(DatabaseName).C(CollectionDevices).Pipe(&pipe).All(&results); err != nil {
+		return nil, err
+	}
+
+	groups := make([]*deployments.FailureGroup, 0, len(results))
+	for _, res := range results {
+		groups = append(groups, &deployments.FailureGroup{
+			Code:       res.Group.Code,
+			DeviceType: res.Group.DeviceType,
+			Count:      res.Count,
+		})
+	}
+	return groups, nil
 }
 
 //GetDeviceStatusesForDeployment retrieve device deployment statuses for a given deployment.
-func (d *DeviceDeploymentsStorage) GetDeviceStatusesForDeployment(deploymentID string) ([]deployments.DeviceDeployment, error) {
+// Returns requested page of statuses, all of them if page is not set, together with
+// total number of devices of the deployment. Statuses are ordered by device id unless
+// other sort is requested.
+func (d *DeviceDeploymentsStorage) GetDeviceStatusesForDeployment(deploymentID string,
+	page paging.Query) ([]deployments.DeviceDeployment, int, error) {
+
 	session := d.session.Copy()
 	defer session.Close()
 
@@ -282,14 +762,23 @@ func (d *DeviceDeploymentsStorage) GetDeviceStatusesForDeployment(deploymentID s
 		StorageKeyDeviceDeploymentDeploymentID: deploymentID,
 	}
 
+	total, err := session.DB(DatabaseName).C(CollectionDevices).Find(query).Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
 	var statuses []deployments.DeviceDeployment
 
-	err := session.DB(DatabaseName).C(CollectionDevices).Find(query).All(&statuses)
+	err = session.DB(DatabaseName).C(CollectionDevices).Find(query).
+		Sort(page.SortKey(deviceDeploymentSortKeys, deployments.SortFieldDeviceID),
+			StorageKeyDeviceDeploymentDeviceId).
+		Skip(page.Skip()).Limit(page.PerPage).
+		All(&statuses)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return statuses, nil
+	return statuses, total, nil
 }
 
 // Returns true if deployment of ID `deploymentID` is assigned to device with ID
@@ -346,25 +835,57 @@ func (d *DeviceDeploymentsStorage) AbortDeviceDeployments(deploymentId string) e
 
 	session := d.session.Copy()
 	defer session.Close()
-	selector := bson.M{"$and": []bson.M{
-		bson.M{StorageKeyDeviceDeploymentDeploymentID: deploymentId},
-		bson.M{
-			"status": bson.M{
-				"$in": deployments.ActiveDeploymentStatuses(),
-			}},
-	}}
 
-	update := bson.M{
-		"$set": bson.M{
-			"status": deployments.DeviceDeploymentStatusAborted,
-		},
+	now := time.Now()
+
+	// device deployments are aborted status by status, so that each of them
+	// records the status it was aborted from; statuses are visited in the
+	// order devices advance through them, not to miss devices moving on
+	for _, status := range deployments.ActiveDeploymentStatuses() {
+		selector := bson.M{
+			StorageKeyDeviceDeploymentDeploymentID: deploymentId,
+			StorageKeyDeviceDeploymentStatus:       status,
+		}
+
+		update := bson.M{
+			"$set": bson.M{
+				StorageKeyDeviceDeploymentStatus:  deployments.DeviceDeploymentStatusAborted,
+				StorageKeyDeviceDeploymentUpdated: now,
+			},
+			"$push": bson.M{
+				StorageKeyDeviceDeploymentHistory: deployments.DeviceDeploymentStatusChange{
+					Previous:  status,
+					Status:    deployments.DeviceDeploymentStatusAborted,
+					Timestamp: now,
+				},
+			},
+		}
+
+		_, err := session.DB(DatabaseName).C(CollectionDevices).UpdateAll(selector, update)
+		if err == mgo.ErrNotFound {
+			return ErrStorageInvalidID
+		}
+		if err != nil {
+			return err
+		}
 	}
 
-	_, err := session.DB(DatabaseName).C(CollectionDevices).UpdateAll(selector, update)
+	return nil
+}
 
-	if err == mgo.ErrNotFound {
+// DeleteDeviceDeployments removes all device deployments of a deployment.
+func (d *DeviceDeploymentsStorage) DeleteDeviceDeployments(deploymentID string) error {
+	if govalidator.IsNull(deploymentID) {
 		return ErrStorageInvalidID
 	}
 
+	session := d.session.Copy()
+	defer session.Close()
+
+	selector := bson.M{
+		StorageKeyDeviceDeploymentDeploymentID: deploymentID,
+	}
+
+	_, err := session.DB(DatabaseName).C(CollectionDevices).RemoveAll(selector)
 	return err
 }
@@ -21,6 +21,8 @@ import (
 
 	"github.com/mendersoftware/deployments/resources/deployments"
 	. "github.com/mendersoftware/deployments/resources/deployments/mongo"
+	"github.com/mendersoftware/deployments/utils/paging"
+	. "github.com/mendersoftware/deployments/utils/pointers"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -142,13 +144,27 @@ func TestUpdateDeviceDeploymentStatus(t *testing.T) {
 		{
 			InputDeviceID:     "456",
 			InputDeploymentID: "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
-			InputStatus:       deployments.DeviceDeploymentStatusInstalling,
+			InputStatus:       deployments.DeviceDeploymentStatusDownloading,
 			InputDeviceDeployment: []*deployments.DeviceDeployment{
 				deployments.NewDeviceDeployment("456", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"),
 			},
 			OutputError:     nil,
 			OutputOldStatus: "pending",
 		},
+		{
+			// invalid status transition
+			InputDeviceID:     "456",
+			InputDeploymentID: "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			InputStatus:       deployments.DeviceDeploymentStatusInstalling,
+			InputDeviceDeployment: []*deployments.DeviceDeployment{
+				deployments.NewDeviceDeployment("456", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"),
+			},
+			OutputError: &deployments.StatusTransitionError{
+				From: deployments.DeviceDeploymentStatusPending,
+				To:   deployments.DeviceDeploymentStatusInstalling,
+			},
+			OutputOldStatus: "",
+		},
 		{
 			InputDeviceID:     "567",
 			InputDeploymentID: "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
@@ -179,7 +195,7 @@ func TestUpdateDeviceDeploymentStatus(t *testing.T) {
 		assert.NoError(t, err)
 
 		old, err := store.UpdateDeviceDeploymentStatus(testCase.InputDeviceID,
-			testCase.InputDeploymentID, testCase.InputStatus, testCase.InputFinishTime)
+			testCase.InputDeploymentID, deployments.DeviceDeploymentState{Status: testCase.InputStatus}, testCase.InputFinishTime)
 
 		if testCase.OutputError != nil {
 			assert.EqualError(t, err, testCase.OutputError.Error())
@@ -200,10 +216,16 @@ func TestUpdateDeviceDeploymentStatus(t *testing.T) {
 
 			if testCase.OutputError != nil {
 				// status must be unchanged in case of errors
-				assert.Equal(t, deployments.DeviceDeploymentStatusPending, deployment.Status)
+				assert.Equal(t, deployments.DeviceDeploymentStatusPending, *deployment.Status)
 			} else {
 				assert.Equal(t, testCase.InputStatus, *deployment.Status)
 				assert.Equal(t, testCase.OutputOldStatus, old)
+				// verify recorded status change
+				if assert.Len(t, deployment.History, 1) {
+					assert.Equal(t, testCase.OutputOldStatus, deployment.History[0].Previous)
+					assert.Equal(t, testCase.InputStatus, deployment.History[0].Status)
+					assert.WithinDuration(t, *deployment.Updated, deployment.History[0].Timestamp, time.Millisecond)
+				}
 				// verify deployment finish time
 				if testCase.InputFinishTime != nil && assert.NotNil(t, deployment.Finished) {
 					// mongo might have trimmed our time a bit, let's check that we are within a 1s range
@@ -217,6 +239,125 @@ func TestUpdateDeviceDeploymentStatus(t *testing.T) {
 	}
 }
 
+func TestUpdateDeviceDeploymentStatusHistory(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestUpdateDeviceDeploymentStatusHistory in short mode.")
+	}
+
+	deploymentID := "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentsStorage(session)
+
+	err := store.InsertMany(deployments.NewDeviceDeployment("123", deploymentID))
+	assert.NoError(t, err)
+
+	for _, status := range []string{
+		deployments.DeviceDeploymentStatusDownloading,
+		deployments.DeviceDeploymentStatusDownloading,
+		deployments.DeviceDeploymentStatusInstalling,
+	} {
+		_, err := store.UpdateDeviceDeploymentStatus("123", deploymentID, deployments.DeviceDeploymentState{Status: status}, nil)
+		assert.NoError(t, err)
+	}
+
+	dd, err := store.FindOldestDeploymentForDeviceIDWithStatuses("123",
+		deployments.DeviceDeploymentStatusInstalling)
+	assert.NoError(t, err)
+	assert.NotNil(t, dd)
+
+	// repeated status is not recorded
+	if assert.Len(t, dd.History, 2) {
+		assert.Equal(t, deployments.DeviceDeploymentStatusPending, dd.History[0].Previous)
+		assert.Equal(t, deployments.DeviceDeploymentStatusDownloading, dd.History[0].Status)
+		assert.Equal(t, deployments.DeviceDeploymentStatusDownloading, dd.History[1].Previous)
+		assert.Equal(t, deployments.DeviceDeploymentStatusInstalling, dd.History[1].Status)
+		assert.False(t, dd.History[1].Timestamp.Before(dd.History[0].Timestamp))
+	}
+
+	// rejected transition is not recorded
+	_, err = store.UpdateDeviceDeploymentStatus("123", deploymentID,
+		deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusDownloading}, nil)
+	assert.EqualError(t, err, (&deployments.StatusTransitionError{
+		From: deployments.DeviceDeploymentStatusInstalling,
+		To:   deployments.DeviceDeploymentStatusDownloading,
+	}).Error())
+
+	dd, err = store.FindOldestDeploymentForDeviceIDWithStatuses("123",
+		deployments.DeviceDeploymentStatusInstalling)
+	assert.NoError(t, err)
+	if assert.NotNil(t, dd) {
+		assert.Len(t, dd.History, 2)
+	}
+}
+
+func TestUpdateDeviceDeploymentStatusProgress(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestUpdateDeviceDeploymentStatusProgress in short mode.")
+	}
+
+	deploymentID := "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentsStorage(session)
+
+	err := store.InsertMany(deployments.NewDeviceDeployment("123", deploymentID))
+	assert.NoError(t, err)
+
+	_, err = store.UpdateDeviceDeploymentStatus("123", deploymentID,
+		deployments.DeviceDeploymentState{
+			Status:   deployments.DeviceDeploymentStatusDownloading,
+			SubState: StringToPointer("fetching rootfs"),
+			Progress: IntToPointer(42),
+		}, nil)
+	assert.NoError(t, err)
+
+	dd, err := store.FindOldestDeploymentForDeviceIDWithStatuses("123",
+		deployments.DeviceDeploymentStatusDownloading)
+	assert.NoError(t, err)
+	if assert.NotNil(t, dd) {
+		assert.Equal(t, StringToPointer("fetching rootfs"), dd.SubState)
+		assert.Equal(t, IntToPointer(42), dd.Progress)
+	}
+
+	// details of the previous report are cleared
+	_, err = store.UpdateDeviceDeploymentStatus("123", deploymentID,
+		deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusInstalling}, nil)
+	assert.NoError(t, err)
+
+	dd, err = store.FindOldestDeploymentForDeviceIDWithStatuses("123",
+		deployments.DeviceDeploymentStatusInstalling)
+	assert.NoError(t, err)
+	if assert.NotNil(t, dd) {
+		assert.Nil(t, dd.SubState)
+		assert.Nil(t, dd.Progress)
+	}
+
+	_, err = store.UpdateDeviceDeploymentStatus("123", deploymentID,
+		deployments.DeviceDeploymentState{
+			Status:        deployments.DeviceDeploymentStatusFailure,
+			FailureReason: &deployments.FailureReason{Code: "disk_full"},
+		}, nil)
+	assert.NoError(t, err)
+
+	dd, err = store.FindOldestDeploymentForDeviceIDWithStatuses("123",
+		deployments.DeviceDeploymentStatusFailure)
+	assert.NoError(t, err)
+	if assert.NotNil(t, dd) {
+		assert.Equal(t, &deployments.FailureReason{Code: "disk_full"}, dd.FailureReason)
+	}
+}
+
 func TestUpdateDeviceDeploymentLogAvailability(t *testing.T) {
 
 	if testing.Short() {
@@ -315,6 +456,14 @@ func newDeviceDeploymentWithStatus(deviceID string, deploymentID string, status
 	return d
 }
 
+func newDeviceDeploymentWithProgress(deviceID string, deploymentID string, status string,
+	progress *int) *deployments.DeviceDeployment {
+
+	d := newDeviceDeploymentWithStatus(deviceID, deploymentID, status)
+	d.Progress = progress
+	return d
+}
+
 func TestAggregateDeviceDeploymentByStatus(t *testing.T) {
 
 	if testing.Short() {
@@ -326,6 +475,7 @@ func TestAggregateDeviceDeploymentByStatus(t *testing.T) {
 		InputDeviceDeployment []*deployments.DeviceDeployment
 		OutputError           error
 		OutputStats           deployments.Stats
+		OutputProgress        deployments.DeploymentProgress
 	}{
 		{
 			InputDeploymentID:     "ee13ea8b-a6d3-4d4c-99a6-bcfcaebc7ec3",
@@ -363,8 +513,40 @@ func TestAggregateDeviceDeploymentByStatus(t *testing.T) {
 				deployments.DeviceDeploymentStatusNoArtifact:  0,
 				deployments.DeviceDeploymentStatusAlreadyInst: 0,
 				deployments.DeviceDeploymentStatusAborted:     0,
+				deployments.DeviceDeploymentStatusExpired:     0,
 			},
 		},
+		{
+			InputDeploymentID: "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			InputDeviceDeployment: []*deployments.DeviceDeployment{
+				newDeviceDeploymentWithProgress("123", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+					deployments.DeviceDeploymentStatusDownloading, IntToPointer(40)),
+				newDeviceDeploymentWithProgress("234", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+					deployments.DeviceDeploymentStatusInstalling, IntToPointer(80)),
+
+				// progress not reported
+				newDeviceDeploymentWithProgress("345", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+					deployments.DeviceDeploymentStatusRebooting, nil),
+
+				// progress of finished devices does not count
+				newDeviceDeploymentWithProgress("456", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+					deployments.DeviceDeploymentStatusSuccess, IntToPointer(100)),
+			},
+			OutputError: nil,
+			OutputStats: deployments.Stats{
+				deployments.DeviceDeploymentStatusPending:     0,
+				deployments.DeviceDeploymentStatusSuccess:     1,
+				deployments.DeviceDeploymentStatusFailure:     0,
+				deployments.DeviceDeploymentStatusRebooting:   1,
+				deployments.DeviceDeploymentStatusDownloading: 1,
+				deployments.DeviceDeploymentStatusInstalling:  1,
+				deployments.DeviceDeploymentStatusNoArtifact:  0,
+				deployments.DeviceDeploymentStatusAlreadyInst: 0,
+				deployments.DeviceDeploymentStatusAborted:     0,
+				deployments.DeviceDeploymentStatusExpired:     0,
+			},
+			OutputProgress: deployments.DeploymentProgress{Progress: 60},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -393,11 +575,79 @@ func TestAggregateDeviceDeploymentByStatus(t *testing.T) {
 			assert.Equal(t, testCase.OutputStats, stats)
 		}
 
+		statistics, err := store.AggregateDeviceDeploymentStatistics(testCase.InputDeploymentID)
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+
+		if testCase.OutputStats != nil && assert.NotNil(t, statistics) {
+			assert.Equal(t, testCase.OutputStats, statistics.Stats)
+			assert.Equal(t, testCase.OutputProgress, statistics.Progress)
+		}
+
 		// Need to close all sessions to be able to call wipe at next test case
 		session.Close()
 	}
 }
 
+func newFailedDeviceDeployment(deviceID string, deploymentID string, deviceType string,
+	reason *deployments.FailureReason) *deployments.DeviceDeployment {
+
+	d := newDeviceDeploymentWithStatus(deviceID, deploymentID, deployments.DeviceDeploymentStatusFailure)
+	d.DeviceType = &deviceType
+	d.FailureReason = reason
+	return d
+}
+
+func TestAggregateDeviceDeploymentFailures(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestAggregateDeviceDeploymentFailures in short mode.")
+	}
+
+	deploymentID := "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"
+	diskFull := &deployments.FailureReason{Code: "disk_full", Message: "No space left on device"}
+	badSignature := &deployments.FailureReason{Code: "bad_signature"}
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentsStorage(session)
+
+	groups, err := store.AggregateDeviceDeploymentFailures(deploymentID)
+	assert.NoError(t, err)
+	assert.Empty(t, groups)
+
+	err = store.InsertMany(
+		newFailedDeviceDeployment("1", deploymentID, "beaglebone", diskFull),
+		newFailedDeviceDeployment("2", deploymentID, "beaglebone", diskFull),
+		newFailedDeviceDeployment("3", deploymentID, "beaglebone", badSignature),
+		newFailedDeviceDeployment("4", deploymentID, "raspberrypi3", diskFull),
+		newFailedDeviceDeployment("5", deploymentID, "raspberrypi3", nil),
+
+		// not failed or other deployment
+		newDeviceDeploymentWithStatus("6", deploymentID, deployments.DeviceDeploymentStatusSuccess),
+		newFailedDeviceDeployment("7", "ee13ea8b-a6d3-4d4c-99a6-bcfcaebc7ec3", "beaglebone", diskFull),
+	)
+	assert.NoError(t, err)
+
+	groups, err = store.AggregateDeviceDeploymentFailures(deploymentID)
+	assert.NoError(t, err)
+	assert.Equal(t, []*deployments.FailureGroup{
+		{Code: "disk_full", DeviceType: "beaglebone", Count: 2},
+		{Code: "", DeviceType: "raspberrypi3", Count: 1},
+		{Code: "bad_signature", DeviceType: "beaglebone", Count: 1},
+		{Code: "disk_full", DeviceType: "raspberrypi3", Count: 1},
+	}, groups)
+
+	_, err = store.AggregateDeviceDeploymentFailures("")
+	assert.EqualError(t, err, ErrStorageInvalidID.Error())
+}
+
 func TestGetDeviceStatusesForDeployment(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping GetDeviceStatusesForDeployment in short mode.")
@@ -425,15 +675,32 @@ func TestGetDeviceStatusesForDeployment(t *testing.T) {
 			caseId string
 
 			inputDeploymentId string
+			inputPage         paging.Query
 			outputStatuses    []*deployments.DeviceDeployment
+			outputTotal       int
 		}{
 			"existing deployments 1": {
 				inputDeploymentId: "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
 				outputStatuses:    input[:3],
+				outputTotal:       3,
 			},
 			"existing deployments 2": {
 				inputDeploymentId: "30b3e62c-9ec2-4312-a7fa-cff24cc7397b",
 				outputStatuses:    input[3:],
+				outputTotal:       2,
+			},
+			"second page": {
+				inputDeploymentId: "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+				inputPage:         paging.Query{Page: 2, PerPage: 2},
+				outputStatuses:    input[2:3],
+				outputTotal:       3,
+			},
+			"sorted descending": {
+				inputDeploymentId: "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+				inputPage: paging.Query{Page: 1, PerPage: 2,
+					Sort: deployments.SortFieldDeviceID, SortDesc: true},
+				outputStatuses: []*deployments.DeviceDeployment{input[2], input[1]},
+				outputTotal:    3,
 			},
 			"nonexistent deployment": {
 				inputDeploymentId: "aaaaaaaa-9ec2-4312-a7fa-cff24cc7397b",
@@ -444,8 +711,9 @@ func TestGetDeviceStatusesForDeployment(t *testing.T) {
 	for id, tc := range testCases {
 		t.Logf("test case: %s", id)
 
-		statuses, err := store.GetDeviceStatusesForDeployment(tc.inputDeploymentId)
+		statuses, total, err := store.GetDeviceStatusesForDeployment(tc.inputDeploymentId, tc.inputPage)
 		assert.NoError(t, err)
+		assert.Equal(t, tc.outputTotal, total)
 
 		assert.Equal(t, len(tc.outputStatuses), len(statuses))
 		for i, out := range tc.outputStatuses {
@@ -598,12 +866,19 @@ func TestAbortDeviceDeployments(t *testing.T) {
 			InputDeploymentID: "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
 			InputDeviceDeployment: []*deployments.DeviceDeployment{
 				deployments.NewDeviceDeployment("456", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"),
-				deployments.NewDeviceDeployment("567", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"),
+				newDeviceDeploymentWithStatus("567", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+					deployments.DeviceDeploymentStatusDownloading),
 			},
 			OutputError: nil,
 		},
 	}
 
+	// status each device was aborted from
+	abortedFrom := map[string]string{
+		"456": deployments.DeviceDeploymentStatusPending,
+		"567": deployments.DeviceDeploymentStatusDownloading,
+	}
+
 	for name, testCase := range testCases {
 
 		t.Logf("testing case %s", name)
@@ -643,6 +918,10 @@ func TestAbortDeviceDeployments(t *testing.T) {
 			} else {
 				for _, deployment := range deploymentList {
 					assert.Equal(t, deployments.DeviceDeploymentStatusAborted, *deployment.Status)
+					if assert.Len(t, deployment.History, 1) {
+						assert.Equal(t, abortedFrom[*deployment.DeviceId], deployment.History[0].Previous)
+						assert.Equal(t, deployments.DeviceDeploymentStatusAborted, deployment.History[0].Status)
+					}
 				}
 			}
 		}
@@ -651,3 +930,511 @@ func TestAbortDeviceDeployments(t *testing.T) {
 		session.Close()
 	}
 }
+
+func TestAggregateDeviceDeploymentByStatusForPhase(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestAggregateDeviceDeploymentByStatusForPhase in short mode.")
+	}
+
+	deploymentID := "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"
+
+	inPhase := func(dd *deployments.DeviceDeployment, phase int) *deployments.DeviceDeployment {
+		dd.Phase = phase
+		return dd
+	}
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentsStorage(session)
+
+	err := store.InsertMany(
+		inPhase(newDeviceDeploymentWithStatus("123", deploymentID,
+			deployments.DeviceDeploymentStatusSuccess), 0),
+		inPhase(newDeviceDeploymentWithStatus("234", deploymentID,
+			deployments.DeviceDeploymentStatusFailure), 0),
+		inPhase(newDeviceDeploymentWithStatus("345", deploymentID,
+			deployments.DeviceDeploymentStatusPending), 1),
+		inPhase(newDeviceDeploymentWithStatus("456", deploymentID,
+			deployments.DeviceDeploymentStatusPending), 1),
+	)
+	assert.NoError(t, err)
+
+	stats, err := store.AggregateDeviceDeploymentByStatusForPhase(deploymentID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats[deployments.DeviceDeploymentStatusSuccess])
+	assert.Equal(t, 1, stats[deployments.DeviceDeploymentStatusFailure])
+	assert.Equal(t, 0, stats[deployments.DeviceDeploymentStatusPending])
+
+	stats, err = store.AggregateDeviceDeploymentByStatusForPhase(deploymentID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats[deployments.DeviceDeploymentStatusSuccess])
+	assert.Equal(t, 2, stats[deployments.DeviceDeploymentStatusPending])
+
+	_, err = store.AggregateDeviceDeploymentByStatusForPhase("", 1)
+	assert.EqualError(t, err, ErrStorageInvalidID.Error())
+}
+
+func TestRetryDeviceDeployment(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestRetryDeviceDeployment in short mode.")
+	}
+
+	deploymentID := "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentsStorage(session)
+
+	err := store.InsertMany(
+		newDeviceDeploymentWithStatus("123", deploymentID,
+			deployments.DeviceDeploymentStatusInstalling),
+		newDeviceDeploymentWithStatus("234", deploymentID,
+			deployments.DeviceDeploymentStatusAborted),
+	)
+	assert.NoError(t, err)
+
+	later := time.Now().Add(time.Minute).Round(time.Millisecond)
+
+	// first retry
+	previous, err := store.RetryDeviceDeployment("123", deploymentID, 2, &later)
+	assert.NoError(t, err)
+	assert.Equal(t, deployments.DeviceDeploymentStatusInstalling, previous)
+
+	dd, err := store.FindOldestDeploymentForDeviceIDWithStatuses("123", deployments.DeviceDeploymentStatusPending)
+	assert.NoError(t, err)
+	assert.NotNil(t, dd)
+	assert.Equal(t, 1, dd.Retries)
+	assert.Equal(t, 2, dd.Attempts())
+	assert.WithinDuration(t, later, *dd.RetryAfter, time.Millisecond)
+	if assert.Len(t, dd.History, 1) {
+		assert.Equal(t, deployments.DeviceDeploymentStatusInstalling, dd.History[0].Previous)
+		assert.Equal(t, deployments.DeviceDeploymentStatusPending, dd.History[0].Status)
+	}
+
+	// second retry, no backoff
+	previous, err = store.RetryDeviceDeployment("123", deploymentID, 2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, deployments.DeviceDeploymentStatusPending, previous)
+
+	// retries exhausted
+	previous, err = store.RetryDeviceDeployment("123", deploymentID, 2, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, previous)
+
+	statistics, err := store.AggregateDeviceDeploymentStatistics(deploymentID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, statistics.Progress.Retries)
+
+	// aborted deployments are not retried
+	previous, err = store.RetryDeviceDeployment("234", deploymentID, 2, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, previous)
+
+	_, err = store.RetryDeviceDeployment("", deploymentID, 2, nil)
+	assert.EqualError(t, err, ErrStorageInvalidID.Error())
+}
+
+func TestFindOldestDeploymentForDeviceIDWithStatusesPriority(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestFindOldestDeploymentForDeviceIDWithStatusesPriority in short mode.")
+	}
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentsStorage(session)
+
+	assert.NoError(t, store.IndexStorage())
+
+	newDeviceDeployment := func(deploymentID string, created time.Time,
+		priority int) *deployments.DeviceDeployment {
+
+		d := deployments.NewDeviceDeployment("123", deploymentID)
+		d.Created = &created
+		d.Priority = priority
+		return d
+	}
+
+	now := time.Now()
+	err := store.InsertMany(
+		newDeviceDeployment("30b3e62c-9ec2-4312-a7fa-cff24cc7397a", now.Add(-3*time.Hour), 0),
+		newDeviceDeployment("30b3e62c-9ec2-4312-a7fa-cff24cc7397b", now.Add(-2*time.Hour), 5),
+		newDeviceDeployment("30b3e62c-9ec2-4312-a7fa-cff24cc7397c", now.Add(-time.Hour), 5),
+	)
+	assert.NoError(t, err)
+
+	// highest priority wins over creation time, ties broken by creation time
+	dd, err := store.FindOldestDeploymentForDeviceIDWithStatuses("123",
+		deployments.DeviceDeploymentStatusPending)
+	assert.NoError(t, err)
+	if assert.NotNil(t, dd) {
+		assert.Equal(t, "30b3e62c-9ec2-4312-a7fa-cff24cc7397b", *dd.DeploymentId)
+	}
+}
+
+func TestFindDeploymentsForDeviceIDWithStatuses(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestFindDeploymentsForDeviceIDWithStatuses in short mode.")
+	}
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentsStorage(session)
+
+	assert.NoError(t, store.IndexStorage())
+
+	newDeviceDeployment := func(deploymentID string, created time.Time,
+		priority int, status string) *deployments.DeviceDeployment {
+
+		d := deployments.NewDeviceDeployment("123", deploymentID)
+		d.Created = &created
+		d.Priority = priority
+		d.Status = &status
+		return d
+	}
+
+	now := time.Now()
+	err := store.InsertMany(
+		newDeviceDeployment("30b3e62c-9ec2-4312-a7fa-cff24cc7397a", now.Add(-3*time.Hour), 0,
+			deployments.DeviceDeploymentStatusPending),
+		newDeviceDeployment("30b3e62c-9ec2-4312-a7fa-cff24cc7397b", now.Add(-2*time.Hour), 5,
+			deployments.DeviceDeploymentStatusPending),
+		newDeviceDeployment("30b3e62c-9ec2-4312-a7fa-cff24cc7397c", now.Add(-time.Hour), 5,
+			deployments.DeviceDeploymentStatusDownloading),
+		newDeviceDeployment("30b3e62c-9ec2-4312-a7fa-cff24cc7397d", now, 9,
+			deployments.DeviceDeploymentStatusSuccess),
+	)
+	assert.NoError(t, err)
+
+	dds, err := store.FindDeploymentsForDeviceIDWithStatuses("123",
+		deployments.ActiveDeploymentStatuses()...)
+	assert.NoError(t, err)
+
+	var ids []string
+	for _, dd := range dds {
+		ids = append(ids, *dd.DeploymentId)
+	}
+	assert.Equal(t, []string{
+		"30b3e62c-9ec2-4312-a7fa-cff24cc7397b",
+		"30b3e62c-9ec2-4312-a7fa-cff24cc7397c",
+		"30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+	}, ids)
+}
+
+func TestMigrateMissingPriority(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestMigrateMissingPriority in short mode.")
+	}
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentsStorage(session)
+
+	// device deployment stored before priorities were introduced
+	now := time.Now()
+	err := session.DB(DatabaseName).C(CollectionDevices).Insert(bson.M{
+		"_id":                                  "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+		StorageKeyDeviceDeploymentDeviceId:     "123",
+		StorageKeyDeviceDeploymentDeploymentID: "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+		StorageKeyDeviceDeploymentStatus:       deployments.DeviceDeploymentStatusPending,
+		StorageKeyDeviceDeploymentCreated:      now.Add(-2 * time.Hour),
+	})
+	assert.NoError(t, err)
+
+	newer := deployments.NewDeviceDeployment("123", "30b3e62c-9ec2-4312-a7fa-cff24cc7397b")
+	created := now.Add(-time.Hour)
+	newer.Created = &created
+	assert.NoError(t, store.InsertMany(newer))
+
+	updated, err := store.MigrateMissingPriority()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, updated)
+
+	// missing priority is the default one, older deployment goes first
+	dd, err := store.FindOldestDeploymentForDeviceIDWithStatuses("123",
+		deployments.DeviceDeploymentStatusPending)
+	assert.NoError(t, err)
+	if assert.NotNil(t, dd) {
+		assert.Equal(t, "30b3e62c-9ec2-4312-a7fa-cff24cc7397a", *dd.DeploymentId)
+		assert.Equal(t, 0, dd.Priority)
+	}
+}
+
+func TestFindStaleDeviceDeployments(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestFindStaleDeviceDeployments in short mode.")
+	}
+
+	deploymentID := "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentsStorage(session)
+
+	old := newDeviceDeploymentWithStatus("123", deploymentID,
+		deployments.DeviceDeploymentStatusPending)
+	old.Created = TimeToPointer(time.Now().Add(-2 * time.Hour))
+
+	// never updated, created long ago
+	legacy := newDeviceDeploymentWithStatus("234", deploymentID,
+		deployments.DeviceDeploymentStatusInstalling)
+	legacy.Created = TimeToPointer(time.Now().Add(-2 * time.Hour))
+
+	err := store.InsertMany(
+		old,
+		legacy,
+		newDeviceDeploymentWithStatus("345", deploymentID,
+			deployments.DeviceDeploymentStatusPending),
+	)
+	assert.NoError(t, err)
+
+	// status updated just now
+	_, err = store.UpdateDeviceDeploymentStatus("123", deploymentID,
+		deployments.DeviceDeploymentState{Status: deployments.DeviceDeploymentStatusDownloading}, nil)
+	assert.NoError(t, err)
+
+	statuses := []string{
+		deployments.DeviceDeploymentStatusDownloading,
+		deployments.DeviceDeploymentStatusInstalling,
+	}
+
+	stale, err := store.FindStaleDeviceDeployments(time.Now().Add(-time.Hour), statuses...)
+	assert.NoError(t, err)
+	if assert.Len(t, stale, 1) {
+		assert.Equal(t, "234", *stale[0].DeviceId)
+	}
+
+	stale, err = store.FindStaleDeviceDeployments(time.Now().Add(time.Minute), statuses...)
+	assert.NoError(t, err)
+	assert.Len(t, stale, 2)
+}
+
+func TestFindDeviceDeploymentsWithStatuses(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestFindDeviceDeploymentsWithStatuses in short mode.")
+	}
+
+	deploymentID := "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentsStorage(session)
+
+	err := store.InsertMany(
+		newDeviceDeploymentWithStatus("123", deploymentID,
+			deployments.DeviceDeploymentStatusPending),
+		newDeviceDeploymentWithStatus("234", deploymentID,
+			deployments.DeviceDeploymentStatusInstalling),
+		newDeviceDeploymentWithStatus("345", "30b3e62c-9ec2-4312-a7fa-cff24cc7397b",
+			deployments.DeviceDeploymentStatusPending),
+	)
+	assert.NoError(t, err)
+
+	found, err := store.FindDeviceDeploymentsWithStatuses(deploymentID,
+		deployments.DeviceDeploymentStatusPending)
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, "123", *found[0].DeviceId)
+	}
+
+	found, err = store.FindDeviceDeploymentsWithStatuses(deploymentID,
+		deployments.DeviceDeploymentStatusPending, deployments.DeviceDeploymentStatusInstalling)
+	assert.NoError(t, err)
+	assert.Len(t, found, 2)
+}
+
+func TestFindDeviceDeploymentsForDevices(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestFindDeviceDeploymentsForDevices in short mode.")
+	}
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentsStorage(session)
+
+	err := store.InsertMany(
+		newDeviceDeploymentWithStatus("123", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			deployments.DeviceDeploymentStatusPending),
+		newDeviceDeploymentWithStatus("123", "30b3e62c-9ec2-4312-a7fa-cff24cc7397b",
+			deployments.DeviceDeploymentStatusSuccess),
+		newDeviceDeploymentWithStatus("234", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			deployments.DeviceDeploymentStatusInstalling),
+		newDeviceDeploymentWithStatus("345", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			deployments.DeviceDeploymentStatusPending),
+	)
+	assert.NoError(t, err)
+
+	found, err := store.FindDeviceDeploymentsForDevices([]string{"123", "234"},
+		deployments.DeviceDeploymentStatusPending)
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, "123", *found[0].DeviceId)
+	}
+
+	found, err = store.FindDeviceDeploymentsForDevices([]string{"123", "234"},
+		deployments.ActiveDeploymentStatuses()...)
+	assert.NoError(t, err)
+	assert.Len(t, found, 2)
+}
+
+func TestFindDeviceDeploymentsForDevice(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestFindDeviceDeploymentsForDevice in short mode.")
+	}
+
+	now := time.Now()
+	input := []*deployments.DeviceDeployment{
+		deployments.NewDeviceDeployment("device0001", "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"),
+		deployments.NewDeviceDeployment("device0001", "30b3e62c-9ec2-4312-a7fa-cff24cc7397b"),
+		deployments.NewDeviceDeployment("device0001", "30b3e62c-9ec2-4312-a7fa-cff24cc7397c"),
+		deployments.NewDeviceDeployment("device0002", "30b3e62c-9ec2-4312-a7fa-cff24cc7397c"),
+	}
+	for i, d := range input {
+		created := now.Add(time.Duration(i) * time.Minute)
+		d.Created = &created
+	}
+	input[1].Status = StringToPointer(deployments.DeviceDeploymentStatusSuccess)
+
+	// setup db - once for all cases
+	db.Wipe()
+
+	session := db.Session()
+	store := NewDeviceDeploymentsStorage(session)
+
+	err := store.InsertMany(input...)
+	assert.NoError(t, err)
+
+	testCases := map[string]struct {
+		InputDeviceID string
+		InputQuery    deployments.DeviceDeploymentHistoryQuery
+
+		OutputDeploymentIDs []string
+		OutputTotal         int
+	}{
+		"all, newest first": {
+			InputDeviceID: "device0001",
+			InputQuery: deployments.DeviceDeploymentHistoryQuery{
+				Paging: paging.Query{Page: 1, PerPage: 10},
+			},
+			OutputDeploymentIDs: []string{
+				"30b3e62c-9ec2-4312-a7fa-cff24cc7397c",
+				"30b3e62c-9ec2-4312-a7fa-cff24cc7397b",
+				"30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			},
+			OutputTotal: 3,
+		},
+		"second page": {
+			InputDeviceID: "device0001",
+			InputQuery: deployments.DeviceDeploymentHistoryQuery{
+				Paging: paging.Query{Page: 2, PerPage: 2},
+			},
+			OutputDeploymentIDs: []string{
+				"30b3e62c-9ec2-4312-a7fa-cff24cc7397a",
+			},
+			OutputTotal: 3,
+		},
+		"status": {
+			InputDeviceID: "device0001",
+			InputQuery: deployments.DeviceDeploymentHistoryQuery{
+				Status: deployments.DeviceDeploymentStatusSuccess,
+				Paging: paging.Query{Page: 1, PerPage: 10},
+			},
+			OutputDeploymentIDs: []string{
+				"30b3e62c-9ec2-4312-a7fa-cff24cc7397b",
+			},
+			OutputTotal: 1,
+		},
+		"unknown device": {
+			InputDeviceID: "device0003",
+			InputQuery: deployments.DeviceDeploymentHistoryQuery{
+				Paging: paging.Query{Page: 1, PerPage: 10},
+			},
+			OutputDeploymentIDs: []string{},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Logf("test case: %s", name)
+
+		devDeps, total, err := store.FindDeviceDeploymentsForDevice(tc.InputDeviceID, tc.InputQuery)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.OutputTotal, total)
+
+		assert.Len(t, devDeps, len(tc.OutputDeploymentIDs))
+		for i, id := range tc.OutputDeploymentIDs {
+			assert.Equal(t, id, *devDeps[i].DeploymentId)
+			assert.Equal(t, tc.InputDeviceID, *devDeps[i].DeviceId)
+		}
+	}
+
+	session.Close()
+}
+
+func TestDeleteDeviceDeployments(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestDeleteDeviceDeployments in short mode.")
+	}
+
+	deploymentID := "30b3e62c-9ec2-4312-a7fa-cff24cc7397a"
+	otherDeploymentID := "30b3e62c-9ec2-4312-a7fa-cff24cc7397b"
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewDeviceDeploymentsStorage(session)
+
+	err := store.InsertMany(
+		newDeviceDeploymentWithStatus("123", deploymentID,
+			deployments.DeviceDeploymentStatusSuccess),
+		newDeviceDeploymentWithStatus("234", deploymentID,
+			deployments.DeviceDeploymentStatusFailure),
+		newDeviceDeploymentWithStatus("123", otherDeploymentID,
+			deployments.DeviceDeploymentStatusPending),
+	)
+	assert.NoError(t, err)
+
+	assert.EqualError(t, store.DeleteDeviceDeployments(""), ErrStorageInvalidID.Error())
+	assert.NoError(t, store.DeleteDeviceDeployments(deploymentID))
+
+	found, _, err := store.GetDeviceStatusesForDeployment(deploymentID, paging.Query{})
+	assert.NoError(t, err)
+	assert.Len(t, found, 0)
+
+	found, _, err = store.GetDeviceStatusesForDeployment(otherDeploymentID, paging.Query{})
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+}
@@ -0,0 +1,158 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mongo
+
+import (
+	"time"
+
+	"github.com/mendersoftware/deployments/resources/deployments"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Database settings
+const (
+	CollectionEvents = "deployments.events"
+
+	// Size of capped events collection, oldest events are overwritten
+	EventsCollectionSize = 16 * 1024 * 1024
+)
+
+// Mongo error codes
+const (
+	errCodeNamespaceExists = 48
+)
+
+// How long tailing waits for new events before checking if it should stop
+const tailTimeout = time.Second
+
+type eventDocument struct {
+	Id                bson.ObjectId `bson:"_id"`
+	deployments.Event `bson:",inline"`
+}
+
+// EventsStorage is a data layer for deployment events based on MongoDB capped
+// collection. All service instances sharing the database see the same events.
+type EventsStorage struct {
+	session *mgo.Session
+}
+
+// NewEventsStorage new data layer object
+func NewEventsStorage(session *mgo.Session) *EventsStorage {
+	return &EventsStorage{
+		session: session,
+	}
+}
+
+// EnsureCollection creates capped events collection if it does not exist.
+// Tailing requires the collection to be capped.
+func (e *EventsStorage) EnsureCollection() error {
+
+	session := e.session.Copy()
+	defer session.Close()
+
+	err := session.DB(DatabaseName).C(CollectionEvents).Create(&mgo.CollectionInfo{
+		Capped:   true,
+		MaxBytes: EventsCollectionSize,
+	})
+	if qerr, ok := err.(*mgo.QueryError); ok && qerr.Code == errCodeNamespaceExists {
+		return nil
+	}
+
+	return err
+}
+
+// Insert stores event.
+func (e *EventsStorage) Insert(event *deployments.Event) error {
+
+	session := e.session.Copy()
+	defer session.Close()
+
+	return session.DB(DatabaseName).C(CollectionEvents).Insert(&eventDocument{
+		Id:    bson.NewObjectId(),
+		Event: *event,
+	})
+}
+
+// Tail calls handler for each event inserted after tailing started, until stop
+// is closed.
+// Events are followed in insertion order, as ids generated by different service
+// instances are not ordered. When the cursor dies, tailing resumes after the
+// last handled event.
+func (e *EventsStorage) Tail(handler func(event *deployments.Event), stop <-chan struct{}) error {
+
+	session := e.session.Copy()
+	defer session.Close()
+
+	collection := session.DB(DatabaseName).C(CollectionEvents)
+
+	// skip events stored before tailing started
+	var last eventDocument
+	err := collection.Find(nil).Sort("-$natural").One(&last)
+	if err != nil && err != mgo.ErrNotFound {
+		return err
+	}
+
+	for {
+		// events up to the last handled one are skipped, unless it was
+		// already overwritten and all stored events are newer
+		skip := false
+		if last.Id != "" {
+			count, err := collection.FindId(last.Id).Count()
+			if err != nil {
+				return err
+			}
+			skip = count != 0
+		}
+
+		iter := collection.Find(nil).Sort("$natural").Tail(tailTimeout)
+		for {
+			var doc eventDocument
+			for iter.Next(&doc) {
+				if skip {
+					skip = doc.Id != last.Id
+					continue
+				}
+				last.Id = doc.Id
+				event := doc.Event
+				handler(&event)
+			}
+			if err := iter.Err(); err != nil {
+				iter.Close()
+				return err
+			}
+
+			select {
+			case <-stop:
+				return iter.Close()
+			default:
+			}
+
+			// cursor died, e.g. on empty collection, query again
+			if !iter.Timeout() {
+				break
+			}
+		}
+		if err := iter.Close(); err != nil {
+			return err
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(tailTimeout):
+		}
+	}
+}
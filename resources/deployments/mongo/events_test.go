@@ -0,0 +1,97 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mongo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mendersoftware/deployments/resources/deployments"
+	. "github.com/mendersoftware/deployments/resources/deployments/mongo"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestEventsStorageTail(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping TestEventsStorageTail in short mode.")
+	}
+
+	// Make sure we start test with empty database
+	db.Wipe()
+
+	session := db.Session()
+	defer session.Close()
+	store := NewEventsStorage(session)
+
+	assert.NoError(t, store.EnsureCollection())
+	// collection already exists
+	assert.NoError(t, store.EnsureCollection())
+
+	// stored before tailing started, skipped
+	assert.NoError(t, store.Insert(deployments.NewStatsEvent("123", deployments.Stats{})))
+
+	received := make(chan *deployments.Event, 10)
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- store.Tail(func(event *deployments.Event) {
+			received <- event
+		}, stop)
+	}()
+
+	// let tailing start
+	time.Sleep(100 * time.Millisecond)
+
+	assert.NoError(t, store.Insert(deployments.NewDeviceStatusEvent("234", "device-1",
+		deployments.DeviceDeploymentStatusPending, deployments.DeviceDeploymentStatusDownloading)))
+
+	select {
+	case event := <-received:
+		assert.Equal(t, "234", event.DeploymentID)
+		assert.Equal(t, deployments.EventTypeDeviceStatus, event.Type)
+		assert.Equal(t, "device-1", event.DeviceID)
+		assert.Equal(t, deployments.DeviceDeploymentStatusDownloading, event.Status)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not received")
+	}
+
+	// stored by another instance, with older id than the last event
+	err := session.DB(DatabaseName).C(CollectionEvents).Insert(&struct {
+		Id                bson.ObjectId `bson:"_id"`
+		deployments.Event `bson:",inline"`
+	}{
+		Id:    bson.NewObjectIdWithTime(time.Now().Add(-time.Hour)),
+		Event: *deployments.NewStatsEvent("345", deployments.Stats{}),
+	})
+	assert.NoError(t, err)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, "345", event.DeploymentID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not received")
+	}
+
+	close(stop)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("tailing not stopped")
+	}
+	assert.Len(t, received, 0)
+}
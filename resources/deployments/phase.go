@@ -0,0 +1,215 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments
+
+import (
+	"errors"
+	"time"
+)
+
+// Phase statuses
+const (
+	PhaseStatusScheduled = "scheduled"
+	PhaseStatusActive    = "active"
+	PhaseStatusFinished  = "finished"
+)
+
+// Errors
+var (
+	ErrPhaseInvalidBatchSize      = errors.New("Phase batch size has to be between 1 and 100 percent")
+	ErrPhaseInvalidDeviceCount    = errors.New("Phase device count has to be a positive number")
+	ErrPhaseSizeAmbiguous         = errors.New("Phase can specify either batch size or device count, not both")
+	ErrPhaseSizeMissing           = errors.New("Phase needs to specify batch size or device count")
+	ErrPhaseStartMissing          = errors.New("Phase needs to specify start time or wait for previous phase")
+	ErrPhaseFirstAfterPrevious    = errors.New("First phase can not wait for previous phase")
+	ErrPhasesExceedDeploymentSize = errors.New("Phases target more devices than deployment")
+)
+
+// PhaseConstructor describes a single rollout phase as requested by the user.
+type PhaseConstructor struct {
+	// Percentage of deployment devices included in the phase
+	BatchSize int `json:"batch_size,omitempty"`
+
+	// Number of deployment devices included in the phase, alternative to batch size
+	DeviceCount int `json:"device_count,omitempty"`
+
+	// Phase opens at this time
+	StartTime *time.Time `json:"start_ts,omitempty"`
+
+	// Phase opens once previous phase finished without failures
+	AfterPrevious bool `json:"after_previous,omitempty"`
+}
+
+// Validate checks phase settings that do not depend on the phase position.
+func (p *PhaseConstructor) Validate() error {
+	if p.BatchSize != 0 && p.DeviceCount != 0 {
+		return ErrPhaseSizeAmbiguous
+	}
+	if p.BatchSize < 0 || p.BatchSize > 100 {
+		return ErrPhaseInvalidBatchSize
+	}
+	if p.DeviceCount < 0 {
+		return ErrPhaseInvalidDeviceCount
+	}
+	return nil
+}
+
+// size returns number of devices phase takes out of total deployment devices.
+func (p *PhaseConstructor) size(total int) int {
+	if p.DeviceCount != 0 {
+		return p.DeviceCount
+	}
+
+	size := total * p.BatchSize / 100
+	// non empty batch always gets at least one device
+	if size == 0 && p.BatchSize != 0 {
+		size = 1
+	}
+	return size
+}
+
+// ValidatePhases checks a list of phases against deployment size.
+// Every phase except the last one needs to have its size specified, the last
+// phase receives all remaining devices. Every phase except the first one needs
+// to specify when it opens.
+func ValidatePhases(phases []*PhaseConstructor, total int) error {
+	if err := validatePhaseSettings(phases); err != nil {
+		return err
+	}
+
+	assigned := 0
+	for _, p := range phases {
+		assigned += p.size(total)
+	}
+
+	if assigned > total {
+		return ErrPhasesExceedDeploymentSize
+	}
+
+	return nil
+}
+
+// validatePhaseSettings checks a list of phases without knowing deployment size.
+func validatePhaseSettings(phases []*PhaseConstructor) error {
+	for i, p := range phases {
+		if p == nil {
+			return ErrPhaseSizeMissing
+		}
+		if err := p.Validate(); err != nil {
+			return err
+		}
+
+		last := i == len(phases)-1
+		if !last && p.BatchSize == 0 && p.DeviceCount == 0 {
+			return ErrPhaseSizeMissing
+		}
+
+		if i == 0 && p.AfterPrevious {
+			return ErrPhaseFirstAfterPrevious
+		}
+		if i != 0 && !p.AfterPrevious && p.StartTime == nil {
+			return ErrPhaseStartMissing
+		}
+	}
+
+	return nil
+}
+
+// Phase is a rollout phase of a deployment.
+type Phase struct {
+	// Number of devices assigned to the phase
+	DeviceCount int `json:"device_count"`
+
+	// Phase opens at this time
+	StartTime *time.Time `json:"start_ts,omitempty"`
+
+	// Phase opens once previous phase finished without failures
+	AfterPrevious bool `json:"after_previous,omitempty"`
+
+	// Aggregated device status counters of the phase, filled on request
+	Stats Stats `json:"stats,omitempty" bson:"-"`
+
+	// Phase status, filled together with stats
+	Status string `json:"status,omitempty" bson:"-"`
+}
+
+// NewPhases resolves phase constructors into phases for deployment of
+// `total` devices. Last phase receives all remaining devices.
+func NewPhases(constructors []*PhaseConstructor, total int) []*Phase {
+	if len(constructors) == 0 {
+		return nil
+	}
+
+	phases := make([]*Phase, 0, len(constructors))
+	remaining := total
+	for i, c := range constructors {
+		size := c.size(total)
+		if size > remaining || i == len(constructors)-1 {
+			size = remaining
+		}
+		remaining -= size
+
+		phases = append(phases, &Phase{
+			DeviceCount:   size,
+			StartTime:     c.StartTime,
+			AfterPrevious: c.AfterPrevious,
+		})
+	}
+
+	return phases
+}
+
+// IsFinished checks if all devices of the phase reached final status.
+// Requires phase stats to be set.
+func (p *Phase) IsFinished() bool {
+	for _, s := range ActiveDeploymentStatuses() {
+		if p.Stats[s] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSuccessful checks if the phase finished and none of its devices failed.
+// Requires phase stats to be set.
+func (p *Phase) IsSuccessful() bool {
+	return p.IsFinished() && p.Stats[DeviceDeploymentStatusFailure] == 0
+}
+
+// IsOpen checks if devices of the phase can receive the deployment at given
+// time. Phases waiting for previous phase require previous phase stats to be set.
+func (p *Phase) IsOpen(now time.Time, previous *Phase) bool {
+	if p.StartTime != nil && now.Before(*p.StartTime) {
+		return false
+	}
+	if p.AfterPrevious && previous != nil && !previous.IsSuccessful() {
+		return false
+	}
+	return true
+}
+
+// SetStats sets phase stats and updates phase status accordingly.
+func (p *Phase) SetStats(stats Stats, now time.Time, previous *Phase) {
+	p.Stats = stats
+
+	switch {
+	case !p.IsOpen(now, previous):
+		p.Status = PhaseStatusScheduled
+	case p.IsFinished():
+		p.Status = PhaseStatusFinished
+	default:
+		p.Status = PhaseStatusActive
+	}
+}
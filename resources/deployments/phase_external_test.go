@@ -0,0 +1,233 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/mendersoftware/deployments/resources/deployments"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePhases(t *testing.T) {
+
+	t.Parallel()
+
+	later := time.Now().Add(time.Hour)
+
+	testCases := map[string]struct {
+		InputPhases []*PhaseConstructor
+		InputTotal  int
+
+		OutputError error
+	}{
+		"no phases": {
+			InputTotal: 10,
+		},
+		"batch sizes": {
+			InputPhases: []*PhaseConstructor{
+				{BatchSize: 10},
+				{BatchSize: 30, AfterPrevious: true},
+				{StartTime: &later},
+			},
+			InputTotal: 10,
+		},
+		"device counts": {
+			InputPhases: []*PhaseConstructor{
+				{DeviceCount: 2},
+				{DeviceCount: 8, AfterPrevious: true},
+			},
+			InputTotal: 10,
+		},
+		"size ambiguous": {
+			InputPhases: []*PhaseConstructor{
+				{BatchSize: 10, DeviceCount: 1},
+			},
+			InputTotal:  10,
+			OutputError: ErrPhaseSizeAmbiguous,
+		},
+		"batch size out of range": {
+			InputPhases: []*PhaseConstructor{
+				{BatchSize: 101},
+			},
+			InputTotal:  10,
+			OutputError: ErrPhaseInvalidBatchSize,
+		},
+		"negative device count": {
+			InputPhases: []*PhaseConstructor{
+				{DeviceCount: -1},
+			},
+			InputTotal:  10,
+			OutputError: ErrPhaseInvalidDeviceCount,
+		},
+		"size missing": {
+			InputPhases: []*PhaseConstructor{
+				{},
+				{AfterPrevious: true},
+			},
+			InputTotal:  10,
+			OutputError: ErrPhaseSizeMissing,
+		},
+		"first after previous": {
+			InputPhases: []*PhaseConstructor{
+				{BatchSize: 10, AfterPrevious: true},
+				{AfterPrevious: true},
+			},
+			InputTotal:  10,
+			OutputError: ErrPhaseFirstAfterPrevious,
+		},
+		"start missing": {
+			InputPhases: []*PhaseConstructor{
+				{BatchSize: 10},
+				{},
+			},
+			InputTotal:  10,
+			OutputError: ErrPhaseStartMissing,
+		},
+		"too many devices": {
+			InputPhases: []*PhaseConstructor{
+				{DeviceCount: 5},
+				{DeviceCount: 6, AfterPrevious: true},
+			},
+			InputTotal:  10,
+			OutputError: ErrPhasesExceedDeploymentSize,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		err := ValidatePhases(testCase.InputPhases, testCase.InputTotal)
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestNewPhases(t *testing.T) {
+
+	t.Parallel()
+
+	assert.Nil(t, NewPhases(nil, 10))
+
+	phases := NewPhases([]*PhaseConstructor{
+		{BatchSize: 1},
+		{DeviceCount: 3, AfterPrevious: true},
+		{BatchSize: 10, AfterPrevious: true},
+	}, 10)
+
+	assert.Len(t, phases, 3)
+	// non empty batch gets at least one device
+	assert.Equal(t, 1, phases[0].DeviceCount)
+	assert.Equal(t, 3, phases[1].DeviceCount)
+	// last phase gets all remaining devices
+	assert.Equal(t, 6, phases[2].DeviceCount)
+	assert.True(t, phases[2].AfterPrevious)
+}
+
+func TestDeploymentPhaseOf(t *testing.T) {
+
+	t.Parallel()
+
+	constructor := NewDeploymentConstructor()
+	constructor.Name = StringToPointer("foo")
+	constructor.ArtifactName = StringToPointer("bar")
+	constructor.Devices = []string{"a", "b", "c", "d"}
+	constructor.Phases = []*PhaseConstructor{
+		{DeviceCount: 1},
+		{AfterPrevious: true},
+	}
+
+	dep := NewDeploymentFromConstructor(constructor)
+	assert.Len(t, dep.Phases, 2)
+
+	assert.Equal(t, 0, dep.PhaseOf(0))
+	assert.Equal(t, 1, dep.PhaseOf(1))
+	assert.Equal(t, 1, dep.PhaseOf(3))
+
+	// no phases, everything is in the first phase
+	assert.Equal(t, 0, NewDeployment().PhaseOf(3))
+}
+
+func TestPhaseSetStats(t *testing.T) {
+
+	t.Parallel()
+
+	now := time.Now()
+	later := now.Add(time.Hour)
+
+	finished := Stats{DeviceDeploymentStatusSuccess: 2}
+	failed := Stats{DeviceDeploymentStatusSuccess: 1, DeviceDeploymentStatusFailure: 1}
+	running := Stats{DeviceDeploymentStatusSuccess: 1, DeviceDeploymentStatusDownloading: 1}
+
+	testCases := map[string]struct {
+		InputPhase    *Phase
+		InputStats    Stats
+		InputPrevious *Phase
+
+		OutputStatus string
+	}{
+		"first phase, running": {
+			InputPhase:   &Phase{},
+			InputStats:   running,
+			OutputStatus: PhaseStatusActive,
+		},
+		"first phase, finished": {
+			InputPhase:   &Phase{},
+			InputStats:   finished,
+			OutputStatus: PhaseStatusFinished,
+		},
+		"start time not reached": {
+			InputPhase:   &Phase{StartTime: &later},
+			InputStats:   Stats{DeviceDeploymentStatusPending: 1},
+			OutputStatus: PhaseStatusScheduled,
+		},
+		"start time reached": {
+			InputPhase:   &Phase{StartTime: &now},
+			InputStats:   Stats{DeviceDeploymentStatusPending: 1},
+			OutputStatus: PhaseStatusActive,
+		},
+		"previous phase running": {
+			InputPhase:    &Phase{AfterPrevious: true},
+			InputStats:    Stats{DeviceDeploymentStatusPending: 1},
+			InputPrevious: &Phase{Stats: running},
+			OutputStatus:  PhaseStatusScheduled,
+		},
+		"previous phase failed": {
+			InputPhase:    &Phase{AfterPrevious: true},
+			InputStats:    Stats{DeviceDeploymentStatusPending: 1},
+			InputPrevious: &Phase{Stats: failed},
+			OutputStatus:  PhaseStatusScheduled,
+		},
+		"previous phase succeeded": {
+			InputPhase:    &Phase{AfterPrevious: true},
+			InputStats:    Stats{DeviceDeploymentStatusPending: 1},
+			InputPrevious: &Phase{Stats: finished},
+			OutputStatus:  PhaseStatusActive,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		testCase.InputPhase.SetStats(testCase.InputStats, now, testCase.InputPrevious)
+		assert.Equal(t, testCase.OutputStatus, testCase.InputPhase.Status)
+		assert.Equal(t, testCase.InputStats, testCase.InputPhase.Stats)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package deployments
+
+import (
+	"errors"
+	"time"
+)
+
+// Maintenance window time of day format
+const MaintenanceWindowTimeFormat = "15:04"
+
+// Errors
+var (
+	ErrMaintenanceWindowInvalidTime = errors.New("Maintenance window times have to be in HH:MM format")
+	ErrMaintenanceWindowEmpty       = errors.New("Maintenance window start and end can not be equal")
+)
+
+// MaintenanceWindow is a daily recurring time range during which devices
+// can receive the deployment. Times are UTC, window may span midnight.
+type MaintenanceWindow struct {
+	// Window start, HH:MM
+	Start string `json:"start"`
+
+	// Window end, HH:MM
+	End string `json:"end"`
+}
+
+func (w *MaintenanceWindow) Validate() error {
+	start, err := time.Parse(MaintenanceWindowTimeFormat, w.Start)
+	if err != nil {
+		return ErrMaintenanceWindowInvalidTime
+	}
+	end, err := time.Parse(MaintenanceWindowTimeFormat, w.End)
+	if err != nil {
+		return ErrMaintenanceWindowInvalidTime
+	}
+	if start.Equal(end) {
+		return ErrMaintenanceWindowEmpty
+	}
+	return nil
+}
+
+// minuteOfDay converts HH:MM to number of minutes since midnight.
+// Assumes valid format.
+func minuteOfDay(value string) int {
+	t, _ := time.Parse(MaintenanceWindowTimeFormat, value)
+	return t.Hour()*60 + t.Minute()
+}
+
+// Contains checks if given time falls into the window.
+func (w *MaintenanceWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	now := t.Hour()*60 + t.Minute()
+	start := minuteOfDay(w.Start)
+	end := minuteOfDay(w.End)
+
+	if start < end {
+		return now >= start && now < end
+	}
+	// window spans midnight
+	return now >= start || now < end
+}
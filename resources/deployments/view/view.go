@@ -15,12 +15,16 @@
 package view
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/mendersoftware/deployments/resources/deployments"
 	"github.com/mendersoftware/deployments/resources/images/view"
+	"github.com/mendersoftware/go-lib-micro/log"
+	"github.com/mendersoftware/go-lib-micro/requestid"
 )
 
 type DeploymentsView struct {
@@ -31,11 +35,51 @@ func (d *DeploymentsView) RenderNoUpdateForDevice(w rest.ResponseWriter) {
 	d.RenderEmptySuccessResponse(w)
 }
 
+// Deployment created by a request to other resource, 201 Created with
+// location of the new deployment
+func (d *DeploymentsView) RenderDeploymentCreated(w rest.ResponseWriter, id string) {
+	w.Header().Add(view.HttpHeaderLocation, "./deployments/"+id)
+	w.WriteHeader(http.StatusCreated)
+}
+
 // Success response with no data aka. 204 No Content
 func (d *DeploymentsView) RenderEmptySuccessResponse(w rest.ResponseWriter) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Invalid device deployment status change, 409 Conflict with current status
+func (d *DeploymentsView) RenderStatusTransitionError(w rest.ResponseWriter, r *rest.Request,
+	err *deployments.StatusTransitionError, l *log.Logger) {
+
+	l.Error(err.Error())
+	w.WriteHeader(http.StatusConflict)
+	writeErr := w.WriteJson(map[string]string{
+		"error":      err.Error(),
+		"status":     err.From,
+		"request_id": requestid.GetReqId(r),
+	})
+	if writeErr != nil {
+		panic(writeErr)
+	}
+}
+
+// Deployment rejected due to conflicting deployments, 409 Conflict with
+// conflicting devices
+func (d *DeploymentsView) RenderConflictError(w rest.ResponseWriter, r *rest.Request,
+	err *deployments.ConflictError, l *log.Logger) {
+
+	l.Error(err.Error())
+	w.WriteHeader(http.StatusConflict)
+	writeErr := w.WriteJson(map[string]interface{}{
+		"error":      err.Error(),
+		"devices":    err.Devices,
+		"request_id": requestid.GetReqId(r),
+	})
+	if writeErr != nil {
+		panic(writeErr)
+	}
+}
+
 func (d *DeploymentsView) RenderDeploymentLog(w rest.ResponseWriter, dlog deployments.DeploymentLog) {
 	h, _ := w.(http.ResponseWriter)
 
@@ -50,3 +94,47 @@ func (d *DeploymentsView) RenderDeploymentLog(w rest.ResponseWriter, dlog deploy
 		}
 	}
 }
+
+// Start of Server-Sent Events stream, 200 OK with event stream content type
+func (d *DeploymentsView) RenderEventStream(w rest.ResponseWriter) {
+	h, _ := w.(http.ResponseWriter)
+
+	h.Header().Set("Content-Type", "text/event-stream")
+	h.Header().Set("Cache-Control", "no-cache")
+	// disable response buffering of nginx based gateways
+	h.Header().Set("X-Accel-Buffering", "no")
+	h.WriteHeader(http.StatusOK)
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Single event of Server-Sent Events stream, event type is the SSE event name
+func (d *DeploymentsView) RenderEvent(w rest.ResponseWriter, event *deployments.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return writeEventStream(w, fmt.Sprintf("event: %s\ndata: %s\n\n", event.Type, data))
+}
+
+// Comment line keeping idle Server-Sent Events stream open
+func (d *DeploymentsView) RenderEventKeepAlive(w rest.ResponseWriter) error {
+	return writeEventStream(w, ": keep-alive\n\n")
+}
+
+func writeEventStream(w rest.ResponseWriter, chunk string) error {
+	h, _ := w.(http.ResponseWriter)
+
+	if _, err := h.Write([]byte(chunk)); err != nil {
+		return err
+	}
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	return nil
+}
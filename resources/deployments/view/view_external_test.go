@@ -117,3 +117,35 @@ func TestRenderDeploymentLog(t *testing.T) {
 		assert.Equal(t, tc.Body, recorded.Recorder.Body.String())
 	}
 }
+
+func TestRenderEventStream(t *testing.T) {
+
+	t.Parallel()
+
+	event := deployments.NewDeviceStatusEvent("f826484e-1157-4109-af21-304e6d711560", "device-id-1",
+		deployments.DeviceDeploymentStatusPending, deployments.DeviceDeploymentStatusDownloading)
+	event.Time = *parseTime(t, "2006-01-02T15:04:05Z")
+
+	router, err := rest.MakeRouter(rest.Get("/test", func(w rest.ResponseWriter, r *rest.Request) {
+		view := &DeploymentsView{}
+		view.RenderEventStream(w)
+		assert.NoError(t, view.RenderEvent(w, event))
+		assert.NoError(t, view.RenderEventKeepAlive(w))
+	}))
+	assert.NoError(t, err)
+
+	api := rest.NewApi()
+	api.SetApp(router)
+
+	recorded := test.RunRequest(t, api.MakeHandler(),
+		test.MakeSimpleRequest("GET", "http://localhost/test", nil))
+
+	recorded.CodeIs(http.StatusOK)
+	recorded.HeaderIs("Content-Type", "text/event-stream")
+	assert.Equal(t, "event: device_status\n"+
+		`data: {"type":"device_status","deployment_id":"f826484e-1157-4109-af21-304e6d711560",`+
+		`"time":"2006-01-02T15:04:05Z","device_id":"device-id-1","status":"downloading",`+
+		`"previous_status":"pending"}`+"\n\n"+
+		": keep-alive\n\n",
+		recorded.Recorder.Body.String())
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/asaskevich/govalidator"
 	"github.com/mendersoftware/deployments/resources/images"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/mendersoftware/go-lib-micro/requestlog"
 	"github.com/pkg/errors"
 )
@@ -84,13 +85,19 @@ func (s *SoftwareImagesController) GetImage(w rest.ResponseWriter, r *rest.Reque
 func (s *SoftwareImagesController) ListImages(w rest.ResponseWriter, r *rest.Request) {
 	l := requestlog.GetRequestLogger(r.Env)
 
-	list, err := s.model.ListImages(r.PathParams)
+	query, err := paging.ParseQuery(r.URL.Query(), images.SortFields()...)
+	if err != nil {
+		s.view.RenderError(w, r, err, http.StatusBadRequest, l)
+		return
+	}
+
+	list, total, err := s.model.ListImages(query)
 	if err != nil {
 		s.view.RenderInternalError(w, r, err, l)
 		return
 	}
 
-	s.view.RenderSuccessGet(w, list)
+	s.view.RenderSuccessGetPage(w, r, list, query, total)
 }
 
 func (s *SoftwareImagesController) DownloadLink(w rest.ResponseWriter, r *rest.Request) {
@@ -35,6 +35,7 @@ import (
 	. "github.com/mendersoftware/deployments/resources/images/controller"
 	"github.com/mendersoftware/deployments/resources/images/controller/mocks"
 	"github.com/mendersoftware/deployments/resources/images/view"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/mendersoftware/deployments/utils/pointers"
 	h "github.com/mendersoftware/deployments/utils/testing"
 	"github.com/mendersoftware/go-lib-micro/requestid"
@@ -55,6 +56,7 @@ type fakeImageModeler struct {
 	getImageError     error
 	imagesList        []*images.SoftwareImage
 	listImagesError   error
+	listImagesQuery   paging.Query
 	downloadLink      *images.Link
 	downloadLinkError error
 	editImage         bool
@@ -69,8 +71,9 @@ type Part struct {
 	FieldValue  string
 }
 
-func (fim *fakeImageModeler) ListImages(filters map[string]string) ([]*images.SoftwareImage, error) {
-	return fim.imagesList, fim.listImagesError
+func (fim *fakeImageModeler) ListImages(query paging.Query) ([]*images.SoftwareImage, int, error) {
+	fim.listImagesQuery = query
+	return fim.imagesList, len(fim.imagesList), fim.listImagesError
 }
 
 func (fim *fakeImageModeler) DownloadLink(imageID string, expire time.Duration) (*images.Link, error) {
@@ -173,6 +176,22 @@ func TestControllerListImages(t *testing.T) {
 		test.MakeSimpleRequest("GET", "http://localhost/api/0.0.1/images", nil))
 	recorded.CodeIs(http.StatusOK)
 	recorded.ContentTypeIsJson()
+	recorded.HeaderIs(paging.HttpHeaderTotalCount, "1")
+	recorded.HeaderIs(paging.HttpHeaderLink, "")
+	assert.Equal(t, paging.Query{Page: 1}, imagesModel.listImagesQuery)
+
+	//page and sort
+	recorded = test.RunRequest(t, api.MakeHandler(),
+		test.MakeSimpleRequest("GET",
+			"http://localhost/api/0.0.1/images?page=2&per_page=10&sort=name:desc", nil))
+	recorded.CodeIs(http.StatusOK)
+	assert.Equal(t, paging.Query{Page: 2, PerPage: 10, Sort: images.SortFieldName, SortDesc: true},
+		imagesModel.listImagesQuery)
+
+	//unsupported sort
+	recorded = test.RunRequest(t, api.MakeHandler(),
+		test.MakeSimpleRequest("GET", "http://localhost/api/0.0.1/images?sort=size", nil))
+	recorded.CodeIs(http.StatusBadRequest)
 }
 
 func TestControllerDeleteImage(t *testing.T) {
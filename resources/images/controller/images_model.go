@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/mendersoftware/deployments/resources/images"
+	"github.com/mendersoftware/deployments/utils/paging"
 )
 
 // Errors expected from interface
@@ -35,7 +36,7 @@ var (
 )
 
 type ImagesModel interface {
-	ListImages(filters map[string]string) ([]*images.SoftwareImage, error)
+	ListImages(query paging.Query) ([]*images.SoftwareImage, int, error)
 	DownloadLink(imageID string, expire time.Duration) (*images.Link, error)
 	GetImage(id string) (*images.SoftwareImage, error)
 	DeleteImage(imageID string) error
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/mendersoftware/deployments/resources/images"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -132,25 +133,32 @@ func (_m *ImagesModel) GetImage(id string) (*images.SoftwareImage, error) {
 	return r0, r1
 }
 
-// ListImages provides a mock function with given fields: filters
-func (_m *ImagesModel) ListImages(filters map[string]string) ([]*images.SoftwareImage, error) {
-	ret := _m.Called(filters)
+// ListImages provides a mock function with given fields: query
+func (_m *ImagesModel) ListImages(query paging.Query) ([]*images.SoftwareImage, int, error) {
+	ret := _m.Called(query)
 
 	var r0 []*images.SoftwareImage
-	if rf, ok := ret.Get(0).(func(map[string]string) []*images.SoftwareImage); ok {
-		r0 = rf(filters)
+	if rf, ok := ret.Get(0).(func(paging.Query) []*images.SoftwareImage); ok {
+		r0 = rf(query)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*images.SoftwareImage)
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(map[string]string) error); ok {
-		r1 = rf(filters)
+	var r1 int
+	if rf, ok := ret.Get(1).(func(paging.Query) int); ok {
+		r1 = rf(query)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(int)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(paging.Query) error); ok {
+		r2 = rf(query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
@@ -16,12 +16,14 @@ package controller
 
 import (
 	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/mendersoftware/go-lib-micro/log"
 )
 
 type RESTView interface {
 	RenderSuccessPost(w rest.ResponseWriter, r *rest.Request, id string)
 	RenderSuccessGet(w rest.ResponseWriter, object interface{})
+	RenderSuccessGetPage(w rest.ResponseWriter, r *rest.Request, object interface{}, query paging.Query, total int)
 	RenderError(w rest.ResponseWriter, r *rest.Request, err error, status int, l *log.Logger)
 	RenderInternalError(w rest.ResponseWriter, r *rest.Request, err error, l *log.Logger)
 	RenderErrorNotFound(w rest.ResponseWriter, r *rest.Request, l *log.Logger)
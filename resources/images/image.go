@@ -106,6 +106,18 @@ func (s *SoftwareImageMetaArtifactConstructor) Validate() error {
 	return err
 }
 
+// Fields images can be sorted by
+const (
+	SortFieldName         = "name"
+	SortFieldArtifactName = "artifact_name"
+	SortFieldModified     = "modified"
+)
+
+// SortFields lists fields images can be sorted by.
+func SortFields() []string {
+	return []string{SortFieldName, SortFieldArtifactName, SortFieldModified}
+}
+
 // SoftwareImage YOCTO image with user application
 type SoftwareImage struct {
 	// User provided field set
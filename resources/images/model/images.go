@@ -21,6 +21,8 @@ import (
 
 	"github.com/mendersoftware/deployments/resources/images"
 	"github.com/mendersoftware/deployments/resources/images/controller"
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/mendersoftware/mender-artifact/metadata"
 	"github.com/mendersoftware/mender-artifact/parser"
 	"github.com/mendersoftware/mender-artifact/reader"
@@ -36,17 +38,20 @@ type ImagesModel struct {
 	fileStorage   FileStorage
 	deployments   ImageUsedIn
 	imagesStorage SoftwareImagesStorage
+	notifier      Notifier
 }
 
 func NewImagesModel(
 	fileStorage FileStorage,
 	checker ImageUsedIn,
 	imagesStorage SoftwareImagesStorage,
+	notifier Notifier,
 ) *ImagesModel {
 	return &ImagesModel{
 		fileStorage:   fileStorage,
 		deployments:   checker,
 		imagesStorage: imagesStorage,
+		notifier:      notifier,
 	}
 }
 
@@ -158,6 +163,8 @@ func (i *ImagesModel) handleArtifact(
 		return "", errors.Wrap(err, "Fail to store the metadata")
 	}
 
+	i.notify(webhooks.EventArtifactUploaded, image)
+
 	return artifactID, nil
 }
 
@@ -214,22 +221,31 @@ func (i *ImagesModel) DeleteImage(imageID string) error {
 		return errors.Wrap(err, "Deleting image metadata")
 	}
 
+	i.notify(webhooks.EventArtifactDeleted, found)
+
 	return nil
 }
 
-// ListImages according to specified filers.
-func (i *ImagesModel) ListImages(filters map[string]string) ([]*images.SoftwareImage, error) {
+// notify passes lifecycle event to notifier, if there is one.
+func (i *ImagesModel) notify(event string, data interface{}) {
+	if i.notifier != nil {
+		i.notifier.Notify(event, data)
+	}
+}
+
+// ListImages returns a page of images together with total number of images.
+func (i *ImagesModel) ListImages(query paging.Query) ([]*images.SoftwareImage, int, error) {
 
-	imageList, err := i.imagesStorage.FindAll()
+	imageList, total, err := i.imagesStorage.Find(query)
 	if err != nil {
-		return nil, errors.Wrap(err, "Searching for image metadata")
+		return nil, 0, errors.Wrap(err, "Searching for image metadata")
 	}
 
 	if imageList == nil {
-		return make([]*images.SoftwareImage, 0), nil
+		return make([]*images.SoftwareImage, 0), total, nil
 	}
 
-	return imageList, nil
+	return imageList, total, nil
 }
 
 // EditObject allows editing only if image have not been used yet in any deployment.
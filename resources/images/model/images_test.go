@@ -26,6 +26,8 @@ import (
 
 	"github.com/mendersoftware/deployments/resources/images"
 	"github.com/mendersoftware/deployments/resources/images/controller"
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/mendersoftware/mender-artifact/parser"
 	atutils "github.com/mendersoftware/mender-artifact/test_utils"
 	"github.com/mendersoftware/mender-artifact/writer"
@@ -35,14 +37,14 @@ import (
 const validUUIDv4 = "d50eda0d-2cea-4de1-8d42-9cd3e7e8670d"
 
 func TestCreateImageEmptyConstructor(t *testing.T) {
-	iModel := NewImagesModel(nil, nil, nil)
+	iModel := NewImagesModel(nil, nil, nil, nil)
 	if _, err := iModel.CreateImage(nil, nil); err != controller.ErrModelMissingInputMetadata {
 		t.FailNow()
 	}
 }
 
 func TestCreateImageMissingFields(t *testing.T) {
-	iModel := NewImagesModel(nil, nil, nil)
+	iModel := NewImagesModel(nil, nil, nil, nil)
 
 	imageMeta := images.NewSoftwareImageMetaConstructor()
 	if _, err := iModel.CreateImage(imageMeta, nil); err == nil {
@@ -86,8 +88,8 @@ func (fis *FakeImageStorage) Delete(id string) error {
 	return fis.deleteError
 }
 
-func (fis *FakeImageStorage) FindAll() ([]*images.SoftwareImage, error) {
-	return fis.findAllImages, fis.findAllError
+func (fis *FakeImageStorage) Find(query paging.Query) ([]*images.SoftwareImage, int, error) {
+	return fis.findAllImages, len(fis.findAllImages), fis.findAllError
 }
 
 func (fis *FakeImageStorage) IsArtifactUnique(artifactName string, deviceTypesCompatible []string) (bool, error) {
@@ -120,7 +122,7 @@ func TestCreateImageInsertError(t *testing.T) {
 	fakeIS := new(FakeImageStorage)
 	fakeIS.insertError = errors.New("insert error")
 
-	iModel := NewImagesModel(nil, nil, fakeIS)
+	iModel := NewImagesModel(nil, nil, fakeIS, nil)
 	imageMeta := createValidImageMeta()
 
 	if _, err := iModel.CreateImage(imageMeta, nil); err == nil {
@@ -134,7 +136,7 @@ func TestCreateImageArtifactUploadError(t *testing.T) {
 	fakeFS := new(FakeFileStorage)
 	fakeFS.uploadArtifactError = errors.New("Cannot upload artifact")
 
-	iModel := NewImagesModel(fakeFS, nil, fakeIS)
+	iModel := NewImagesModel(fakeFS, nil, fakeIS, nil)
 
 	imageMeta := createValidImageMeta()
 	td, _ := ioutil.TempDir("", "mender-install-update-")
@@ -153,13 +155,22 @@ func TestCreateImageArtifactUploadError(t *testing.T) {
 	}
 }
 
+type FakeNotifier struct {
+	events []string
+}
+
+func (fn *FakeNotifier) Notify(event string, data interface{}) {
+	fn.events = append(fn.events, event)
+}
+
 func TestCreateImageCreateOK(t *testing.T) {
 	fakeIS := new(FakeImageStorage)
 	fakeIS.insertError = nil
 	fakeIS.isArtifactUnique = true
 	fakeFS := new(FakeFileStorage)
+	fakeNotifier := new(FakeNotifier)
 
-	iModel := NewImagesModel(fakeFS, nil, fakeIS)
+	iModel := NewImagesModel(fakeFS, nil, fakeIS, fakeNotifier)
 
 	imageMeta := createValidImageMeta()
 	td, _ := ioutil.TempDir("", "mender-install-update-")
@@ -177,13 +188,15 @@ func TestCreateImageCreateOK(t *testing.T) {
 	if _, err := iModel.CreateImage(imageMeta, f); err != nil {
 		t.FailNow()
 	}
+
+	assert.Equal(t, []string{webhooks.EventArtifactUploaded}, fakeNotifier.events)
 }
 
 func TestGetImageFindByIDError(t *testing.T) {
 	fakeIS := new(FakeImageStorage)
 	fakeIS.findByIdError = errors.New("find by id error")
 
-	iModel := NewImagesModel(nil, nil, fakeIS)
+	iModel := NewImagesModel(nil, nil, fakeIS, nil)
 	if _, err := iModel.GetImage(""); err == nil {
 		t.FailNow()
 	}
@@ -193,7 +206,7 @@ func TestGetImageFindByIDEmptyImage(t *testing.T) {
 	fakeIS := new(FakeImageStorage)
 	fakeIS.findByIdImage = nil
 
-	iModel := NewImagesModel(nil, nil, fakeIS)
+	iModel := NewImagesModel(nil, nil, fakeIS, nil)
 	if image, err := iModel.GetImage(""); err != nil || image != nil {
 		t.FailNow()
 	}
@@ -251,7 +264,7 @@ func TestGetImageOK(t *testing.T) {
 	fakeFS := new(FakeFileStorage)
 	fakeFS.lastModifiedTime = time.Now()
 
-	iModel := NewImagesModel(fakeFS, nil, fakeIS)
+	iModel := NewImagesModel(fakeFS, nil, fakeIS, nil)
 	if image, err := iModel.GetImage(""); err != nil || image == nil {
 		t.FailNow()
 	}
@@ -285,7 +298,9 @@ func TestDeleteImage(t *testing.T) {
 
 	fakeChecker.usedInActiveDeploymentsErr = errors.New("error")
 
-	iModel := NewImagesModel(fakeFS, fakeChecker, fakeIS)
+	fakeNotifier := new(FakeNotifier)
+
+	iModel := NewImagesModel(fakeFS, fakeChecker, fakeIS, fakeNotifier)
 
 	if err := iModel.DeleteImage(""); err == nil {
 		t.FailNow()
@@ -302,6 +317,7 @@ func TestDeleteImage(t *testing.T) {
 	if err := iModel.DeleteImage(""); err != nil {
 		t.FailNow()
 	}
+	assert.Equal(t, []string{webhooks.EventArtifactDeleted}, fakeNotifier.events)
 
 	fakeFS.deleteError = errors.New("error")
 	if err := iModel.DeleteImage(""); err == nil {
@@ -332,16 +348,16 @@ func TestListImages(t *testing.T) {
 	fakeChecker := new(FakeUseChecker)
 	fakeFS := new(FakeFileStorage)
 	fakeIS := new(FakeImageStorage)
-	iModel := NewImagesModel(fakeFS, fakeChecker, fakeIS)
+	iModel := NewImagesModel(fakeFS, fakeChecker, fakeIS, nil)
 
 	fakeIS.findAllError = errors.New("error")
-	if _, err := iModel.ListImages(nil); err == nil {
+	if _, _, err := iModel.ListImages(*paging.NewQuery()); err == nil {
 		t.FailNow()
 	}
 
 	//no error; empty images list
 	fakeIS.findAllError = nil
-	if _, err := iModel.ListImages(nil); err != nil {
+	if _, _, err := iModel.ListImages(*paging.NewQuery()); err != nil {
 		t.FailNow()
 	}
 
@@ -354,7 +370,8 @@ func TestListImages(t *testing.T) {
 
 	listedImages := []*images.SoftwareImage{constructorImage}
 	fakeIS.findAllImages = listedImages
-	if _, err := iModel.ListImages(nil); err != nil {
+	if list, total, err := iModel.ListImages(*paging.NewQuery()); err != nil ||
+		len(list) != 1 || total != 1 {
 		t.FailNow()
 	}
 }
@@ -365,7 +382,7 @@ func TestEditImage(t *testing.T) {
 
 	fakeChecker := new(FakeUseChecker)
 	fakeIS := new(FakeImageStorage)
-	iModel := NewImagesModel(nil, fakeChecker, fakeIS)
+	iModel := NewImagesModel(nil, fakeChecker, fakeIS, nil)
 
 	// error checking if image is used in deployments
 	fakeChecker.usedInDeploymentsErr = errors.New("error")
@@ -413,7 +430,7 @@ func TestDownloadLink(t *testing.T) {
 	fakeChecker := new(FakeUseChecker)
 	fakeIS := new(FakeImageStorage)
 	fakeFS := new(FakeFileStorage)
-	iModel := NewImagesModel(fakeFS, fakeChecker, fakeIS)
+	iModel := NewImagesModel(fakeFS, fakeChecker, fakeIS, nil)
 
 	// image exists error
 	fakeIS.imageEsistsError = errors.New("error")
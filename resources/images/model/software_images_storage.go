@@ -18,6 +18,7 @@ import (
 	"errors"
 
 	"github.com/mendersoftware/deployments/resources/images"
+	"github.com/mendersoftware/deployments/utils/paging"
 )
 
 // Common errors for interface SoftwareImagesStorage
@@ -37,5 +38,5 @@ type SoftwareImagesStorage interface {
 	FindByID(id string) (*images.SoftwareImage, error)
 	IsArtifactUnique(artifactName string, deviceTypesCompatible []string) (bool, error)
 	Delete(id string) error
-	FindAll() ([]*images.SoftwareImage, error)
+	Find(query paging.Query) ([]*images.SoftwareImage, int, error)
 }
@@ -20,6 +20,7 @@ import (
 	"github.com/asaskevich/govalidator"
 	"github.com/mendersoftware/deployments/resources/images"
 	"github.com/mendersoftware/deployments/resources/images/model"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -33,8 +34,16 @@ const (
 	StorageKeySoftwareImageArtifactName = "meta_artifact.artifact_name"
 	StorageKeySoftwareImageName         = "meta.name"
 	StorageKeySoftwareImageId           = "_id"
+	StorageKeySoftwareImageModified     = "modified"
 )
 
+// Storage keys of fields images can be sorted by
+var sortKeys = map[string]string{
+	images.SortFieldName:         StorageKeySoftwareImageName,
+	images.SortFieldArtifactName: StorageKeySoftwareImageArtifactName,
+	images.SortFieldModified:     StorageKeySoftwareImageModified,
+}
+
 // Indexes
 const (
 	IndexUniqeNameAndDeviceTypeStr = "uniqueNameAndDeviceTypeIndex"
@@ -249,19 +258,26 @@ func (i *SoftwareImagesStorage) Delete(id string) error {
 	return nil
 }
 
-// FindAll lists all images
-func (i *SoftwareImagesStorage) FindAll() ([]*images.SoftwareImage, error) {
+// Find lists a page of images, together with total number of images.
+// Images are ordered by modification time unless other sort is requested.
+func (i *SoftwareImagesStorage) Find(query paging.Query) ([]*images.SoftwareImage, int, error) {
 
 	session := i.session.Copy()
 	defer session.Close()
 
-	var images []*images.SoftwareImage
-	if err := session.DB(DatabaseName).C(CollectionImages).Find(nil).All(&images); err != nil {
-		if err.Error() == mgo.ErrNotFound.Error() {
-			return images, nil
-		}
-		return nil, err
+	total, err := session.DB(DatabaseName).C(CollectionImages).Find(nil).Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var list []*images.SoftwareImage
+	err = session.DB(DatabaseName).C(CollectionImages).Find(nil).
+		Sort(query.SortKey(sortKeys, images.SortFieldModified), StorageKeySoftwareImageId).
+		Skip(query.Skip()).Limit(query.PerPage).
+		All(&list)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return images, nil
+	return list, total, nil
 }
@@ -18,8 +18,10 @@ import (
 	"github.com/mendersoftware/deployments/resources/images"
 	model "github.com/mendersoftware/deployments/resources/images/model"
 	. "github.com/mendersoftware/deployments/resources/images/mongo"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestSoftwareImagesStorageImageByNameAndDeviceType(t *testing.T) {
@@ -235,3 +237,71 @@ func TestIsArtifactUnique(t *testing.T) {
 	}
 
 }
+
+func TestSoftwareImagesStorageFind(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestSoftwareImagesStorageFind in short mode.")
+	}
+
+	now := time.Now()
+	newImage := func(id string, name string, modified time.Time) interface{} {
+		return &images.SoftwareImage{
+			Id: id,
+			SoftwareImageMetaConstructor: images.SoftwareImageMetaConstructor{
+				Name: name,
+			},
+			SoftwareImageMetaArtifactConstructor: images.SoftwareImageMetaArtifactConstructor{
+				ArtifactName:          name,
+				DeviceTypesCompatible: []string{"foo"},
+				Updates:               []images.Update{},
+			},
+			Modified: &modified,
+		}
+	}
+
+	db.Wipe()
+	session := db.Session()
+	defer session.Close()
+
+	coll := session.DB(DatabaseName).C(CollectionImages)
+	assert.NoError(t, coll.Insert(
+		newImage("1", "b", now.Add(-time.Hour)),
+		newImage("2", "c", now.Add(-2*time.Hour)),
+		newImage("3", "a", now),
+	))
+
+	testCases := map[string]struct {
+		query paging.Query
+
+		ids []string
+	}{
+		"default order": {
+			query: paging.Query{Page: 1, PerPage: 10},
+			ids:   []string{"2", "1", "3"},
+		},
+		"by name": {
+			query: paging.Query{Page: 1, PerPage: 10, Sort: images.SortFieldName},
+			ids:   []string{"3", "1", "2"},
+		},
+		"second page by name descending": {
+			query: paging.Query{Page: 2, PerPage: 2, Sort: images.SortFieldName, SortDesc: true},
+			ids:   []string{"3"},
+		},
+	}
+
+	store := NewSoftwareImagesStorage(session)
+
+	for name, tc := range testCases {
+		t.Logf("testing case %s", name)
+
+		list, total, err := store.Find(tc.query)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, total)
+
+		ids := []string{}
+		for _, image := range list {
+			ids = append(ids, image.Id)
+		}
+		assert.Equal(t, tc.ids, ids)
+	}
+}
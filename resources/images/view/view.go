@@ -18,9 +18,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/mendersoftware/go-lib-micro/log"
 	"github.com/mendersoftware/go-lib-micro/requestid"
 )
@@ -47,6 +49,17 @@ func (p *RESTView) RenderSuccessGet(w rest.ResponseWriter, object interface{}) {
 	w.WriteJson(object)
 }
 
+// Page of a list, with total number of entries and links to other pages
+func (p *RESTView) RenderSuccessGetPage(w rest.ResponseWriter, r *rest.Request,
+	object interface{}, query paging.Query, total int) {
+
+	w.Header().Set(paging.HttpHeaderTotalCount, strconv.Itoa(total))
+	for _, link := range paging.Links(r.URL, query, total) {
+		w.Header().Add(paging.HttpHeaderLink, link)
+	}
+	p.RenderSuccessGet(w, object)
+}
+
 func (p *RESTView) RenderError(w rest.ResponseWriter, r *rest.Request, err error, status int, l *log.Logger) {
 	l.Error(err.Error())
 	renderErrorWithMsg(w, r, status, err.Error())
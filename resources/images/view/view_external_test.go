@@ -21,6 +21,7 @@ import (
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/ant0ine/go-json-rest/rest/test"
 	. "github.com/mendersoftware/deployments/resources/images/view"
+	"github.com/mendersoftware/deployments/utils/paging"
 	"github.com/mendersoftware/go-lib-micro/log"
 	"github.com/stretchr/testify/assert"
 )
@@ -67,6 +68,35 @@ func TestRenderSuccessGet(t *testing.T) {
 	recorded.BodyIs(`"test"`)
 }
 
+func TestRenderSuccessGetPage(t *testing.T) {
+
+	router, err := rest.MakeRouter(rest.Get("/test", func(w rest.ResponseWriter, r *rest.Request) {
+		new(RESTView).RenderSuccessGetPage(w, r, []string{"test"},
+			paging.Query{Page: 2, PerPage: 1}, 3)
+	}))
+
+	if err != nil {
+		assert.NoError(t, err)
+	}
+
+	api := rest.NewApi()
+	api.SetApp(router)
+
+	recorded := test.RunRequest(t, api.MakeHandler(),
+		test.MakeSimpleRequest("GET", "http://localhost/test?page=2&per_page=1", nil))
+
+	recorded.CodeIs(http.StatusOK)
+	recorded.ContentTypeIsJson()
+	recorded.BodyIs(`["test"]`)
+	recorded.HeaderIs(paging.HttpHeaderTotalCount, "3")
+	assert.Equal(t, []string{
+		`</test?page=1&per_page=1>; rel="first"`,
+		`</test?page=1&per_page=1>; rel="prev"`,
+		`</test?page=3&per_page=1>; rel="next"`,
+		`</test?page=3&per_page=1>; rel="last"`,
+	}, recorded.Recorder.HeaderMap[paging.HttpHeaderLink])
+}
+
 func TestRenderSuccessDelete(t *testing.T) {
 
 	router, err := rest.MakeRouter(rest.Delete("/test", func(w rest.ResponseWriter, r *rest.Request) {
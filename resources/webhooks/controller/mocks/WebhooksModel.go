@@ -0,0 +1,144 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mocks
+
+import (
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/utils/paging"
+	"github.com/stretchr/testify/mock"
+)
+
+// WebhooksModel is an autogenerated mock type for the WebhooksModel type
+type WebhooksModel struct {
+	mock.Mock
+}
+
+// CreateWebhook provides a mock function with given fields: constructor
+func (_m *WebhooksModel) CreateWebhook(constructor *webhooks.WebhookConstructor) (string, error) {
+	ret := _m.Called(constructor)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*webhooks.WebhookConstructor) string); ok {
+		r0 = rf(constructor)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*webhooks.WebhookConstructor) error); ok {
+		r1 = rf(constructor)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteWebhook provides a mock function with given fields: id
+func (_m *WebhooksModel) DeleteWebhook(id string) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetWebhook provides a mock function with given fields: id
+func (_m *WebhooksModel) GetWebhook(id string) (*webhooks.Webhook, error) {
+	ret := _m.Called(id)
+
+	var r0 *webhooks.Webhook
+	if rf, ok := ret.Get(0).(func(string) *webhooks.Webhook); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*webhooks.Webhook)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListDeliveries provides a mock function with given fields: webhookID, query
+func (_m *WebhooksModel) ListDeliveries(webhookID string, query paging.Query) ([]*webhooks.Delivery, int, error) {
+	ret := _m.Called(webhookID, query)
+
+	var r0 []*webhooks.Delivery
+	if rf, ok := ret.Get(0).(func(string, paging.Query) []*webhooks.Delivery); ok {
+		r0 = rf(webhookID, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*webhooks.Delivery)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(string, paging.Query) int); ok {
+		r1 = rf(webhookID, query)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, paging.Query) error); ok {
+		r2 = rf(webhookID, query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListWebhooks provides a mock function with given fields: query
+func (_m *WebhooksModel) ListWebhooks(query paging.Query) ([]*webhooks.Webhook, int, error) {
+	ret := _m.Called(query)
+
+	var r0 []*webhooks.Webhook
+	if rf, ok := ret.Get(0).(func(paging.Query) []*webhooks.Webhook); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*webhooks.Webhook)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(paging.Query) int); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(paging.Query) error); ok {
+		r2 = rf(query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
@@ -0,0 +1,162 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package controller
+
+import (
+	"net/http"
+
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/asaskevich/govalidator"
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/utils/paging"
+	"github.com/mendersoftware/go-lib-micro/requestlog"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrIDNotUUIDv4 = errors.New("ID is not UUIDv4")
+)
+
+type WebhooksController struct {
+	view  RESTView
+	model WebhooksModel
+}
+
+func NewWebhooksController(model WebhooksModel, view RESTView) *WebhooksController {
+	return &WebhooksController{
+		model: model,
+		view:  view,
+	}
+}
+
+func (c *WebhooksController) PostWebhook(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	var constructor *webhooks.WebhookConstructor
+	if err := r.DecodeJsonPayload(&constructor); err != nil {
+		c.view.RenderError(w, r, errors.Wrap(err, "Validating request body"), http.StatusBadRequest, l)
+		return
+	}
+	if constructor == nil {
+		c.view.RenderError(w, r, ErrModelMissingInput, http.StatusBadRequest, l)
+		return
+	}
+	if err := constructor.Validate(); err != nil {
+		c.view.RenderError(w, r, errors.Wrap(err, "Validating request body"), http.StatusBadRequest, l)
+		return
+	}
+
+	id, err := c.model.CreateWebhook(constructor)
+	if err != nil {
+		c.view.RenderInternalError(w, r, err, l)
+		return
+	}
+
+	c.view.RenderSuccessPost(w, r, id)
+}
+
+func (c *WebhooksController) ListWebhooks(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	query, err := paging.ParsePagedQuery(r.URL.Query())
+	if err != nil {
+		c.view.RenderError(w, r, err, http.StatusBadRequest, l)
+		return
+	}
+
+	list, total, err := c.model.ListWebhooks(query)
+	if err != nil {
+		c.view.RenderInternalError(w, r, err, l)
+		return
+	}
+
+	c.view.RenderSuccessGetPage(w, r, list, query, total)
+}
+
+func (c *WebhooksController) GetWebhook(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	id := r.PathParam("id")
+
+	if !govalidator.IsUUIDv4(id) {
+		c.view.RenderError(w, r, ErrIDNotUUIDv4, http.StatusBadRequest, l)
+		return
+	}
+
+	webhook, err := c.model.GetWebhook(id)
+	if err != nil {
+		c.view.RenderInternalError(w, r, err, l)
+		return
+	}
+
+	if webhook == nil {
+		c.view.RenderErrorNotFound(w, r, l)
+		return
+	}
+
+	c.view.RenderSuccessGet(w, webhook)
+}
+
+func (c *WebhooksController) DeleteWebhook(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	id := r.PathParam("id")
+
+	if !govalidator.IsUUIDv4(id) {
+		c.view.RenderError(w, r, ErrIDNotUUIDv4, http.StatusBadRequest, l)
+		return
+	}
+
+	if err := c.model.DeleteWebhook(id); err != nil {
+		if errors.Cause(err) == ErrModelWebhookNotFound {
+			c.view.RenderErrorNotFound(w, r, l)
+			return
+		}
+		c.view.RenderInternalError(w, r, err, l)
+		return
+	}
+
+	c.view.RenderSuccessDelete(w)
+}
+
+// ListDeliveries returns delivery log of a webhook, newest deliveries first.
+func (c *WebhooksController) ListDeliveries(w rest.ResponseWriter, r *rest.Request) {
+	l := requestlog.GetRequestLogger(r.Env)
+
+	id := r.PathParam("id")
+
+	if !govalidator.IsUUIDv4(id) {
+		c.view.RenderError(w, r, ErrIDNotUUIDv4, http.StatusBadRequest, l)
+		return
+	}
+
+	query, err := paging.ParsePagedQuery(r.URL.Query())
+	if err != nil {
+		c.view.RenderError(w, r, err, http.StatusBadRequest, l)
+		return
+	}
+
+	list, total, err := c.model.ListDeliveries(id, query)
+	if err != nil {
+		if errors.Cause(err) == ErrModelWebhookNotFound {
+			c.view.RenderErrorNotFound(w, r, l)
+			return
+		}
+		c.view.RenderInternalError(w, r, err, l)
+		return
+	}
+
+	c.view.RenderSuccessGetPage(w, r, list, query, total)
+}
@@ -0,0 +1,351 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package controller_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/ant0ine/go-json-rest/rest/test"
+	"github.com/mendersoftware/deployments/resources/images/view"
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	. "github.com/mendersoftware/deployments/resources/webhooks/controller"
+	"github.com/mendersoftware/deployments/resources/webhooks/controller/mocks"
+	"github.com/mendersoftware/deployments/utils/paging"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	h "github.com/mendersoftware/deployments/utils/testing"
+	"github.com/mendersoftware/go-lib-micro/requestid"
+	"github.com/mendersoftware/go-lib-micro/requestlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const validUUIDv4 = "d50eda0d-2cea-4de1-8d42-9cd3e7e8670d"
+
+func makeApi(router rest.App) *rest.Api {
+	api := rest.NewApi()
+	api.Use(
+		&requestlog.RequestLogMiddleware{
+			BaseLogger: &logrus.Logger{Out: ioutil.Discard},
+		},
+		&requestid.RequestIdMiddleware{},
+	)
+	api.SetApp(router)
+	return api
+}
+
+func runRequest(t *testing.T, route *rest.Route, method string, url string, body interface{}) *test.Recorded {
+	router, err := rest.MakeRouter(route)
+	assert.NoError(t, err)
+
+	req := test.MakeSimpleRequest(method, url, body)
+	req.Header.Add(requestid.RequestIdHeader, "test")
+
+	return test.RunRequest(t, makeApi(router).MakeHandler(), req)
+}
+
+func TestControllerPostWebhook(t *testing.T) {
+
+	t.Parallel()
+
+	valid := &webhooks.WebhookConstructor{
+		URL:    StringToPointer("https://example.com/hook"),
+		Secret: StringToPointer("0123456789abcdef"),
+		Events: []string{webhooks.EventDeploymentFinished},
+	}
+
+	testCases := map[string]struct {
+		h.JSONResponseParams
+
+		InputBody       interface{}
+		InputModelID    string
+		InputModelError error
+	}{
+		"empty body": {
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("Validating request body: JSON payload is empty")),
+			},
+		},
+		"invalid webhook": {
+			InputBody: &webhooks.WebhookConstructor{
+				URL:    StringToPointer("ftp://example.com"),
+				Secret: StringToPointer("0123456789abcdef"),
+				Events: []string{webhooks.EventDeploymentFinished},
+			},
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("Validating request body: " +
+					webhooks.ErrInvalidWebhookURL.Error())),
+			},
+		},
+		"model error": {
+			InputBody:       valid,
+			InputModelError: errors.New("model error"),
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusInternalServerError,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("internal error")),
+			},
+		},
+		"created": {
+			InputBody:    valid,
+			InputModelID: validUUIDv4,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:  http.StatusCreated,
+				OutputHeaders: map[string]string{"Location": "./webhooks/" + validUUIDv4},
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		model := new(mocks.WebhooksModel)
+		model.On("CreateWebhook", mock.AnythingOfType("*webhooks.WebhookConstructor")).
+			Return(testCase.InputModelID, testCase.InputModelError)
+
+		recorded := runRequest(t,
+			rest.Post("/api/0.0.1/webhooks",
+				NewWebhooksController(model, new(view.RESTView)).PostWebhook),
+			"POST", "http://localhost/api/0.0.1/webhooks", testCase.InputBody)
+
+		h.CheckRecordedResponse(t, recorded, testCase.JSONResponseParams)
+	}
+}
+
+func TestControllerGetWebhook(t *testing.T) {
+
+	t.Parallel()
+
+	webhook := &webhooks.Webhook{
+		Id:     StringToPointer(validUUIDv4),
+		URL:    "https://example.com/hook",
+		Secret: "0123456789abcdef",
+		Events: []string{webhooks.EventDeploymentFinished},
+	}
+
+	testCases := map[string]struct {
+		h.JSONResponseParams
+
+		InputID           string
+		InputModelWebhook *webhooks.Webhook
+		InputModelError   error
+	}{
+		"invalid id": {
+			InputID: "not-uuid",
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(ErrIDNotUUIDv4),
+			},
+		},
+		"not found": {
+			InputID: validUUIDv4,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusNotFound,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("Resource not found")),
+			},
+		},
+		"model error": {
+			InputID:         validUUIDv4,
+			InputModelError: errors.New("model error"),
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusInternalServerError,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("internal error")),
+			},
+		},
+		"found, secret not exposed": {
+			InputID:           validUUIDv4,
+			InputModelWebhook: webhook,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusOK,
+				OutputBodyObject: map[string]interface{}{
+					"id":      validUUIDv4,
+					"url":     "https://example.com/hook",
+					"events":  []string{webhooks.EventDeploymentFinished},
+					"created": nil,
+				},
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		model := new(mocks.WebhooksModel)
+		model.On("GetWebhook", testCase.InputID).
+			Return(testCase.InputModelWebhook, testCase.InputModelError)
+
+		recorded := runRequest(t,
+			rest.Get("/api/0.0.1/webhooks/:id",
+				NewWebhooksController(model, new(view.RESTView)).GetWebhook),
+			"GET", "http://localhost/api/0.0.1/webhooks/"+testCase.InputID, nil)
+
+		h.CheckRecordedResponse(t, recorded, testCase.JSONResponseParams)
+	}
+}
+
+func TestControllerListWebhooks(t *testing.T) {
+
+	t.Parallel()
+
+	model := new(mocks.WebhooksModel)
+	model.On("ListWebhooks", paging.Query{Page: 2, PerPage: 1}).
+		Return([]*webhooks.Webhook{{Id: StringToPointer(validUUIDv4)}}, 3, nil)
+	model.On("ListWebhooks", *paging.NewQuery()).
+		Return(nil, 0, errors.New("model error"))
+
+	route := rest.Get("/api/0.0.1/webhooks",
+		NewWebhooksController(model, new(view.RESTView)).ListWebhooks)
+
+	recorded := runRequest(t, route, "GET", "http://localhost/api/0.0.1/webhooks?page=2&per_page=1", nil)
+	recorded.CodeIs(http.StatusOK)
+	recorded.HeaderIs(paging.HttpHeaderTotalCount, "3")
+
+	var list []*webhooks.Webhook
+	assert.NoError(t, json.Unmarshal(recorded.Recorder.Body.Bytes(), &list))
+	assert.Equal(t, []*webhooks.Webhook{{Id: StringToPointer(validUUIDv4)}}, list)
+
+	recorded = runRequest(t, route, "GET", "http://localhost/api/0.0.1/webhooks", nil)
+	recorded.CodeIs(http.StatusInternalServerError)
+
+	recorded = runRequest(t, route, "GET", "http://localhost/api/0.0.1/webhooks?page=0", nil)
+	recorded.CodeIs(http.StatusBadRequest)
+}
+
+func TestControllerDeleteWebhook(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		h.JSONResponseParams
+
+		InputID         string
+		InputModelError error
+	}{
+		"invalid id": {
+			InputID: "not-uuid",
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusBadRequest,
+				OutputBodyObject: h.ErrorToErrStruct(ErrIDNotUUIDv4),
+			},
+		},
+		"not found": {
+			InputID:         validUUIDv4,
+			InputModelError: ErrModelWebhookNotFound,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusNotFound,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("Resource not found")),
+			},
+		},
+		"model error": {
+			InputID:         validUUIDv4,
+			InputModelError: errors.New("model error"),
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus:     http.StatusInternalServerError,
+				OutputBodyObject: h.ErrorToErrStruct(errors.New("internal error")),
+			},
+		},
+		"deleted": {
+			InputID: validUUIDv4,
+			JSONResponseParams: h.JSONResponseParams{
+				OutputStatus: http.StatusNoContent,
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		model := new(mocks.WebhooksModel)
+		model.On("DeleteWebhook", testCase.InputID).
+			Return(testCase.InputModelError)
+
+		recorded := runRequest(t,
+			rest.Delete("/api/0.0.1/webhooks/:id",
+				NewWebhooksController(model, new(view.RESTView)).DeleteWebhook),
+			"DELETE", "http://localhost/api/0.0.1/webhooks/"+testCase.InputID, nil)
+
+		h.CheckRecordedResponse(t, recorded, testCase.JSONResponseParams)
+	}
+}
+
+func TestControllerListDeliveries(t *testing.T) {
+
+	t.Parallel()
+
+	delivery := &webhooks.Delivery{
+		Id:        "1",
+		WebhookID: validUUIDv4,
+		Event:     webhooks.EventArtifactUploaded,
+		Status:    webhooks.DeliveryStatusDelivered,
+		Attempts:  1,
+	}
+
+	testCases := map[string]struct {
+		InputID         string
+		InputModelList  []*webhooks.Delivery
+		InputModelError error
+
+		OutputStatus int
+		OutputList   []*webhooks.Delivery
+	}{
+		"invalid id": {
+			InputID:      "not-uuid",
+			OutputStatus: http.StatusBadRequest,
+		},
+		"not found": {
+			InputID:         validUUIDv4,
+			InputModelError: ErrModelWebhookNotFound,
+			OutputStatus:    http.StatusNotFound,
+		},
+		"model error": {
+			InputID:         validUUIDv4,
+			InputModelError: errors.New("model error"),
+			OutputStatus:    http.StatusInternalServerError,
+		},
+		"listed": {
+			InputID:        validUUIDv4,
+			InputModelList: []*webhooks.Delivery{delivery},
+			OutputStatus:   http.StatusOK,
+			OutputList:     []*webhooks.Delivery{delivery},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		model := new(mocks.WebhooksModel)
+		model.On("ListDeliveries", testCase.InputID, *paging.NewQuery()).
+			Return(testCase.InputModelList, len(testCase.InputModelList), testCase.InputModelError)
+
+		recorded := runRequest(t,
+			rest.Get("/api/0.0.1/webhooks/:id/deliveries",
+				NewWebhooksController(model, new(view.RESTView)).ListDeliveries),
+			"GET", "http://localhost/api/0.0.1/webhooks/"+testCase.InputID+"/deliveries", nil)
+
+		recorded.CodeIs(testCase.OutputStatus)
+		if testCase.OutputList != nil {
+			var list []*webhooks.Delivery
+			assert.NoError(t, json.NewDecoder(bytes.NewReader(recorded.Recorder.Body.Bytes())).Decode(&list))
+			assert.Equal(t, testCase.OutputList, list)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package controller
+
+import (
+	"errors"
+
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/utils/paging"
+)
+
+// Errors expected from interface
+var (
+	ErrModelMissingInput    = errors.New("Missing input webhook data")
+	ErrModelWebhookNotFound = errors.New("Webhook not found")
+)
+
+type WebhooksModel interface {
+	CreateWebhook(constructor *webhooks.WebhookConstructor) (string, error)
+	GetWebhook(id string) (*webhooks.Webhook, error)
+	ListWebhooks(query paging.Query) ([]*webhooks.Webhook, int, error)
+	DeleteWebhook(id string) error
+	ListDeliveries(webhookID string, query paging.Query) ([]*webhooks.Delivery, int, error)
+}
@@ -0,0 +1,122 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/satori/go.uuid"
+)
+
+// Delivery statuses
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// HTTP headers of delivered events
+const (
+	HttpHeaderEvent     = "X-Mender-Event"
+	HttpHeaderDelivery  = "X-Mender-Delivery"
+	HttpHeaderSignature = "X-Mender-Signature"
+)
+
+// Delivery retry backoff
+const (
+	RetryBackoffBase = 30 * time.Second
+	RetryBackoffMax  = 6 * time.Hour
+)
+
+// Notification is the body posted to webhooks.
+type Notification struct {
+	Event string      `json:"event"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data"`
+}
+
+// Delivery of an event to a webhook, persisted until delivered or failed for
+// good.
+type Delivery struct {
+	Id        string `json:"id" bson:"_id"`
+	WebhookID string `json:"webhook_id"`
+	Event     string `json:"event"`
+
+	// Notification JSON, signed and posted as is on every attempt
+	Payload string `json:"payload"`
+
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+
+	// Time of the next delivery attempt, unset once delivery is done
+	NextAttempt *time.Time `json:"next_attempt,omitempty"`
+
+	// Outcome of the last attempt
+	LastError      string `json:"last_error,omitempty"`
+	ResponseStatus int    `json:"response_status,omitempty"`
+
+	Created   *time.Time `json:"created"`
+	Delivered *time.Time `json:"delivered,omitempty"`
+}
+
+// NewDelivery creates pending delivery of event with given data to a webhook.
+func NewDelivery(webhookID string, event string, data interface{}) (*Delivery, error) {
+
+	now := time.Now()
+
+	payload, err := json.Marshal(&Notification{
+		Event: event,
+		Time:  now,
+		Data:  data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Delivery{
+		Id:          uuid.NewV4().String(),
+		WebhookID:   webhookID,
+		Event:       event,
+		Payload:     string(payload),
+		Status:      DeliveryStatusPending,
+		NextAttempt: &now,
+		Created:     &now,
+	}, nil
+}
+
+// Sign returns HMAC-SHA256 signature of payload in "sha256=<hex digest>" form.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RetryBackoff returns delay before next delivery attempt after given number
+// of failed attempts. The delay doubles with every attempt, up to
+// RetryBackoffMax.
+func RetryBackoff(attempts int) time.Duration {
+	backoff := RetryBackoffBase
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= RetryBackoffMax {
+			return RetryBackoffMax
+		}
+	}
+	return backoff
+}
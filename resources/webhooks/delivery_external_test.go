@@ -0,0 +1,79 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package webhooks_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDelivery(t *testing.T) {
+
+	t.Parallel()
+
+	delivery, err := NewDelivery("webhook-1", EventArtifactDeleted, map[string]string{"id": "123"})
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, delivery.Id)
+	assert.Equal(t, "webhook-1", delivery.WebhookID)
+	assert.Equal(t, EventArtifactDeleted, delivery.Event)
+	assert.Equal(t, DeliveryStatusPending, delivery.Status)
+	assert.Equal(t, 0, delivery.Attempts)
+	assert.Equal(t, delivery.Created, delivery.NextAttempt)
+
+	var notification struct {
+		Event string            `json:"event"`
+		Time  time.Time         `json:"time"`
+		Data  map[string]string `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(delivery.Payload), &notification))
+	assert.Equal(t, EventArtifactDeleted, notification.Event)
+	assert.Equal(t, map[string]string{"id": "123"}, notification.Data)
+
+	_, err = NewDelivery("webhook-1", EventArtifactDeleted, make(chan int))
+	assert.Error(t, err)
+}
+
+func TestSign(t *testing.T) {
+
+	t.Parallel()
+
+	// echo -n '{"event":"artifact_deleted"}' | openssl dgst -sha256 -hmac 0123456789abcdef
+	assert.Equal(t, "sha256=839259b43f6501a3e97731906c1dab3135e6a653c7cb75d4d8c844aaaaca96ab",
+		Sign("0123456789abcdef", []byte(`{"event":"artifact_deleted"}`)))
+}
+
+func TestRetryBackoff(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		10: 512 * 30 * time.Second,
+		11: 6 * time.Hour,
+		64: 6 * time.Hour,
+	}
+
+	for attempts, backoff := range testCases {
+		t.Logf("testing %d attempts", attempts)
+		assert.Equal(t, backoff, RetryBackoff(attempts))
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/utils/paging"
+)
+
+// Common errors for interface DeliveriesStorage
+var (
+	ErrDeliveriesStorageInvalidID       = errors.New("Invalid id")
+	ErrDeliveriesStorageInvalidDelivery = errors.New("Invalid delivery")
+)
+
+// DeliveriesStorage is a persistent queue and log of webhook deliveries
+type DeliveriesStorage interface {
+	Insert(delivery *webhooks.Delivery) error
+	// AcquireDue picks one pending delivery due at given time and postpones
+	// its next attempt by lease, so that other instances skip it while
+	// it's being delivered. Returns nil if there is nothing to deliver.
+	AcquireDue(now time.Time, lease time.Duration) (*webhooks.Delivery, error)
+	Update(delivery *webhooks.Delivery) error
+	FindForWebhook(webhookID string, query paging.Query) ([]*webhooks.Delivery, int, error)
+	DeleteForWebhook(webhookID string) error
+}
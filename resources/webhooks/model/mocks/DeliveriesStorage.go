@@ -0,0 +1,123 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mocks
+
+import (
+	"time"
+
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/utils/paging"
+	"github.com/stretchr/testify/mock"
+)
+
+// DeliveriesStorage is an autogenerated mock type for the DeliveriesStorage type
+type DeliveriesStorage struct {
+	mock.Mock
+}
+
+// AcquireDue provides a mock function with given fields: now, lease
+func (_m *DeliveriesStorage) AcquireDue(now time.Time, lease time.Duration) (*webhooks.Delivery, error) {
+	ret := _m.Called(now, lease)
+
+	var r0 *webhooks.Delivery
+	if rf, ok := ret.Get(0).(func(time.Time, time.Duration) *webhooks.Delivery); ok {
+		r0 = rf(now, lease)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*webhooks.Delivery)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(time.Time, time.Duration) error); ok {
+		r1 = rf(now, lease)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteForWebhook provides a mock function with given fields: webhookID
+func (_m *DeliveriesStorage) DeleteForWebhook(webhookID string) error {
+	ret := _m.Called(webhookID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(webhookID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindForWebhook provides a mock function with given fields: webhookID, query
+func (_m *DeliveriesStorage) FindForWebhook(webhookID string, query paging.Query) ([]*webhooks.Delivery, int, error) {
+	ret := _m.Called(webhookID, query)
+
+	var r0 []*webhooks.Delivery
+	if rf, ok := ret.Get(0).(func(string, paging.Query) []*webhooks.Delivery); ok {
+		r0 = rf(webhookID, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*webhooks.Delivery)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(string, paging.Query) int); ok {
+		r1 = rf(webhookID, query)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, paging.Query) error); ok {
+		r2 = rf(webhookID, query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Insert provides a mock function with given fields: delivery
+func (_m *DeliveriesStorage) Insert(delivery *webhooks.Delivery) error {
+	ret := _m.Called(delivery)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*webhooks.Delivery) error); ok {
+		r0 = rf(delivery)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Update provides a mock function with given fields: delivery
+func (_m *DeliveriesStorage) Update(delivery *webhooks.Delivery) error {
+	ret := _m.Called(delivery)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*webhooks.Delivery) error); ok {
+		r0 = rf(delivery)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
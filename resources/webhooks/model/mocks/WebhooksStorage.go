@@ -0,0 +1,130 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mocks
+
+import (
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/utils/paging"
+	"github.com/stretchr/testify/mock"
+)
+
+// WebhooksStorage is an autogenerated mock type for the WebhooksStorage type
+type WebhooksStorage struct {
+	mock.Mock
+}
+
+// Delete provides a mock function with given fields: id
+func (_m *WebhooksStorage) Delete(id string) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Find provides a mock function with given fields: query
+func (_m *WebhooksStorage) Find(query paging.Query) ([]*webhooks.Webhook, int, error) {
+	ret := _m.Called(query)
+
+	var r0 []*webhooks.Webhook
+	if rf, ok := ret.Get(0).(func(paging.Query) []*webhooks.Webhook); ok {
+		r0 = rf(query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*webhooks.Webhook)
+		}
+	}
+
+	var r1 int
+	if rf, ok := ret.Get(1).(func(paging.Query) int); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(paging.Query) error); ok {
+		r2 = rf(query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// FindByID provides a mock function with given fields: id
+func (_m *WebhooksStorage) FindByID(id string) (*webhooks.Webhook, error) {
+	ret := _m.Called(id)
+
+	var r0 *webhooks.Webhook
+	if rf, ok := ret.Get(0).(func(string) *webhooks.Webhook); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*webhooks.Webhook)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindForEvent provides a mock function with given fields: event
+func (_m *WebhooksStorage) FindForEvent(event string) ([]*webhooks.Webhook, error) {
+	ret := _m.Called(event)
+
+	var r0 []*webhooks.Webhook
+	if rf, ok := ret.Get(0).(func(string) []*webhooks.Webhook); ok {
+		r0 = rf(event)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*webhooks.Webhook)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(event)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Insert provides a mock function with given fields: webhook
+func (_m *WebhooksStorage) Insert(webhook *webhooks.Webhook) error {
+	ret := _m.Called(webhook)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*webhooks.Webhook) error); ok {
+		r0 = rf(webhook)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,296 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/resources/webhooks/controller"
+	"github.com/mendersoftware/deployments/utils/paging"
+	"github.com/mendersoftware/go-lib-micro/log"
+	"github.com/pkg/errors"
+)
+
+// Defaults
+const (
+	DefaultDeliveryTimeout = 10 * time.Second
+	DefaultMaxAttempts     = 10
+)
+
+type WebhooksModelConfig struct {
+	WebhooksStorage   WebhooksStorage
+	DeliveriesStorage DeliveriesStorage
+	// Client posting deliveries, its timeout bounds a single attempt
+	Client *http.Client
+	// Number of attempts after which a delivery fails for good
+	MaxAttempts int
+}
+
+type WebhooksModel struct {
+	webhooksStorage   WebhooksStorage
+	deliveriesStorage DeliveriesStorage
+	client            *http.Client
+	maxAttempts       int
+	log               *log.Logger
+}
+
+func NewWebhooksModel(config WebhooksModelConfig) *WebhooksModel {
+	client := config.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultDeliveryTimeout}
+	}
+
+	maxAttempts := config.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	return &WebhooksModel{
+		webhooksStorage:   config.WebhooksStorage,
+		deliveriesStorage: config.DeliveriesStorage,
+		client:            client,
+		maxAttempts:       maxAttempts,
+		log:               log.New(log.Ctx{"module": "webhooks"}),
+	}
+}
+
+// CreateWebhook stores new webhook subscription.
+// Returns webhook ID and nil on success.
+func (w *WebhooksModel) CreateWebhook(constructor *webhooks.WebhookConstructor) (string, error) {
+
+	if constructor == nil {
+		return "", controller.ErrModelMissingInput
+	}
+
+	if err := constructor.Validate(); err != nil {
+		return "", errors.Wrap(err, "Validating webhook")
+	}
+
+	webhook := webhooks.NewWebhookFromConstructor(constructor)
+
+	if err := w.webhooksStorage.Insert(webhook); err != nil {
+		return "", errors.Wrap(err, "Storing webhook data")
+	}
+
+	return *webhook.Id, nil
+}
+
+// GetWebhook returns webhook with given ID, nil if not found.
+func (w *WebhooksModel) GetWebhook(id string) (*webhooks.Webhook, error) {
+
+	webhook, err := w.webhooksStorage.FindByID(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "Searching for webhook with specified ID")
+	}
+
+	return webhook, nil
+}
+
+// ListWebhooks returns a page of webhooks and total number of webhooks.
+func (w *WebhooksModel) ListWebhooks(query paging.Query) ([]*webhooks.Webhook, int, error) {
+
+	list, total, err := w.webhooksStorage.Find(query)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "Searching for webhooks")
+	}
+
+	if list == nil {
+		return make([]*webhooks.Webhook, 0), total, nil
+	}
+
+	return list, total, nil
+}
+
+// DeleteWebhook removes webhook together with its delivery log, pending
+// deliveries are dropped.
+func (w *WebhooksModel) DeleteWebhook(id string) error {
+
+	found, err := w.GetWebhook(id)
+	if err != nil {
+		return err
+	}
+
+	if found == nil {
+		return controller.ErrModelWebhookNotFound
+	}
+
+	if err := w.webhooksStorage.Delete(id); err != nil {
+		return errors.Wrap(err, "Deleting webhook")
+	}
+
+	if err := w.deliveriesStorage.DeleteForWebhook(id); err != nil {
+		return errors.Wrap(err, "Deleting webhook deliveries")
+	}
+
+	return nil
+}
+
+// ListDeliveries returns a page of deliveries to the webhook, newest first,
+// and total number of its deliveries.
+func (w *WebhooksModel) ListDeliveries(webhookID string, query paging.Query) ([]*webhooks.Delivery, int, error) {
+
+	found, err := w.GetWebhook(webhookID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if found == nil {
+		return nil, 0, controller.ErrModelWebhookNotFound
+	}
+
+	list, total, err := w.deliveriesStorage.FindForWebhook(webhookID, query)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "Searching for webhook deliveries")
+	}
+
+	if list == nil {
+		return make([]*webhooks.Delivery, 0), total, nil
+	}
+
+	return list, total, nil
+}
+
+// Notify queues delivery of the event to all webhooks subscribed to it.
+// Notifications are best effort from the caller's point of view, errors are
+// only logged.
+func (w *WebhooksModel) Notify(event string, data interface{}) {
+
+	list, err := w.webhooksStorage.FindForEvent(event)
+	if err != nil {
+		w.log.Errorf("searching for webhooks subscribed to %s: %v", event, err)
+		return
+	}
+
+	for _, webhook := range list {
+		delivery, err := webhooks.NewDelivery(*webhook.Id, event, data)
+		if err != nil {
+			w.log.Errorf("preparing %s delivery to webhook %s: %v", event, *webhook.Id, err)
+			continue
+		}
+
+		if err := w.deliveriesStorage.Insert(delivery); err != nil {
+			w.log.Errorf("queueing %s delivery to webhook %s: %v", event, *webhook.Id, err)
+		}
+	}
+}
+
+// DeliverPending attempts all deliveries which are due. Failed attempts are
+// retried with exponential backoff until maximum number of attempts.
+// Returns number of attempted deliveries.
+func (w *WebhooksModel) DeliverPending() (int, error) {
+
+	// lease outlives the attempt, so that no other instance picks
+	// the delivery while it's in flight
+	lease := 2 * w.client.Timeout
+	if lease <= 0 {
+		lease = 2 * DefaultDeliveryTimeout
+	}
+
+	count := 0
+	for {
+		delivery, err := w.deliveriesStorage.AcquireDue(time.Now(), lease)
+		if err != nil {
+			return count, errors.Wrap(err, "Searching for pending deliveries")
+		}
+
+		if delivery == nil {
+			return count, nil
+		}
+
+		if err := w.deliver(delivery); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+// deliver makes a single attempt of the delivery and stores its outcome.
+func (w *WebhooksModel) deliver(delivery *webhooks.Delivery) error {
+
+	webhook, err := w.webhooksStorage.FindByID(delivery.WebhookID)
+	if err != nil {
+		return errors.Wrap(err, "Searching for webhook with specified ID")
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+
+	if webhook == nil {
+		delivery.Status = webhooks.DeliveryStatusFailed
+		delivery.NextAttempt = nil
+		delivery.LastError = "webhook deleted"
+	} else if status, err := w.post(webhook, delivery); err == nil {
+		delivery.Status = webhooks.DeliveryStatusDelivered
+		delivery.NextAttempt = nil
+		delivery.Delivered = &now
+		delivery.LastError = ""
+		delivery.ResponseStatus = status
+	} else {
+		delivery.LastError = err.Error()
+		delivery.ResponseStatus = status
+
+		if delivery.Attempts >= w.maxAttempts {
+			delivery.Status = webhooks.DeliveryStatusFailed
+			delivery.NextAttempt = nil
+		} else {
+			next := now.Add(webhooks.RetryBackoff(delivery.Attempts))
+			delivery.NextAttempt = &next
+		}
+	}
+
+	if err := w.deliveriesStorage.Update(delivery); err != nil {
+		return errors.Wrap(err, "Storing delivery outcome")
+	}
+
+	return nil
+}
+
+// post sends the delivery payload to the webhook, signed with its secret.
+// Returns response status code, if any, and error unless webhook responded
+// with 2xx.
+func (w *WebhooksModel) post(webhook *webhooks.Webhook, delivery *webhooks.Delivery) (int, error) {
+
+	payload := []byte(delivery.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhooks.HttpHeaderEvent, delivery.Event)
+	req.Header.Set(webhooks.HttpHeaderDelivery, delivery.Id)
+	req.Header.Set(webhooks.HttpHeaderSignature, webhooks.Sign(webhook.Secret, payload))
+
+	rsp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+	// drain body to let the connection be reused
+	io.Copy(ioutil.Discard, rsp.Body)
+
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return rsp.StatusCode, fmt.Errorf("unexpected response status %s", rsp.Status)
+	}
+
+	return rsp.StatusCode, nil
+}
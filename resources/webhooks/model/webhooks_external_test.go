@@ -0,0 +1,380 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/resources/webhooks/controller"
+	. "github.com/mendersoftware/deployments/resources/webhooks/model"
+	"github.com/mendersoftware/deployments/resources/webhooks/model/mocks"
+	"github.com/mendersoftware/deployments/utils/paging"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const validUUIDv4 = "d50eda0d-2cea-4de1-8d42-9cd3e7e8670d"
+
+func TestWebhooksModelCreateWebhook(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputConstructor *webhooks.WebhookConstructor
+		InputInsertError error
+
+		OutputError error
+	}{
+		"nil constructor": {
+			OutputError: controller.ErrModelMissingInput,
+		},
+		"invalid constructor": {
+			InputConstructor: &webhooks.WebhookConstructor{
+				URL:    StringToPointer("http://example.com"),
+				Secret: StringToPointer("0123456789abcdef"),
+			},
+			OutputError: errors.New("Validating webhook: " + webhooks.ErrMissingWebhookEvents.Error()),
+		},
+		"storage error": {
+			InputConstructor: &webhooks.WebhookConstructor{
+				URL:    StringToPointer("http://example.com"),
+				Secret: StringToPointer("0123456789abcdef"),
+				Events: []string{webhooks.EventDeploymentCreated},
+			},
+			InputInsertError: errors.New("storage error"),
+			OutputError:      errors.New("Storing webhook data: storage error"),
+		},
+		"created": {
+			InputConstructor: &webhooks.WebhookConstructor{
+				URL:    StringToPointer("http://example.com"),
+				Secret: StringToPointer("0123456789abcdef"),
+				Events: []string{webhooks.EventDeploymentCreated},
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		webhooksStorage := new(mocks.WebhooksStorage)
+		webhooksStorage.On("Insert", mock.AnythingOfType("*webhooks.Webhook")).
+			Return(testCase.InputInsertError)
+
+		model := NewWebhooksModel(WebhooksModelConfig{
+			WebhooksStorage: webhooksStorage,
+		})
+
+		id, err := model.CreateWebhook(testCase.InputConstructor)
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+			assert.Empty(t, id)
+		} else {
+			assert.NoError(t, err)
+			assert.NotEmpty(t, id)
+		}
+	}
+}
+
+func TestWebhooksModelDeleteWebhook(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputWebhook   *webhooks.Webhook
+		InputFindError error
+
+		OutputError error
+	}{
+		"find error": {
+			InputFindError: errors.New("storage error"),
+			OutputError:    errors.New("Searching for webhook with specified ID: storage error"),
+		},
+		"not found": {
+			OutputError: controller.ErrModelWebhookNotFound,
+		},
+		"deleted": {
+			InputWebhook: &webhooks.Webhook{Id: StringToPointer(validUUIDv4)},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		webhooksStorage := new(mocks.WebhooksStorage)
+		webhooksStorage.On("FindByID", validUUIDv4).
+			Return(testCase.InputWebhook, testCase.InputFindError)
+		webhooksStorage.On("Delete", validUUIDv4).
+			Return(nil)
+
+		deliveriesStorage := new(mocks.DeliveriesStorage)
+		deliveriesStorage.On("DeleteForWebhook", validUUIDv4).
+			Return(nil)
+
+		model := NewWebhooksModel(WebhooksModelConfig{
+			WebhooksStorage:   webhooksStorage,
+			DeliveriesStorage: deliveriesStorage,
+		})
+
+		err := model.DeleteWebhook(validUUIDv4)
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+			deliveriesStorage.AssertNotCalled(t, "DeleteForWebhook", validUUIDv4)
+		} else {
+			assert.NoError(t, err)
+			webhooksStorage.AssertCalled(t, "Delete", validUUIDv4)
+			deliveriesStorage.AssertCalled(t, "DeleteForWebhook", validUUIDv4)
+		}
+	}
+}
+
+func TestWebhooksModelListDeliveries(t *testing.T) {
+
+	t.Parallel()
+
+	query := *paging.NewQuery()
+
+	webhooksStorage := new(mocks.WebhooksStorage)
+	webhooksStorage.On("FindByID", validUUIDv4).
+		Return(&webhooks.Webhook{Id: StringToPointer(validUUIDv4)}, nil)
+	webhooksStorage.On("FindByID", "missing").
+		Return(nil, nil)
+
+	deliveriesStorage := new(mocks.DeliveriesStorage)
+	deliveriesStorage.On("FindForWebhook", validUUIDv4, query).
+		Return(nil, 0, nil)
+
+	model := NewWebhooksModel(WebhooksModelConfig{
+		WebhooksStorage:   webhooksStorage,
+		DeliveriesStorage: deliveriesStorage,
+	})
+
+	list, total, err := model.ListDeliveries(validUUIDv4, query)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Equal(t, []*webhooks.Delivery{}, list)
+
+	_, _, err = model.ListDeliveries("missing", query)
+	assert.EqualError(t, err, controller.ErrModelWebhookNotFound.Error())
+}
+
+func TestWebhooksModelNotify(t *testing.T) {
+
+	t.Parallel()
+
+	webhooksStorage := new(mocks.WebhooksStorage)
+	webhooksStorage.On("FindForEvent", webhooks.EventDeploymentFinished).
+		Return([]*webhooks.Webhook{
+			{Id: StringToPointer("1")},
+			{Id: StringToPointer("2")},
+		}, nil)
+	webhooksStorage.On("FindForEvent", webhooks.EventArtifactDeleted).
+		Return(nil, errors.New("storage error"))
+
+	var queued []*webhooks.Delivery
+	deliveriesStorage := new(mocks.DeliveriesStorage)
+	deliveriesStorage.On("Insert", mock.AnythingOfType("*webhooks.Delivery")).
+		Run(func(args mock.Arguments) {
+			queued = append(queued, args.Get(0).(*webhooks.Delivery))
+		}).
+		Return(nil)
+
+	model := NewWebhooksModel(WebhooksModelConfig{
+		WebhooksStorage:   webhooksStorage,
+		DeliveriesStorage: deliveriesStorage,
+	})
+
+	model.Notify(webhooks.EventDeploymentFinished, map[string]string{"id": "123"})
+	model.Notify(webhooks.EventArtifactDeleted, map[string]string{"id": "456"})
+
+	if assert.Len(t, queued, 2) {
+		for i, delivery := range queued {
+			assert.Equal(t, []string{"1", "2"}[i], delivery.WebhookID)
+			assert.Equal(t, webhooks.EventDeploymentFinished, delivery.Event)
+			assert.Equal(t, webhooks.DeliveryStatusPending, delivery.Status)
+			assert.Contains(t, delivery.Payload, `"data":{"id":"123"}`)
+		}
+	}
+}
+
+func TestWebhooksModelDeliverPending(t *testing.T) {
+
+	t.Parallel()
+
+	const secret = "0123456789abcdef"
+
+	testCases := map[string]struct {
+		InputResponseStatus int
+		InputAttempts       int
+		InputWebhookDeleted bool
+		InputUnreachable    bool
+
+		OutputStatus         string
+		OutputAttempts       int
+		OutputResponseStatus int
+		OutputRetry          bool
+		OutputPosted         bool
+	}{
+		"delivered": {
+			InputResponseStatus:  http.StatusNoContent,
+			OutputStatus:         webhooks.DeliveryStatusDelivered,
+			OutputAttempts:       1,
+			OutputResponseStatus: http.StatusNoContent,
+			OutputPosted:         true,
+		},
+		"rejected, retry": {
+			InputResponseStatus:  http.StatusInternalServerError,
+			InputAttempts:        2,
+			OutputStatus:         webhooks.DeliveryStatusPending,
+			OutputAttempts:       3,
+			OutputResponseStatus: http.StatusInternalServerError,
+			OutputRetry:          true,
+			OutputPosted:         true,
+		},
+		"rejected, out of attempts": {
+			InputResponseStatus:  http.StatusBadRequest,
+			InputAttempts:        4,
+			OutputStatus:         webhooks.DeliveryStatusFailed,
+			OutputAttempts:       5,
+			OutputResponseStatus: http.StatusBadRequest,
+			OutputPosted:         true,
+		},
+		"unreachable, retry": {
+			InputUnreachable: true,
+			OutputStatus:     webhooks.DeliveryStatusPending,
+			OutputAttempts:   1,
+			OutputRetry:      true,
+		},
+		"webhook deleted": {
+			InputWebhookDeleted: true,
+			OutputStatus:        webhooks.DeliveryStatusFailed,
+			OutputAttempts:      1,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		delivery, err := webhooks.NewDelivery(validUUIDv4, webhooks.EventDeploymentCreated,
+			map[string]string{"id": "123"})
+		assert.NoError(t, err)
+		delivery.Attempts = testCase.InputAttempts
+
+		var posted *http.Request
+		var postedBody []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			posted = r
+			postedBody, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(testCase.InputResponseStatus)
+		}))
+		if testCase.InputUnreachable {
+			server.Close()
+		} else {
+			defer server.Close()
+		}
+
+		var webhook *webhooks.Webhook
+		if !testCase.InputWebhookDeleted {
+			webhook = &webhooks.Webhook{
+				Id:     StringToPointer(validUUIDv4),
+				URL:    server.URL,
+				Secret: secret,
+			}
+		}
+
+		webhooksStorage := new(mocks.WebhooksStorage)
+		webhooksStorage.On("FindByID", validUUIDv4).
+			Return(webhook, nil)
+
+		deliveriesStorage := new(mocks.DeliveriesStorage)
+		deliveriesStorage.On("AcquireDue", mock.AnythingOfType("time.Time"), 2*time.Second).
+			Return(delivery, nil).Once()
+		deliveriesStorage.On("AcquireDue", mock.AnythingOfType("time.Time"), 2*time.Second).
+			Return(nil, nil).Once()
+		deliveriesStorage.On("Update", delivery).
+			Return(nil)
+
+		model := NewWebhooksModel(WebhooksModelConfig{
+			WebhooksStorage:   webhooksStorage,
+			DeliveriesStorage: deliveriesStorage,
+			Client:            &http.Client{Timeout: time.Second},
+			MaxAttempts:       5,
+		})
+
+		before := time.Now()
+		count, err := model.DeliverPending()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		deliveriesStorage.AssertCalled(t, "Update", delivery)
+
+		assert.Equal(t, testCase.OutputStatus, delivery.Status)
+		assert.Equal(t, testCase.OutputAttempts, delivery.Attempts)
+		assert.Equal(t, testCase.OutputResponseStatus, delivery.ResponseStatus)
+
+		if testCase.OutputRetry {
+			assert.NotEmpty(t, delivery.LastError)
+			if assert.NotNil(t, delivery.NextAttempt) {
+				assert.WithinDuration(t,
+					before.Add(webhooks.RetryBackoff(testCase.OutputAttempts)),
+					*delivery.NextAttempt, time.Second)
+			}
+		} else {
+			assert.Nil(t, delivery.NextAttempt)
+		}
+
+		if testCase.OutputStatus == webhooks.DeliveryStatusDelivered {
+			assert.NotNil(t, delivery.Delivered)
+			assert.Empty(t, delivery.LastError)
+		} else {
+			assert.Nil(t, delivery.Delivered)
+			assert.NotEmpty(t, delivery.LastError)
+		}
+
+		if testCase.OutputPosted {
+			if assert.NotNil(t, posted) {
+				assert.Equal(t, http.MethodPost, posted.Method)
+				assert.Equal(t, webhooks.EventDeploymentCreated, posted.Header.Get(webhooks.HttpHeaderEvent))
+				assert.Equal(t, delivery.Id, posted.Header.Get(webhooks.HttpHeaderDelivery))
+				assert.Equal(t, webhooks.Sign(secret, postedBody), posted.Header.Get(webhooks.HttpHeaderSignature))
+				assert.Equal(t, delivery.Payload, string(postedBody))
+			}
+		} else {
+			assert.Nil(t, posted)
+		}
+	}
+}
+
+func TestWebhooksModelDeliverPendingError(t *testing.T) {
+
+	t.Parallel()
+
+	deliveriesStorage := new(mocks.DeliveriesStorage)
+	deliveriesStorage.On("AcquireDue", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Duration")).
+		Return(nil, errors.New("storage error"))
+
+	model := NewWebhooksModel(WebhooksModelConfig{
+		DeliveriesStorage: deliveriesStorage,
+	})
+
+	count, err := model.DeliverPending()
+	assert.EqualError(t, err, "Searching for pending deliveries: storage error")
+	assert.Equal(t, 0, count)
+}
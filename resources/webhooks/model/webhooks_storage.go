@@ -0,0 +1,37 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package model
+
+import (
+	"errors"
+
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/utils/paging"
+)
+
+// Common errors for interface WebhooksStorage
+var (
+	ErrWebhooksStorageInvalidID      = errors.New("Invalid id")
+	ErrWebhooksStorageInvalidWebhook = errors.New("Invalid webhook")
+)
+
+// WebhooksStorage allows to store and manage webhooks
+type WebhooksStorage interface {
+	Insert(webhook *webhooks.Webhook) error
+	FindByID(id string) (*webhooks.Webhook, error)
+	Find(query paging.Query) ([]*webhooks.Webhook, int, error)
+	FindForEvent(event string) ([]*webhooks.Webhook, error)
+	Delete(id string) error
+}
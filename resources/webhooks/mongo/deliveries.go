@@ -0,0 +1,195 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mongo
+
+import (
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/resources/webhooks/model"
+	"github.com/mendersoftware/deployments/utils/paging"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Database KEYS
+const (
+	// Need to be kept in sync with Delivery structure field names
+	StorageKeyDeliveryId          = "_id"
+	StorageKeyDeliveryWebhookID   = "webhookid"
+	StorageKeyDeliveryStatus      = "status"
+	StorageKeyDeliveryNextAttempt = "nextattempt"
+	StorageKeyDeliveryCreated     = "created"
+)
+
+// Indexes
+const (
+	IndexDeliveryStatusAndNextAttemptStr = "deliveryStatusAndNextAttemptIndex"
+	IndexDeliveryWebhookAndCreatedStr    = "deliveryWebhookAndCreatedIndex"
+)
+
+// Database
+const (
+	CollectionDeliveries = "webhooks.deliveries"
+)
+
+// DeliveriesStorage is a data layer for webhook deliveries based on MongoDB
+// Implements model.DeliveriesStorage
+type DeliveriesStorage struct {
+	session *mgo.Session
+}
+
+// NewDeliveriesStorage new data layer object
+func NewDeliveriesStorage(session *mgo.Session) *DeliveriesStorage {
+
+	return &DeliveriesStorage{
+		session: session,
+	}
+}
+
+// IndexStorage set required indexes.
+// * Pending deliveries by next attempt time, to pick due deliveries.
+// * Deliveries of a webhook by creation time, to list delivery log.
+func (d *DeliveriesStorage) IndexStorage() error {
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	dueIndex := mgo.Index{
+		Key:        []string{StorageKeyDeliveryStatus, StorageKeyDeliveryNextAttempt},
+		Name:       IndexDeliveryStatusAndNextAttemptStr,
+		Background: false,
+	}
+
+	if err := session.DB(DatabaseName).C(CollectionDeliveries).EnsureIndex(dueIndex); err != nil {
+		return err
+	}
+
+	logIndex := mgo.Index{
+		Key:        []string{StorageKeyDeliveryWebhookID, StorageKeyDeliveryCreated},
+		Name:       IndexDeliveryWebhookAndCreatedStr,
+		Background: false,
+	}
+
+	return session.DB(DatabaseName).C(CollectionDeliveries).EnsureIndex(logIndex)
+}
+
+// Insert persists object
+func (d *DeliveriesStorage) Insert(delivery *webhooks.Delivery) error {
+
+	if delivery == nil || govalidator.IsNull(delivery.Id) {
+		return model.ErrDeliveriesStorageInvalidDelivery
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	return session.DB(DatabaseName).C(CollectionDeliveries).Insert(delivery)
+}
+
+// AcquireDue picks the pending delivery waiting longest for its attempt at
+// given time. Its next attempt is moved by lease in the same update, so that
+// concurrent callers don't pick it again. Returns nil if nothing is due.
+func (d *DeliveriesStorage) AcquireDue(now time.Time, lease time.Duration) (*webhooks.Delivery, error) {
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	query := bson.M{
+		StorageKeyDeliveryStatus:      webhooks.DeliveryStatusPending,
+		StorageKeyDeliveryNextAttempt: bson.M{"$lte": now},
+	}
+
+	change := mgo.Change{
+		Update: bson.M{
+			"$set": bson.M{
+				StorageKeyDeliveryNextAttempt: now.Add(lease),
+			},
+		},
+		ReturnNew: true,
+	}
+
+	var delivery *webhooks.Delivery
+	_, err := session.DB(DatabaseName).C(CollectionDeliveries).Find(query).
+		Sort(StorageKeyDeliveryNextAttempt).
+		Apply(change, &delivery)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Update replaces stored delivery with provided one
+func (d *DeliveriesStorage) Update(delivery *webhooks.Delivery) error {
+
+	if delivery == nil || govalidator.IsNull(delivery.Id) {
+		return model.ErrDeliveriesStorageInvalidDelivery
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	return session.DB(DatabaseName).C(CollectionDeliveries).UpdateId(delivery.Id, delivery)
+}
+
+// FindForWebhook lists a page of deliveries to the webhook, newest first,
+// together with total number of its deliveries.
+func (d *DeliveriesStorage) FindForWebhook(webhookID string, query paging.Query) ([]*webhooks.Delivery, int, error) {
+
+	if govalidator.IsNull(webhookID) {
+		return nil, 0, model.ErrDeliveriesStorageInvalidID
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	filter := bson.M{StorageKeyDeliveryWebhookID: webhookID}
+
+	total, err := session.DB(DatabaseName).C(CollectionDeliveries).Find(filter).Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var list []*webhooks.Delivery
+	err = session.DB(DatabaseName).C(CollectionDeliveries).Find(filter).
+		Sort("-"+StorageKeyDeliveryCreated, StorageKeyDeliveryId).
+		Skip(query.Skip()).Limit(query.PerPage).
+		All(&list)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return list, total, nil
+}
+
+// DeleteForWebhook removes all deliveries to the webhook.
+func (d *DeliveriesStorage) DeleteForWebhook(webhookID string) error {
+
+	if govalidator.IsNull(webhookID) {
+		return model.ErrDeliveriesStorageInvalidID
+	}
+
+	session := d.session.Copy()
+	defer session.Close()
+
+	_, err := session.DB(DatabaseName).C(CollectionDeliveries).
+		RemoveAll(bson.M{StorageKeyDeliveryWebhookID: webhookID})
+	return err
+}
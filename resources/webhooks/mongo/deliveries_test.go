@@ -0,0 +1,143 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mongo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/resources/webhooks/model"
+	. "github.com/mendersoftware/deployments/resources/webhooks/mongo"
+	"github.com/mendersoftware/deployments/utils/paging"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDelivery(id string, webhookID string, status string, due time.Time) *webhooks.Delivery {
+	return &webhooks.Delivery{
+		Id:          id,
+		WebhookID:   webhookID,
+		Event:       webhooks.EventDeploymentCreated,
+		Payload:     `{"event":"deployment_created"}`,
+		Status:      status,
+		NextAttempt: TimeToPointer(due),
+		Created:     TimeToPointer(due),
+	}
+}
+
+func TestDeliveriesStorageAcquireDue(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestDeliveriesStorageAcquireDue in short mode.")
+	}
+
+	db.Wipe()
+	session := db.Session()
+	defer session.Close()
+
+	store := NewDeliveriesStorage(session)
+	assert.NoError(t, store.IndexStorage())
+
+	now := time.Now().Round(time.Millisecond)
+	webhookID := "b1b6f0c6-4b42-4f4b-8c53-6a1b52a1b4e1"
+
+	assert.EqualError(t, store.Insert(nil), model.ErrDeliveriesStorageInvalidDelivery.Error())
+	for _, delivery := range []*webhooks.Delivery{
+		newTestDelivery("1", webhookID, webhooks.DeliveryStatusPending, now.Add(-time.Minute)),
+		newTestDelivery("2", webhookID, webhooks.DeliveryStatusPending, now.Add(-time.Hour)),
+		newTestDelivery("3", webhookID, webhooks.DeliveryStatusPending, now.Add(time.Hour)),
+		newTestDelivery("4", webhookID, webhooks.DeliveryStatusFailed, now.Add(-time.Hour)),
+	} {
+		assert.NoError(t, store.Insert(delivery))
+	}
+
+	// longest waiting due delivery first, leased until now + lease
+	delivery, err := store.AcquireDue(now, time.Minute)
+	assert.NoError(t, err)
+	if assert.NotNil(t, delivery) {
+		assert.Equal(t, "2", delivery.Id)
+		assert.WithinDuration(t, now.Add(time.Minute), *delivery.NextAttempt, time.Millisecond)
+	}
+
+	delivery, err = store.AcquireDue(now, time.Minute)
+	assert.NoError(t, err)
+	if assert.NotNil(t, delivery) {
+		assert.Equal(t, "1", delivery.Id)
+
+		delivery.Status = webhooks.DeliveryStatusDelivered
+		delivery.NextAttempt = nil
+		assert.NoError(t, store.Update(delivery))
+	}
+
+	// leased and not yet due deliveries are skipped
+	delivery, err = store.AcquireDue(now, time.Minute)
+	assert.NoError(t, err)
+	assert.Nil(t, delivery)
+
+	// lease of delivery 2 expired, delivery 1 is done
+	delivery, err = store.AcquireDue(now.Add(2*time.Minute), time.Minute)
+	assert.NoError(t, err)
+	if assert.NotNil(t, delivery) {
+		assert.Equal(t, "2", delivery.Id)
+	}
+}
+
+func TestDeliveriesStorageFindAndDeleteForWebhook(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestDeliveriesStorageFindAndDeleteForWebhook in short mode.")
+	}
+
+	db.Wipe()
+	session := db.Session()
+	defer session.Close()
+
+	store := NewDeliveriesStorage(session)
+
+	now := time.Now().Round(time.Millisecond)
+	webhookID := "b1b6f0c6-4b42-4f4b-8c53-6a1b52a1b4e1"
+	otherWebhookID := "b1b6f0c6-4b42-4f4b-8c53-6a1b52a1b4e2"
+
+	older := newTestDelivery("1", webhookID, webhooks.DeliveryStatusDelivered, now.Add(-time.Hour))
+	newer := newTestDelivery("2", webhookID, webhooks.DeliveryStatusPending, now)
+	other := newTestDelivery("3", otherWebhookID, webhooks.DeliveryStatusPending, now)
+	for _, delivery := range []*webhooks.Delivery{older, newer, other} {
+		assert.NoError(t, store.Insert(delivery))
+	}
+
+	list, total, err := store.FindForWebhook(webhookID, *paging.NewQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, []*webhooks.Delivery{newer, older}, list)
+
+	list, total, err = store.FindForWebhook(webhookID, paging.Query{Page: 2, PerPage: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, []*webhooks.Delivery{older}, list)
+
+	_, _, err = store.FindForWebhook("", *paging.NewQuery())
+	assert.EqualError(t, err, model.ErrDeliveriesStorageInvalidID.Error())
+
+	assert.NoError(t, store.DeleteForWebhook(webhookID))
+
+	list, total, err = store.FindForWebhook(webhookID, *paging.NewQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, list)
+
+	list, total, err = store.FindForWebhook(otherWebhookID, *paging.NewQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []*webhooks.Delivery{other}, list)
+}
@@ -0,0 +1,148 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mongo
+
+import (
+	"github.com/asaskevich/govalidator"
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/resources/webhooks/model"
+	"github.com/mendersoftware/deployments/utils/paging"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Database KEYS
+const (
+	// Need to be kept in sync with Webhook structure field names
+	StorageKeyWebhookId      = "_id"
+	StorageKeyWebhookEvents  = "events"
+	StorageKeyWebhookCreated = "created"
+)
+
+// Database
+const (
+	DatabaseName       = "deployment_service"
+	CollectionWebhooks = "webhooks"
+)
+
+// WebhooksStorage is a data layer for webhooks based on MongoDB
+// Implements model.WebhooksStorage
+type WebhooksStorage struct {
+	session *mgo.Session
+}
+
+// NewWebhooksStorage new data layer object
+func NewWebhooksStorage(session *mgo.Session) *WebhooksStorage {
+
+	return &WebhooksStorage{
+		session: session,
+	}
+}
+
+// Insert persists object
+func (w *WebhooksStorage) Insert(webhook *webhooks.Webhook) error {
+
+	if webhook == nil || webhook.Id == nil {
+		return model.ErrWebhooksStorageInvalidWebhook
+	}
+
+	session := w.session.Copy()
+	defer session.Close()
+
+	return session.DB(DatabaseName).C(CollectionWebhooks).Insert(webhook)
+}
+
+// FindByID search storage for webhook with ID, returns nil if not found
+func (w *WebhooksStorage) FindByID(id string) (*webhooks.Webhook, error) {
+
+	if govalidator.IsNull(id) {
+		return nil, model.ErrWebhooksStorageInvalidID
+	}
+
+	session := w.session.Copy()
+	defer session.Close()
+
+	var webhook *webhooks.Webhook
+	if err := session.DB(DatabaseName).C(CollectionWebhooks).FindId(id).One(&webhook); err != nil {
+		if err.Error() == mgo.ErrNotFound.Error() {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// Find lists a page of webhooks in order of creation, together with total
+// number of webhooks.
+func (w *WebhooksStorage) Find(query paging.Query) ([]*webhooks.Webhook, int, error) {
+
+	session := w.session.Copy()
+	defer session.Close()
+
+	total, err := session.DB(DatabaseName).C(CollectionWebhooks).Find(nil).Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var list []*webhooks.Webhook
+	err = session.DB(DatabaseName).C(CollectionWebhooks).Find(nil).
+		Sort(StorageKeyWebhookCreated, StorageKeyWebhookId).
+		Skip(query.Skip()).Limit(query.PerPage).
+		All(&list)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return list, total, nil
+}
+
+// FindForEvent lists all webhooks subscribed to the event.
+func (w *WebhooksStorage) FindForEvent(event string) ([]*webhooks.Webhook, error) {
+
+	session := w.session.Copy()
+	defer session.Close()
+
+	var list []*webhooks.Webhook
+	err := session.DB(DatabaseName).C(CollectionWebhooks).
+		Find(bson.M{StorageKeyWebhookEvents: event}).
+		All(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Delete webhook specified by ID
+// Noop on if not found.
+func (w *WebhooksStorage) Delete(id string) error {
+
+	if govalidator.IsNull(id) {
+		return model.ErrWebhooksStorageInvalidID
+	}
+
+	session := w.session.Copy()
+	defer session.Close()
+
+	if err := session.DB(DatabaseName).C(CollectionWebhooks).RemoveId(id); err != nil {
+		if err.Error() == mgo.ErrNotFound.Error() {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
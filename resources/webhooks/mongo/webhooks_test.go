@@ -0,0 +1,98 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package mongo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mendersoftware/deployments/resources/webhooks"
+	"github.com/mendersoftware/deployments/resources/webhooks/model"
+	. "github.com/mendersoftware/deployments/resources/webhooks/mongo"
+	"github.com/mendersoftware/deployments/utils/paging"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestWebhook(id string, created time.Time, events ...string) *webhooks.Webhook {
+	return &webhooks.Webhook{
+		Id:      StringToPointer(id),
+		URL:     "http://example.com/" + id,
+		Secret:  "0123456789abcdef",
+		Events:  events,
+		Created: TimeToPointer(created),
+	}
+}
+
+func TestWebhooksStorage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestWebhooksStorage in short mode.")
+	}
+
+	db.Wipe()
+	session := db.Session()
+	defer session.Close()
+
+	store := NewWebhooksStorage(session)
+
+	now := time.Now().Round(time.Millisecond)
+	first := newTestWebhook("b1b6f0c6-4b42-4f4b-8c53-6a1b52a1b4e1", now.Add(-time.Hour),
+		webhooks.EventDeploymentCreated, webhooks.EventDeploymentFinished)
+	second := newTestWebhook("b1b6f0c6-4b42-4f4b-8c53-6a1b52a1b4e2", now,
+		webhooks.EventDeploymentFinished)
+
+	assert.EqualError(t, store.Insert(nil), model.ErrWebhooksStorageInvalidWebhook.Error())
+	assert.NoError(t, store.Insert(second))
+	assert.NoError(t, store.Insert(first))
+
+	found, err := store.FindByID(*first.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, first, found)
+
+	found, err = store.FindByID("b1b6f0c6-4b42-4f4b-8c53-6a1b52a1b4e3")
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	list, total, err := store.Find(*paging.NewQuery())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, []*webhooks.Webhook{first, second}, list)
+
+	list, total, err = store.Find(paging.Query{Page: 2, PerPage: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, []*webhooks.Webhook{second}, list)
+
+	list, err = store.FindForEvent(webhooks.EventDeploymentFinished)
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	list, err = store.FindForEvent(webhooks.EventDeploymentCreated)
+	assert.NoError(t, err)
+	assert.Equal(t, []*webhooks.Webhook{first}, list)
+
+	list, err = store.FindForEvent(webhooks.EventArtifactDeleted)
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	assert.NoError(t, store.Delete(*first.Id))
+	found, err = store.FindByID(*first.Id)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	// deleting again is a noop
+	assert.NoError(t, store.Delete(*first.Id))
+	assert.EqualError(t, store.Delete(""), model.ErrWebhooksStorageInvalidID.Error())
+}
@@ -0,0 +1,124 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package webhooks
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/pkg/errors"
+	"github.com/satori/go.uuid"
+)
+
+// Events webhooks can subscribe to
+const (
+	EventDeploymentCreated          = "deployment_created"
+	EventDeploymentFinished         = "deployment_finished"
+	EventDeploymentAborted          = "deployment_aborted"
+	EventDeploymentFailureThreshold = "deployment_failure_threshold"
+	EventArtifactUploaded           = "artifact_uploaded"
+	EventArtifactDeleted            = "artifact_deleted"
+)
+
+// Events returns all events webhooks can subscribe to.
+func Events() []string {
+	return []string{
+		EventDeploymentCreated,
+		EventDeploymentFinished,
+		EventDeploymentAborted,
+		EventDeploymentFailureThreshold,
+		EventArtifactUploaded,
+		EventArtifactDeleted,
+	}
+}
+
+// Errors
+var (
+	ErrInvalidWebhookURL    = errors.New("Webhook URL has to be an absolute http or https URL")
+	ErrMissingWebhookEvents = errors.New("Webhook has to subscribe to at least one event")
+)
+
+// Webhook subscription provided by the user
+type WebhookConstructor struct {
+	// URL events are posted to
+	URL *string `json:"url" valid:"required"`
+
+	// Secret used to sign posted events
+	Secret *string `json:"secret" valid:"length(16|256),required"`
+
+	// Events the webhook subscribes to
+	Events []string `json:"events" valid:"-"`
+}
+
+// Validate checks structure according to valid tags, URL and events.
+func (c *WebhookConstructor) Validate() error {
+	if _, err := govalidator.ValidateStruct(c); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(*c.URL)
+	if err != nil || !u.IsAbs() || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return ErrInvalidWebhookURL
+	}
+
+	if len(c.Events) == 0 {
+		return ErrMissingWebhookEvents
+	}
+	for _, event := range c.Events {
+		if !isEvent(event) {
+			return fmt.Errorf("Unsupported webhook event %s, use one of: %s",
+				event, strings.Join(Events(), ", "))
+		}
+	}
+
+	return nil
+}
+
+func isEvent(event string) bool {
+	for _, e := range Events() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook subscription
+type Webhook struct {
+	Id     *string  `json:"id" bson:"_id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"-"`
+	Events []string `json:"events"`
+
+	Created *time.Time `json:"created"`
+}
+
+// NewWebhookFromConstructor creates webhook with new ID from validated constructor.
+func NewWebhookFromConstructor(constructor *WebhookConstructor) *Webhook {
+
+	now := time.Now()
+	id := uuid.NewV4().String()
+
+	return &Webhook{
+		Id:      &id,
+		URL:     *constructor.URL,
+		Secret:  *constructor.Secret,
+		Events:  constructor.Events,
+		Created: &now,
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package webhooks_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/mendersoftware/deployments/resources/webhooks"
+	. "github.com/mendersoftware/deployments/utils/pointers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookConstructorValidate(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		InputURL    *string
+		InputSecret *string
+		InputEvents []string
+
+		OutputError error
+	}{
+		"valid": {
+			InputURL:    StringToPointer("https://chat.example.com/hooks/123"),
+			InputSecret: StringToPointer("0123456789abcdef"),
+			InputEvents: []string{EventDeploymentCreated, EventArtifactDeleted},
+		},
+		"missing url": {
+			InputSecret: StringToPointer("0123456789abcdef"),
+			InputEvents: []string{EventDeploymentCreated},
+			OutputError: errors.New("URL: non zero value required;"),
+		},
+		"relative url": {
+			InputURL:    StringToPointer("/hooks/123"),
+			InputSecret: StringToPointer("0123456789abcdef"),
+			InputEvents: []string{EventDeploymentCreated},
+			OutputError: ErrInvalidWebhookURL,
+		},
+		"unsupported scheme": {
+			InputURL:    StringToPointer("ftp://chat.example.com/hooks/123"),
+			InputSecret: StringToPointer("0123456789abcdef"),
+			InputEvents: []string{EventDeploymentCreated},
+			OutputError: ErrInvalidWebhookURL,
+		},
+		"short secret": {
+			InputURL:    StringToPointer("https://chat.example.com/hooks/123"),
+			InputSecret: StringToPointer("secret"),
+			InputEvents: []string{EventDeploymentCreated},
+			OutputError: errors.New("Secret: secret does not validate as length(16|256);"),
+		},
+		"no events": {
+			InputURL:    StringToPointer("https://chat.example.com/hooks/123"),
+			InputSecret: StringToPointer("0123456789abcdef"),
+			OutputError: ErrMissingWebhookEvents,
+		},
+		"unsupported event": {
+			InputURL:    StringToPointer("https://chat.example.com/hooks/123"),
+			InputSecret: StringToPointer("0123456789abcdef"),
+			InputEvents: []string{"device_added"},
+			OutputError: errors.New("Unsupported webhook event device_added, use one of: " +
+				"deployment_created, deployment_finished, deployment_aborted, " +
+				"deployment_failure_threshold, artifact_uploaded, artifact_deleted"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Logf("testing case %s", name)
+
+		constructor := &WebhookConstructor{
+			URL:    testCase.InputURL,
+			Secret: testCase.InputSecret,
+			Events: testCase.InputEvents,
+		}
+
+		err := constructor.Validate()
+		if testCase.OutputError != nil {
+			assert.EqualError(t, err, testCase.OutputError.Error())
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestNewWebhookFromConstructor(t *testing.T) {
+
+	t.Parallel()
+
+	webhook := NewWebhookFromConstructor(&WebhookConstructor{
+		URL:    StringToPointer("https://chat.example.com/hooks/123"),
+		Secret: StringToPointer("0123456789abcdef"),
+		Events: []string{EventDeploymentCreated},
+	})
+
+	assert.NotNil(t, webhook.Id)
+	assert.NotNil(t, webhook.Created)
+	assert.Equal(t, "https://chat.example.com/hooks/123", webhook.URL)
+	assert.Equal(t, "0123456789abcdef", webhook.Secret)
+	assert.Equal(t, []string{EventDeploymentCreated}, webhook.Events)
+}
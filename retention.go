@@ -0,0 +1,46 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/mendersoftware/go-lib-micro/log"
+)
+
+// DeploymentsPurger removes finished deployments past their retention period.
+type DeploymentsPurger interface {
+	PurgeDeployments(age time.Duration, archive bool) (int, error)
+}
+
+// RunRetention periodically purges deployments which finished more than
+// `days` days ago, archiving them first if requested. Runs until stop is
+// closed.
+func RunRetention(purger DeploymentsPurger, interval time.Duration, days int, archive bool,
+	stop <-chan struct{}) {
+	l := log.New(log.Ctx{"job": "retention"})
+
+	age := time.Duration(days) * 24 * time.Hour
+
+	runPeriodically(interval, stop, func() {
+		purged, err := purger.PurgeDeployments(age, archive)
+		if err != nil {
+			l.Errorf("purging deployments: %s", err)
+		}
+		if purged != 0 {
+			l.Infof("purged %d deployments older than %d days", purged, days)
+		}
+	})
+}
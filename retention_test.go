@@ -0,0 +1,71 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type purgerFunc func(age time.Duration, archive bool) (int, error)
+
+func (f purgerFunc) PurgeDeployments(age time.Duration, archive bool) (int, error) {
+	return f(age, archive)
+}
+
+func TestRunRetention(t *testing.T) {
+
+	job := newTestJob()
+	purger := purgerFunc(func(age time.Duration, archive bool) (int, error) {
+		if age != 30*24*time.Hour {
+			t.Errorf("unexpected age: %s", age)
+		}
+		if !archive {
+			t.Error("archive not requested")
+		}
+		return job.run()
+	})
+
+	runTestJob(t, "retention", job, func(stop <-chan struct{}) {
+		RunRetention(purger, time.Millisecond, 30, true, stop)
+	})
+}
+
+func TestValidateRetention(t *testing.T) {
+
+	c := viper.New()
+	SetDefaultConfigs(c)
+	if err := ValidateRetention(c); err != nil {
+		t.FailNow()
+	}
+
+	c.Set(SettingRetentionDays, -1)
+	if err := ValidateRetention(c); err == nil {
+		t.FailNow()
+	}
+
+	c.Set(SettingRetentionDays, 90)
+	c.Set(SettingRetentionInterval, "0s")
+	if err := ValidateRetention(c); err == nil {
+		t.FailNow()
+	}
+
+	c.Set(SettingRetentionInterval, "1h")
+	if err := ValidateRetention(c); err != nil {
+		t.FailNow()
+	}
+}
@@ -15,10 +15,18 @@
 package main
 
 import (
+	"net/http"
+
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/mendersoftware/deployments/config"
 	"github.com/mendersoftware/deployments/integration"
+	"github.com/mendersoftware/deployments/resources/audit"
+	auditController "github.com/mendersoftware/deployments/resources/audit/controller"
+	auditModel "github.com/mendersoftware/deployments/resources/audit/model"
+	auditMongo "github.com/mendersoftware/deployments/resources/audit/mongo"
+	auditView "github.com/mendersoftware/deployments/resources/audit/view"
 	deploymentsController "github.com/mendersoftware/deployments/resources/deployments/controller"
+	"github.com/mendersoftware/deployments/resources/deployments/events"
 	"github.com/mendersoftware/deployments/resources/deployments/generator"
 	deploymentsModel "github.com/mendersoftware/deployments/resources/deployments/model"
 	deploymentsMongo "github.com/mendersoftware/deployments/resources/deployments/mongo"
@@ -28,6 +36,10 @@ import (
 	imagesMongo "github.com/mendersoftware/deployments/resources/images/mongo"
 	"github.com/mendersoftware/deployments/resources/images/s3"
 	imagesView "github.com/mendersoftware/deployments/resources/images/view"
+	webhooksController "github.com/mendersoftware/deployments/resources/webhooks/controller"
+	webhooksModel "github.com/mendersoftware/deployments/resources/webhooks/model"
+	webhooksMongo "github.com/mendersoftware/deployments/resources/webhooks/mongo"
+	webhooksView "github.com/mendersoftware/deployments/resources/webhooks/view"
 	"github.com/mendersoftware/deployments/utils/restutil"
 	"github.com/pkg/errors"
 	"gopkg.in/mgo.v2"
@@ -66,19 +78,50 @@ func NewRouter(c config.ConfigReader) (rest.App, error) {
 		return nil, err
 	}
 	deploymentsStorage := deploymentsMongo.NewDeploymentsStorage(dbSession)
+	if err := deploymentsStorage.IndexStorage(); err != nil {
+		return nil, err
+	}
 	deviceDeploymentsStorage := deploymentsMongo.NewDeviceDeploymentsStorage(dbSession)
+	if err := deviceDeploymentsStorage.IndexStorage(); err != nil {
+		return nil, err
+	}
 	deviceDeploymentLogsStorage := deploymentsMongo.NewDeviceDeploymentLogsStorage(dbSession)
+	eventsStorage := deploymentsMongo.NewEventsStorage(dbSession)
+	if err := eventsStorage.EnsureCollection(); err != nil {
+		return nil, err
+	}
 	imagesStorage := imagesMongo.NewSoftwareImagesStorage(dbSession)
 	if err := imagesStorage.IndexStorage(); err != nil {
 		return nil, err
 	}
+	webhooksStorage := webhooksMongo.NewWebhooksStorage(dbSession)
+	deliveriesStorage := webhooksMongo.NewDeliveriesStorage(dbSession)
+	if err := deliveriesStorage.IndexStorage(); err != nil {
+		return nil, err
+	}
+	auditStorage := auditMongo.NewAuditStorage(dbSession)
+	if err := auditStorage.IndexStorage(); err != nil {
+		return nil, err
+	}
 
 	inventory, err := integration.NewMenderAPI(c.GetString(SettingGateway))
 	if err != nil {
 		return nil, errors.Wrap(err, "init inventory client")
 	}
 
+	deviceInventory := generator.NewInventory(inventory)
+
+	eventsHub := events.NewHub(eventsStorage)
+	go eventsHub.Run(events.DefaultRetryInterval, nil)
+
 	// Domain Models
+	webhooksModel := webhooksModel.NewWebhooksModel(webhooksModel.WebhooksModelConfig{
+		WebhooksStorage:   webhooksStorage,
+		DeliveriesStorage: deliveriesStorage,
+		Client:            &http.Client{Timeout: c.GetDuration(SettingWebhooksTimeout)},
+		MaxAttempts:       c.GetInt(SettingWebhooksMaxAttempts),
+	})
+
 	deploymentModel := deploymentsModel.NewDeploymentModel(deploymentsModel.DeploymentsModelConfig{
 		DeploymentsStorage:          deploymentsStorage,
 		DeviceDeploymentsStorage:    deviceDeploymentsStorage,
@@ -86,22 +129,49 @@ func NewRouter(c config.ConfigReader) (rest.App, error) {
 		ImageLinker:                 fileStorage,
 		DeviceDeploymentGenerator: generator.NewImageBasedDeviceDeployment(
 			imagesStorage,
-			generator.NewInventory(inventory),
+			deviceInventory,
 		),
+		DeviceSearcher:   deviceInventory,
+		EventsHub:        eventsHub,
+		Notifier:         webhooksModel,
 		ImageContentType: imagesModel.ImageContentType,
 	})
 
-	imagesModel := imagesModel.NewImagesModel(fileStorage, deploymentModel, imagesStorage)
+	if timeout := c.GetDuration(SettingReaperTimeout); timeout > 0 {
+		go RunReaper(deploymentModel, c.GetDuration(SettingReaperInterval), timeout, nil)
+	}
+
+	if interval := c.GetDuration(SettingExpiryInterval); interval > 0 {
+		go RunExpiry(deploymentModel, interval, nil)
+	}
+
+	if days := c.GetInt(SettingRetentionDays); days > 0 {
+		go RunRetention(deploymentModel, c.GetDuration(SettingRetentionInterval), days,
+			c.GetBool(SettingRetentionArchive), nil)
+	}
+
+	go RunWebhookDeliveries(webhooksModel, c.GetDuration(SettingWebhooksInterval), nil)
+
+	imagesModel := imagesModel.NewImagesModel(fileStorage, deploymentModel, imagesStorage, webhooksModel)
+	auditModel := auditModel.NewAuditModel(auditStorage)
 
 	// Controllers
 	imagesController := imagesController.NewSoftwareImagesController(imagesModel, new(imagesView.RESTView))
 	deploymentsController := deploymentsController.NewDeploymentsController(deploymentModel, new(deploymentsView.DeploymentsView))
+	webhooksController := webhooksController.NewWebhooksController(webhooksModel, new(webhooksView.WebhooksView))
+	auditController := auditController.NewAuditController(auditModel, new(auditView.AuditView))
 
 	// Routing
 	imageRoutes := NewImagesResourceRoutes(imagesController)
 	deploymentsRoutes := NewDeploymentsResourceRoutes(deploymentsController)
 
+	webhooksRoutes := NewWebhooksResourceRoutes(webhooksController)
+	auditRoutes := NewAuditResourceRoutes(auditController)
+
 	routes := append(imageRoutes, deploymentsRoutes...)
+	routes = append(routes, webhooksRoutes...)
+	routes = AuditRoutes(auditController, routes)
+	routes = append(routes, auditRoutes...)
 
 	return rest.MakeRouter(restutil.AutogenOptionsRoutes(restutil.NewOptionsHandler, routes...)...)
 }
@@ -134,10 +204,15 @@ func NewDeploymentsResourceRoutes(controller *deploymentsController.DeploymentsC
 
 		// Deployments
 		rest.Post("/api/0.0.1/deployments", controller.PostDeployment),
+		rest.Post("/api/0.0.1/deployments/preview", controller.PreviewDeployment),
 		rest.Get("/api/0.0.1/deployments", controller.LookupDeployment),
 		rest.Get("/api/0.0.1/deployments/:id", controller.GetDeployment),
+		rest.Delete("/api/0.0.1/deployments/:id", controller.DeleteDeployment),
 		rest.Get("/api/0.0.1/deployments/:id/statistics", controller.GetDeploymentStats),
+		rest.Get("/api/0.0.1/deployments/:id/failures", controller.GetDeploymentFailures),
+		rest.Get("/api/0.0.1/deployments/:id/events", controller.GetDeploymentEvents),
 		rest.Put("/api/0.0.1/deployments/:id/status", controller.AbortDeployment),
+		rest.Post("/api/0.0.1/deployments/:id/retry", controller.RetryDeployment),
 
 		// Devices
 		rest.Get("/api/0.0.1/device/deployments/next", controller.GetDeploymentForDevice),
@@ -149,5 +224,67 @@ func NewDeploymentsResourceRoutes(controller *deploymentsController.DeploymentsC
 			controller.PutDeploymentLogForDevice),
 		rest.Get("/api/0.0.1/deployments/:id/devices/:devid/log",
 			controller.GetDeploymentLogForDevice),
+		rest.Put("/api/0.0.1/deployments/:id/devices/:devid/status",
+			controller.AbortDeviceDeployment),
+		rest.Get("/api/0.0.1/devices/:id/deployments",
+			controller.GetDeviceDeploymentHistory),
 	}
 }
+
+func NewWebhooksResourceRoutes(controller *webhooksController.WebhooksController) []*rest.Route {
+
+	if controller == nil {
+		return []*rest.Route{}
+	}
+
+	return []*rest.Route{
+		rest.Post("/api/0.0.1/webhooks", controller.PostWebhook),
+		rest.Get("/api/0.0.1/webhooks", controller.ListWebhooks),
+
+		rest.Get("/api/0.0.1/webhooks/:id", controller.GetWebhook),
+		rest.Delete("/api/0.0.1/webhooks/:id", controller.DeleteWebhook),
+
+		rest.Get("/api/0.0.1/webhooks/:id/deliveries", controller.ListDeliveries),
+	}
+}
+
+func NewAuditResourceRoutes(controller *auditController.AuditController) []*rest.Route {
+
+	if controller == nil {
+		return []*rest.Route{}
+	}
+
+	return []*rest.Route{
+		rest.Get("/api/0.0.1/audit", controller.ListEntries),
+	}
+}
+
+// auditedRoutes maps mutating management API routes to the audit action recorded for them.
+var auditedRoutes = map[string]string{
+	"POST /api/0.0.1/deployments":                          audit.ActionDeploymentCreate,
+	"POST /api/0.0.1/deployments/:id/retry":                audit.ActionDeploymentRetry,
+	"PUT /api/0.0.1/deployments/:id/status":                audit.ActionDeploymentStatusChange,
+	"DELETE /api/0.0.1/deployments/:id":                    audit.ActionDeploymentDelete,
+	"PUT /api/0.0.1/deployments/:id/devices/:devid/status": audit.ActionDeviceDeploymentAbort,
+	"POST /api/0.0.1/artifacts":                            audit.ActionArtifactUpload,
+	"PUT /api/0.0.1/artifacts/:id":                         audit.ActionArtifactEdit,
+	"DELETE /api/0.0.1/artifacts/:id":                      audit.ActionArtifactDelete,
+	"POST /api/0.0.1/webhooks":                             audit.ActionWebhookCreate,
+	"DELETE /api/0.0.1/webhooks/:id":                       audit.ActionWebhookDelete,
+}
+
+// AuditRoutes wraps handlers of mutating management API routes so that each call is recorded in the audit log.
+func AuditRoutes(controller *auditController.AuditController, routes []*rest.Route) []*rest.Route {
+
+	if controller == nil {
+		return routes
+	}
+
+	for _, route := range routes {
+		if action, ok := auditedRoutes[route.HttpMethod+" "+route.PathExp]; ok {
+			route.Func = controller.Audit(action, route.Func)
+		}
+	}
+
+	return routes
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	auditController "github.com/mendersoftware/deployments/resources/audit/controller"
+	deploymentsController "github.com/mendersoftware/deployments/resources/deployments/controller"
+	imagesController "github.com/mendersoftware/deployments/resources/images/controller"
+	webhooksController "github.com/mendersoftware/deployments/resources/webhooks/controller"
+)
+
+// unauditedRoutes lists management API routes that do not change any state.
+var unauditedRoutes = map[string]bool{
+	"POST /api/0.0.1/deployments/preview": true,
+}
+
+func testRoutes() []*rest.Route {
+
+	routes := NewImagesResourceRoutes(imagesController.NewSoftwareImagesController(nil, nil))
+	routes = append(routes, NewDeploymentsResourceRoutes(deploymentsController.NewDeploymentsController(nil, nil))...)
+	routes = append(routes, NewWebhooksResourceRoutes(webhooksController.NewWebhooksController(nil, nil))...)
+	routes = append(routes, NewAuditResourceRoutes(auditController.NewAuditController(nil, nil))...)
+
+	return routes
+}
+
+func TestAuditedRoutesRegistered(t *testing.T) {
+
+	registered := make(map[string]bool)
+	for _, route := range testRoutes() {
+		registered[route.HttpMethod+" "+route.PathExp] = true
+	}
+
+	for route := range auditedRoutes {
+		if !registered[route] {
+			t.Errorf("audited route %s is not registered", route)
+		}
+	}
+}
+
+func TestMutatingRoutesAudited(t *testing.T) {
+
+	for _, route := range testRoutes() {
+		key := route.HttpMethod + " " + route.PathExp
+
+		if route.HttpMethod == http.MethodGet ||
+			strings.HasPrefix(route.PathExp, "/api/0.0.1/device/") ||
+			unauditedRoutes[key] {
+			continue
+		}
+
+		if _, ok := auditedRoutes[key]; !ok {
+			t.Errorf("mutating management route %s is not audited", key)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package paging
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Paging defaults
+const (
+	DefaultPerPage = 20
+	MaxPerPage     = 500
+)
+
+// Request parameters
+const (
+	ParamPage    = "page"
+	ParamPerPage = "per_page"
+	ParamSort    = "sort"
+)
+
+// Sort directions
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// Response headers
+const (
+	HttpHeaderTotalCount = "X-Total-Count"
+	HttpHeaderLink       = "Link"
+)
+
+// Errors
+var (
+	ErrInvalidPage    = errors.New("Page has to be a positive number")
+	ErrInvalidPerPage = errors.New("Number of entries per page has to be between 1 and 500")
+	ErrInvalidSort    = errors.New("Sort has to be in form of field or field:asc or field:desc")
+)
+
+// Query selects a page of a sorted list.
+type Query struct {
+	// Page number, starting from 1
+	Page int
+
+	// Number of entries per page, no limit if 0
+	PerPage int
+
+	// Field to sort by, default order of the list if empty
+	Sort string
+
+	// Sort in descending order
+	SortDesc bool
+}
+
+// NewQuery creates query for the first page of the list in default order.
+func NewQuery() *Query {
+	return &Query{
+		Page:    1,
+		PerPage: DefaultPerPage,
+	}
+}
+
+// Skip returns number of entries preceding the requested page.
+func (q *Query) Skip() int {
+	if q.Page < 1 {
+		return 0
+	}
+	return (q.Page - 1) * q.PerPage
+}
+
+// SortKey translates requested sort field to storage key using given
+// mapping, default field is used if no sort was requested. Key of descending
+// sort is prefixed with "-".
+func (q *Query) SortKey(keys map[string]string, defaultField string) string {
+	field := q.Sort
+	if field == "" {
+		field = defaultField
+	}
+
+	if q.SortDesc {
+		return "-" + keys[field]
+	}
+	return keys[field]
+}
+
+// ParseQuery reads page, per_page and sort request parameters. Sort is
+// accepted only for one of listed fields. The whole list is requested if
+// neither page nor per_page is given, pages have DefaultPerPage entries unless
+// requested otherwise.
+func ParseQuery(vals url.Values, sortFields ...string) (Query, error) {
+	query := *NewQuery()
+	if vals.Get(ParamPage) == "" && vals.Get(ParamPerPage) == "" {
+		query.PerPage = 0
+	}
+
+	return parseQuery(vals, query, sortFields)
+}
+
+// ParsePagedQuery is like ParseQuery, but requests the first page of
+// DefaultPerPage entries if neither page nor per_page is given.
+func ParsePagedQuery(vals url.Values, sortFields ...string) (Query, error) {
+	return parseQuery(vals, *NewQuery(), sortFields)
+}
+
+func parseQuery(vals url.Values, query Query, sortFields []string) (Query, error) {
+	if page := vals.Get(ParamPage); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return query, ErrInvalidPage
+		}
+		query.Page = n
+	}
+
+	if perPage := vals.Get(ParamPerPage); perPage != "" {
+		n, err := strconv.Atoi(perPage)
+		if err != nil || n < 1 || n > MaxPerPage {
+			return query, ErrInvalidPerPage
+		}
+		query.PerPage = n
+	}
+
+	if sort := vals.Get(ParamSort); sort != "" {
+		parts := strings.SplitN(sort, ":", 2)
+		if len(parts) == 2 {
+			switch parts[1] {
+			case SortAsc:
+			case SortDesc:
+				query.SortDesc = true
+			default:
+				return query, ErrInvalidSort
+			}
+		}
+
+		for _, field := range sortFields {
+			if parts[0] == field {
+				query.Sort = field
+			}
+		}
+		if query.Sort == "" {
+			return query, fmt.Errorf("Sorting by %s is not supported, use one of: %s",
+				parts[0], strings.Join(sortFields, ", "))
+		}
+	}
+
+	return query, nil
+}
+
+// Links returns RFC 5988 links to the first, previous, next and last page
+// of the list, relative to the requested URL.
+func Links(u *url.URL, query Query, total int) []string {
+	if query.PerPage < 1 {
+		return nil
+	}
+
+	last := (total + query.PerPage - 1) / query.PerPage
+	if last < 1 {
+		last = 1
+	}
+
+	link := func(page int, rel string) string {
+		vals := u.Query()
+		vals.Set(ParamPage, strconv.Itoa(page))
+		vals.Set(ParamPerPage, strconv.Itoa(query.PerPage))
+		target := url.URL{Path: u.Path, RawQuery: vals.Encode()}
+		return fmt.Sprintf("<%s>; rel=\"%s\"", target.String(), rel)
+	}
+
+	links := []string{link(1, "first")}
+	if query.Page > 1 {
+		links = append(links, link(query.Page-1, "prev"))
+	}
+	if query.Page < last {
+		links = append(links, link(query.Page+1, "next"))
+	}
+	links = append(links, link(last, "last"))
+
+	return links
+}
@@ -0,0 +1,215 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package paging_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	. "github.com/mendersoftware/deployments/utils/paging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		vals   url.Values
+		fields []string
+
+		query Query
+		err   error
+	}{
+		"defaults": {
+			vals:  url.Values{},
+			query: Query{Page: 1},
+		},
+		"page only": {
+			vals:  url.Values{"page": {"2"}},
+			query: Query{Page: 2, PerPage: DefaultPerPage},
+		},
+		"per page only": {
+			vals:  url.Values{"per_page": {"50"}},
+			query: Query{Page: 1, PerPage: 50},
+		},
+		"page": {
+			vals:  url.Values{"page": {"3"}, "per_page": {"50"}},
+			query: Query{Page: 3, PerPage: 50},
+		},
+		"bad page": {
+			vals: url.Values{"page": {"0"}},
+			err:  ErrInvalidPage,
+		},
+		"page not a number": {
+			vals: url.Values{"page": {"foo"}},
+			err:  ErrInvalidPage,
+		},
+		"too many per page": {
+			vals: url.Values{"per_page": {"501"}},
+			err:  ErrInvalidPerPage,
+		},
+		"sort": {
+			vals:   url.Values{"sort": {"name"}},
+			fields: []string{"created", "name"},
+			query:  Query{Page: 1, Sort: "name"},
+		},
+		"sort asc": {
+			vals:   url.Values{"sort": {"name:asc"}},
+			fields: []string{"created", "name"},
+			query:  Query{Page: 1, Sort: "name"},
+		},
+		"sort desc": {
+			vals:   url.Values{"sort": {"created:desc"}},
+			fields: []string{"created", "name"},
+			query:  Query{Page: 1, Sort: "created", SortDesc: true},
+		},
+		"bad sort direction": {
+			vals:   url.Values{"sort": {"created:up"}},
+			fields: []string{"created"},
+			err:    ErrInvalidSort,
+		},
+		"unsupported sort field": {
+			vals:   url.Values{"sort": {"size"}},
+			fields: []string{"created", "name"},
+			err:    errors.New("Sorting by size is not supported, use one of: created, name"),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Logf("testing case %s", name)
+
+		query, err := ParseQuery(tc.vals, tc.fields...)
+		if tc.err != nil {
+			assert.EqualError(t, err, tc.err.Error())
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, tc.query, query)
+		}
+	}
+}
+
+func TestParsePagedQuery(t *testing.T) {
+
+	t.Parallel()
+
+	testCases := map[string]struct {
+		vals url.Values
+
+		query Query
+		err   error
+	}{
+		"defaults": {
+			vals:  url.Values{},
+			query: Query{Page: 1, PerPage: DefaultPerPage},
+		},
+		"page": {
+			vals:  url.Values{"page": {"3"}, "per_page": {"50"}},
+			query: Query{Page: 3, PerPage: 50},
+		},
+		"bad per page": {
+			vals: url.Values{"per_page": {"0"}},
+			err:  ErrInvalidPerPage,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Logf("testing case %s", name)
+
+		query, err := ParsePagedQuery(tc.vals)
+		if tc.err != nil {
+			assert.EqualError(t, err, tc.err.Error())
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, tc.query, query)
+		}
+	}
+}
+
+func TestQuerySkip(t *testing.T) {
+
+	t.Parallel()
+
+	assert.Equal(t, 0, NewQuery().Skip())
+	assert.Equal(t, 40, (&Query{Page: 3, PerPage: 20}).Skip())
+	assert.Equal(t, 0, (&Query{}).Skip())
+}
+
+func TestQuerySortKey(t *testing.T) {
+
+	t.Parallel()
+
+	keys := map[string]string{
+		"name":    "meta.name",
+		"created": "created",
+	}
+
+	assert.Equal(t, "created", (&Query{}).SortKey(keys, "created"))
+	assert.Equal(t, "meta.name", (&Query{Sort: "name"}).SortKey(keys, "created"))
+	assert.Equal(t, "-meta.name", (&Query{Sort: "name", SortDesc: true}).SortKey(keys, "created"))
+}
+
+func TestLinks(t *testing.T) {
+
+	t.Parallel()
+
+	u, err := url.Parse("/api/0.0.1/deployments?page=2&per_page=10&status=finished")
+	assert.NoError(t, err)
+
+	testCases := map[string]struct {
+		query Query
+		total int
+
+		links []string
+	}{
+		"middle page": {
+			query: Query{Page: 2, PerPage: 10},
+			total: 35,
+			links: []string{
+				`</api/0.0.1/deployments?page=1&per_page=10&status=finished>; rel="first"`,
+				`</api/0.0.1/deployments?page=1&per_page=10&status=finished>; rel="prev"`,
+				`</api/0.0.1/deployments?page=3&per_page=10&status=finished>; rel="next"`,
+				`</api/0.0.1/deployments?page=4&per_page=10&status=finished>; rel="last"`,
+			},
+		},
+		"single page": {
+			query: Query{Page: 1, PerPage: 10},
+			total: 10,
+			links: []string{
+				`</api/0.0.1/deployments?page=1&per_page=10&status=finished>; rel="first"`,
+				`</api/0.0.1/deployments?page=1&per_page=10&status=finished>; rel="last"`,
+			},
+		},
+		"empty list": {
+			query: Query{Page: 1, PerPage: 10},
+			total: 0,
+			links: []string{
+				`</api/0.0.1/deployments?page=1&per_page=10&status=finished>; rel="first"`,
+				`</api/0.0.1/deployments?page=1&per_page=10&status=finished>; rel="last"`,
+			},
+		},
+		"no limit": {
+			query: Query{Page: 1},
+			total: 10,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Logf("testing case %s", name)
+
+		assert.Equal(t, tc.links, Links(u, tc.query, tc.total))
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package params
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// ParseTime reads optional RFC 3339 time parameter. Returns nil if the
+// parameter is not set.
+func ParseTime(vals url.Values, param string) (*time.Time, error) {
+	val := vals.Get(param)
+	if val == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s time %s, RFC 3339 format expected", param, val)
+	}
+	return &t, nil
+}
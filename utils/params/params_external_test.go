@@ -0,0 +1,64 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package params_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/mendersoftware/deployments/utils/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTime(t *testing.T) {
+
+	t.Parallel()
+
+	ts := time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		vals url.Values
+
+		time *time.Time
+		err  error
+	}{
+		"not set": {
+			vals: url.Values{},
+		},
+		"time": {
+			vals: url.Values{"created_after": {"2017-01-01T12:00:00Z"}},
+			time: &ts,
+		},
+		"bad time": {
+			vals: url.Values{"created_after": {"yesterday"}},
+			err:  errors.New("invalid created_after time yesterday, RFC 3339 format expected"),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Logf("testing case %s", name)
+
+		out, err := ParseTime(tc.vals, "created_after")
+		if tc.err != nil {
+			assert.EqualError(t, err, tc.err.Error())
+			assert.Nil(t, out)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, tc.time, out)
+		}
+	}
+}
@@ -23,3 +23,11 @@ func StringToPointer(str string) *string {
 func TimeToPointer(time time.Time) *time.Time {
 	return &time
 }
+
+func BoolToPointer(b bool) *bool {
+	return &b
+}
+
+func IntToPointer(i int) *int {
+	return &i
+}
@@ -30,3 +30,13 @@ func TestTimeToPointer(t *testing.T) {
 	expected := time.Now()
 	assert.Equal(t, &expected, TimeToPointer(expected))
 }
+
+func TestBoolToPointer(t *testing.T) {
+	expected := true
+	assert.Equal(t, &expected, BoolToPointer(expected))
+}
+
+func TestIntToPointer(t *testing.T) {
+	expected := 42
+	assert.Equal(t, &expected, IntToPointer(expected))
+}
@@ -0,0 +1,42 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/mendersoftware/go-lib-micro/log"
+)
+
+// WebhookDeliverer attempts pending webhook deliveries.
+type WebhookDeliverer interface {
+	DeliverPending() (int, error)
+}
+
+// RunWebhookDeliveries periodically attempts webhook deliveries which are
+// due. Runs until stop is closed.
+func RunWebhookDeliveries(deliverer WebhookDeliverer, interval time.Duration, stop <-chan struct{}) {
+	l := log.New(log.Ctx{"job": "webhooks"})
+
+	runPeriodically(interval, stop, func() {
+		delivered, err := deliverer.DeliverPending()
+		if err != nil {
+			l.Errorf("delivering webhooks: %s", err)
+		}
+		if delivered != 0 {
+			l.Infof("attempted %d webhook deliveries", delivered)
+		}
+	})
+}
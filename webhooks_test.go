@@ -0,0 +1,67 @@
+// Copyright 2016 Mender Software AS
+//
+//    Licensed under the Apache License, Version 2.0 (the "License");
+//    you may not use this file except in compliance with the License.
+//    You may obtain a copy of the License at
+//
+//        http://www.apache.org/licenses/LICENSE-2.0
+//
+//    Unless required by applicable law or agreed to in writing, software
+//    distributed under the License is distributed on an "AS IS" BASIS,
+//    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//    See the License for the specific language governing permissions and
+//    limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type delivererFunc func() (int, error)
+
+func (f delivererFunc) DeliverPending() (int, error) {
+	return f()
+}
+
+func TestRunWebhookDeliveries(t *testing.T) {
+
+	job := newTestJob()
+	runTestJob(t, "webhook deliveries", job, func(stop <-chan struct{}) {
+		RunWebhookDeliveries(delivererFunc(job.run), time.Millisecond, stop)
+	})
+}
+
+func TestValidateWebhooks(t *testing.T) {
+
+	c := viper.New()
+	SetDefaultConfigs(c)
+	if err := ValidateWebhooks(c); err != nil {
+		t.FailNow()
+	}
+
+	c.Set(SettingWebhooksInterval, "0s")
+	if err := ValidateWebhooks(c); err == nil {
+		t.FailNow()
+	}
+
+	c.Set(SettingWebhooksInterval, "10s")
+	c.Set(SettingWebhooksTimeout, "0s")
+	if err := ValidateWebhooks(c); err == nil {
+		t.FailNow()
+	}
+
+	c.Set(SettingWebhooksTimeout, "10s")
+	c.Set(SettingWebhooksMaxAttempts, 0)
+	if err := ValidateWebhooks(c); err == nil {
+		t.FailNow()
+	}
+
+	c.Set(SettingWebhooksMaxAttempts, 3)
+	if err := ValidateWebhooks(c); err != nil {
+		t.FailNow()
+	}
+}